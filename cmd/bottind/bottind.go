@@ -23,9 +23,21 @@ const (
 	CodeLDAP          = -3
 	CodeBottin        = -4
 	CodeRun           = -5
+	CodeLDIF          = -6
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			export(os.Args[2:])
+			return
+		case "import":
+			importLDIF(os.Args[2:])
+			return
+		}
+	}
+
 	fs, getLogConfig := log.LogFlagSet()
 	flag.CommandLine.AddFlagSet(fs)
 	fs, getObservabilityConfig := observability.ObservabilityFlagSet("bottin")
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/teapotovh/teapot/lib/log"
+	"github.com/teapotovh/teapot/lib/run"
+	"github.com/teapotovh/teapot/service/bottin"
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+// task runs a one-off function once the store has started, stopping all
+// components when it completes.
+type task struct {
+	fn     func(ctx context.Context) error
+	cancel context.CancelFunc
+	err    error
+}
+
+// Run implements run.Runnable.
+func (t *task) Run(ctx context.Context, notify run.Notify) error {
+	notify.Notify()
+
+	t.err = t.fn(ctx)
+	t.cancel()
+
+	return nil
+}
+
+// runWithStore parses the command line flags, starts the configured store
+// and runs fn against it. Logs are written to stderr, as stdout may be used
+// for the LDIF output.
+func runWithStore(
+	fs *flag.FlagSet,
+	args []string,
+	fn func(context.Context, bottin.BottinConfig, store.Store, *slog.Logger) error,
+) {
+	logFS, getLogConfig := log.LogFlagSet()
+	fs.AddFlagSet(logFS)
+	bottinFS, getBottinConfig := bottin.BottinFlagSet()
+	fs.AddFlagSet(bottinFS)
+
+	if err := fs.Parse(args); err != nil {
+		slog.Error("error while parsing flags", "err", err) //nolint:sloglint
+		os.Exit(CodeLDIF)
+	}
+
+	logConfig := getLogConfig()
+	logConfig.Output = os.Stderr
+
+	logger, err := log.NewLogger(logConfig)
+	if err != nil {
+		slog.Error("error while configuring the logger", "err", err) //nolint:sloglint
+		os.Exit(CodeLog)
+	}
+
+	config := getBottinConfig()
+
+	store, err := store.NewStore(config.Store, logger.With("sub", "store"))
+	if err != nil {
+		logger.Error("error while initializing bottin store", "err", err)
+		os.Exit(CodeBottin)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t := task{
+		fn: func(ctx context.Context) error {
+			return fn(ctx, config, store, logger)
+		},
+		cancel: cancel,
+	}
+
+	run := run.NewRun(run.RunConfig{Timeout: 5 * time.Second}, logger.With("sub", "run"))
+	run.Add("bottin/store", store, nil)
+	run.Add(fs.Name(), &t, nil)
+
+	if err := run.Run(ctx); err != nil {
+		logger.Error("error while running bottin components", "err", err)
+		os.Exit(CodeRun)
+	}
+
+	if t.err != nil {
+		logger.Error("error while running command", "command", fs.Name(), "err", t.err)
+		os.Exit(CodeLDIF)
+	}
+}
+
+// export dumps the directory as LDIF.
+func export(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	output := fs.StringP("output", "o", "-", "the file to write the LDIF to, or - for stdout")
+
+	runWithStore(fs, args, func(
+		ctx context.Context,
+		config bottin.BottinConfig,
+		s store.Store,
+		logger *slog.Logger,
+	) error {
+		var (
+			w    io.Writer = os.Stdout
+			file *os.File
+		)
+
+		if *output != "-" {
+			var err error
+
+			file, err = os.Create(*output)
+			if err != nil {
+				return err
+			}
+
+			w = file
+		}
+
+		count, err := bottin.Export(ctx, s, config.BaseDN, w)
+
+		// The export is only complete once the file has been closed, as
+		// closing may flush buffered data and report write errors
+		if file != nil {
+			if closeErr := file.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("error while closing %q: %w", *output, closeErr)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		logger.InfoContext(ctx, "exported entries", "count", count)
+
+		return nil
+	})
+}
+
+// importLDIF loads entries from LDIF into the directory.
+func importLDIF(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	input := fs.StringP("input", "i", "-", "the file to read the LDIF from, or - for stdin")
+	dryRun := fs.Bool("dry-run", false, "validate the LDIF without storing any entry")
+	replace := fs.Bool("replace", false, "replace entries which already exist, instead of failing")
+
+	runWithStore(fs, args, func(
+		ctx context.Context,
+		config bottin.BottinConfig,
+		s store.Store,
+		logger *slog.Logger,
+	) error {
+		var r io.Reader = os.Stdin
+
+		if *input != "-" {
+			file, err := os.Open(*input)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			r = file
+		}
+
+		importConfig := bottin.ImportConfig{
+			BaseDN:  config.BaseDN,
+			DryRun:  *dryRun,
+			Replace: *replace,
+		}
+
+		if config.SchemaCheck && len(config.Schemas) > 0 {
+			schema, err := bottin.LoadSchema(config.Schemas)
+			if err != nil {
+				return fmt.Errorf("error while loading schema: %w", err)
+			}
+
+			importConfig.Schema = schema
+		}
+
+		count, err := bottin.Import(ctx, s, r, importConfig, logger)
+		if err != nil {
+			return err
+		}
+
+		logger.InfoContext(ctx, "imported entries", "count", count, "dry_run", *dryRun)
+
+		return nil
+	})
+}
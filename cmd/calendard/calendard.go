@@ -85,6 +85,7 @@ func main() {
 
 	run.Add("calendar/ldap", calendar.LDAPFactory(), nil)
 	run.Add("calendar/store", calendar.Store(), nil)
+	run.Add("calendar/reminder", calendar.Reminder(), nil)
 	run.Add("httpsrv", httpsrv, nil)
 	run.Add("observability", observability, nil)
 
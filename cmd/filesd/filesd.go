@@ -16,6 +16,7 @@ import (
 	"github.com/teapotovh/teapot/lib/observability"
 	"github.com/teapotovh/teapot/lib/run"
 	"github.com/teapotovh/teapot/service/files"
+	"github.com/teapotovh/teapot/service/files/sftp"
 	"github.com/teapotovh/teapot/service/files/web"
 	"github.com/teapotovh/teapot/service/files/webdav"
 )
@@ -28,6 +29,7 @@ const (
 	CodeWebDav        = -5
 	CodeWeb           = -6
 	CodeRun           = -7
+	CodeSFTP          = -8
 )
 
 const (
@@ -46,8 +48,12 @@ func main() {
 	flag.CommandLine.AddFlagSet(fs)
 	fs, getFilesConfig := files.FilesFlagSet()
 	flag.CommandLine.AddFlagSet(fs)
+	fs, getWebDavConfig := webdav.WebDavFlagSet()
+	flag.CommandLine.AddFlagSet(fs)
 	fs, getWebConfig := web.WebFlagSet()
 	flag.CommandLine.AddFlagSet(fs)
+	fs, getSFTPConfig := sftp.SFTPFlagSet()
+	flag.CommandLine.AddFlagSet(fs)
 	flag.Parse()
 
 	logger, err := log.NewLogger(getLogConfig())
@@ -89,13 +95,14 @@ func main() {
 	defer stop()
 
 	if slices.Contains(*components, "webdav") {
-		webdav, err := webdav.NewWebDav(files, webdav.WebDavConfig{}, logger.With("sub", "webdav"))
+		webdav, err := webdav.NewWebDav(files, getWebDavConfig(), logger.With("sub", "webdav"))
 		if err != nil {
 			logger.Error("error while initiating the webdav subsystem", "err", err)
 			os.Exit(CodeWeb)
 		}
 
 		httpsrv.Register("webdav", webdav, HTTPWebDavPrefix)
+		run.Add("webdav", webdav, nil)
 		observability.RegisterMetrics(webdav)
 	}
 
@@ -109,6 +116,18 @@ func main() {
 		httpsrv.Register("web", web, HTTPWebPrefix)
 	}
 
+	if slices.Contains(*components, "sftp") {
+		sftp, err := sftp.NewSFTP(files, getSFTPConfig(), logger.With("sub", "sftp"))
+		if err != nil {
+			logger.Error("error while initiating the sftp subsystem", "err", err)
+			os.Exit(CodeSFTP)
+		}
+
+		run.Add("sftp", sftp, nil)
+		observability.RegisterMetrics(sftp)
+	}
+
+	run.Add("files", files, nil)
 	run.Add("httpsrv", httpsrv, nil)
 	run.Add("observability", observability, nil)
 
@@ -0,0 +1,69 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-ldap/ldap/v3"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/teapotovh/teapot/lib/observability"
+)
+
+var ErrGroupNotFound = errors.New("group not found")
+
+// Group is an abstracted view over a group entry in LDAP.
+type Group struct {
+	DN      string
+	Name    string
+	Members []string
+}
+
+// Group looks up a group stored below the groups DN by its name, i.e., its
+// common name.
+func (c *Client) Group(ctx context.Context, name string) (group *Group, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "Client.Group")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	span.SetAttributes(attribute.String("name", name))
+
+	defer func() {
+		if err != nil {
+			c.errored = true
+		}
+	}()
+
+	searchRequest := ldap.NewSearchRequest(
+		c.groupsDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		fmt.Sprintf("(cn=%s)", ldap.EscapeFilter(name)),
+		[]string{"cn", "member"},
+		nil,
+	)
+
+	search, err := search(ctx, c.metrics, c.conn, searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("error while performing search for group: %w", err)
+	}
+
+	if len(search.Entries) == 0 {
+		return nil, ErrGroupNotFound
+	}
+
+	if len(search.Entries) > 1 {
+		return nil, fmt.Errorf("%w: found %d groups named %q", ErrTooManyMatches, len(search.Entries), name)
+	}
+
+	entry := search.Entries[0]
+
+	return &Group{
+		DN:      entry.DN,
+		Name:    entry.GetAttributeValue("cn"),
+		Members: entry.GetAttributeValues("member"),
+	}, nil
+}
@@ -128,3 +128,35 @@ func (c *Client) User(ctx context.Context, username string) (user *User, err err
 
 	return c.mapUser(entry)
 }
+
+// UserAttributes returns the values of some attributes of a user, keyed by
+// the given names and matched regardless of case. It allows services to read
+// attributes which are specific to them, and thus not part of User.
+func (c *Client) UserAttributes(
+	ctx context.Context,
+	username string,
+	names ...string,
+) (attributes map[string][]string, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "Client.UserAttributes")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	span.SetAttributes(attribute.String("username", username), attribute.StringSlice("attributes", names))
+
+	defer func() {
+		if err != nil {
+			c.errored = true
+		}
+	}()
+
+	entry, err := c.find(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("error while looking up user: %w", err)
+	}
+
+	attributes = make(map[string][]string, len(names))
+	for _, name := range names {
+		attributes[name] = entry.GetEqualFoldAttributeValues(name)
+	}
+
+	return attributes, nil
+}
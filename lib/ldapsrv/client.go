@@ -26,6 +26,7 @@ var (
 type client[T any] struct {
 	rwc         net.Conn
 	chanOut     chan *ldap.LDAPMessage
+	chanFlush   chan chan struct{}
 	srv         *LDAPSrv[T]
 	br          *bufio.Reader
 	bw          *bufio.Writer
@@ -99,15 +100,25 @@ func (c *client[T]) serve(serveCtx context.Context) {
 	// buffered to 20 means that If client is slow to handler responses, Server
 	// Handlers will stop to send more respones
 	c.chanOut = make(chan *ldap.LDAPMessage)
+	c.chanFlush = make(chan chan struct{})
 	c.writeDone = make(chan bool)
-	// for each message in c.chanOut send it to client
+	// for each message in c.chanOut send it to client, and notify flush
+	// requests once all the messages queued before them have been written
 	go func() {
-		for msg := range c.chanOut {
-			if err := c.writeMessage(serveCtx, msg); err != nil {
-				c.logger.ErrorContext(serveCtx, "error while marshaling response", "err", err, "msg", msg)
+		defer close(c.writeDone)
+		for {
+			select {
+			case msg, ok := <-c.chanOut:
+				if !ok {
+					return
+				}
+				if err := c.writeMessage(serveCtx, msg); err != nil {
+					c.logger.ErrorContext(serveCtx, "error while marshaling response", "err", err, "msg", msg)
+				}
+			case flushed := <-c.chanFlush:
+				close(flushed)
 			}
 		}
-		close(c.writeDone)
 	}()
 
 	// Listen for server signal to shutdown
@@ -181,7 +192,7 @@ func (c *client[T]) serve(serveCtx context.Context) {
 		var traceparent, tracestate string
 		if controls := message.Controls(); controls != nil {
 			for _, control := range *controls {
-				if control.ControlType() == "1.3.6.1.4.1.1337.1" &&
+				if control.ControlType() == ControlTraceContext &&
 					control.ControlValue() != nil {
 					parts := bytes.SplitN(control.ControlValue().Bytes(), []byte{0}, 2)
 
@@ -231,6 +242,8 @@ func (c *client[T]) serve(serveCtx context.Context) {
 type ResponseWriter interface {
 	// Write writes the LDAPResponse to the connection as part of an LDAP reply.
 	Write(po ldap.ProtocolOp)
+	// WriteWithControls writes the LDAPResponse along with the given controls.
+	WriteWithControls(po ldap.ProtocolOp, controls ldap.Controls)
 }
 
 type responseWriterImpl struct {
@@ -239,9 +252,17 @@ type responseWriterImpl struct {
 }
 
 func (w responseWriterImpl) Write(po ldap.ProtocolOp) {
+	w.WriteWithControls(po, nil)
+}
+
+func (w responseWriterImpl) WriteWithControls(po ldap.ProtocolOp, controls ldap.Controls) {
 	m := ldap.NewLDAPMessageWithProtocolOp(po)
 	m.SetMessageID(w.messageID)
 
+	if len(controls) > 0 {
+		m.SetControls(controls.Pointer())
+	}
+
 	w.chanOut <- m
 }
 
@@ -48,3 +48,11 @@ const (
 	NoticeOfGetConnectionID ldap.LDAPOID = "1.3.6.1.4.1.26027.1.6.2"
 	NoticeOfPasswordModify  ldap.LDAPOID = "1.3.6.1.4.1.4203.1.11.1"
 )
+
+// Request and response controls types.
+const (
+	ControlTraceContext           ldap.LDAPOID = "1.3.6.1.4.1.1337.1"
+	ControlPagedResults           ldap.LDAPOID = "1.2.840.113556.1.4.319"
+	ControlServerSideSort         ldap.LDAPOID = "1.2.840.113556.1.4.473"
+	ControlServerSideSortResponse ldap.LDAPOID = "1.2.840.113556.1.4.474"
+)
@@ -0,0 +1,98 @@
+package ldapsrv
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+)
+
+const ContextKeyControls ContextKey = "controls"
+
+// ControlsHandler can be implemented by a Handler to advertise the request
+// controls it understands. Requests carrying a critical control which is not
+// supported by either ldapsrv or the handler are rejected with
+// ErrUnavailableCriticalExtension, as mandated by RFC 4511.
+type ControlsHandler interface {
+	SupportedControls() []ldap.LDAPOID
+}
+
+// builtinControls are the controls which are handled by ldapsrv itself.
+var builtinControls = []ldap.LDAPOID{ControlTraceContext}
+
+// controls holds the controls sent by the client with a request, and
+// the ones to be sent back with the final response.
+type controls struct {
+	request ldap.Controls
+
+	mu       sync.Mutex
+	response ldap.Controls
+}
+
+func newControls(message *ldap.LDAPMessage) *controls {
+	var c controls
+	if message.Controls() != nil {
+		c.request = *message.Controls()
+	}
+
+	return &c
+}
+
+func (c *controls) responseControls() ldap.Controls {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.response)
+}
+
+// RequestControls returns all the controls sent by the client along with
+// the request being handled.
+func RequestControls(ctx context.Context) ldap.Controls {
+	if c, ok := ctx.Value(ContextKeyControls).(*controls); ok {
+		return c.request
+	}
+
+	return nil
+}
+
+// RequestControl returns the control with the given type sent by the client
+// along with the request being handled, if any.
+func RequestControl(ctx context.Context, controlType ldap.LDAPOID) (ldap.Control, bool) {
+	for _, control := range RequestControls(ctx) {
+		if control.ControlType() == controlType {
+			return control, true
+		}
+	}
+
+	return ldap.Control{}, false
+}
+
+// AddResponseControl attaches a control to the final response of the request
+// being handled (i.e., the SearchResultDone for searches).
+func AddResponseControl(ctx context.Context, control ldap.Control) {
+	if c, ok := ctx.Value(ContextKeyControls).(*controls); ok {
+		c.mu.Lock()
+		c.response = append(c.response, control)
+		c.mu.Unlock()
+	}
+}
+
+// checkControls returns an error if the request carries a critical control
+// that is not supported.
+func (s *LDAPSrv[T]) checkControls(ctx context.Context, request ldap.Controls) error {
+	supported := SupportedControls(ctx)
+
+	for _, control := range request {
+		if control.Criticality().Bool() && !slices.Contains(supported, control.ControlType()) {
+			return fmt.Errorf(
+				"(%w) control %s is not supported",
+				ErrUnavailableCriticalExtension,
+				control.ControlType(),
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package ldapsrv
+
+import (
+	"context"
+	"slices"
+
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+)
+
+const (
+	ContextKeyExtendedResponse ContextKey = "extended-response"
+	ContextKeyCapabilities     ContextKey = "capabilities"
+)
+
+// ExtensionsHandler can be implemented by a Handler to advertise the extended
+// operations it supports, in addition to the ones handled by ldapsrv itself.
+type ExtensionsHandler interface {
+	SupportedExtensions() []ldap.LDAPOID
+}
+
+// extendedResponse holds the responseName and responseValue to be sent back
+// for an extended request. By default, the responseName is the requestName.
+type extendedResponse struct {
+	set   bool
+	name  *ldap.LDAPOID
+	value *ldap.OCTETSTRING
+}
+
+// SetExtendedResponse sets the responseName and responseValue of the response
+// to the extended request being handled. Either may be nil, in which case it
+// is omitted from the response (i.e., WhoAmI responses have no name).
+func SetExtendedResponse(ctx context.Context, name *ldap.LDAPOID, value *ldap.OCTETSTRING) {
+	if res, ok := ctx.Value(ContextKeyExtendedResponse).(*extendedResponse); ok {
+		res.set = true
+		res.name = name
+		res.value = value
+	}
+}
+
+// capabilities are the features supported by the server, as a whole.
+type capabilities struct {
+	controls   []ldap.LDAPOID
+	extensions []ldap.LDAPOID
+}
+
+// SupportedControls returns the request controls supported by the server,
+// either by ldapsrv itself or by the handler.
+func SupportedControls(ctx context.Context) []ldap.LDAPOID {
+	if c, ok := ctx.Value(ContextKeyCapabilities).(*capabilities); ok {
+		return slices.Clone(c.controls)
+	}
+
+	return nil
+}
+
+// SupportedExtensions returns the extended operations supported by the
+// server, either by ldapsrv itself or by the handler.
+func SupportedExtensions(ctx context.Context) []ldap.LDAPOID {
+	if c, ok := ctx.Value(ContextKeyCapabilities).(*capabilities); ok {
+		return slices.Clone(c.extensions)
+	}
+
+	return nil
+}
+
+// supportedCapabilities collects the capabilities of ldapsrv and its handler.
+func (s *LDAPSrv[T]) supportedCapabilities() *capabilities {
+	controls := builtinControls
+	if ch, ok := s.handler.(ControlsHandler); ok {
+		controls = slices.Concat(controls, ch.SupportedControls())
+	}
+
+	var extensions []ldap.LDAPOID
+
+	// StartTLS is only available if a certificate has been configured
+	if s.certificate != nil {
+		extensions = append(extensions, NoticeOfStartTLS)
+	}
+
+	if eh, ok := s.handler.(ExtensionsHandler); ok {
+		extensions = slices.Concat(extensions, eh.SupportedExtensions())
+	}
+
+	return &capabilities{controls: controls, extensions: extensions}
+}
@@ -16,13 +16,39 @@ func LDAPSrvFlagSet() (*flag.FlagSet, func() LDAPSrvConfig) {
 	shutdownDelay := fs.Duration("ldapsrv-shutdown-delay", time.Second, "allowed wait time for graceful shutdown")
 	readTimeout := fs.Duration("ldapsrv-read-timeout", 0, "read timeout on the TCP connection (0 to disable)")
 	writeTimeout := fs.Duration("ldapsrv-write-timeout", 0, "write timeout on the TCP connection (0 to disable)")
+	tlsPort := fs.Int16(
+		"ldapsrv-tls-port",
+		0,
+		"the port on which to open the LDAPS (implicit TLS) server (0 to disable)",
+	)
+	tlsCert := fs.String("ldapsrv-tls-cert", "", "path to the PEM certificate used for LDAPS and StartTLS")
+	tlsKey := fs.String("ldapsrv-tls-key", "", "path to the PEM private key used for LDAPS and StartTLS")
+	tlsReloadInterval := fs.Duration(
+		"ldapsrv-tls-reload-interval",
+		time.Minute,
+		"how often the TLS certificate files are checked for changes",
+	)
+	requireTLSBind := fs.Bool("ldapsrv-require-tls-bind", false, "refuse simple binds on unencrypted connections")
 
 	return fs, func() LDAPSrvConfig {
+		var tlsAddress string
+		if *tlsPort != 0 {
+			tlsAddress = net.JoinHostPort(ip.String(), strconv.Itoa(int(*tlsPort)))
+		}
+
 		return LDAPSrvConfig{
 			Address:       net.JoinHostPort(ip.String(), strconv.Itoa(int(*port))),
+			TLSAddress:    tlsAddress,
 			ShutdownDelay: *shutdownDelay,
 			ReadTimeout:   *readTimeout,
 			WriteTimeout:  *writeTimeout,
+
+			TLS: TLSConfig{
+				CertFile:       *tlsCert,
+				KeyFile:        *tlsKey,
+				ReloadInterval: *tlsReloadInterval,
+			},
+			RequireTLSBind: *requireTLSBind,
 		}
 	}
 }
@@ -2,10 +2,12 @@ package message
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
 	ErrCriticalityNotSpecified = errors.New("readComponents: criticality default value FALSE should not be specified")
+	ErrMissingControlValue     = errors.New("missing ControlValue")
 )
 
 //
@@ -14,6 +16,15 @@ var (
 //             criticality             BOOLEAN DEFAULT FALSE,
 //             controlValue            OCTET STRING OPTIONAL }
 
+// NewControl builds a control to be attached to a response message.
+func NewControl(controlType LDAPOID, criticality BOOLEAN, controlValue *OCTETSTRING) Control {
+	return Control{
+		controlType:  controlType,
+		criticality:  criticality,
+		controlValue: controlValue,
+	}
+}
+
 func (control *Control) ControlType() LDAPOID {
 	return control.controlType
 }
@@ -109,3 +120,41 @@ func (control Control) size() (size int) {
 
 	return
 }
+
+// controlValue is implemented by the values of the controls which can be sent
+// in responses.
+type controlValue interface {
+	write(bytes *Bytes) int
+	size() int
+}
+
+// encodeControlValue encodes the BER representation of a control value.
+func encodeControlValue(value controlValue) (ret OCTETSTRING, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = LdapError{fmt.Sprintf("Error in encodeControlValue: %s", e)}
+		}
+	}()
+
+	totalSize := value.size()
+	bytes := &Bytes{
+		bytes:  make([]byte, totalSize),
+		offset: totalSize,
+	}
+
+	size := value.write(bytes)
+	if size != totalSize || bytes.offset != 0 {
+		err = LdapError{
+			fmt.Sprintf(
+				"Something went wrong while writing the control value ! Size is %d instead of %d, final offset is %d instead of 0",
+				size,
+				totalSize,
+				bytes.offset,
+			),
+		}
+
+		return
+	}
+
+	return OCTETSTRING(bytes.getBytes()), nil
+}
@@ -0,0 +1,90 @@
+package message
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPagedResultsControl(t *testing.T) {
+	t.Parallel()
+
+	value := OCTETSTRING([]byte{0x30, 0x07, 0x02, 0x01, 0x0a, 0x04, 0x02, 0x61, 0x62})
+
+	control := NewControl("1.2.840.113556.1.4.319", false, value.Pointer())
+
+	paged, err := control.PagedResultsControl()
+	if err != nil {
+		t.Fatalf("error parsing control: %s", err)
+	}
+
+	if paged.Size() != 10 || paged.Cookie() != "ab" {
+		t.Errorf("GOT size %d, cookie %q, EXPECTED size 10, cookie \"ab\"", paged.Size(), paged.Cookie())
+	}
+
+	encoded, err := NewPagedResultsControl(10, "ab").Encode()
+	if err != nil {
+		t.Fatalf("error encoding control: %s", err)
+	}
+
+	if !reflect.DeepEqual(encoded, value) {
+		t.Errorf("GOT:\n%#v\nEXPECTED:\n%#v", encoded, value)
+	}
+}
+
+func TestSortKeyList(t *testing.T) {
+	t.Parallel()
+
+	value := OCTETSTRING([]byte{
+		0x30, 0x18,
+		0x30, 0x07, 0x04, 0x02, 0x63, 0x6e, 0x81, 0x01, 0xff,
+		0x30, 0x0d, 0x04, 0x03, 0x75, 0x69, 0x64, 0x80, 0x06, 0x31, 0x2e, 0x32, 0x2e, 0x33, 0x34,
+	})
+
+	control := NewControl("1.2.840.113556.1.4.473", true, value.Pointer())
+
+	keys, err := control.SortKeyList()
+	if err != nil {
+		t.Fatalf("error parsing control: %s", err)
+	}
+
+	expected := SortKeyList{
+		{attributeType: "cn", reverseOrder: true},
+		{attributeType: "uid", orderingRule: MatchingRuleID("1.2.34").Pointer()},
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("GOT:\n%#v\nEXPECTED:\n%#v", keys, expected)
+	}
+}
+
+func TestSortResult(t *testing.T) {
+	t.Parallel()
+
+	encoded, err := NewSortResult(ResultCodeInappropriateMatching, AttributeDescription("cn").Pointer()).Encode()
+	if err != nil {
+		t.Fatalf("error encoding result: %s", err)
+	}
+
+	expected := OCTETSTRING([]byte{0x30, 0x07, 0x0a, 0x01, 0x12, 0x80, 0x02, 0x63, 0x6e})
+	if !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("GOT:\n%#v\nEXPECTED:\n%#v", encoded, expected)
+	}
+}
+
+func TestSortKeyListBER(t *testing.T) {
+	t.Parallel()
+
+	// An empty ordering rule and a BER (non DER) encoded TRUE for reverseOrder.
+	value := OCTETSTRING([]byte{0x30, 0x0b, 0x30, 0x09, 0x04, 0x02, 0x63, 0x6e, 0x80, 0x00, 0x81, 0x01, 0x01})
+
+	control := NewControl("1.2.840.113556.1.4.473", false, value.Pointer())
+
+	keys, err := control.SortKeyList()
+	if err != nil {
+		t.Fatalf("error parsing control: %s", err)
+	}
+
+	expected := SortKeyList{{attributeType: "cn", reverseOrder: true}}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("GOT:\n%#v\nEXPECTED:\n%#v", keys, expected)
+	}
+}
@@ -12,6 +12,10 @@ func (extended *ExtendedResponse) SetResponseName(name LDAPOID) {
 	extended.responseName = &name
 }
 
+func (extended *ExtendedResponse) SetResponseValue(value OCTETSTRING) {
+	extended.responseValue = &value
+}
+
 func readExtendedResponse(bytes *Bytes) (ret ExtendedResponse, err error) {
 	err = bytes.ReadSubBytes(classApplication, TagExtendedResponse, ret.readComponents)
 	if err != nil {
@@ -28,3 +28,19 @@ func (f FilterExtensibleMatch) getFilterTag() int {
 func (f FilterExtensibleMatch) size() int {
 	return MatchingRuleAssertion(f).sizeTagged(TagFilterExtensibleMatch)
 }
+
+func (f *FilterExtensibleMatch) MatchingRule() *MatchingRuleID {
+	return f.matchingRule
+}
+
+func (f *FilterExtensibleMatch) Type_() *AttributeDescription {
+	return f.type_
+}
+
+func (f *FilterExtensibleMatch) MatchValue() AssertionValue {
+	return f.matchValue
+}
+
+func (f *FilterExtensibleMatch) DnAttributes() BOOLEAN {
+	return f.dnAttributes
+}
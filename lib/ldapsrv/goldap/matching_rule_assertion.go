@@ -35,13 +35,16 @@ func (m *MatchingRuleAssertion) readComponents(bytes *Bytes) (err error) {
 		return LdapError{"readComponents: " + err.Error()}
 	}
 
-	m.dnAttributes, err = readTaggedBOOLEAN(
-		bytes,
-		classContextSpecific,
-		TagMatchingRuleAssertionDnAttributes,
-	)
-	if err != nil {
-		return LdapError{"readComponents: " + err.Error()}
+	// dnAttributes defaults to FALSE, and is usually omitted in that case.
+	if bytes.HasMoreData() {
+		m.dnAttributes, err = readTaggedBOOLEAN(
+			bytes,
+			classContextSpecific,
+			TagMatchingRuleAssertionDnAttributes,
+		)
+		if err != nil {
+			return LdapError{"readComponents: " + err.Error()}
+		}
 	}
 
 	return
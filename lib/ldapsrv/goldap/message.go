@@ -148,6 +148,10 @@ func (l *LDAPMessage) Controls() *Controls {
 	return l.controls
 }
 
+func (l *LDAPMessage) SetControls(controls *Controls) {
+	l.controls = controls
+}
+
 func (l *LDAPMessage) ProtocolOp() ProtocolOp {
 	return l.protocolOp
 }
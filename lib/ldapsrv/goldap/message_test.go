@@ -91,3 +91,37 @@ func TestSearchEntry(t *testing.T) {
 		t.Logf("Malformed message: %v", toHex(buf.Bytes()))
 	}
 }
+
+func TestExtendedResponseValue(t *testing.T) {
+	t.Parallel()
+
+	res := ExtendedResponse{}
+	res.SetResultCode(ResultCodeSuccess)
+	res.SetResponseValue("dn:uid=alice,ou=users,dc=teapot,dc=ovh")
+
+	m := NewLDAPMessageWithProtocolOp(res)
+	m.SetMessageID(7)
+
+	buf, err := m.Write()
+	if err != nil {
+		t.Fatalf("marshalling failed with %v", err)
+	}
+
+	ret, err := ReadLDAPMessage(NewBytes(0, buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unmarshalling failed with %v", err)
+	}
+
+	extended, ok := ret.ProtocolOp().(ExtendedResponse)
+	if !ok {
+		t.Fatalf("should be an extended response, got %T", ret.ProtocolOp())
+	}
+
+	if extended.responseName != nil {
+		t.Errorf("expected no response name, got %q", *extended.responseName)
+	}
+
+	if extended.responseValue == nil || *extended.responseValue != "dn:uid=alice,ou=users,dc=teapot,dc=ovh" {
+		t.Errorf("unexpected response value %v", extended.responseValue)
+	}
+}
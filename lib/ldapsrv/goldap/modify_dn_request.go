@@ -95,3 +95,19 @@ func (m ModifyDNRequest) size() (size int) {
 
 	return
 }
+
+func (m *ModifyDNRequest) Entry() LDAPDN {
+	return m.entry
+}
+
+func (m *ModifyDNRequest) NewRDN() RelativeLDAPDN {
+	return m.newrdn
+}
+
+func (m *ModifyDNRequest) DeleteOldRDN() BOOLEAN {
+	return m.deleteoldrdn
+}
+
+func (m *ModifyDNRequest) NewSuperior() *LDAPDN {
+	return m.newSuperior
+}
@@ -1,5 +1,9 @@
 package message
 
+func (m *ModifyDNResponse) SetResultCode(code ENUMERATED) {
+	m.resultCode = code
+}
+
 // ModifyDNResponse ::= [APPLICATION 13] LDAPResult.
 func readModifyDNResponse(bytes *Bytes) (ret ModifyDNResponse, err error) {
 	var res LDAPResult
@@ -0,0 +1,83 @@
+package message
+
+import "fmt"
+
+//	realSearchControlValue ::= SEQUENCE {
+//	        size            INTEGER (0..maxInt),
+//	        cookie          OCTET STRING }
+
+// NewPagedResultsControl builds the value of a simple paged results control
+// (RFC 2696).
+func NewPagedResultsControl(size INTEGER, cookie OCTETSTRING) PagedResultsControl {
+	return PagedResultsControl{pageSize: size, cookie: cookie}
+}
+
+// PagedResultsControl parses the value of the control as a simple paged
+// results control (RFC 2696).
+func (control *Control) PagedResultsControl() (*PagedResultsControl, error) {
+	if control.controlValue == nil {
+		return nil, ErrMissingControlValue
+	}
+
+	paged, err := readPagedResultsControl(NewBytes(0, control.controlValue.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ControlValue as PagedResultsControl: %w", err)
+	}
+
+	return &paged, nil
+}
+
+func (paged *PagedResultsControl) Size() INTEGER {
+	return paged.pageSize
+}
+
+func (paged *PagedResultsControl) Cookie() OCTETSTRING {
+	return paged.cookie
+}
+
+// Encode returns the BER encoding of the control, to be used as a ControlValue.
+func (paged PagedResultsControl) Encode() (OCTETSTRING, error) {
+	return encodeControlValue(paged)
+}
+
+func readPagedResultsControl(bytes *Bytes) (paged PagedResultsControl, err error) {
+	err = bytes.ReadSubBytes(classUniversal, tagSequence, paged.readComponents)
+	if err != nil {
+		err = LdapError{"readPagedResultsControl:\n" + err.Error()}
+		return
+	}
+
+	return
+}
+
+func (paged *PagedResultsControl) readComponents(bytes *Bytes) (err error) {
+	paged.pageSize, err = readPositiveINTEGER(bytes)
+	if err != nil {
+		err = LdapError{"readComponents:\n" + err.Error()}
+		return
+	}
+
+	paged.cookie, err = readOCTETSTRING(bytes)
+	if err != nil {
+		err = LdapError{"readComponents:\n" + err.Error()}
+		return
+	}
+
+	return
+}
+
+func (paged PagedResultsControl) write(bytes *Bytes) (size int) {
+	size += paged.cookie.write(bytes)
+	size += paged.pageSize.write(bytes)
+	size += bytes.WriteTagAndLength(classUniversal, isCompound, tagSequence, size)
+
+	return
+}
+
+func (paged PagedResultsControl) size() (size int) {
+	size += paged.cookie.size()
+	size += paged.pageSize.size()
+	size += sizeTagAndLength(tagSequence, size)
+
+	return
+}
@@ -51,6 +51,10 @@ func (s SearchResultEntry) size() (size int) {
 	return
 }
 
+func (s *SearchResultEntry) ObjectName() LDAPDN {
+	return s.objectName
+}
+
 func (s *SearchResultEntry) SetObjectName(on string) {
 	s.objectName = LDAPDN(on)
 }
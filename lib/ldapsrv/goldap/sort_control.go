@@ -0,0 +1,192 @@
+package message
+
+import "fmt"
+
+//	SortKeyList ::= SEQUENCE OF SEQUENCE {
+//	        attributeType   AttributeDescription,
+//	        orderingRule    [0] MatchingRuleId OPTIONAL,
+//	        reverseOrder    [1] BOOLEAN DEFAULT FALSE }
+
+// SortKeyList parses the value of the control as a server side sorting
+// request control (RFC 2891).
+func (control *Control) SortKeyList() (SortKeyList, error) {
+	if control.controlValue == nil {
+		return nil, ErrMissingControlValue
+	}
+
+	keys, err := readSortKeyList(NewBytes(0, control.controlValue.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ControlValue as SortKeyList: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (key *SortKey) AttributeType() AttributeDescription {
+	return key.attributeType
+}
+
+func (key *SortKey) OrderingRule() *MatchingRuleID {
+	return key.orderingRule
+}
+
+func (key *SortKey) ReverseOrder() BOOLEAN {
+	return key.reverseOrder
+}
+
+func readSortKeyList(bytes *Bytes) (keys SortKeyList, err error) {
+	err = bytes.ReadSubBytes(classUniversal, tagSequence, keys.readComponents)
+	if err != nil {
+		err = LdapError{"readSortKeyList:\n" + err.Error()}
+		return
+	}
+
+	return
+}
+
+func (keys *SortKeyList) readComponents(bytes *Bytes) (err error) {
+	for bytes.HasMoreData() {
+		var key SortKey
+
+		key, err = readSortKey(bytes)
+		if err != nil {
+			err = LdapError{"readComponents:\n" + err.Error()}
+			return
+		}
+
+		*keys = append(*keys, key)
+	}
+
+	return
+}
+
+func readSortKey(bytes *Bytes) (key SortKey, err error) {
+	err = bytes.ReadSubBytes(classUniversal, tagSequence, key.readComponents)
+	if err != nil {
+		err = LdapError{"readSortKey:\n" + err.Error()}
+		return
+	}
+
+	return
+}
+
+func (key *SortKey) readComponents(bytes *Bytes) (err error) {
+	key.attributeType, err = readAttributeDescription(bytes)
+	if err != nil {
+		err = LdapError{"readComponents:\n" + err.Error()}
+		return
+	}
+
+	if bytes.HasMoreData() {
+		var tag TagAndLength
+
+		tag, err = bytes.PreviewTagAndLength()
+		if err != nil {
+			err = LdapError{"readComponents:\n" + err.Error()}
+			return
+		}
+
+		if tag.Tag == TagSortKeyOrderingRule {
+			var rule MatchingRuleID
+
+			rule, err = readTaggedMatchingRuleID(bytes, classContextSpecific, TagSortKeyOrderingRule)
+			if err != nil {
+				err = LdapError{"readComponents:\n" + err.Error()}
+				return
+			}
+
+			// Some clients always send the ordering rule, leaving it empty
+			// when the default one is to be used.
+			if rule != "" {
+				key.orderingRule = rule.Pointer()
+			}
+		}
+	}
+
+	if bytes.HasMoreData() {
+		key.reverseOrder, err = readTaggedBERBOOLEAN(bytes, classContextSpecific, TagSortKeyReverseOrder)
+		if err != nil {
+			err = LdapError{"readComponents:\n" + err.Error()}
+			return
+		}
+	}
+
+	return
+}
+
+// readTaggedBERBOOLEAN reads a BOOLEAN allowing any non-zero octet for TRUE,
+// as permitted by BER. Some clients encode reverseOrder this way, while
+// readTaggedBOOLEAN only accepts the DER encoding.
+func readTaggedBERBOOLEAN(bytes *Bytes, class int, tag int) (ret BOOLEAN, err error) {
+	var value any
+
+	value, err = bytes.ReadPrimitiveSubBytes(class, tag, tagOctetString)
+	if err != nil {
+		err = LdapError{"readTaggedBERBOOLEAN:\n" + err.Error()}
+		return
+	}
+
+	raw := value.([]byte)
+	if len(raw) != 1 {
+		err = LdapError{"readTaggedBERBOOLEAN: invalid boolean: should be encoded on one byte only"}
+		return
+	}
+
+	return BOOLEAN(raw[0] != 0), nil
+}
+
+//	SortResult ::= SEQUENCE {
+//	        sortResult  ENUMERATED {
+//	            success                   (0),
+//	            operationsError           (1),
+//	            timeLimitExceeded         (3),
+//	            strongAuthRequired        (8),
+//	            adminLimitExceeded        (11),
+//	            noSuchAttribute           (16),
+//	            inappropriateMatching     (18),
+//	            insufficientAccessRights  (50),
+//	            busy                      (51),
+//	            unwillingToPerform        (53),
+//	            other                     (80) },
+//	        attributeType [0] AttributeDescription OPTIONAL }
+
+// NewSortResult builds the value of a server side sorting response control
+// (RFC 2891).
+func NewSortResult(sortResult ENUMERATED, attributeType *AttributeDescription) SortResult {
+	return SortResult{sortResult: sortResult, attributeType: attributeType}
+}
+
+func (result *SortResult) SortResult() ENUMERATED {
+	return result.sortResult
+}
+
+func (result *SortResult) AttributeType() *AttributeDescription {
+	return result.attributeType
+}
+
+// Encode returns the BER encoding of the result, to be used as a ControlValue.
+func (result SortResult) Encode() (OCTETSTRING, error) {
+	return encodeControlValue(result)
+}
+
+func (result SortResult) write(bytes *Bytes) (size int) {
+	if result.attributeType != nil {
+		size += result.attributeType.writeTagged(bytes, classContextSpecific, TagSortResultAttributeType)
+	}
+
+	size += result.sortResult.write(bytes)
+	size += bytes.WriteTagAndLength(classUniversal, isCompound, tagSequence, size)
+
+	return
+}
+
+func (result SortResult) size() (size int) {
+	if result.attributeType != nil {
+		size += result.attributeType.sizeTagged(TagSortResultAttributeType)
+	}
+
+	size += result.sortResult.size()
+	size += sizeTagAndLength(tagSequence, size)
+
+	return
+}
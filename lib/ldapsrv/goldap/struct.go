@@ -388,6 +388,41 @@ type PasswordModifyResponse struct {
 	genPassword *OCTETSTRING
 }
 
+//	realSearchControlValue ::= SEQUENCE {
+//	        size            INTEGER (0..maxInt),
+//	        cookie          OCTET STRING }
+type PagedResultsControl struct {
+	cookie   OCTETSTRING
+	pageSize INTEGER
+}
+
+//	SortKeyList ::= SEQUENCE OF SEQUENCE {
+//	        attributeType   AttributeDescription,
+//	        orderingRule    [0] MatchingRuleId OPTIONAL,
+//	        reverseOrder    [1] BOOLEAN DEFAULT FALSE }
+type SortKeyList []SortKey
+
+type SortKey struct {
+	orderingRule  *MatchingRuleID
+	attributeType AttributeDescription
+	reverseOrder  BOOLEAN
+}
+
+const (
+	TagSortKeyOrderingRule = 0
+	TagSortKeyReverseOrder = 1
+)
+
+//	SortResult ::= SEQUENCE {
+//	        sortResult  ENUMERATED,
+//	        attributeType [0] AttributeDescription OPTIONAL }
+type SortResult struct {
+	attributeType *AttributeDescription
+	sortResult    ENUMERATED
+}
+
+const TagSortResultAttributeType = 0
+
 //	SearchRequest ::= [APPLICATION 3] SEQUENCE {
 //	     baseObject      LDAPDN,
 //	     scope           ENUMERATED {
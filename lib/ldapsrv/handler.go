@@ -17,6 +17,7 @@ type Handler[T any] interface {
 	Add(ctx context.Context, state T, r ldap.AddRequest) (T, error)
 	Del(ctx context.Context, state T, r ldap.DelRequest) (T, error)
 	Modify(ctx context.Context, state T, r ldap.ModifyRequest) (T, error)
+	ModifyDN(ctx context.Context, state T, r ldap.ModifyDNRequest) (T, error)
 	Compare(ctx context.Context, state T, r ldap.CompareRequest) (bool, T, error)
 	Extended(ctx context.Context, state T, r ldap.ExtendedRequest) (T, error)
 }
@@ -48,6 +49,10 @@ func (u *UnimplementedHandler[T]) Modify(ctx context.Context, state T, r ldap.Mo
 	return state, ErrUnimplemented
 }
 
+func (u *UnimplementedHandler[T]) ModifyDN(ctx context.Context, state T, r ldap.ModifyDNRequest) (T, error) {
+	return state, ErrUnimplemented
+}
+
 func (u *UnimplementedHandler[T]) Compare(ctx context.Context, state T, r ldap.CompareRequest) (bool, T, error) {
 	return false, state, ErrUnimplemented
 }
@@ -66,6 +71,7 @@ const (
 	operationAdd      = "AddRequest"
 	operationDel      = "DelRequest"
 	operationModify   = "ModifyRequest"
+	operationModifyDN = "ModifyDNRequest"
 	operationCompare  = "CompareRequest"
 	operationExtended = "ExtendedRequest"
 )
@@ -91,6 +97,13 @@ func (s *LDAPSrv[T]) handle(ctx context.Context, state T, w ResponseWriter, r *M
 			Observe(time.Since(start).Seconds())
 	}()
 
+	ctrls := newControls(r.LDAPMessage)
+	ctx = context.WithValue(ctx, ContextKeyControls, ctrls)
+	ctx = context.WithValue(ctx, ContextKeyCapabilities, s.capabilities)
+
+	var extended extendedResponse
+	ctx = context.WithValue(ctx, ContextKeyExtendedResponse, &extended)
+
 	state, code, err = s.runHandler(ctx, state, w, r)
 
 	res := NewResponse(code)
@@ -98,27 +111,56 @@ func (s *LDAPSrv[T]) handle(ctx context.Context, state T, w ResponseWriter, r *M
 		res.SetDiagnosticMessage(err.Error())
 	}
 
+	controls := ctrls.responseControls()
+
 	// Write with the appropriate format by casting to the correct type
 	switch r.ProtocolOpName() {
 	case operationBind:
-		w.Write(ldap.BindResponse{LDAPResult: res})
+		w.WriteWithControls(ldap.BindResponse{LDAPResult: res}, controls)
 	case operationSearch:
-		w.Write(ldap.SearchResultDone(res))
+		w.WriteWithControls(ldap.SearchResultDone(res), controls)
 	case operationAdd:
-		w.Write(ldap.AddResponse(res))
+		w.WriteWithControls(ldap.AddResponse(res), controls)
 	case operationDel:
-		w.Write(ldap.DelResponse(res))
+		w.WriteWithControls(ldap.DelResponse(res), controls)
 	case operationModify:
-		w.Write(ldap.ModifyResponse(res))
+		w.WriteWithControls(ldap.ModifyResponse(res), controls)
+	case operationModifyDN:
+		w.WriteWithControls(ldap.ModifyDNResponse(res), controls)
 	case operationCompare:
-		w.Write(ldap.CompareResponse(res))
+		w.WriteWithControls(ldap.CompareResponse(res), controls)
 	case operationExtended:
-		r := ldap.ExtendedResponse{LDAPResult: res}
-		r.SetResponseName(NoticeOfPasswordModify)
-		w.Write(r)
+		req := r.GetExtendedRequest()
+		res := ldap.ExtendedResponse{LDAPResult: res}
+
+		switch {
+		case !extended.set:
+			res.SetResponseName(req.RequestName())
+		case extended.name != nil:
+			res.SetResponseName(*extended.name)
+		}
+
+		if extended.value != nil {
+			res.SetResponseValue(*extended.value)
+		}
+
+		w.WriteWithControls(res, controls)
 
 	default:
-		w.Write(res)
+		w.WriteWithControls(res, controls)
+	}
+
+	// The connection is upgraded only after the StartTLS response has been
+	// sent. If the TLS negotiation fails, the connection is closed, as its
+	// state can not be recovered.
+	if code == ldap.ResultCodeSuccess && isStartTLS(r) {
+		if err := r.Client.startTLS(ctx); err != nil {
+			s.logger.WarnContext(ctx, "error while starting TLS", "err", err)
+
+			if err := r.Client.rwc.Close(); err != nil {
+				s.logger.DebugContext(ctx, "error while closing connection", "err", err)
+			}
+		}
 	}
 
 	return state
@@ -140,8 +182,48 @@ func (s *LDAPSrv[T]) runHandler(
 		attribute.String("x-request-id", RequestID(ctx).String()),
 	)
 
+	state = is
+	if err = s.checkControls(ctx, RequestControls(ctx)); err == nil {
+		state, code, err = s.dispatch(ctx, is, w, r)
+	}
+
+	// If the error is not nil, extract the status code from the error type, if available,
+	// let's use the unknown error code 'Other'.
+	if err != nil {
+		s.logger.ErrorContext(ctx, "error while handling operation", "operation", r.ProtocolOpName(), "err", err)
+
+		type withErrorCode interface {
+			error
+			LDAPCode() ldap.ENUMERATED
+		}
+
+		if ec, ok := errors.AsType[withErrorCode](err); ok {
+			code = ec.LDAPCode()
+		} else {
+			code = ldap.ResultCodeOther
+		}
+	}
+
+	span.SetAttributes(attribute.Int("code", code.Int()))
+
+	return state, code, err
+}
+
+// dispatch calls the handler method for the requested operation.
+func (s *LDAPSrv[T]) dispatch(
+	ctx context.Context,
+	is T,
+	w ResponseWriter,
+	r *Message[T],
+) (state T, code ldap.ENUMERATED, err error) {
+	code = ldap.ResultCodeSuccess
+
 	switch r.ProtocolOpName() {
 	case operationBind:
+		if err = s.checkBind(r); err != nil {
+			return is, code, err
+		}
+
 		state, err = s.handler.Bind(ctx, is, r.GetBindRequest())
 	case operationSearch:
 		var results []ldap.SearchResultEntry
@@ -157,6 +239,8 @@ func (s *LDAPSrv[T]) runHandler(
 		state, err = s.handler.Del(ctx, is, r.GetDeleteRequest())
 	case operationModify:
 		state, err = s.handler.Modify(ctx, is, r.GetModifyRequest())
+	case operationModifyDN:
+		state, err = s.handler.ModifyDN(ctx, is, r.GetModifyDNRequest())
 	case operationCompare:
 		var matched bool
 
@@ -168,29 +252,15 @@ func (s *LDAPSrv[T]) runHandler(
 			code = ldap.ResultCodeCompareFalse
 		}
 	case operationExtended:
-		state, err = s.handler.Extended(ctx, is, r.GetExtendedRequest())
-	default:
-		err = ErrUnsupported
-	}
-
-	// If the error is not nil, extract the status code from the error type, if available,
-	// let's use the unknown error code 'Other'.
-	if err != nil {
-		s.logger.ErrorContext(ctx, "error while handling operation", "operation", r.ProtocolOpName(), "err", err)
-
-		type withErrorCode interface {
-			error
-			LDAPCode() ldap.ENUMERATED
+		// StartTLS is handled by ldapsrv itself, as it affects the connection
+		if isStartTLS(r) {
+			return is, code, s.checkStartTLS(r)
 		}
 
-		if ec, ok := errors.AsType[withErrorCode](err); ok {
-			code = ec.LDAPCode()
-		} else {
-			code = ldap.ResultCodeOther
-		}
+		state, err = s.handler.Extended(ctx, is, r.GetExtendedRequest())
+	default:
+		state, err = is, ErrUnsupported
 	}
 
-	span.SetAttributes(attribute.Int("code", code.Int()))
-
 	return state, code, err
 }
@@ -3,6 +3,7 @@ package ldapsrv
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -23,24 +24,37 @@ var ErrMissingHandler = errors.New("ldap server has no defined handler function"
 type unit struct{}
 
 type LDAPSrvConfig struct {
-	Address       string
+	Address string
+	// TLSAddress is the address of the implicit TLS (LDAPS) listener, which
+	// is disabled if empty.
+	TLSAddress    string
 	ShutdownDelay time.Duration
 	ReadTimeout   time.Duration
 	WriteTimeout  time.Duration
+
+	// TLS configures the certificate used for LDAPS and StartTLS, which are
+	// unavailable if no certificate is provided.
+	TLS TLSConfig
+	// RequireTLSBind refuses simple binds on unencrypted connections.
+	RequireTLSBind bool
 }
 
 // LDAPSrv is an LDAP server.
 type LDAPSrv[T any] struct {
 	logger *slog.Logger
 
-	address       string
-	shutdownDelay time.Duration
-	readTimeout   time.Duration
-	writeTimeout  time.Duration
+	address        string
+	tlsAddress     string
+	shutdownDelay  time.Duration
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	certificate    *certificate
+	requireTLSBind bool
 
 	initialState T
-	listener     net.Listener
+	listeners    []net.Listener
 	handler      Handler[T]
+	capabilities *capabilities
 	wg           sync.WaitGroup
 	metrics      metrics
 	tracer       trace.Tracer
@@ -54,10 +68,25 @@ func NewServer[T any](config LDAPSrvConfig, logger *slog.Logger) (*LDAPSrv[T], e
 
 		tracer: observability.NoopTracer,
 
-		address:       config.Address,
-		shutdownDelay: config.ShutdownDelay,
-		readTimeout:   config.ReadTimeout,
-		writeTimeout:  config.WriteTimeout,
+		address:        config.Address,
+		tlsAddress:     config.TLSAddress,
+		shutdownDelay:  config.ShutdownDelay,
+		readTimeout:    config.ReadTimeout,
+		writeTimeout:   config.WriteTimeout,
+		requireTLSBind: config.RequireTLSBind,
+	}
+
+	if config.TLS.CertFile != "" || config.TLS.KeyFile != "" {
+		certificate, err := newCertificate(config.TLS, srv.logger)
+		if err != nil {
+			return nil, fmt.Errorf("error while loading TLS certificate: %w", err)
+		}
+
+		srv.certificate = certificate
+	}
+
+	if srv.certificate == nil && (srv.tlsAddress != "" || srv.requireTLSBind) {
+		return nil, fmt.Errorf("LDAPS and requiring TLS for binds need a certificate: %w", ErrTLSNotConfigured)
 	}
 
 	srv.initMetrics()
@@ -72,6 +101,7 @@ func (s *LDAPSrv[T]) Register(h Handler[T]) {
 	}
 
 	s.handler = h
+	s.capabilities = s.supportedCapabilities()
 }
 
 // Run implements run.Runnable.
@@ -82,77 +112,106 @@ func (s *LDAPSrv[T]) Run(ctx context.Context, notify run.Notify) (err error) {
 
 	cfg := net.ListenConfig{}
 
-	s.listener, err = cfg.Listen(ctx, "tcp", s.address)
+	listener, err := cfg.Listen(ctx, "tcp", s.address)
 	if err != nil {
 		return fmt.Errorf("error while listening on tcp socket %q: %w", s.address, err)
 	}
 
+	s.listeners = []net.Listener{listener}
+
+	if s.tlsAddress != "" {
+		listener, err := cfg.Listen(ctx, "tcp", s.tlsAddress)
+		if err != nil {
+			_ = s.listeners[0].Close()
+			return fmt.Errorf("error while listening on tcp socket %q: %w", s.tlsAddress, err)
+		}
+
+		s.listeners = append(s.listeners, tls.NewListener(listener, s.certificate.tlsConfig()))
+	}
+
 	s.running.Store(true)
 
 	defer func() {
 		s.running.Store(false)
 
-		if lisErr := s.listener.Close(); lisErr != nil && err == nil {
-			err = fmt.Errorf("error while closing ldap listener: %w", err)
+		for _, listener := range s.listeners {
+			if lisErr := listener.Close(); lisErr != nil && !errors.Is(lisErr, net.ErrClosed) && err == nil {
+				err = fmt.Errorf("error while closing ldap listener: %w", lisErr)
+			}
 		}
 	}()
 
+	for _, listener := range s.listeners {
+		go s.accept(ctx, listener)
+	}
+
 	notify.Notify()
 
-	i := 0
+	<-ctx.Done()
+	s.logger.DebugContext(ctx, "gracefully closing client connections")
 
-	for {
-		select {
-		case <-ctx.Done():
-			s.logger.DebugContext(ctx, "gracefully closing client connections")
+	// Stop accepting new connections
+	for _, listener := range s.listeners {
+		if err := listener.Close(); err != nil {
+			s.logger.WarnContext(ctx, "error while closing ldap listener", "err", err)
+		}
+	}
 
-			ch := make(chan unit)
-			defer close(ch)
+	ch := make(chan unit)
+	defer close(ch)
 
-			go func() {
-				s.wg.Wait()
+	go func() {
+		s.wg.Wait()
 
-				ch <- unit{}
-			}()
+		ch <- unit{}
+	}()
 
-			ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(s.shutdownDelay))
-			defer cancel()
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(s.shutdownDelay))
+	defer cancel()
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-ch:
-				return nil
-			}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ch:
+		return nil
+	}
+}
 
-		default:
-			conn, err := s.listener.Accept()
-			if nil != err {
-				var ne *net.OpError
-				if ok := errors.As(err, &ne); ok && ne.Timeout() {
-					continue
-				}
+// accept accepts connections on listener until it is closed.
+func (s *LDAPSrv[T]) accept(ctx context.Context, listener net.Listener) {
+	i := 0
 
-				s.logger.WarnContext(ctx, "error while handling incoming connection", "err", err)
+	for {
+		conn, err := listener.Accept()
+		if nil != err {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 
+			var ne *net.OpError
+			if ok := errors.As(err, &ne); ok && ne.Timeout() {
 				continue
 			}
 
-			ctx := context.WithValue(ctx, ContextKeyAddr, conn.RemoteAddr().String())
+			s.logger.WarnContext(ctx, "error while handling incoming connection", "err", err)
 
-			uid, err := uuid.NewRandom()
-			if err != nil {
-				s.logger.WarnContext(ctx, "could not generate request id", "err", err)
+			continue
+		}
 
-				uid = uuid.UUID{}
-			}
+		ctx := context.WithValue(ctx, ContextKeyAddr, conn.RemoteAddr().String())
 
-			ctx = context.WithValue(ctx, ContextKeyRequestID, uid)
+		uid, err := uuid.NewRandom()
+		if err != nil {
+			s.logger.WarnContext(ctx, "could not generate request id", "err", err)
 
-			if err := s.setupConnection(ctx, conn, i); err != nil {
-				s.logger.ErrorContext(ctx, "error while setting up connection", "err", err)
-				continue
-			}
+			uid = uuid.UUID{}
+		}
+
+		ctx = context.WithValue(ctx, ContextKeyRequestID, uid)
+
+		if err := s.setupConnection(ctx, conn, i); err != nil {
+			s.logger.ErrorContext(ctx, "error while setting up connection", "err", err)
+			continue
 		}
 	}
 }
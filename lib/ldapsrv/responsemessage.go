@@ -37,6 +37,13 @@ func NewModifyResponse(resultCode ldap.ENUMERATED) ldap.ModifyResponse {
 	return r
 }
 
+func NewModifyDNResponse(resultCode ldap.ENUMERATED) ldap.ModifyDNResponse {
+	r := ldap.ModifyDNResponse{}
+	r.SetResultCode(resultCode)
+
+	return r
+}
+
 func NewDeleteResponse(resultCode ldap.ENUMERATED) ldap.DelResponse {
 	r := ldap.DelResponse{}
 	r.SetResultCode(resultCode)
@@ -0,0 +1,207 @@
+package ldapsrv
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+)
+
+var (
+	ErrMissingCertificate = errors.New("TLS requires both a certificate and a key file")
+	ErrTLSNotConfigured   = errors.New("TLS is not configured")
+	ErrTLSAlreadyActive   = errors.New("TLS is already active on this connection")
+	ErrBindOverPlaintext  = errors.New("simple binds are not allowed on unencrypted connections")
+	ErrStartTLSPipelined  = errors.New("messages were sent after StartTLS before the TLS negotiation")
+)
+
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ReloadInterval is how often the certificate files are checked for
+	// changes, so that rotated certificates are picked up without restarts.
+	ReloadInterval time.Duration
+}
+
+// certificate loads a TLS certificate from files, and reloads it when the
+// files change (i.e., when they are rotated by cert-manager).
+type certificate struct {
+	logger *slog.Logger
+
+	certFile       string
+	keyFile        string
+	reloadInterval time.Duration
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertificate(config TLSConfig, logger *slog.Logger) (*certificate, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, ErrMissingCertificate
+	}
+
+	c := certificate{
+		logger: logger,
+
+		certFile:       config.CertFile,
+		keyFile:        config.KeyFile,
+		reloadInterval: config.ReloadInterval,
+	}
+
+	modTime, err := c.lastModified()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.load(modTime); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// lastModified returns the most recent modification time of the certificate
+// and key files.
+func (c *certificate) lastModified() (time.Time, error) {
+	var modTime time.Time
+
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error while checking TLS file %q: %w", path, err)
+		}
+
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+
+	return modTime, nil
+}
+
+func (c *certificate) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("error while loading TLS certificate: %w", err)
+	}
+
+	c.cert = &cert
+	c.modTime = modTime
+	c.checked = time.Now()
+
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate. If reloading a changed
+// certificate fails, the previous one keeps being served.
+func (c *certificate) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checked) < c.reloadInterval {
+		return c.cert, nil
+	}
+
+	c.checked = time.Now()
+
+	modTime, err := c.lastModified()
+	if err != nil {
+		c.logger.WarnContext(hello.Context(), "error while checking TLS certificate for changes", "err", err)
+		return c.cert, nil
+	}
+
+	if modTime.Equal(c.modTime) {
+		return c.cert, nil
+	}
+
+	if err := c.load(modTime); err != nil {
+		c.logger.WarnContext(hello.Context(), "error while reloading TLS certificate", "err", err)
+		return c.cert, nil
+	}
+
+	c.logger.InfoContext(hello.Context(), "reloaded TLS certificate", "cert", c.certFile)
+
+	return c.cert, nil
+}
+
+func (c *certificate) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}
+}
+
+// checkStartTLS validates a StartTLS request, before the success response
+// is sent to the client.
+func (s *LDAPSrv[T]) checkStartTLS(r *Message[T]) error {
+	if s.certificate == nil {
+		return fmt.Errorf("(%w) %w", ErrProtocolError, ErrTLSNotConfigured)
+	}
+
+	if r.Client.IsTLS() {
+		return fmt.Errorf("(%w) %w", ErrOperationsError, ErrTLSAlreadyActive)
+	}
+
+	// Bytes already read past the StartTLS request would be lost by the TLS
+	// upgrade, so the request is refused as per RFC 4511, section 4.14.1.
+	if r.Client.br.Buffered() > 0 {
+		return fmt.Errorf("(%w) %w", ErrOperationsError, ErrStartTLSPipelined)
+	}
+
+	return nil
+}
+
+// checkBind refuses simple binds with credentials on unencrypted connections,
+// if the server is configured to do so. Anonymous binds are always allowed.
+func (s *LDAPSrv[T]) checkBind(r *Message[T]) error {
+	if !s.requireTLSBind || r.Client.IsTLS() {
+		return nil
+	}
+
+	bind := r.GetBindRequest()
+	if bind.AuthenticationChoice() == "simple" && len(bind.AuthenticationSimple()) > 0 {
+		return fmt.Errorf("(%w) %w", ErrConfidentialityRequired, ErrBindOverPlaintext)
+	}
+
+	return nil
+}
+
+// isStartTLS reports whether the message is a StartTLS extended request.
+func isStartTLS[T any](r *Message[T]) bool {
+	extended, ok := r.ProtocolOp().(ldap.ExtendedRequest)
+	return ok && extended.RequestName() == NoticeOfStartTLS
+}
+
+// startTLS upgrades the connection to TLS. It must only be called after the
+// StartTLS response has been queued, and before any other message is read.
+func (c *client[T]) startTLS(ctx context.Context) error {
+	// Wait for the StartTLS response to be written in plaintext.
+	flushed := make(chan struct{})
+	c.chanFlush <- flushed
+	<-flushed
+
+	conn := tls.Server(c.rwc, c.srv.certificate.tlsConfig())
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("error during TLS handshake: %w", err)
+	}
+
+	c.SetConn(conn)
+
+	return nil
+}
+
+// IsTLS reports whether the connection is encrypted, either because it was
+// accepted on the LDAPS listener or after a StartTLS operation.
+func (c *client[T]) IsTLS() bool {
+	_, ok := c.rwc.(*tls.Conn)
+	return ok
+}
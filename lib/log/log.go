@@ -3,6 +3,7 @@ package log
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 
@@ -15,6 +16,8 @@ var (
 )
 
 type LogConfig struct {
+	// Output is where logs are written to, defaulting to os.Stdout.
+	Output io.Writer
 	Level  string
 	Format string
 }
@@ -34,6 +37,11 @@ func NewLogger(config LogConfig) (*slog.Logger, error) {
 		return nil, err
 	}
 
+	output := config.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
 	var handler slog.Handler
 
 	opts := &slog.HandlerOptions{
@@ -42,11 +50,11 @@ func NewLogger(config LogConfig) (*slog.Logger, error) {
 
 	switch config.Format {
 	case "json":
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+		handler = slog.NewJSONHandler(output, opts)
 	case "text":
-		handler = slog.NewTextHandler(os.Stdout, opts)
+		handler = slog.NewTextHandler(output, opts)
 	case "tint":
-		handler = tint.NewTextHandler(os.Stdout, &tint.Options{Level: level})
+		handler = tint.NewTextHandler(output, &tint.Options{Level: level})
 	default:
 		return nil, fmt.Errorf("could not parse log format %q: %w", config.Format, ErrInvalidFormat)
 	}
@@ -47,6 +47,9 @@ type (
 		cache  *SortedMap[K, T]
 		loaded atomic.Bool
 
+		listenersMu sync.RWMutex
+		listeners   []Listener[K, T]
+
 		listFunc   List[K, T]
 		getFunc    Get[K, T]
 		storeFunc  Store[K, T]
@@ -148,12 +151,29 @@ func NewTable[K Key[K], T Object[K]](pool *pgxpool.Pool,
 	return &t, nil
 }
 
+// AddListener registers a listener that will be notified of all changes to
+// the cache. Listeners should be added before the table is run, as they are
+// not notified of objects that were already loaded.
+func (t *Table[K, T]) AddListener(listener Listener[K, T]) {
+	t.listenersMu.Lock()
+	defer t.listenersMu.Unlock()
+
+	t.listeners = append(t.listeners, listener)
+}
+
 func (t *Table[K, T]) store(key K, object T) {
 	if _, existed := t.cache.Load(key); !existed {
 		defer objectsTotal.WithLabelValues(t.table).Inc()
 	}
 
 	t.cache.Store(key, object)
+
+	t.listenersMu.RLock()
+	defer t.listenersMu.RUnlock()
+
+	for _, listener := range t.listeners {
+		listener.Stored(key, object)
+	}
 }
 
 func (t *Table[K, T]) delete(key K) {
@@ -162,6 +182,13 @@ func (t *Table[K, T]) delete(key K) {
 	}
 
 	t.cache.Delete(key)
+
+	t.listenersMu.RLock()
+	defer t.listenersMu.RUnlock()
+
+	for _, listener := range t.listeners {
+		listener.Deleted(key)
+	}
 }
 
 func (t *Table[K, T]) Get(key K) (T, bool) {
@@ -261,6 +288,31 @@ func (ttx *TableTx[K, T]) Delete(ctx context.Context, keys []K) error {
 	return nil
 }
 
+// Update runs a custom write query within the transaction. The update function
+// must return the new version of every object it changed, which are then
+// handled as if they had been passed to Store.
+func (ttx *TableTx[K, T]) Update(ctx context.Context, fn func(ctx context.Context, tx pgx.Tx) ([]T, error)) error {
+	if ttx.committed.Load() {
+		return ErrAlreadyCommitted
+	}
+
+	objects, err := fn(ctx, ttx.tx)
+	if err != nil {
+		return fmt.Errorf("error while performing the update operation: %w", err)
+	}
+
+	ttx.mu.Lock()
+	defer ttx.mu.Unlock()
+
+	for _, object := range objects {
+		ttx.updates = append(ttx.updates, update[K, T]{
+			store: &object,
+		})
+	}
+
+	return nil
+}
+
 func (ttx *TableTx[K, T]) Commit(ctx context.Context) error {
 	if ttx.committed.Load() {
 		return ErrAlreadyCommitted
@@ -308,6 +360,26 @@ func (ttx *TableTx[K, T]) Commit(ctx context.Context) error {
 	return nil
 }
 
+// Rollback aborts the transaction, discarding its updates. Rolling back a
+// committed transaction is a no-op, so that Rollback can always be deferred.
+func (ttx *TableTx[K, T]) Rollback(ctx context.Context) error {
+	if ttx.committed.Load() {
+		return nil
+	}
+
+	ttx.mu.Lock()
+	defer ttx.mu.Unlock()
+
+	ttx.updates = ttx.updates[:0]
+
+	// The transaction is closed already if committing it failed
+	if err := ttx.tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return fmt.Errorf("error while rolling back: %w", err)
+	}
+
+	return nil
+}
+
 func (t *Table[K, T]) handleEvents(ctx context.Context, events []Event[K]) (err error) {
 	start := time.Now()
 
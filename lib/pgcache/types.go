@@ -34,4 +34,12 @@ type (
 
 	// Delete deletes the given objects from the database.
 	Delete[K Key[K], T Object[K]] func(ctx context.Context, tx pgx.Tx, keys []K) error
+
+	// Listener is notified of every change applied to the in-memory cache,
+	// regardless of whether it originated from this process or from a
+	// notification sent by another one.
+	Listener[K Key[K], T Object[K]] interface {
+		Stored(key K, object T)
+		Deleted(key K)
+	}
 )
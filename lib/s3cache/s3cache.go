@@ -168,6 +168,17 @@ func (c *S3Cache) Remove(ctx context.Context, key string) error {
 	return nil
 }
 
+// Stat returns the size of the object stored in S3 under key, without
+// fetching its content.
+func (c *S3Cache) Stat(ctx context.Context, key string) (int64, error) {
+	info, err := c.client.StatObject(ctx, c.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("error while fetching info of object %q: %w", key, err)
+	}
+
+	return info.Size, nil
+}
+
 func (c *S3Cache) cap() int64 {
 	return c.capacity - c.meta.metadataSize() - c.meta.cacheSize()
 }
@@ -0,0 +1,278 @@
+package s3fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"time"
+
+	hpfs "github.com/hack-pad/hackpadfs"
+	"github.com/minio/minio-go/v7"
+)
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	etag    string
+	dir     bool
+}
+
+func newFileInfo(name string, object minio.ObjectInfo) *fileInfo {
+	return &fileInfo{
+		name:    path.Base(name),
+		size:    object.Size,
+		modTime: object.LastModified,
+		etag:    object.ETag,
+	}
+}
+
+// Name implements hackpadfs.FileInfo.
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+// Size implements hackpadfs.FileInfo.
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
+
+// Mode implements hackpadfs.FileInfo.
+func (fi *fileInfo) Mode() hpfs.FileMode {
+	if fi.dir {
+		return hpfs.ModeDir | DirPerm
+	}
+
+	return FilePerm
+}
+
+// ModTime implements hackpadfs.FileInfo.
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+// IsDir implements hackpadfs.FileInfo.
+func (fi *fileInfo) IsDir() bool {
+	return fi.dir
+}
+
+// Sys implements hackpadfs.FileInfo.
+func (fi *fileInfo) Sys() any {
+	return nil
+}
+
+type dirEntry struct {
+	info *fileInfo
+}
+
+// Name implements hackpadfs.DirEntry.
+func (de dirEntry) Name() string {
+	return de.info.name
+}
+
+// IsDir implements hackpadfs.DirEntry.
+func (de dirEntry) IsDir() bool {
+	return de.info.dir
+}
+
+// Type implements hackpadfs.DirEntry.
+func (de dirEntry) Type() hpfs.FileMode {
+	return de.info.Mode().Type()
+}
+
+// Info implements hackpadfs.DirEntry.
+func (de dirEntry) Info() (hpfs.FileInfo, error) {
+	return de.info, nil
+}
+
+// dirFile is an open directory, whose entries are listed on the first read.
+type dirFile struct {
+	fs   *FS
+	name string
+	info *fileInfo
+
+	entries []hpfs.DirEntry
+	listed  bool
+}
+
+func newDirFile(fs *FS, name string, info *fileInfo) *dirFile {
+	return &dirFile{fs: fs, name: name, info: info}
+}
+
+// Stat implements hackpadfs.File.
+func (f *dirFile) Stat() (hpfs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Read implements hackpadfs.File.
+func (f *dirFile) Read([]byte) (int, error) {
+	return 0, &hpfs.PathError{Op: "read", Path: f.name, Err: hpfs.ErrIsDir}
+}
+
+// Close implements hackpadfs.File.
+func (f *dirFile) Close() error {
+	return nil
+}
+
+// ReadDir implements hackpadfs.DirReaderFile.
+func (f *dirFile) ReadDir(n int) ([]hpfs.DirEntry, error) {
+	if !f.listed {
+		entries, err := f.fs.readDir(context.Background(), f.name)
+		if err != nil {
+			return nil, &hpfs.PathError{Op: "readdir", Path: f.name, Err: err}
+		}
+
+		f.entries, f.listed = entries, true
+	}
+
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+
+		return entries, nil
+	} else if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+
+	return entries, nil
+}
+
+// cachedFile is an open file whose content has been read through the cache.
+type cachedFile struct {
+	*bytes.Reader
+
+	name string
+	info *fileInfo
+}
+
+func newCachedFile(name string, info *fileInfo, data []byte) *cachedFile {
+	return &cachedFile{Reader: bytes.NewReader(data), name: name, info: info}
+}
+
+// Stat implements hackpadfs.File.
+func (f *cachedFile) Stat() (hpfs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Close implements hackpadfs.File.
+func (f *cachedFile) Close() error {
+	return nil
+}
+
+// streamFile is an open file too large to be cached, which is read from S3
+// as needed.
+type streamFile struct {
+	*minio.Object
+
+	info *fileInfo
+}
+
+// Stat implements hackpadfs.File.
+func (f *streamFile) Stat() (hpfs.FileInfo, error) {
+	return f.info, nil
+}
+
+// writerFile is a file being written, which is streamed to S3 as a multipart
+// upload completed when the file is closed.
+type writerFile struct {
+	name string
+	info *fileInfo
+
+	writer *io.PipeWriter
+	done   chan error
+}
+
+func newWriterFile(fs *FS, name string) *writerFile {
+	reader, writer := io.Pipe()
+	f := writerFile{
+		name: name,
+		info: &fileInfo{name: path.Base(name), modTime: time.Now()},
+
+		writer: writer,
+		done:   make(chan error, 1),
+	}
+
+	go func() {
+		_, err := fs.client.PutObject(
+			context.Background(),
+			fs.bucket,
+			fs.key(name),
+			reader,
+			-1,
+			minio.PutObjectOptions{PartSize: PartSize},
+		)
+		// Unblock any pending write if the upload failed
+		reader.CloseWithError(err)
+		f.done <- err
+	}()
+
+	return &f
+}
+
+// Stat implements hackpadfs.File.
+func (f *writerFile) Stat() (hpfs.FileInfo, error) {
+	return f.info, nil
+}
+
+// Read implements hackpadfs.File.
+func (f *writerFile) Read([]byte) (int, error) {
+	return 0, &hpfs.PathError{Op: "read", Path: f.name, Err: hpfs.ErrNotImplemented}
+}
+
+// Write implements hackpadfs.ReadWriterFile.
+func (f *writerFile) Write(p []byte) (int, error) {
+	n, err := f.writer.Write(p)
+	f.info.size += int64(n)
+
+	if err != nil {
+		return n, &hpfs.PathError{Op: "write", Path: f.name, Err: err}
+	}
+
+	return n, nil
+}
+
+// Seek implements hackpadfs.SeekerFile. Only the current offset can be
+// queried, as uploads cannot go back.
+func (f *writerFile) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && (whence == io.SeekCurrent || whence == io.SeekEnd) {
+		return f.info.size, nil
+	}
+
+	return 0, &hpfs.PathError{Op: "seek", Path: f.name, Err: hpfs.ErrNotImplemented}
+}
+
+// Close implements hackpadfs.File, completing the upload.
+func (f *writerFile) Close() error {
+	if f.done == nil {
+		return &hpfs.PathError{Op: "close", Path: f.name, Err: hpfs.ErrClosed}
+	}
+
+	if err := f.writer.Close(); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+		return &hpfs.PathError{Op: "close", Path: f.name, Err: err}
+	}
+
+	err := <-f.done
+	f.done = nil
+
+	if err != nil {
+		return &hpfs.PathError{Op: "close", Path: f.name, Err: err}
+	}
+
+	return nil
+}
+
+var (
+	_ hpfs.DirReaderFile  = &dirFile{}
+	_ hpfs.SeekerFile     = &cachedFile{}
+	_ hpfs.ReaderAtFile   = &cachedFile{}
+	_ hpfs.SeekerFile     = &streamFile{}
+	_ hpfs.ReaderAtFile   = &streamFile{}
+	_ hpfs.ReadWriterFile = &writerFile{}
+	_ hpfs.SeekerFile     = &writerFile{}
+)
@@ -0,0 +1,486 @@
+// Package s3fs implements a hackpadfs filesystem over the objects of an S3
+// bucket below a prefix. Directories are emulated over key prefixes, and are
+// materialized as empty objects with a trailing slash when created explicitly.
+// Directories which only exist through their entries disappear along with the
+// last one of them.
+package s3fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	hpfs "github.com/hack-pad/hackpadfs"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/teapotovh/teapot/lib/s3cache"
+)
+
+const (
+	// PartSize is the size of the parts of multipart uploads, which is also the
+	// amount of memory buffered by each file being written.
+	PartSize = 16 << 20 // 16MiB
+
+	DirPerm  = os.FileMode(0o0750)
+	FilePerm = os.FileMode(0o0640)
+)
+
+var ErrMissingBucket = errors.New("S3 bucket not provided")
+
+// maxCopySize is the size of the largest object S3 copies in a single request.
+// Larger objects are copied part by part. It is a variable so that tests can
+// lower it.
+var maxCopySize int64 = 5 << 30 // 5GiB
+
+type FSConfig struct {
+	// Prefix is the key prefix all files are stored below, without leading or
+	// trailing slashes. An empty prefix exposes the whole bucket.
+	Prefix string
+	// MaxCachedSize is the size of the largest file read through the cache.
+	// Larger files are streamed from S3.
+	MaxCachedSize int64
+}
+
+// FS is a hackpadfs.FS backed by S3.
+type FS struct {
+	client *minio.Client
+	cache  *s3cache.S3Cache
+	bucket string
+
+	prefix        string
+	maxCachedSize int64
+}
+
+// NewFS returns a filesystem over the objects of bucket. The cache must read
+// from the same bucket.
+func NewFS(config FSConfig, client *minio.Client, cache *s3cache.S3Cache, bucket string) (*FS, error) {
+	if bucket == "" {
+		return nil, ErrMissingBucket
+	}
+
+	return &FS{
+		client: client,
+		cache:  cache,
+		bucket: bucket,
+
+		prefix:        strings.Trim(config.Prefix, "/"),
+		maxCachedSize: config.MaxCachedSize,
+	}, nil
+}
+
+// key returns the object key of a file.
+func (fs *FS) key(name string) string {
+	if name == "." {
+		return fs.prefix
+	} else if fs.prefix == "" {
+		return name
+	}
+
+	return fs.prefix + "/" + name
+}
+
+// dirKey returns the prefix of the keys of the entries of a directory.
+func (fs *FS) dirKey(name string) string {
+	if key := fs.key(name); key != "" {
+		return key + "/"
+	}
+
+	return ""
+}
+
+func isNotFound(err error) bool {
+	return minio.ToErrorResponse(err).StatusCode == http.StatusNotFound
+}
+
+// stat returns the info of a file or directory.
+func (fs *FS) stat(ctx context.Context, name string) (*fileInfo, error) {
+	if name == "." {
+		return &fileInfo{name: name, dir: true}, nil
+	}
+
+	info, err := fs.client.StatObject(ctx, fs.bucket, fs.key(name), minio.StatObjectOptions{})
+	if err == nil {
+		return newFileInfo(name, info), nil
+	} else if !isNotFound(err) {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for entry := range fs.client.ListObjects(ctx, fs.bucket, minio.ListObjectsOptions{
+		Prefix:  fs.dirKey(name),
+		MaxKeys: 1,
+	}) {
+		if entry.Err != nil {
+			return nil, entry.Err
+		}
+
+		return &fileInfo{name: path.Base(name), dir: true, modTime: entry.LastModified}, nil
+	}
+
+	return nil, hpfs.ErrNotExist
+}
+
+// Stat implements hackpadfs.StatFS.
+func (fs *FS) Stat(name string) (hpfs.FileInfo, error) {
+	if !hpfs.ValidPath(name) {
+		return nil, &hpfs.PathError{Op: "stat", Path: name, Err: hpfs.ErrInvalid}
+	}
+
+	info, err := fs.stat(context.Background(), name)
+	if err != nil {
+		return nil, &hpfs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	return info, nil
+}
+
+// Open implements hackpadfs.FS.
+func (fs *FS) Open(name string) (hpfs.File, error) {
+	return fs.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile implements hackpadfs.OpenFileFS. Files can either be read or be
+// written from the start, as objects cannot be modified in place.
+//
+//nolint:gocyclo
+func (fs *FS) OpenFile(name string, flag int, perm hpfs.FileMode) (hpfs.File, error) {
+	if !hpfs.ValidPath(name) {
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: hpfs.ErrInvalid}
+	}
+
+	ctx := context.Background()
+
+	info, err := fs.stat(ctx, name)
+	if err != nil && !errors.Is(err, hpfs.ErrNotExist) {
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		switch {
+		case info == nil:
+			return nil, &hpfs.PathError{Op: "open", Path: name, Err: hpfs.ErrNotExist}
+		case info.dir:
+			return newDirFile(fs, name, info), nil
+		default:
+			return fs.openReader(ctx, name, info)
+		}
+	}
+
+	switch {
+	case info != nil && info.dir:
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: hpfs.ErrIsDir}
+	case info != nil && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: hpfs.ErrExist}
+	case info == nil && flag&os.O_CREATE == 0:
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: hpfs.ErrNotExist}
+	case info != nil && (flag&os.O_TRUNC == 0 || flag&os.O_APPEND != 0):
+		// Existing objects can only be replaced as a whole
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: hpfs.ErrNotImplemented}
+	}
+
+	parent, err := fs.stat(ctx, path.Dir(name))
+	if err != nil {
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: err}
+	} else if !parent.dir {
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: hpfs.ErrNotDir}
+	}
+
+	return newWriterFile(fs, name), nil
+}
+
+func (fs *FS) openReader(ctx context.Context, name string, info *fileInfo) (hpfs.File, error) {
+	if info.size <= fs.maxCachedSize {
+		data, _, err := fs.cache.Get(ctx, fs.key(name), s3cache.Hash(info.etag))
+		if err != nil {
+			return nil, &hpfs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		return newCachedFile(name, info, data), nil
+	}
+
+	object, err := fs.client.GetObject(ctx, fs.bucket, fs.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &streamFile{Object: object, info: info}, nil
+}
+
+// Mkdir implements hackpadfs.MkdirFS.
+func (fs *FS) Mkdir(name string, perm hpfs.FileMode) error {
+	if !hpfs.ValidPath(name) {
+		return &hpfs.PathError{Op: "mkdir", Path: name, Err: hpfs.ErrInvalid}
+	}
+
+	ctx := context.Background()
+
+	if _, err := fs.stat(ctx, name); err == nil {
+		return &hpfs.PathError{Op: "mkdir", Path: name, Err: hpfs.ErrExist}
+	} else if !errors.Is(err, hpfs.ErrNotExist) {
+		return &hpfs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+
+	parent, err := fs.stat(ctx, path.Dir(name))
+	if err != nil {
+		return &hpfs.PathError{Op: "mkdir", Path: name, Err: err}
+	} else if !parent.dir {
+		return &hpfs.PathError{Op: "mkdir", Path: name, Err: hpfs.ErrNotDir}
+	}
+
+	_, err = fs.client.PutObject(ctx, fs.bucket, fs.dirKey(name), strings.NewReader(""), 0, minio.PutObjectOptions{})
+	if err != nil {
+		return &hpfs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+
+	return nil
+}
+
+// ReadDir implements hackpadfs.ReadDirFS.
+func (fs *FS) ReadDir(name string) ([]hpfs.DirEntry, error) {
+	if !hpfs.ValidPath(name) {
+		return nil, &hpfs.PathError{Op: "readdir", Path: name, Err: hpfs.ErrInvalid}
+	}
+
+	ctx := context.Background()
+
+	info, err := fs.stat(ctx, name)
+	if err != nil {
+		return nil, &hpfs.PathError{Op: "readdir", Path: name, Err: err}
+	} else if !info.dir {
+		return nil, &hpfs.PathError{Op: "readdir", Path: name, Err: hpfs.ErrNotDir}
+	}
+
+	entries, err := fs.readDir(ctx, name)
+	if err != nil {
+		return nil, &hpfs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	return entries, nil
+}
+
+// readDir lists the entries of a directory, sorted by name.
+func (fs *FS) readDir(ctx context.Context, name string) ([]hpfs.DirEntry, error) {
+	prefix := fs.dirKey(name)
+
+	var entries []hpfs.DirEntry
+
+	for object := range fs.client.ListObjects(ctx, fs.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+
+		base := strings.TrimPrefix(object.Key, prefix)
+		if base == "" {
+			// The marker of the directory itself
+			continue
+		}
+
+		var info *fileInfo
+		if dir, ok := strings.CutSuffix(base, "/"); ok {
+			info = &fileInfo{name: dir, dir: true, modTime: object.LastModified}
+		} else {
+			info = newFileInfo(base, object)
+		}
+
+		entries = append(entries, dirEntry{info})
+	}
+
+	return entries, nil
+}
+
+// Remove implements hackpadfs.RemoveFS.
+func (fs *FS) Remove(name string) error {
+	if !hpfs.ValidPath(name) || name == "." {
+		return &hpfs.PathError{Op: "remove", Path: name, Err: hpfs.ErrInvalid}
+	}
+
+	ctx := context.Background()
+
+	info, err := fs.stat(ctx, name)
+	if err != nil {
+		return &hpfs.PathError{Op: "remove", Path: name, Err: err}
+	}
+
+	key := fs.key(name)
+
+	if info.dir {
+		entries, err := fs.readDir(ctx, name)
+		if err != nil {
+			return &hpfs.PathError{Op: "remove", Path: name, Err: err}
+		} else if len(entries) > 0 {
+			return &hpfs.PathError{Op: "remove", Path: name, Err: hpfs.ErrNotEmpty}
+		}
+
+		key = fs.dirKey(name)
+	}
+
+	if err := fs.removeKey(ctx, key); err != nil {
+		return &hpfs.PathError{Op: "remove", Path: name, Err: err}
+	}
+
+	return nil
+}
+
+// removeKey removes an object, along with its cached copy.
+func (fs *FS) removeKey(ctx context.Context, key string) error {
+	if err := fs.cache.Remove(ctx, key); err != nil && !isNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// RemoveAll implements hackpadfs.RemoveAllFS.
+func (fs *FS) RemoveAll(name string) error {
+	if !hpfs.ValidPath(name) || name == "." {
+		return &hpfs.PathError{Op: "removeall", Path: name, Err: hpfs.ErrInvalid}
+	}
+
+	ctx := context.Background()
+
+	info, err := fs.stat(ctx, name)
+	if errors.Is(err, hpfs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return &hpfs.PathError{Op: "removeall", Path: name, Err: err}
+	}
+
+	if !info.dir {
+		if err := fs.removeKey(ctx, fs.key(name)); err != nil {
+			return &hpfs.PathError{Op: "removeall", Path: name, Err: err}
+		}
+
+		return nil
+	}
+
+	objects := fs.client.ListObjects(ctx, fs.bucket, minio.ListObjectsOptions{
+		Prefix:    fs.dirKey(name),
+		Recursive: true,
+	})
+	for object := range objects {
+		if object.Err != nil {
+			return &hpfs.PathError{Op: "removeall", Path: name, Err: object.Err}
+		}
+
+		if err := fs.removeKey(ctx, object.Key); err != nil {
+			return &hpfs.PathError{Op: "removeall", Path: name, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// Rename implements hackpadfs.RenameFS. S3 cannot rename objects, so they are
+// copied over to their new key and then removed, one by one.
+//
+//nolint:gocyclo
+func (fs *FS) Rename(oldName, newName string) error {
+	if !hpfs.ValidPath(oldName) || !hpfs.ValidPath(newName) || oldName == "." || newName == "." {
+		return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: hpfs.ErrInvalid}
+	}
+
+	// A directory cannot be moved into its own subtree, which the listing of
+	// its entries would otherwise keep growing
+	if strings.HasPrefix(newName, oldName+"/") {
+		return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: hpfs.ErrInvalid}
+	}
+
+	ctx := context.Background()
+
+	info, err := fs.stat(ctx, oldName)
+	if err != nil {
+		return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: err}
+	}
+
+	dest, err := fs.stat(ctx, newName)
+	if err != nil && !errors.Is(err, hpfs.ErrNotExist) {
+		return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: err}
+	}
+
+	switch {
+	case dest != nil && dest.dir && !info.dir:
+		return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: hpfs.ErrIsDir}
+	case dest != nil && !dest.dir && info.dir:
+		return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: hpfs.ErrNotDir}
+	case dest != nil && dest.dir:
+		entries, err := fs.readDir(ctx, newName)
+		if err != nil {
+			return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: err}
+		} else if len(entries) > 0 {
+			return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: hpfs.ErrExist}
+		}
+	}
+
+	if !info.dir {
+		if err := fs.moveKey(ctx, fs.key(oldName), fs.key(newName), info.size); err != nil {
+			return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: err}
+		}
+
+		return nil
+	}
+
+	oldPrefix, newPrefix := fs.dirKey(oldName), fs.dirKey(newName)
+
+	// Directories without a marker only exist through their entries, so one
+	// is created to make sure the destination exists even if it is empty.
+	_, err = fs.client.PutObject(ctx, fs.bucket, newPrefix, strings.NewReader(""), 0, minio.PutObjectOptions{})
+	if err != nil {
+		return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: err}
+	}
+
+	objects := fs.client.ListObjects(ctx, fs.bucket, minio.ListObjectsOptions{Prefix: oldPrefix, Recursive: true})
+	for object := range objects {
+		if object.Err != nil {
+			return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: object.Err}
+		}
+
+		if object.Key == oldPrefix {
+			err = fs.removeKey(ctx, oldPrefix)
+		} else {
+			err = fs.moveKey(ctx, object.Key, newPrefix+strings.TrimPrefix(object.Key, oldPrefix), object.Size)
+		}
+
+		if err != nil {
+			return &hpfs.LinkError{Op: "rename", Old: oldName, New: newName, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// moveKey copies an object of the given size to a new key, and removes the
+// original one. Objects larger than maxCopySize are copied with a server-side
+// multipart copy, as a plain copy would be rejected.
+func (fs *FS) moveKey(ctx context.Context, oldKey, newKey string, size int64) error {
+	dst := minio.CopyDestOptions{Bucket: fs.bucket, Object: newKey}
+	src := minio.CopySrcOptions{Bucket: fs.bucket, Object: oldKey}
+
+	var err error
+	if size > maxCopySize {
+		_, err = fs.client.ComposeObject(ctx, dst, src)
+	} else {
+		_, err = fs.client.CopyObject(ctx, dst, src)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error while copying object %q to %q: %w", oldKey, newKey, err)
+	}
+
+	return fs.removeKey(ctx, oldKey)
+}
+
+var (
+	_ hpfs.OpenFileFS  = &FS{}
+	_ hpfs.StatFS      = &FS{}
+	_ hpfs.MkdirFS     = &FS{}
+	_ hpfs.ReadDirFS   = &FS{}
+	_ hpfs.RemoveFS    = &FS{}
+	_ hpfs.RemoveAllFS = &FS{}
+	_ hpfs.RenameFS    = &FS{}
+)
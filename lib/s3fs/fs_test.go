@@ -0,0 +1,282 @@
+package s3fs
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	hpfs "github.com/hack-pad/hackpadfs"
+
+	"github.com/teapotovh/teapot/lib/s3cache"
+)
+
+const testBucket = "test"
+
+// newTestFS returns a filesystem over a fake S3 bucket holding the given
+// objects, with the prefix "files".
+func newTestFS(t *testing.T, objects map[string]string) (*FS, *fakeS3) {
+	t.Helper()
+
+	fake, client := newFakeS3(t)
+	for key, data := range objects {
+		fake.put("files/"+key, data)
+	}
+
+	cache, err := s3cache.NewS3Cache(
+		s3cache.S3CacheConfig{Bucket: testBucket, Path: t.TempDir()},
+		client,
+		slog.New(slog.DiscardHandler),
+	)
+	if err != nil {
+		t.Fatalf("error creating S3 cache: %s", err)
+	}
+
+	fs, err := NewFS(FSConfig{Prefix: "files", MaxCachedSize: 1 << 20}, client, cache, testBucket)
+	if err != nil {
+		t.Fatalf("error creating filesystem: %s", err)
+	}
+
+	return fs, fake
+}
+
+func readFile(t *testing.T, fs *FS, name string) string {
+	t.Helper()
+
+	data, err := hpfs.ReadFile(fs, name)
+	if err != nil {
+		t.Fatalf("error reading %q: %s", name, err)
+	}
+
+	return string(data)
+}
+
+func TestOpenFile(t *testing.T) {
+	fs, _ := newTestFS(t, map[string]string{
+		"file":      "hello",
+		"dir/":      "",
+		"dir/entry": "entry",
+	})
+
+	tests := []struct {
+		name string
+		file string
+		flag int
+		err  error
+	}{
+		{name: "read file", file: "file", flag: os.O_RDONLY},
+		{name: "read directory", file: "dir", flag: os.O_RDONLY},
+		{name: "read missing", file: "missing", flag: os.O_RDONLY, err: hpfs.ErrNotExist},
+		{name: "write missing", file: "missing", flag: os.O_WRONLY, err: hpfs.ErrNotExist},
+		{name: "create missing", file: "missing", flag: os.O_WRONLY | os.O_CREATE},
+		{name: "create in directory", file: "dir/new", flag: os.O_WRONLY | os.O_CREATE},
+		{name: "create exclusive", file: "file", flag: os.O_WRONLY | os.O_CREATE | os.O_EXCL, err: hpfs.ErrExist},
+		{name: "truncate", file: "file", flag: os.O_WRONLY | os.O_TRUNC},
+		{name: "overwrite", file: "file", flag: os.O_WRONLY, err: hpfs.ErrNotImplemented},
+		{name: "append", file: "file", flag: os.O_WRONLY | os.O_APPEND | os.O_TRUNC, err: hpfs.ErrNotImplemented},
+		{name: "write directory", file: "dir", flag: os.O_WRONLY | os.O_CREATE | os.O_TRUNC, err: hpfs.ErrIsDir},
+		{name: "create below file", file: "file/new", flag: os.O_WRONLY | os.O_CREATE, err: hpfs.ErrNotDir},
+		{name: "create below missing", file: "absent/new", flag: os.O_WRONLY | os.O_CREATE, err: hpfs.ErrNotExist},
+		{name: "invalid path", file: "/file", flag: os.O_RDONLY, err: hpfs.ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := fs.OpenFile(tt.file, tt.flag, FilePerm)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if err == nil {
+				// Files opened for writing are created when closed
+				if err := file.Close(); err != nil {
+					t.Errorf("error closing %q: %s", tt.file, err)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenFileWrite(t *testing.T) {
+	fs, fake := newTestFS(t, map[string]string{"dir/": "", "dir/file": "old"})
+
+	file, err := fs.OpenFile("dir/file", os.O_WRONLY|os.O_TRUNC, FilePerm)
+	if err != nil {
+		t.Fatalf("error opening file: %s", err)
+	}
+
+	w, ok := file.(io.Writer)
+	if !ok {
+		t.Fatalf("expected file to be writable")
+	}
+
+	if _, err := io.WriteString(w, "new contents"); err != nil {
+		t.Fatalf("error writing file: %s", err)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("error closing file: %s", err)
+	}
+
+	if data, _ := fake.get("files/dir/file"); data != "new contents" {
+		t.Errorf("expected object to hold the new contents, got %q", data)
+	}
+
+	if data := readFile(t, fs, "dir/file"); data != "new contents" {
+		t.Errorf("expected file to be read back, got %q", data)
+	}
+}
+
+func TestRename(t *testing.T) {
+	objects := map[string]string{
+		"file":          "file",
+		"other":         "other",
+		"empty/":        "",
+		"full/":         "",
+		"full/entry":    "entry",
+		"src/":          "",
+		"src/a":         "a",
+		"src/sub/b":     "b",
+		"implicit/only": "only",
+	}
+
+	tests := []struct {
+		name     string
+		old, new string
+		err      error
+		keys     []string
+	}{
+		{
+			name: "file",
+			old:  "file",
+			new:  "moved",
+			keys: []string{"moved"},
+		},
+		{
+			name: "file onto file",
+			old:  "file",
+			new:  "other",
+			keys: []string{"other"},
+		},
+		{
+			name: "file into directory",
+			old:  "file",
+			new:  "src/file",
+			keys: []string{"src/file"},
+		},
+		{
+			name: "file onto directory",
+			old:  "file",
+			new:  "empty",
+			err:  hpfs.ErrIsDir,
+		},
+		{
+			name: "directory onto file",
+			old:  "src",
+			new:  "file",
+			err:  hpfs.ErrNotDir,
+		},
+		{
+			name: "directory onto non-empty directory",
+			old:  "src",
+			new:  "full",
+			err:  hpfs.ErrExist,
+		},
+		{
+			name: "directory onto empty directory",
+			old:  "src",
+			new:  "empty",
+			keys: []string{"empty/", "empty/a", "empty/sub/b"},
+		},
+		{
+			name: "directory",
+			old:  "src",
+			new:  "dst",
+			keys: []string{"dst/", "dst/a", "dst/sub/b"},
+		},
+		{
+			name: "implicit directory",
+			old:  "implicit",
+			new:  "explicit",
+			keys: []string{"explicit/", "explicit/only"},
+		},
+		{
+			name: "missing",
+			old:  "missing",
+			new:  "dst",
+			err:  hpfs.ErrNotExist,
+		},
+		{
+			name: "root",
+			old:  ".",
+			new:  "dst",
+			err:  hpfs.ErrInvalid,
+		},
+		{
+			name: "directory into itself",
+			old:  "src",
+			new:  "src/sub/dst",
+			err:  hpfs.ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, fake := newTestFS(t, objects)
+
+			err := fs.Rename(tt.old, tt.new)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if err != nil {
+				if keys := fake.keys(); len(keys) != len(objects) {
+					t.Errorf("expected a failed rename to leave objects untouched, got %v", keys)
+				}
+
+				return
+			}
+
+			for _, key := range tt.keys {
+				if _, ok := fake.get("files/" + key); !ok {
+					t.Errorf("expected %q to exist after the rename, got %v", key, fake.keys())
+				}
+			}
+
+			if _, err := fs.Stat(tt.old); !errors.Is(err, hpfs.ErrNotExist) {
+				t.Errorf("expected %q to be gone after the rename, got %v", tt.old, err)
+			}
+		})
+	}
+}
+
+func TestRenameMultipartCopy(t *testing.T) {
+	// Lower the limit so that objects are copied part by part
+	limit := maxCopySize
+	maxCopySize = 4
+
+	t.Cleanup(func() { maxCopySize = limit })
+
+	fs, fake := newTestFS(t, map[string]string{"src/": "", "src/large": "larger than the limit"})
+
+	if err := fs.Rename("src", "dst"); err != nil {
+		t.Fatalf("error renaming directory: %s", err)
+	}
+
+	fake.mu.Lock()
+	composed := fake.composed
+	fake.mu.Unlock()
+
+	if composed == 0 {
+		t.Errorf("expected large objects to be copied part by part")
+	}
+
+	if data := readFile(t, fs, "dst/large"); data != "larger than the limit" {
+		t.Errorf("expected contents to be preserved, got %q", data)
+	}
+
+	if _, ok := fake.get("files/src/large"); ok {
+		t.Errorf("expected the source object to be removed")
+	}
+}
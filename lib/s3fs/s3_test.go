@@ -0,0 +1,378 @@
+package s3fs
+
+import (
+	"crypto/md5" //nolint:gosec
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// fakeS3 is an in-memory S3 server implementing the subset of the API used
+// by FS: objects, listings, copies and multipart uploads of a single bucket.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	uploads map[string]map[int][]byte
+	next    int
+
+	// composed counts the parts copied with UploadPartCopy.
+	composed int
+}
+
+type fakeObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+func (o fakeObject) etag() string {
+	sum := md5.Sum(o.data) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+// newFakeS3 starts a fake S3 server and returns it along with a client
+// connected to it.
+func newFakeS3(t *testing.T) (*fakeS3, *minio.Client) {
+	t.Helper()
+
+	fake := &fakeS3{
+		objects: map[string]fakeObject{},
+		uploads: map[string]map[int][]byte{},
+	}
+
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	// Anonymous requests are neither signed nor chunk-encoded
+	client, err := minio.New(strings.TrimPrefix(srv.URL, "http://"), &minio.Options{
+		Creds:        credentials.NewStaticV4("", "", ""),
+		Region:       "us-east-1",
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		t.Fatalf("error creating S3 client: %s", err)
+	}
+
+	return fake, client
+}
+
+// put stores an object directly.
+func (f *fakeS3) put(key, data string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.objects[key] = fakeObject{data: []byte(data), modTime: time.Now()}
+}
+
+// get returns the contents of an object directly.
+func (f *fakeS3) get(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	object, ok := f.objects[key]
+
+	return string(object.data), ok
+}
+
+// keys returns the sorted keys of all objects.
+func (f *fakeS3) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+
+	switch {
+	case key == "" && r.Method == http.MethodGet && query.Has("location"):
+		writeXML(w, http.StatusOK, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+		}{})
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, query)
+	case r.Method == http.MethodHead, r.Method == http.MethodGet:
+		f.read(w, r, key)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		f.uploadPart(w, r, query)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		f.copy(w, r, key)
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = fakeObject{data: data, modTime: time.Now()}
+		w.Header().Set("ETag", strconv.Quote(f.objects[key].etag()))
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.next++
+		id := strconv.Itoa(f.next)
+		f.uploads[id] = map[int][]byte{}
+
+		writeXML(w, http.StatusOK, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Key      string
+			UploadID string `xml:"UploadId"`
+		}{Key: key, UploadID: id})
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		f.complete(w, r, bucket, key, query.Get("uploadId"))
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// read serves HEAD and GET object requests, with an optional byte range.
+func (f *fakeS3) read(w http.ResponseWriter, r *http.Request, key string) {
+	object, ok := f.objects[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	data, status := object.data, http.StatusOK
+
+	var start, end int
+	if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err == nil {
+		end = min(end, len(data)-1)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data, status = data[start:end+1], http.StatusPartialContent
+	}
+
+	w.Header().Set("ETag", strconv.Quote(object.etag()))
+	w.Header().Set("Last-Modified", object.modTime.UTC().Format(http.TimeFormat))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(data)
+	}
+}
+
+// source returns the object referenced by the copy source header.
+func (f *fakeS3) source(r *http.Request) (fakeObject, bool) {
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		return fakeObject{}, false
+	}
+
+	source, _, _ = strings.Cut(source, "?")
+	_, key, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	object, ok := f.objects[key]
+
+	return object, ok
+}
+
+func (f *fakeS3) copy(w http.ResponseWriter, r *http.Request, key string) {
+	object, ok := f.source(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	object.modTime = time.Now()
+	f.objects[key] = object
+
+	writeXML(w, http.StatusOK, struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		LastModified string
+		ETag         string
+	}{LastModified: object.modTime.UTC().Format(time.RFC3339), ETag: strconv.Quote(object.etag())})
+}
+
+// uploadPart stores a part of a multipart upload, either from the body or
+// from a range of another object.
+func (f *fakeS3) uploadPart(w http.ResponseWriter, r *http.Request, query url.Values) {
+	parts, ok := f.uploads[query.Get("uploadId")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	number, _ := strconv.Atoi(query.Get("partNumber"))
+
+	if r.Header.Get("X-Amz-Copy-Source") == "" {
+		data, _ := io.ReadAll(r.Body)
+		parts[number] = data
+		w.Header().Set("ETag", strconv.Quote(fakeObject{data: data}.etag()))
+
+		return
+	}
+
+	object, ok := f.source(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	data := object.data
+
+	var start, end int
+	if _, err := fmt.Sscanf(r.Header.Get("X-Amz-Copy-Source-Range"), "bytes=%d-%d", &start, &end); err == nil {
+		data = data[start : end+1]
+	}
+
+	parts[number] = data
+	f.composed++
+
+	writeXML(w, http.StatusOK, struct {
+		XMLName      xml.Name `xml:"CopyPartResult"`
+		LastModified string
+		ETag         string
+	}{LastModified: time.Now().UTC().Format(time.RFC3339), ETag: strconv.Quote(fakeObject{data: data}.etag())})
+}
+
+func (f *fakeS3) complete(w http.ResponseWriter, r *http.Request, bucket, key, id string) {
+	parts, ok := f.uploads[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	var req struct {
+		Parts []struct {
+			PartNumber int
+		} `xml:"Part"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+
+	var data []byte
+	for _, part := range req.Parts {
+		data = append(data, parts[part.PartNumber]...)
+	}
+
+	delete(f.uploads, id)
+	f.objects[key] = fakeObject{data: data, modTime: time.Now()}
+
+	writeXML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+		Bucket  string
+		Key     string
+		ETag    string
+	}{Bucket: bucket, Key: key, ETag: strconv.Quote(f.objects[key].etag())})
+}
+
+// list serves ListObjectsV2 requests. Keys and common prefixes are returned
+// in a single sorted sequence, which the continuation token points into.
+func (f *fakeS3) list(w http.ResponseWriter, query url.Values) {
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+
+	type commonPrefix struct {
+		Prefix string
+	}
+
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+
+	maxKeys, err := strconv.Atoi(query.Get("max-keys"))
+	if err != nil || maxKeys <= 0 {
+		maxKeys = 1000
+	}
+
+	var entries []string
+
+	for key := range f.objects {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+
+		if i := strings.Index(rest, delimiter); delimiter != "" && i >= 0 {
+			key = prefix + rest[:i+len(delimiter)]
+		}
+
+		if !slices.Contains(entries, key) {
+			entries = append(entries, key)
+		}
+	}
+
+	slices.Sort(entries)
+
+	if token := query.Get("continuation-token"); token != "" {
+		i, _ := slices.BinarySearch(entries, token)
+		entries = entries[min(i+1, len(entries)):]
+	}
+
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Prefix                string
+		Delimiter             string
+		MaxKeys               int
+		KeyCount              int
+		IsTruncated           bool
+		NextContinuationToken string
+		Contents              []content
+		CommonPrefixes        []commonPrefix
+	}{Prefix: prefix, Delimiter: delimiter, MaxKeys: maxKeys}
+
+	if len(entries) > maxKeys {
+		entries = entries[:maxKeys]
+		result.IsTruncated = true
+		result.NextContinuationToken = entries[len(entries)-1]
+	}
+
+	for _, entry := range entries {
+		object, ok := f.objects[entry]
+		if !ok || (delimiter != "" && strings.HasSuffix(entry, delimiter) && entry != prefix) {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: entry})
+			continue
+		}
+
+		result.Contents = append(result.Contents, content{
+			Key:          entry,
+			LastModified: object.modTime.UTC().Format(time.RFC3339),
+			ETag:         strconv.Quote(object.etag()),
+			Size:         len(object.data),
+		})
+	}
+
+	result.KeyCount = len(entries)
+
+	writeXML(w, http.StatusOK, result)
+}
+
+func writeXML(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	writeXML(w, status, struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+	}{Code: code})
+}
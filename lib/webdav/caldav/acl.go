@@ -0,0 +1,168 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+
+	daverr "github.com/teapotovh/teapot/lib/webdav/error"
+	"github.com/teapotovh/teapot/lib/webdav/internal"
+)
+
+// Privilege is a WebDAV ACL privilege, as defined in RFC 3744, section 3.
+type Privilege string
+
+const (
+	PrivilegeAll      Privilege = "all"
+	PrivilegeRead     Privilege = "read"
+	PrivilegeWrite    Privilege = "write"
+	PrivilegeWriteACL Privilege = "write-acl"
+)
+
+// ACE is an access control entry, granting privileges on a calendar to the
+// principal at the given path, as defined in RFC 3744, section 5.5. Protected
+// entries cannot be changed by clients.
+type ACE struct {
+	Principal  string
+	Privileges []Privilege
+	Protected  bool
+}
+
+// ACLBackend can be implemented by a Backend to let clients change the
+// access control list of calendars through the ACL method.
+type ACLBackend interface {
+	// SetCalendarACL replaces the unprotected entries of the access control
+	// list of the calendar at path.
+	SetCalendarACL(ctx context.Context, path string, acl []ACE) error
+}
+
+// ACLPreconditionType implements https://datatracker.ietf.org/doc/html/rfc3744#section-8.1.1
+type ACLPreconditionType string
+
+const (
+	ACLPreconditionNoProtectedACEConflict ACLPreconditionType = "no-protected-ace-conflict"
+	ACLPreconditionGrantOnly              ACLPreconditionType = "grant-only"
+	ACLPreconditionNotSupportedPrivilege  ACLPreconditionType = "not-supported-privilege"
+	ACLPreconditionRecognizedPrincipal    ACLPreconditionType = "recognized-principal"
+)
+
+func NewACLPreconditionError(err ACLPreconditionType) error {
+	name := xml.Name{Space: internal.Namespace, Local: string(err)}
+	elem := internal.NewRawXMLElement(name, nil, nil)
+
+	return &daverr.HTTPError{
+		Code: http.StatusForbidden,
+		Err: &internal.Error{
+			Raw: []internal.RawXMLValue{*elem},
+		},
+	}
+}
+
+func encodePrivileges(privileges []Privilege) []internal.Privilege {
+	out := make([]internal.Privilege, 0, len(privileges))
+
+	for _, privilege := range privileges {
+		var p internal.Privilege
+
+		switch privilege {
+		case PrivilegeAll:
+			p.All = &struct{}{}
+		case PrivilegeRead:
+			p.Read = &struct{}{}
+		case PrivilegeWrite:
+			p.Write = &struct{}{}
+		case PrivilegeWriteACL:
+			p.WriteACL = &struct{}{}
+		default:
+			continue
+		}
+
+		out = append(out, p)
+	}
+
+	return out
+}
+
+func decodePrivileges(privileges []internal.Privilege) ([]Privilege, error) {
+	out := make([]Privilege, 0, len(privileges))
+
+	for _, p := range privileges {
+		switch {
+		case p.All != nil:
+			out = append(out, PrivilegeAll)
+		case p.Read != nil:
+			out = append(out, PrivilegeRead)
+		case p.Write != nil:
+			out = append(out, PrivilegeWrite)
+		case p.WriteACL != nil:
+			out = append(out, PrivilegeWriteACL)
+		default:
+			return nil, NewACLPreconditionError(ACLPreconditionNotSupportedPrivilege)
+		}
+	}
+
+	return out, nil
+}
+
+func encodeACL(acl []ACE) *internal.ACL {
+	aces := make([]internal.ACE, 0, len(acl))
+
+	for _, ace := range acl {
+		e := internal.ACE{
+			Principal: internal.Principal{Href: &internal.Href{Path: ace.Principal}},
+			Grant:     &internal.Grant{Privileges: encodePrivileges(ace.Privileges)},
+		}
+		if ace.Protected {
+			e.Protected = &struct{}{}
+		}
+
+		aces = append(aces, e)
+	}
+
+	return &internal.ACL{ACEs: aces}
+}
+
+func decodeACL(acl *internal.ACL) ([]ACE, error) {
+	aces := make([]ACE, 0, len(acl.ACEs))
+
+	for _, ace := range acl.ACEs {
+		if ace.Deny != nil || ace.Grant == nil {
+			return nil, NewACLPreconditionError(ACLPreconditionGrantOnly)
+		} else if ace.Protected != nil {
+			return nil, NewACLPreconditionError(ACLPreconditionNoProtectedACEConflict)
+		} else if ace.Principal.Href == nil {
+			return nil, NewACLPreconditionError(ACLPreconditionRecognizedPrincipal)
+		}
+
+		privileges, err := decodePrivileges(ace.Grant.Privileges)
+		if err != nil {
+			return nil, err
+		}
+
+		aces = append(aces, ACE{Principal: ace.Principal.Href.Path, Privileges: privileges})
+	}
+
+	return aces, nil
+}
+
+// https://datatracker.ietf.org/doc/html/rfc3744#section-8.1
+func (b *backend) ACL(r *http.Request, acl *internal.ACL) error {
+	ab, ok := b.Backend.(ACLBackend)
+	if !ok {
+		return daverr.HTTPErrorf(http.StatusMethodNotAllowed, "caldav: server does not support ACL")
+	}
+
+	if b.resourceTypeAtPath(r.URL.Path) != resourceTypeCalendar {
+		return daverr.HTTPErrorf(http.StatusMethodNotAllowed, "caldav: ACL is only supported on calendars")
+	}
+
+	aces, err := decodeACL(acl)
+	if err != nil {
+		return err
+	}
+
+	return ab.SetCalendarACL(r.Context(), r.URL.Path, aces)
+}
+
+// Ensure backend implements internal.WithACL.
+var _ internal.WithACL = &backend{}
@@ -0,0 +1,244 @@
+package caldav
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// Alarm is a single trigger of a VALARM, attached to an instance of the
+// calendar component the VALARM belongs to.
+type Alarm struct {
+	// UID and RecurrenceID identify the instance of the component. The
+	// recurrence id is zero for components which do not recur.
+	UID          string
+	RecurrenceID time.Time
+	Start        time.Time
+	End          time.Time
+
+	Trigger time.Time
+	// Action is the ACTION of the VALARM, i.e., DISPLAY or EMAIL.
+	Action string
+	// Summary is the SUMMARY of the VALARM, falling back to the one of the
+	// component, while Description is the DESCRIPTION of the VALARM.
+	Summary     string
+	Description string
+}
+
+// trigger is a parsed VALARM, which can be resolved into alarms for any
+// instance of its component.
+type trigger struct {
+	alarm *ics.ComponentBase
+
+	// at is the time of absolute triggers. Relative ones are offset from the
+	// start of the instance, or from its end if fromEnd is set.
+	at      time.Time
+	offset  time.Duration
+	fromEnd bool
+
+	repeat   int
+	interval time.Duration
+}
+
+// times returns the times the trigger fires at for an instance.
+func (t trigger) times(start, end time.Time) []time.Time {
+	first := t.at
+	if first.IsZero() {
+		first = start
+		if t.fromEnd {
+			first = end
+		}
+
+		first = first.Add(t.offset)
+	}
+
+	times := []time.Time{first}
+	for i := 1; i <= t.repeat; i++ {
+		times = append(times, first.Add(time.Duration(i)*t.interval))
+	}
+
+	return times
+}
+
+// span is the farthest a relative trigger may fire from its instance.
+func (t trigger) span() time.Duration {
+	return max(t.offset, -t.offset) + time.Duration(t.repeat)*t.interval
+}
+
+// componentTriggers parses the VALARMs of a component.
+func componentTriggers(comp ics.Component) ([]trigger, error) {
+	base, err := componentBase(comp)
+	if err != nil {
+		return nil, err
+	}
+
+	var triggers []trigger
+
+	for _, child := range base.Components {
+		valarm, ok := child.(*ics.VAlarm)
+		if !ok {
+			continue
+		}
+
+		prop := valarm.GetProperty(ics.ComponentPropertyTrigger)
+		if prop == nil {
+			continue
+		}
+
+		t := trigger{alarm: &valarm.ComponentBase}
+
+		if slices.Contains(prop.ICalParameters[string(ics.ParameterValue)], string(ics.ValueDataTypeDateTime)) {
+			t.at, err = time.Parse(dateTimeLayout, prop.Value)
+		} else {
+			t.offset, err = parseDuration(prop.Value)
+			t.fromEnd = slices.Contains(prop.ICalParameters[string(ics.ParameterRelated)], "END")
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing alarm trigger %q: %w", prop.Value, err)
+		}
+
+		repeat := valarm.GetProperty(ics.ComponentProperty(ics.PropertyRepeat))
+		duration := valarm.GetProperty(ics.ComponentPropertyDuration)
+
+		if repeat != nil && duration != nil {
+			t.repeat, err = strconv.Atoi(repeat.Value)
+			if err != nil {
+				return nil, fmt.Errorf("error while parsing alarm repeat %q: %w", repeat.Value, err)
+			}
+
+			t.interval, err = parseDuration(duration.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		triggers = append(triggers, t)
+	}
+
+	return triggers, nil
+}
+
+// alarms resolves the triggers of an instance into the alarms firing within
+// the time range.
+func alarms(triggers []trigger, inst instance, rangeStart, rangeEnd time.Time) []Alarm {
+	base, _ := componentBase(inst.component)
+
+	var uid, summary string
+	if prop := base.GetProperty(ics.ComponentPropertyUniqueId); prop != nil {
+		uid = prop.Value
+	}
+
+	if prop := base.GetProperty(ics.ComponentPropertySummary); prop != nil {
+		summary = prop.Value
+	}
+
+	var result []Alarm
+
+	for _, t := range triggers {
+		alarm := Alarm{
+			UID:          uid,
+			RecurrenceID: inst.recurrenceID,
+			Start:        inst.start,
+			End:          inst.end,
+			Summary:      summary,
+		}
+
+		if prop := t.alarm.GetProperty(ics.ComponentPropertyAction); prop != nil {
+			alarm.Action = strings.ToUpper(prop.Value)
+		}
+
+		if prop := t.alarm.GetProperty(ics.ComponentPropertySummary); prop != nil && prop.Value != "" {
+			alarm.Summary = prop.Value
+		}
+
+		if prop := t.alarm.GetProperty(ics.ComponentPropertyDescription); prop != nil {
+			alarm.Description = prop.Value
+		}
+
+		for _, at := range t.times(inst.start, inst.end) {
+			if !at.Before(rangeStart) && at.Before(rangeEnd) {
+				alarm.Trigger = at
+				result = append(result, alarm)
+			}
+		}
+	}
+
+	return result
+}
+
+// Alarms returns the alarms of the components of a calendar which fire within
+// the time range, repetitions included. Alarms relative to a recurring
+// component fire once per instance, while absolute ones fire once per
+// component. Relative alarms of components without a start never fire.
+//
+//nolint:gocyclo
+func Alarms(cal *ics.Calendar, rangeStart, rangeEnd time.Time) ([]Alarm, error) {
+	var result []Alarm
+
+	sets, _ := recurrenceSets(cal)
+	for _, set := range sets {
+		absolute := map[ics.Component][]trigger{}
+		relative := map[ics.Component][]trigger{}
+
+		var lead time.Duration
+
+		for _, comp := range set {
+			triggers, err := componentTriggers(comp)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, t := range triggers {
+				if !t.at.IsZero() {
+					absolute[comp] = append(absolute[comp], t)
+					continue
+				}
+
+				relative[comp] = append(relative[comp], t)
+				lead = max(lead, t.span())
+			}
+		}
+
+		for comp, triggers := range absolute {
+			start, end, err := componentTimeRange(comp)
+			if err != nil {
+				return nil, err
+			}
+
+			id, err := recurrenceID(comp)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, alarms(triggers, instance{id, start, end, comp}, rangeStart, rangeEnd)...)
+		}
+
+		if len(relative) == 0 {
+			continue
+		}
+
+		// Relative alarms may fire well before or after their instance, so
+		// instances are searched in a range widened by the farthest of them.
+		err := instances(set, rangeStart.Add(-lead), rangeEnd.Add(lead), func(inst instance) bool {
+			if !inst.start.IsZero() {
+				result = append(result, alarms(relative[inst.component], inst, rangeStart, rangeEnd)...)
+			}
+
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error while computing instances: %w", err)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b Alarm) int {
+		return a.Trigger.Compare(b.Trigger)
+	})
+
+	return result, nil
+}
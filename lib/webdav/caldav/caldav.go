@@ -32,6 +32,26 @@ type Calendar struct {
 	MaxResourceSize       int64
 	SupportedComponentSet []string
 	Color                 string
+	// SyncToken identifies the current state of the calendar, as defined in
+	// RFC 6578. It is not advertised if empty.
+	SyncToken string
+	// Owner is the path of the principal owning the calendar. It is not
+	// advertised if empty.
+	Owner string
+	// Privileges are the privileges of the current user principal on the
+	// calendar. If nil, the user is assumed to be able to read and write.
+	Privileges []Privilege
+	// ACL is the access control list of the calendar. It is not advertised
+	// if nil.
+	ACL []ACE
+}
+
+// CalendarUpdate holds the properties of a calendar changed by a PROPPATCH
+// request. Nil fields are left untouched, while empty ones are removed.
+type CalendarUpdate struct {
+	Name        *string
+	Description *string
+	Color       *string
 }
 
 type CalendarCompRequest struct {
@@ -43,7 +63,12 @@ type CalendarCompRequest struct {
 	AllComps bool
 	Comps    []CalendarCompRequest
 
+	// Expand requests recurring components to be expanded into their
+	// instances, in UTC, within the time range.
 	Expand *CalendarExpandRequest
+	// LimitRecurrenceSet requests overridden instances outside of the time
+	// range to be left out.
+	LimitRecurrenceSet *CalendarExpandRequest
 }
 
 type CalendarExpandRequest struct {
@@ -94,3 +119,23 @@ type CalendarObject struct {
 	ETag          string
 	Data          *ics.Calendar
 }
+
+// SyncQuery represents a sync-collection request, as defined in RFC 6578.
+type SyncQuery struct {
+	CompRequest CalendarCompRequest
+	// SyncToken is the token returned by a previous sync, or empty for the
+	// initial sync.
+	SyncToken string
+	Limit     int // <= 0 means unlimited
+}
+
+// SyncResponse contains the objects changed since the token of a SyncQuery,
+// along with the token to be used for the next sync.
+type SyncResponse struct {
+	SyncToken string
+	Updated   []CalendarObject
+	Deleted   []string
+	// Truncated is set when only part of the changes have been returned,
+	// because of the query limit.
+	Truncated bool
+}
@@ -30,6 +30,11 @@ var (
 
 	calendarQueryName    = xml.Name{Space: caldavNS, Local: "calendar-query"}
 	calendarMultigetName = xml.Name{Space: caldavNS, Local: "calendar-multiget"}
+	freeBusyQueryName    = xml.Name{Space: caldavNS, Local: "free-busy-query"}
+
+	scheduleOutboxName         = xml.Name{Space: caldavNS, Local: "schedule-outbox"}
+	scheduleOutboxURLName      = xml.Name{Space: caldavNS, Local: "schedule-outbox-URL"}
+	calendarUserAddressSetName = xml.Name{Space: caldavNS, Local: "calendar-user-address-set"}
 
 	calendarName     = xml.Name{Space: caldavNS, Local: "calendar"}
 	calendarDataName = xml.Name{Space: caldavNS, Local: "calendar-data"}
@@ -45,6 +50,38 @@ func (a *calendarHomeSet) GetXMLName() xml.Name {
 	return calendarHomeSetName
 }
 
+// https://tools.ietf.org/html/rfc6638#section-2.1.1
+type scheduleOutboxURL struct {
+	XMLName xml.Name      `xml:"urn:ietf:params:xml:ns:caldav schedule-outbox-URL"`
+	Href    internal.Href `xml:"DAV: href"`
+}
+
+// https://tools.ietf.org/html/rfc6638#section-2.4.1
+type calendarUserAddressSet struct {
+	XMLName xml.Name        `xml:"urn:ietf:params:xml:ns:caldav calendar-user-address-set"`
+	Hrefs   []internal.Href `xml:"DAV: href"`
+}
+
+// https://tools.ietf.org/html/rfc6638#section-10.1
+type scheduleResponse struct {
+	XMLName   xml.Name                `xml:"urn:ietf:params:xml:ns:caldav schedule-response"`
+	Responses []scheduleResponseEntry `xml:"response"`
+}
+
+// https://tools.ietf.org/html/rfc6638#section-10.2
+type scheduleResponseEntry struct {
+	XMLName       xml.Name          `xml:"urn:ietf:params:xml:ns:caldav response"`
+	Recipient     recipient         `xml:"recipient"`
+	RequestStatus string            `xml:"request-status"`
+	CalendarData  *calendarDataResp `xml:"calendar-data,omitempty"`
+}
+
+// https://tools.ietf.org/html/rfc6638#section-10.3
+type recipient struct {
+	XMLName xml.Name      `xml:"urn:ietf:params:xml:ns:caldav recipient"`
+	Href    internal.Href `xml:"DAV: href"`
+}
+
 // https://tools.ietf.org/html/rfc4791#section-5.2.1
 type calendarDescription struct {
 	XMLName     xml.Name `xml:"urn:ietf:params:xml:ns:caldav calendar-description"`
@@ -69,6 +106,16 @@ type supportedCalendarComponentSet struct {
 	Comp    []comp   `xml:"comp"`
 }
 
+// compNames returns the names of the given comp elements.
+func compNames(comps []comp) []string {
+	var names []string
+	for _, c := range comps {
+		names = append(names, c.Name)
+	}
+
+	return names
+}
+
 // https://tools.ietf.org/html/rfc4791#section-9.6
 type calendarDataType struct {
 	XMLName     xml.Name `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
@@ -200,7 +247,9 @@ type calendarDataReq struct {
 	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
 	Comp    *comp    `xml:"comp,omitempty"`
 	Expand  *expand  `xml:"expand,omitempty"`
-	// TODO: limit-recurrence-set, limit-freebusy-set
+
+	LimitRecurrenceSet *limitRecurrenceSet `xml:"limit-recurrence-set,omitempty"`
+	// TODO: limit-freebusy-set
 }
 
 // https://tools.ietf.org/html/rfc4791#section-9.6.1
@@ -221,6 +270,13 @@ type expand struct {
 	End     dateWithUTCTime `xml:"end,attr"`
 }
 
+// https://tools.ietf.org/html/rfc4791#section-9.6.6
+type limitRecurrenceSet struct {
+	XMLName xml.Name        `xml:"urn:ietf:params:xml:ns:caldav limit-recurrence-set"`
+	Start   dateWithUTCTime `xml:"start,attr"`
+	End     dateWithUTCTime `xml:"end,attr"`
+}
+
 // https://tools.ietf.org/html/rfc4791#section-9.6.4
 type prop struct {
 	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:caldav prop"`
@@ -234,10 +290,17 @@ type calendarDataResp struct {
 	Data    []byte   `xml:",chardata"`
 }
 
+// https://tools.ietf.org/html/rfc4791#section-9.11
+type freeBusyQuery struct {
+	XMLName   xml.Name   `xml:"urn:ietf:params:xml:ns:caldav free-busy-query"`
+	TimeRange *timeRange `xml:"time-range"`
+}
+
 type reportReq struct {
-	Query    *calendarQuery
-	Multiget *calendarMultiget
-	// TODO: CALDAV:free-busy-query
+	Query          *calendarQuery
+	Multiget       *calendarMultiget
+	SyncCollection *internal.SyncCollectionQuery
+	FreeBusyQuery  *freeBusyQuery
 }
 
 func (r *reportReq) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
@@ -250,6 +313,12 @@ func (r *reportReq) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	case calendarMultigetName:
 		r.Multiget = &calendarMultiget{}
 		v = r.Multiget
+	case internal.SyncCollectionName:
+		r.SyncCollection = &internal.SyncCollectionQuery{}
+		v = r.SyncCollection
+	case freeBusyQueryName:
+		r.FreeBusyQuery = &freeBusyQuery{}
+		v = r.FreeBusyQuery
 	default:
 		return fmt.Errorf("caldav: %w %q %q", ErrUnsupportedREPORTRoot, start.Name.Space, start.Name.Local)
 	}
@@ -262,7 +331,7 @@ type mkcolReq struct {
 	ResourceType        internal.ResourceType `xml:"set>prop>resourcetype"`
 	DisplayName         string                `xml:"set>prop>displayname"`
 	Description         string                `xml:"set>prop>calendar-description"`
-	SupportedComponents []string              `xml:"set>prop>supported-calendar-component-set>comp"`
+	SupportedComponents []comp                `xml:"set>prop>supported-calendar-component-set>comp"`
 	MaxResourceSize     int64                 `xml:"set>prop>max-resource-size"`
 }
 
@@ -270,6 +339,6 @@ type mkcalendarReq struct {
 	XMLName             xml.Name `xml:"urn:ietf:params:xml:ns:caldav mkcalendar"`
 	DisplayName         string   `xml:"set>prop>displayname"`
 	Description         string   `xml:"set>prop>calendar-description"`
-	SupportedComponents []string `xml:"set>prop>supported-calendar-component-set>comp"`
+	SupportedComponents []comp   `xml:"set>prop>supported-calendar-component-set>comp"`
 	MaxResourceSize     int64    `xml:"set>prop>max-resource-size"`
 }
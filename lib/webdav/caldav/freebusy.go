@@ -0,0 +1,244 @@
+package caldav
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// FreeBusyQuery is a request for the busy time of a calendar user within a
+// time range, as defined in RFC 4791, section 7.10.
+type FreeBusyQuery struct {
+	Start, End time.Time
+}
+
+// BusyPeriod is a period of time in which a calendar user is busy.
+type BusyPeriod struct {
+	Start, End time.Time
+	Type       ics.FreeBusyTimeType
+}
+
+// FreeBusy computes the busy periods within the time range from the events
+// and free/busy components of the given calendars, following RFC 4791,
+// section 7.10. Transparent and cancelled events are ignored, and so are
+// events which the owner of the calendars, identified by the calendar user
+// addresses, has declined.
+//
+// Periods of the same type are merged, and all periods are clipped to the
+// time range.
+func FreeBusy(cals []*ics.Calendar, start, end time.Time, addresses []string) ([]BusyPeriod, error) {
+	if start.IsZero() || end.IsZero() {
+		return nil, ErrUnboundedTimeRange
+	}
+
+	var periods []BusyPeriod
+
+	for _, cal := range cals {
+		sets, others := recurrenceSets(cal)
+
+		for _, set := range sets {
+			if _, ok := set[0].(*ics.VEvent); !ok {
+				continue
+			}
+
+			err := instances(set, start, end, func(inst instance) bool {
+				if fbtype, busy := eventBusyType(inst.component, addresses); busy && inst.end.After(inst.start) {
+					periods = append(periods, BusyPeriod{Start: inst.start, End: inst.end, Type: fbtype})
+				}
+
+				return true
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error while computing instances: %w", err)
+			}
+		}
+
+		for _, comp := range others {
+			busy, ok := comp.(*ics.VBusy)
+			if !ok {
+				continue
+			}
+
+			ps, err := freeBusyPeriods(busy, start, end)
+			if err != nil {
+				return nil, err
+			}
+
+			periods = append(periods, ps...)
+		}
+	}
+
+	return mergeBusyPeriods(periods, start, end), nil
+}
+
+// eventBusyType reports whether an event makes its owner busy, and how.
+func eventBusyType(comp ics.Component, addresses []string) (ics.FreeBusyTimeType, bool) {
+	base, err := componentBase(comp)
+	if err != nil {
+		return "", false
+	}
+
+	if prop := base.GetProperty(ics.ComponentPropertyTransp); prop != nil &&
+		strings.EqualFold(prop.Value, string(ics.TransparencyTransparent)) {
+		return "", false
+	}
+
+	fbtype := ics.FreeBusyTimeTypeBusy
+
+	if prop := base.GetProperty(ics.ComponentPropertyStatus); prop != nil {
+		switch strings.ToUpper(prop.Value) {
+		case string(ics.ObjectStatusCancelled):
+			return "", false
+		case string(ics.ObjectStatusTentative):
+			fbtype = ics.FreeBusyTimeTypeBusyTentative
+		}
+	}
+
+	for _, attendee := range base.GetProperties(ics.ComponentPropertyAttendee) {
+		if !hasAddress(addresses, attendee.Value) {
+			continue
+		}
+
+		partstat := attendee.ICalParameters[string(ics.ParameterParticipationStatus)]
+		switch {
+		case slices.Contains(partstat, string(ics.ParticipationStatusDeclined)):
+			return "", false
+		case slices.Contains(partstat, string(ics.ParticipationStatusTentative)):
+			fbtype = ics.FreeBusyTimeTypeBusyTentative
+		}
+	}
+
+	return fbtype, true
+}
+
+// hasAddress reports whether address is one of the calendar user addresses.
+// Addresses are compared case insensitively, as they usually are mailto URIs.
+func hasAddress(addresses []string, address string) bool {
+	return slices.ContainsFunc(addresses, func(a string) bool { return strings.EqualFold(a, address) })
+}
+
+// freeBusyPeriods returns the busy periods listed by the FREEBUSY properties
+// of a free/busy component which overlap the time range.
+func freeBusyPeriods(busy *ics.VBusy, start, end time.Time) ([]BusyPeriod, error) {
+	var periods []BusyPeriod
+
+	for _, prop := range busy.GetProperties(ics.ComponentPropertyFreebusy) {
+		fbtype := ics.FreeBusyTimeTypeBusy
+		if values := prop.ICalParameters[string(ics.ParameterFbtype)]; len(values) > 0 {
+			fbtype = ics.FreeBusyTimeType(strings.ToUpper(values[0]))
+		}
+
+		if fbtype == ics.FreeBusyTimeTypeFree {
+			continue
+		}
+
+		for value := range strings.SplitSeq(prop.Value, ",") {
+			pstart, pend, err := parsePeriod(value)
+			if err != nil {
+				return nil, err
+			}
+
+			if overlaps(pstart, pend, start, end) {
+				periods = append(periods, BusyPeriod{Start: pstart, End: pend, Type: fbtype})
+			}
+		}
+	}
+
+	return periods, nil
+}
+
+// parsePeriod parses a period of time in UTC, as defined in RFC 5545,
+// section 3.3.9. The period either has an explicit end or a duration.
+func parsePeriod(value string) (start, end time.Time, err error) {
+	rawStart, rawEnd, ok := strings.Cut(value, "/")
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, value)
+	}
+
+	start, err = time.Parse(dateTimeLayout, rawStart)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, value)
+	}
+
+	if strings.HasPrefix(rawEnd, "P") || strings.HasPrefix(rawEnd, "+P") {
+		duration, err := parseDuration(rawEnd)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		return start, start.Add(duration), nil
+	}
+
+	end, err = time.Parse(dateTimeLayout, rawEnd)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q", ErrInvalidPeriod, value)
+	}
+
+	return start, end, nil
+}
+
+// mergeBusyPeriods clips periods to the time range, and merges the
+// overlapping ones of the same type.
+func mergeBusyPeriods(periods []BusyPeriod, start, end time.Time) []BusyPeriod {
+	slices.SortFunc(periods, func(a, b BusyPeriod) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), a.Start.Compare(b.Start))
+	})
+
+	var merged []BusyPeriod
+
+	for _, period := range periods {
+		if period.Start.Before(start) {
+			period.Start = start
+		}
+
+		if period.End.After(end) {
+			period.End = end
+		}
+
+		if last := len(merged) - 1; last >= 0 && merged[last].Type == period.Type &&
+			!period.Start.After(merged[last].End) {
+			if period.End.After(merged[last].End) {
+				merged[last].End = period.End
+			}
+
+			continue
+		}
+
+		merged = append(merged, period)
+	}
+
+	slices.SortStableFunc(merged, func(a, b BusyPeriod) int { return a.Start.Compare(b.Start) })
+
+	return merged
+}
+
+// NewFreeBusy builds a VFREEBUSY component which lists the busy periods
+// within the time range, in UTC. The UID is omitted if empty, as allowed in
+// responses to free-busy-query reports.
+func NewFreeBusy(uid string, start, end time.Time, periods []BusyPeriod) *ics.VBusy {
+	busy := ics.NewBusy(uid)
+	if uid == "" {
+		busy.RemoveProperty(ics.ComponentPropertyUniqueId)
+	}
+
+	busy.SetProperty(ics.ComponentPropertyDtstamp, time.Now().UTC().Format(dateTimeLayout))
+	busy.SetProperty(ics.ComponentPropertyDtStart, start.UTC().Format(dateTimeLayout))
+	busy.SetProperty(ics.ComponentPropertyDtEnd, end.UTC().Format(dateTimeLayout))
+
+	for _, period := range periods {
+		busy.AddProperty(
+			ics.ComponentPropertyFreebusy,
+			period.Start.UTC().Format(dateTimeLayout)+"/"+period.End.UTC().Format(dateTimeLayout),
+			&ics.KeyValues{Key: string(ics.ParameterFbtype), Value: []string{string(period.Type)}},
+		)
+	}
+
+	return busy
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	ics "github.com/arran4/golang-ical"
-	"github.com/teambition/rrule-go"
 )
 
 var (
@@ -42,39 +41,98 @@ func Filter(query *CompFilter, cos []CalendarObject) ([]CalendarObject, error) {
 	return out, nil
 }
 
-// Match reports whether the provided CalendarObject matches the query.
+// Match reports whether the provided CalendarObject matches the query, which
+// applies to the VCALENDAR object as a whole.
 func Match(query CompFilter, co *CalendarObject) (bool, error) {
 	if co.Data == nil || len(co.Data.Components) <= 0 {
 		return false, ErrMatchEmptyObject
 	}
 
-	// TODO handle more than just events
-	for i, event := range co.Data.Events() {
-		matches, err := match(query, event)
+	for _, filter := range query.Comps {
+		matches, err := matchObjectCompFilter(filter, co.Data)
 		if err != nil {
-			return false, fmt.Errorf("parsing component %d: %w", i, err)
+			return false, fmt.Errorf("error while matching component filter %q: %w", filter.Name, err)
 		}
 
-		if matches {
-			return true, nil
+		if !matches {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// matchObjectCompFilter matches a comp-filter against the components of a
+// calendar object. With a time range, a recurring component matches if any of
+// its instances does, in which case the other filters are applied to the
+// component the instance originates from.
+func matchObjectCompFilter(filter CompFilter, cal *ics.Calendar) (bool, error) {
+	var sets [][]ics.Component
+
+	recurring, others := recurrenceSets(cal)
+	for _, set := range recurring {
+		if ComponentName(set[0]) == filter.Name {
+			sets = append(sets, set)
+		}
+	}
+
+	for _, comp := range others {
+		if ComponentName(comp) == filter.Name {
+			sets = append(sets, []ics.Component{comp})
+		}
+	}
+
+	if len(sets) == 0 {
+		return filter.IsNotDefined, nil
+	} else if filter.IsNotDefined {
+		return false, nil
+	}
+
+	untimed := filter
+	untimed.Start, untimed.End = zeroDate, zeroDate
+
+	for _, set := range sets {
+		if filter.Start.IsZero() && filter.End.IsZero() {
+			for _, comp := range set {
+				matches, err := match(untimed, comp)
+				if err != nil || matches {
+					return matches, err
+				}
+			}
+
+			continue
+		}
+
+		var (
+			matches  bool
+			matchErr error
+		)
+
+		err := instances(set, filter.Start, filter.End, func(inst instance) bool {
+			matches, matchErr = match(untimed, inst.component)
+			return !matches && matchErr == nil
+		})
+		if err != nil {
+			return false, fmt.Errorf("error while computing instances: %w", err)
+		} else if matchErr != nil || matches {
+			return matches, matchErr
 		}
 	}
 
 	return false, nil
 }
 
-func match(filter CompFilter, comp *ics.VEvent) (bool, error) {
-	// TODO: can we support this? doesn't look like Components have a NAME property
-	// if comp.Name != filter.Name {
-	// 	return filter.IsNotDefined, nil
-	// }
-	if filter.Start != zeroDate {
-		match, err := matchCompTimeRange(filter.Start, filter.End, comp)
+func match(filter CompFilter, comp ics.Component) (bool, error) {
+	if !filter.Start.IsZero() || !filter.End.IsZero() {
+		start, end, err := componentTimeRange(comp)
 		if err != nil {
 			return false, fmt.Errorf("error while matching time: %w", err)
 		}
 
-		if !match {
+		ok, err := componentOverlaps(comp, start, end, filter.Start, filter.End)
+		if err != nil {
+			return false, fmt.Errorf("error while matching time: %w", err)
+		} else if !ok {
 			return false, nil
 		}
 	}
@@ -104,38 +162,46 @@ func match(filter CompFilter, comp *ics.VEvent) (bool, error) {
 	return true, nil
 }
 
-func matchCompFilter(filter CompFilter, comp *ics.VEvent) (bool, error) {
-	matches := false
-
-	for _, child := range comp.Components {
-		switch event := child.(type) {
-		case *ics.VEvent:
-			match, err := match(filter, event)
-			if err != nil {
-				return false, err
-			} else {
-				matches = matches || match
-			}
-		}
+// matchCompFilter matches a comp-filter against the subcomponents of comp,
+// i.e., the VALARMs of a VEVENT.
+func matchCompFilter(filter CompFilter, comp ics.Component) (bool, error) {
+	base, err := componentBase(comp)
+	if err != nil {
+		return false, err
 	}
 
-	if !matches {
-		return filter.IsNotDefined, nil
+	for _, child := range base.Components {
+		if ComponentName(child) != filter.Name {
+			continue
+		} else if filter.IsNotDefined {
+			return false, nil
+		}
+
+		match, err := match(filter, child)
+		if err != nil || match {
+			return match, err
+		}
 	}
 
-	return true, nil
+	return filter.IsNotDefined, nil
 }
 
-func matchPropFilter(filter PropFilter, comp *ics.VEvent) (bool, error) {
+func matchPropFilter(filter PropFilter, comp ics.Component) (bool, error) {
+	base, err := componentBase(comp)
+	if err != nil {
+		return false, err
+	}
+
 	prop := ics.ComponentProperty(filter.Name)
-	if prop.Singular(comp) {
-		field := comp.GetProperty(prop)
-		if field == nil {
-			return filter.IsNotDefined, nil
-		}
+
+	fields := base.GetProperties(prop)
+	if len(fields) == 0 {
+		return filter.IsNotDefined, nil
+	} else if filter.IsNotDefined {
+		return false, nil
 	}
 
-	for _, field := range comp.GetProperties(prop) {
+	for _, field := range fields {
 		for _, paramFilter := range filter.ParamFilter {
 			if !matchParamFilter(paramFilter, field) {
 				return false, nil
@@ -148,25 +214,12 @@ func matchPropFilter(filter PropFilter, comp *ics.VEvent) (bool, error) {
 	// 2. Text matching
 	switch {
 	case filter.Start != zeroDate:
-		var (
-			dates []time.Time
-			err   error
-		)
-
-		switch prop { //nolint:exhaustive
-		case ics.ComponentPropertyRdate:
-			dates, err = comp.GetRDates()
-
-		case ics.ComponentPropertyExdate:
-			dates, err = comp.GetExDates()
-
-		default:
-			// Matching a date against a non-date prop, invalid
-			return false, nil
-		}
-
+		dates, err := propertyTimes(comp, base, prop)
 		if err != nil {
 			return false, fmt.Errorf("error while getting dates for property %q: %w", string(prop), err)
+		} else if dates == nil {
+			// Matching a date against a non-date prop, invalid
+			return false, nil
 		}
 
 		for _, date := range dates {
@@ -179,7 +232,7 @@ func matchPropFilter(filter PropFilter, comp *ics.VEvent) (bool, error) {
 		return true, nil
 
 	case filter.TextMatch != nil:
-		for _, field := range comp.GetProperties(prop) {
+		for _, field := range fields {
 			if !matchTextMatch(*filter.TextMatch, field.Value) {
 				return false, nil
 			}
@@ -193,73 +246,44 @@ func matchPropFilter(filter PropFilter, comp *ics.VEvent) (bool, error) {
 	return true, nil
 }
 
-//nolint:gocyclo
-func matchCompTimeRange(start, end time.Time, comp *ics.VEvent) (bool, error) {
-	// See https://datatracker.ietf.org/doc/html/rfc4791#section-9.9
-	eventStart, err := comp.GetStartAt()
-	if err != nil {
-		return false, fmt.Errorf("while parsing event start time: %w", err)
-	}
-
-	rules, err := comp.GetRRules()
-	if err != nil {
-		return false, fmt.Errorf("while parsing event recurrence rules: %w", err)
-	}
-
-	switch {
-	case len(rules) > 0:
-		// Build an RRuleSet from the event's recurrence properties
-		rset := &rrule.Set{}
-
-		for _, r := range rules {
-			rr, _ := rrule.StrToRRule(r.String())
-			rr.DTStart(eventStart)
-			rset.RRule(rr)
-		}
-
-		rDates, err := comp.GetRDates()
-		if err != nil {
-			return false, fmt.Errorf("while parsing event recurrence dates: %w", err)
-		}
-
-		for _, rd := range rDates {
-			rset.RDate(rd)
-		}
-
-		exDates, err := comp.GetExDates()
-		if err != nil {
-			return false, fmt.Errorf("while parsing event exclude dates: %w", err)
-		}
-
-		for _, exd := range exDates {
-			rset.ExDate(exd)
+// propertyTimes parses the values of a date or date-time property. It
+// returns nil for properties of any other type.
+func propertyTimes(comp ics.Component, base *ics.ComponentBase, prop ics.ComponentProperty) ([]time.Time, error) {
+	var (
+		t   time.Time
+		err error
+	)
+
+	switch prop { //nolint:exhaustive
+	case ics.ComponentPropertyRdate:
+		return base.GetRDates()
+	case ics.ComponentPropertyExdate:
+		return base.GetExDates()
+	case ics.ComponentPropertyDtStart:
+		t, err = base.GetStartAt()
+	case ics.ComponentPropertyDtEnd:
+		t, err = base.GetEndAt()
+	case ics.ComponentPropertyRecurrenceId:
+		t, err = base.GetRecurrenceID()
+	case ics.ComponentPropertyDue:
+		todo, ok := comp.(*ics.VTodo)
+		if !ok {
+			return nil, nil
 		}
 
-		// TODO we can only set inclusive to true or false, but really the
-		// start time is inclusive while the end time is not :/
-		return len(rset.Between(start, end, true)) > 0, nil
-
+		t, err = todo.GetDueAt()
+	case ics.ComponentPropertyCompleted, ics.ComponentPropertyCreated, ics.ComponentPropertyDtstamp,
+		ics.ComponentPropertyLastModified:
+		t, err = utcProperty(base, prop)
 	default:
-		eventEnd, err := comp.GetEndAt()
-		if err != nil {
-			return false, fmt.Errorf("while parsing event end time: %w", err)
-		}
-
-		// Event starts in time range
-		if eventStart.After(start) && (end.IsZero() || eventStart.Before(end)) {
-			return true, nil
-		}
-		// Event ends in time range
-		if eventEnd.After(start) && (end.IsZero() || eventEnd.Before(end)) {
-			return true, nil
-		}
-		// Event covers entire time range plus some
-		if eventStart.Before(start) && (!end.IsZero() && eventEnd.After(end)) {
-			return true, nil
-		}
+		return nil, nil
+	}
 
-		return false, nil
+	if err != nil {
+		return nil, err
 	}
+
+	return []time.Time{t}, nil
 }
 
 func matchPropTimeRange(start, end, ptime time.Time) bool {
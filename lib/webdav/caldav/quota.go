@@ -0,0 +1,45 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+
+	daverr "github.com/teapotovh/teapot/lib/webdav/error"
+	"github.com/teapotovh/teapot/lib/webdav/internal"
+)
+
+// Quota reports the storage used by a collection and the storage still
+// available to it, as defined in RFC 4331.
+type Quota struct {
+	UsedBytes      int64
+	AvailableBytes int64
+}
+
+// QuotaBackend can be implemented by a Backend to advertise the quota of the
+// calendar home set of the current user.
+type QuotaBackend interface {
+	CalendarHomeSetQuota(ctx context.Context) (*Quota, error)
+}
+
+// NewQuotaExceededError returns the error reported when storing a resource
+// would exceed the quota of a collection, as defined in RFC 4331, section 6.
+func NewQuotaExceededError() error {
+	elem := internal.NewRawXMLElement(internal.QuotaNotExceededName, nil, nil)
+
+	return &daverr.HTTPError{
+		Code: http.StatusInsufficientStorage,
+		Err: &internal.Error{
+			Raw: []internal.RawXMLValue{*elem},
+		},
+	}
+}
+
+func addQuotaProps(props map[xml.Name]internal.PropFindFunc, quota *Quota) {
+	props[internal.QuotaUsedBytesName] = internal.PropFindValue(&internal.QuotaUsedBytes{
+		Bytes: quota.UsedBytes,
+	})
+	props[internal.QuotaAvailableBytesName] = internal.PropFindValue(&internal.QuotaAvailableBytes{
+		Bytes: quota.AvailableBytes,
+	})
+}
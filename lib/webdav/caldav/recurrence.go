@@ -0,0 +1,809 @@
+package caldav
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"github.com/teambition/rrule-go"
+)
+
+const (
+	// MaxRecurrenceIterations bounds the number of recurrences computed for a
+	// single component within a time range, protecting the server from rules
+	// like FREQ=SECONDLY.
+	MaxRecurrenceIterations = 100_000
+	// MaxSkippedRecurrences bounds the number of recurrences skipped to seek
+	// to the start of a time range. It is larger than MaxRecurrenceIterations,
+	// as skipped recurrences are cheaper, so that long running series (i.e.,
+	// hourly for a century) can still be queried years after their start.
+	MaxSkippedRecurrences = 1_000_000
+)
+
+var (
+	ErrInvalidDuration      = errors.New("invalid duration")
+	ErrTooManyRecurrences   = errors.New("too many recurrences")
+	ErrUnboundedTimeRange   = errors.New("unbounded time range")
+	ErrUnsupportedComponent = errors.New("unsupported component")
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405Z"
+)
+
+// instance is a single occurrence of a calendar component: either the
+// component itself, when it does not recur, or one of its recurrences.
+type instance struct {
+	// recurrenceID identifies the recurrence, and is zero for components
+	// which do not recur.
+	recurrenceID time.Time
+	start, end   time.Time
+	// component is the master component, or the one overriding the recurrence.
+	component ics.Component
+}
+
+// componentBase returns the properties and subcomponents of a component.
+func componentBase(comp ics.Component) (*ics.ComponentBase, error) {
+	switch c := comp.(type) {
+	case *ics.VEvent:
+		return &c.ComponentBase, nil
+	case *ics.VTodo:
+		return &c.ComponentBase, nil
+	case *ics.VJournal:
+		return &c.ComponentBase, nil
+	case *ics.VBusy:
+		return &c.ComponentBase, nil
+	case *ics.VAlarm:
+		return &c.ComponentBase, nil
+	case *ics.VTimezone:
+		return &c.ComponentBase, nil
+	case *ics.Standard:
+		return &c.ComponentBase, nil
+	case *ics.Daylight:
+		return &c.ComponentBase, nil
+	case *ics.GeneralComponent:
+		return &c.ComponentBase, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedComponent, comp)
+	}
+}
+
+// ComponentName returns the name of a component, i.e., VEVENT.
+func ComponentName(comp ics.Component) string {
+	switch c := comp.(type) {
+	case *ics.VEvent:
+		return string(ics.ComponentVEvent)
+	case *ics.VTodo:
+		return string(ics.ComponentVTodo)
+	case *ics.VJournal:
+		return string(ics.ComponentVJournal)
+	case *ics.VBusy:
+		return string(ics.ComponentVFreeBusy)
+	case *ics.VAlarm:
+		return string(ics.ComponentVAlarm)
+	case *ics.VTimezone:
+		return string(ics.ComponentVTimezone)
+	case *ics.Standard:
+		return string(ics.ComponentStandard)
+	case *ics.Daylight:
+		return string(ics.ComponentDaylight)
+	case *ics.GeneralComponent:
+		return c.Token
+	default:
+		return ""
+	}
+}
+
+// isDate reports whether a property holds a DATE, rather than a DATE-TIME.
+func isDate(prop *ics.IANAProperty) bool {
+	return slices.Contains(prop.ICalParameters[string(ics.ParameterValue)], string(ics.ValueDataTypeDate)) ||
+		len(prop.Value) == len(dateLayout)
+}
+
+// parseDuration parses a duration value, as defined in RFC 5545, section 3.3.6.
+func parseDuration(value string) (time.Duration, error) {
+	sign := time.Duration(1)
+
+	rest := value
+	if r, ok := strings.CutPrefix(rest, "-"); ok {
+		sign, rest = -1, r
+	} else {
+		rest = strings.TrimPrefix(rest, "+")
+	}
+
+	rest, ok := strings.CutPrefix(rest, "P")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, value)
+	}
+
+	var (
+		duration time.Duration
+		inTime   bool
+	)
+
+	for rest != "" {
+		if rest[0] == 'T' {
+			inTime, rest = true, rest[1:]
+			continue
+		}
+
+		end := strings.IndexAny(rest, "WDHMS")
+		if end <= 0 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, value)
+		}
+
+		n, err := strconv.Atoi(rest[:end])
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, value)
+		}
+
+		var unit time.Duration
+
+		switch designator := rest[end]; {
+		case !inTime && designator == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && designator == 'D':
+			unit = 24 * time.Hour
+		case inTime && designator == 'H':
+			unit = time.Hour
+		case inTime && designator == 'M':
+			unit = time.Minute
+		case inTime && designator == 'S':
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, value)
+		}
+
+		duration += time.Duration(n) * unit
+		rest = rest[end+1:]
+	}
+
+	return sign * duration, nil
+}
+
+// componentTimeRange returns the effective start and end of a component, as
+// described in RFC 4791, section 9.9. Components without a start have a zero
+// start and end, see componentOverlaps for how they are placed in time.
+func componentTimeRange(comp ics.Component) (start, end time.Time, err error) {
+	base, err := componentBase(comp)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	dtstart := base.GetProperty(ics.ComponentPropertyDtStart)
+	if dtstart == nil {
+		return time.Time{}, time.Time{}, nil
+	}
+
+	start, err = base.GetStartAt()
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("error while parsing start time: %w", err)
+	}
+
+	if base.HasProperty(ics.ComponentPropertyDtEnd) {
+		end, err = base.GetEndAt()
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("error while parsing end time: %w", err)
+		}
+
+		return start, end, nil
+	}
+
+	todo, isTodo := comp.(*ics.VTodo)
+	if isTodo && todo.HasProperty(ics.ComponentPropertyDue) {
+		end, err = todo.GetDueAt()
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("error while parsing due time: %w", err)
+		}
+
+		return start, end, nil
+	}
+
+	if duration := base.GetProperty(ics.ComponentPropertyDuration); duration != nil {
+		d, err := parseDuration(duration.Value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		return start, start.Add(d), nil
+	}
+
+	// Events and journals on a date without an end last for the whole day,
+	// while those at a date-time take no time at all. The same goes for tasks
+	// regardless of their start.
+	if isDate(dtstart) && !isTodo {
+		return start, start.AddDate(0, 0, 1), nil
+	}
+
+	return start, start, nil
+}
+
+// overlaps reports whether an occurrence overlaps the time range, as defined
+// in RFC 4791, section 9.9. Zero bounds of the time range are unbounded.
+func overlaps(start, end, rangeStart, rangeEnd time.Time) bool {
+	if end.After(start) {
+		return (rangeEnd.IsZero() || start.Before(rangeEnd)) && (rangeStart.IsZero() || end.After(rangeStart))
+	}
+
+	return (rangeEnd.IsZero() || start.Before(rangeEnd)) && (rangeStart.IsZero() || !start.Before(rangeStart))
+}
+
+// componentOverlaps reports whether an instance of a component, as returned
+// by componentTimeRange, overlaps the time range. Tasks without a start are
+// placed in time by their DUE, COMPLETED and CREATED properties, while other
+// components without a start never overlap.
+func componentOverlaps(comp ics.Component, start, end, rangeStart, rangeEnd time.Time) (bool, error) {
+	if !start.IsZero() {
+		return overlaps(start, end, rangeStart, rangeEnd), nil
+	}
+
+	todo, ok := comp.(*ics.VTodo)
+	if !ok {
+		return false, nil
+	}
+
+	return todoOverlaps(todo, rangeStart, rangeEnd)
+}
+
+// todoOverlaps implements the rules of RFC 4791, section 9.9 for VTODO
+// components without a DTSTART property.
+func todoOverlaps(todo *ics.VTodo, rangeStart, rangeEnd time.Time) (bool, error) {
+	// startsBefore reports rangeStart <= t, endsAfter reports rangeEnd >= t
+	startsBefore := func(t time.Time) bool { return rangeStart.IsZero() || !rangeStart.After(t) }
+	endsAfter := func(t time.Time) bool { return rangeEnd.IsZero() || !rangeEnd.Before(t) }
+
+	if todo.HasProperty(ics.ComponentPropertyDue) {
+		due, err := todo.GetDueAt()
+		if err != nil {
+			return false, fmt.Errorf("error while parsing due time: %w", err)
+		}
+
+		return (rangeStart.IsZero() || rangeStart.Before(due)) && endsAfter(due), nil
+	}
+
+	completed, err := utcProperty(&todo.ComponentBase, ics.ComponentPropertyCompleted)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := utcProperty(&todo.ComponentBase, ics.ComponentPropertyCreated)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case !completed.IsZero() && !created.IsZero():
+		return (startsBefore(created) || startsBefore(completed)) && (endsAfter(created) || endsAfter(completed)), nil
+	case !completed.IsZero():
+		return startsBefore(completed) && endsAfter(completed), nil
+	case !created.IsZero():
+		return rangeEnd.IsZero() || rangeEnd.After(created), nil
+	default:
+		return true, nil
+	}
+}
+
+// utcProperty parses a property which must be a date-time in UTC, such as
+// COMPLETED. It returns the zero time if the property is missing.
+func utcProperty(base *ics.ComponentBase, prop ics.ComponentProperty) (time.Time, error) {
+	p := base.GetProperty(prop)
+	if p == nil {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(dateTimeLayout, p.Value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error while parsing %s: %w", prop, err)
+	}
+
+	return t, nil
+}
+
+// recurrenceSet builds the recurrence set of a component from its RRULE,
+// RDATE and EXDATE properties. It returns nil for components which do not
+// recur.
+func recurrenceSet(base *ics.ComponentBase, start time.Time) (*rrule.Set, error) {
+	rules := base.GetProperties(ics.ComponentPropertyRrule)
+	if len(rules) == 0 && !base.HasProperty(ics.ComponentPropertyRdate) {
+		return nil, nil //nolint:nilnil
+	}
+
+	set := &rrule.Set{}
+
+	// RFC 5545 deprecates multiple RRULEs, so only the first one is considered
+	if len(rules) > 0 {
+		option, err := rrule.StrToROptionInLocation(rules[0].Value, start.Location())
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing recurrence rule %q: %w", rules[0].Value, err)
+		}
+
+		rule, err := rrule.NewRRule(*option)
+		if err != nil {
+			return nil, fmt.Errorf("error while building recurrence rule %q: %w", rules[0].Value, err)
+		}
+
+		set.RRule(rule)
+	}
+
+	set.DTStart(start)
+	// The start is always the first recurrence, even if it does not match the rule
+	set.RDate(start)
+
+	rdates, err := base.GetRDates()
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing recurrence dates: %w", err)
+	}
+
+	for _, rdate := range rdates {
+		set.RDate(rdate)
+	}
+
+	exdates, err := base.GetExDates()
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing exception dates: %w", err)
+	}
+
+	for _, exdate := range exdates {
+		set.ExDate(exdate)
+	}
+
+	return set, nil
+}
+
+// recurrenceID returns the RECURRENCE-ID of a component, or the zero time if
+// it has none.
+func recurrenceID(comp ics.Component) (time.Time, error) {
+	base, err := componentBase(comp)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !base.HasProperty(ics.ComponentPropertyRecurrenceId) {
+		return time.Time{}, nil
+	}
+
+	id, err := base.GetRecurrenceID()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error while parsing recurrence id: %w", err)
+	}
+
+	return id, nil
+}
+
+// instances calls yield with each instance of the recurrence set made of
+// comps that overlaps the time range, until yield returns false. The
+// components must share the same UID: one of them is the master component,
+// without a RECURRENCE-ID, while the others override some of its recurrences.
+// Instances are not yielded in order.
+//
+//nolint:gocyclo
+func instances(comps []ics.Component, rangeStart, rangeEnd time.Time, yield func(instance) bool) error {
+	var master ics.Component
+
+	overridden := map[int64]bool{}
+
+	for _, comp := range comps {
+		id, err := recurrenceID(comp)
+		if err != nil {
+			return err
+		}
+
+		if id.IsZero() {
+			master = comp
+			continue
+		}
+
+		overridden[id.Unix()] = true
+
+		start, end, err := componentTimeRange(comp)
+		if err != nil {
+			return err
+		}
+
+		ok, err := componentOverlaps(comp, start, end, rangeStart, rangeEnd)
+		if err != nil {
+			return err
+		} else if ok && !yield(instance{id, start, end, comp}) {
+			return nil
+		}
+	}
+
+	// Invitations to a single recurrence come without a master component
+	if master == nil {
+		return nil
+	}
+
+	start, end, err := componentTimeRange(master)
+	if err != nil {
+		return err
+	}
+
+	base, err := componentBase(master)
+	if err != nil {
+		return err
+	}
+
+	set, err := recurrenceSet(base, start)
+	if err != nil {
+		return err
+	}
+
+	if set == nil || start.IsZero() {
+		ok, err := componentOverlaps(master, start, end, rangeStart, rangeEnd)
+		if ok {
+			yield(instance{start: start, end: end, component: master})
+		}
+
+		return err
+	}
+
+	return recurrences(set, master, end.Sub(start), overridden, rangeStart, rangeEnd, yield)
+}
+
+// recurrences calls yield with each recurrence of a master component which
+// overlaps the time range and is not overridden, until yield returns false.
+// Recurrences ending before the time range are skipped without being yielded
+// nor counted against MaxRecurrenceIterations, like rrule.Set.Between does.
+func recurrences(
+	set *rrule.Set,
+	master ics.Component,
+	duration time.Duration,
+	overridden map[int64]bool,
+	rangeStart, rangeEnd time.Time,
+	yield func(instance) bool,
+) error {
+	// A recurrence starting at seek ends right at the start of the time range,
+	// so only the ones after it may overlap
+	var seek time.Time
+	if !rangeStart.IsZero() {
+		seek = rangeStart.Add(-duration)
+	}
+
+	next := set.Iterator()
+
+	for skipped, iterations := 0, 0; iterations < MaxRecurrenceIterations; {
+		recurrence, ok := next()
+		if !ok || (!rangeEnd.IsZero() && !recurrence.Before(rangeEnd)) {
+			return nil
+		}
+
+		if recurrence.Before(seek) {
+			if skipped++; skipped >= MaxSkippedRecurrences {
+				return fmt.Errorf(
+					"%w: more than %d before the time range",
+					ErrTooManyRecurrences,
+					MaxSkippedRecurrences,
+				)
+			}
+
+			continue
+		}
+
+		iterations++
+
+		if overridden[recurrence.Unix()] {
+			continue
+		}
+
+		end := recurrence.Add(duration)
+		if overlaps(recurrence, end, rangeStart, rangeEnd) && !yield(instance{recurrence, recurrence, end, master}) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: more than %d", ErrTooManyRecurrences, MaxRecurrenceIterations)
+}
+
+// recurrenceSets groups the components of a calendar which may recur by UID.
+// Other components (i.e., VTIMEZONE) are returned separately.
+func recurrenceSets(cal *ics.Calendar) (sets [][]ics.Component, others []ics.Component) {
+	indexes := map[string]int{}
+
+	for _, comp := range cal.Components {
+		switch comp.(type) {
+		case *ics.VEvent, *ics.VTodo, *ics.VJournal:
+		default:
+			others = append(others, comp)
+			continue
+		}
+
+		base, _ := componentBase(comp)
+		uid := ""
+
+		if prop := base.GetProperty(ics.ComponentPropertyUniqueId); prop != nil {
+			uid = prop.Value
+		}
+
+		i, ok := indexes[uid]
+		if !ok {
+			i = len(sets)
+			indexes[uid] = i
+			sets = append(sets, nil)
+		}
+
+		sets[i] = append(sets[i], comp)
+	}
+
+	return sets, others
+}
+
+// formatUTC rewrites a date-time property in UTC. Dates are left untouched,
+// as they are not bound to any timezone.
+func formatUTC(base *ics.ComponentBase, prop ics.ComponentProperty, t time.Time) {
+	if p := base.GetProperty(prop); p != nil && isDate(p) {
+		base.SetProperty(prop, t.Format(dateLayout), ics.WithValue(string(ics.ValueDataTypeDate)))
+		return
+	}
+
+	base.SetProperty(prop, t.UTC().Format(dateTimeLayout))
+}
+
+// expandInstance builds a standalone component for an instance, without
+// recurrence properties and with its times in UTC.
+func expandInstance(inst instance) (ics.Component, error) {
+	base, err := componentBase(inst.component)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := ics.ComponentBase{
+		Properties: slices.Clone(base.Properties),
+		Components: base.Components,
+	}
+
+	for _, prop := range []ics.ComponentProperty{
+		ics.ComponentPropertyRrule,
+		ics.ComponentPropertyRdate,
+		ics.ComponentPropertyExdate,
+		ics.ComponentPropertyExrule,
+	} {
+		expanded.RemoveProperty(prop)
+	}
+
+	if !inst.start.IsZero() {
+		formatUTC(&expanded, ics.ComponentPropertyDtStart, inst.start)
+	}
+
+	if expanded.HasProperty(ics.ComponentPropertyDtEnd) {
+		formatUTC(&expanded, ics.ComponentPropertyDtEnd, inst.end)
+	}
+
+	if todo, ok := inst.component.(*ics.VTodo); ok && todo.HasProperty(ics.ComponentPropertyDue) {
+		due := inst.end
+		if inst.start.IsZero() {
+			if due, err = todo.GetDueAt(); err != nil {
+				return nil, fmt.Errorf("error while parsing due time: %w", err)
+			}
+		}
+
+		formatUTC(&expanded, ics.ComponentPropertyDue, due)
+	}
+
+	if !inst.recurrenceID.IsZero() {
+		if !expanded.HasProperty(ics.ComponentPropertyRecurrenceId) {
+			expanded.AddProperty(ics.ComponentPropertyRecurrenceId, "")
+		}
+
+		formatUTC(&expanded, ics.ComponentPropertyRecurrenceId, inst.recurrenceID)
+	}
+
+	switch inst.component.(type) {
+	case *ics.VEvent:
+		return &ics.VEvent{ComponentBase: expanded}, nil
+	case *ics.VTodo:
+		return &ics.VTodo{ComponentBase: expanded}, nil
+	case *ics.VJournal:
+		return &ics.VJournal{ComponentBase: expanded}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedComponent, inst.component)
+	}
+}
+
+// ExpandCalendar returns a copy of the calendar where recurring components
+// are replaced by their instances overlapping the time range, as requested by
+// the CALDAV:expand element (RFC 4791, section 9.6.5). Times are converted to
+// UTC, so timezones are not included.
+func ExpandCalendar(cal *ics.Calendar, start, end time.Time) (*ics.Calendar, error) {
+	if start.IsZero() || end.IsZero() {
+		return nil, ErrUnboundedTimeRange
+	}
+
+	expanded := &ics.Calendar{CalendarProperties: cal.CalendarProperties}
+	sets, others := recurrenceSets(cal)
+
+	for _, comp := range others {
+		if _, ok := comp.(*ics.VTimezone); !ok {
+			expanded.Components = append(expanded.Components, comp)
+		}
+	}
+
+	for _, set := range sets {
+		var insts []instance
+
+		err := instances(set, start, end, func(inst instance) bool {
+			insts = append(insts, inst)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		slices.SortFunc(insts, func(a, b instance) int { return a.start.Compare(b.start) })
+
+		for _, inst := range insts {
+			comp, err := expandInstance(inst)
+			if err != nil {
+				return nil, err
+			}
+
+			expanded.Components = append(expanded.Components, comp)
+		}
+	}
+
+	return expanded, nil
+}
+
+// LimitRecurrenceSet returns a copy of the calendar where overridden
+// recurrences which do not overlap the time range are removed, as requested
+// by the CALDAV:limit-recurrence-set element (RFC 4791, section 9.6.6).
+func LimitRecurrenceSet(cal *ics.Calendar, start, end time.Time) (*ics.Calendar, error) {
+	if start.IsZero() || end.IsZero() {
+		return nil, ErrUnboundedTimeRange
+	}
+
+	limited := &ics.Calendar{CalendarProperties: cal.CalendarProperties}
+
+	for _, comp := range cal.Components {
+		switch comp.(type) {
+		case *ics.VEvent, *ics.VTodo, *ics.VJournal:
+		default:
+			limited.Components = append(limited.Components, comp)
+			continue
+		}
+
+		id, err := recurrenceID(comp)
+		if err != nil {
+			return nil, err
+		}
+
+		if !id.IsZero() {
+			compStart, compEnd, err := componentTimeRange(comp)
+			if err != nil {
+				return nil, err
+			}
+
+			ok, err := componentOverlaps(comp, compStart, compEnd, start, end)
+			if err != nil {
+				return nil, err
+			} else if !ok {
+				continue
+			}
+		}
+
+		limited.Components = append(limited.Components, comp)
+	}
+
+	return limited, nil
+}
+
+// recursForever reports whether the recurrence rule of a component has
+// neither a COUNT nor an UNTIL, so that its recurrence set is infinite.
+func recursForever(comp ics.Component) (bool, error) {
+	base, err := componentBase(comp)
+	if err != nil {
+		return false, err
+	}
+
+	rules := base.GetProperties(ics.ComponentPropertyRrule)
+	if len(rules) == 0 {
+		return false, nil
+	}
+
+	option, err := rrule.StrToROption(rules[0].Value)
+	if err != nil {
+		return false, fmt.Errorf("error while parsing recurrence rule %q: %w", rules[0].Value, err)
+	}
+
+	return option.Count == 0 && option.Until.IsZero(), nil
+}
+
+// TimeRange returns the bounds of the time in which the components of a
+// calendar, including all their recurrences, may overlap a time range. Zero
+// bounds are unbounded, as for components recurring forever or for tasks
+// without a start, which are placed in time by other properties.
+//
+//nolint:gocyclo
+func TimeRange(cal *ics.Calendar) (start, end time.Time, err error) {
+	var found, unboundedStart, unboundedEnd bool
+
+	include := func(s, e time.Time) {
+		if s.IsZero() {
+			unboundedStart, unboundedEnd = true, true
+			return
+		}
+
+		if !found || s.Before(start) {
+			start = s
+		}
+
+		if !found || e.After(end) {
+			end = e
+		}
+
+		found = true
+	}
+
+	sets, others := recurrenceSets(cal)
+
+	for _, comp := range others {
+		if _, ok := comp.(*ics.VTimezone); ok {
+			continue
+		}
+
+		s, e, err := componentTimeRange(comp)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		include(s, e)
+	}
+
+	for _, set := range sets {
+		forever := false
+
+		for _, comp := range set {
+			id, err := recurrenceID(comp)
+			if err != nil {
+				return time.Time{}, time.Time{}, err
+			} else if !id.IsZero() {
+				continue
+			}
+
+			if forever, err = recursForever(comp); err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+		}
+
+		if forever {
+			// Only the components themselves are considered, as their first
+			// recurrences are the earliest ones.
+			unboundedEnd = true
+
+			for _, comp := range set {
+				s, e, err := componentTimeRange(comp)
+				if err != nil {
+					return time.Time{}, time.Time{}, err
+				}
+
+				include(s, e)
+			}
+
+			continue
+		}
+
+		err := instances(set, time.Time{}, time.Time{}, func(inst instance) bool {
+			include(inst.start, inst.end)
+			return true
+		})
+		if errors.Is(err, ErrTooManyRecurrences) {
+			unboundedEnd = true
+		} else if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+
+	if !found || unboundedStart {
+		start = time.Time{}
+	}
+
+	if !found || unboundedEnd {
+		end = time.Time{}
+	}
+
+	return start, end, nil
+}
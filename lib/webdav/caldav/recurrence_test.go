@@ -0,0 +1,235 @@
+package caldav
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+func parseCalendar(t *testing.T, components ...string) *ics.Calendar {
+	t.Helper()
+
+	raw := "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:-//teapot//test//EN\n" +
+		strings.Join(components, "") + "END:VCALENDAR\n"
+
+	cal, err := ics.ParseCalendar(strings.NewReader(strings.ReplaceAll(raw, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("error parsing calendar: %s", err)
+	}
+
+	return cal
+}
+
+func mustParseTime(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	parsed, err := time.Parse(dateTimeLayout, value)
+	if err != nil {
+		t.Fatalf("error parsing %q: %s", value, err)
+	}
+
+	return parsed
+}
+
+func event(uid, props string) string {
+	return "BEGIN:VEVENT\nUID:" + uid + "\nDTSTAMP:20260101T000000Z\n" + props + "END:VEVENT\n"
+}
+
+func TestExpandCalendar(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		components []string
+		start, end string
+		expected   []string
+	}{
+		{
+			name:       "single",
+			components: []string{event("a", "DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\n")},
+			start:      "20260101T000000Z",
+			end:        "20260201T000000Z",
+			expected:   []string{"20260105T100000Z"},
+		},
+		{
+			name:       "outside",
+			components: []string{event("a", "DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\n")},
+			start:      "20260106T000000Z",
+			end:        "20260201T000000Z",
+		},
+		{
+			name: "count and exdate",
+			components: []string{event("a", "DTSTART:20260105T100000Z\nDURATION:PT1H\n"+
+				"RRULE:FREQ=DAILY;COUNT=4\nEXDATE:20260106T100000Z\n")},
+			start:    "20260101T000000Z",
+			end:      "20260201T000000Z",
+			expected: []string{"20260105T100000Z", "20260107T100000Z", "20260108T100000Z"},
+		},
+		{
+			name: "rdate",
+			components: []string{event("a", "DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\n"+
+				"RDATE:20260110T080000Z\n")},
+			start:    "20260101T000000Z",
+			end:      "20260201T000000Z",
+			expected: []string{"20260105T100000Z", "20260110T080000Z"},
+		},
+		{
+			name: "override",
+			components: []string{
+				event("a", "DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\nRRULE:FREQ=WEEKLY\n"),
+				event("a", "RECURRENCE-ID:20260112T100000Z\nDTSTART:20260113T150000Z\nDTEND:20260113T160000Z\n"),
+			},
+			start:    "20260110T000000Z",
+			end:      "20260120T000000Z",
+			expected: []string{"20260113T150000Z", "20260119T100000Z"},
+		},
+		{
+			name:       "overlapping start",
+			components: []string{event("a", "DTSTART:20260105T220000Z\nDTEND:20260106T020000Z\nRRULE:FREQ=DAILY\n")},
+			start:      "20260110T000000Z",
+			end:        "20260111T000000Z",
+			expected:   []string{"20260109T220000Z", "20260110T220000Z"},
+		},
+		{
+			// Hourly since 1990 is more than MaxRecurrenceIterations before the
+			// time range, which must be skipped.
+			name:       "seek",
+			components: []string{event("a", "DTSTART:19900101T000000Z\nDURATION:PT30M\nRRULE:FREQ=HOURLY\n")},
+			start:      "20260101T004500Z",
+			end:        "20260101T030000Z",
+			expected:   []string{"20260101T010000Z", "20260101T020000Z"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cal := parseCalendar(t, test.components...)
+
+			expanded, err := ExpandCalendar(cal, mustParseTime(t, test.start), mustParseTime(t, test.end))
+			if err != nil {
+				t.Fatalf("error expanding calendar: %s", err)
+			}
+
+			var starts []string
+
+			for _, event := range expanded.Events() {
+				if event.GetProperty(ics.ComponentPropertyRrule) != nil {
+					t.Errorf("expanded instance has a RRULE")
+				}
+
+				starts = append(starts, event.GetProperty(ics.ComponentPropertyDtStart).Value)
+			}
+
+			slices.Sort(starts)
+
+			if !slices.Equal(starts, test.expected) {
+				t.Errorf("GOT %v EXPECTED %v", starts, test.expected)
+			}
+		})
+	}
+}
+
+func TestTooManyRecurrences(t *testing.T) {
+	t.Parallel()
+
+	cal := parseCalendar(t, event("a", "DTSTART:20260101T000000Z\nRRULE:FREQ=SECONDLY\n"))
+
+	// Too many recurrences within the time range
+	start := mustParseTime(t, "20260101T000000Z")
+	if _, err := ExpandCalendar(cal, start, start.AddDate(0, 0, 7)); !errors.Is(err, ErrTooManyRecurrences) {
+		t.Errorf("GOT error %v EXPECTED %v", err, ErrTooManyRecurrences)
+	}
+
+	// Too many recurrences to skip before the time range
+	start = mustParseTime(t, "20270101T000000Z")
+	if _, err := ExpandCalendar(cal, start, start.Add(time.Minute)); !errors.Is(err, ErrTooManyRecurrences) {
+		t.Errorf("GOT error %v EXPECTED %v", err, ErrTooManyRecurrences)
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		components []string
+		start, end string
+	}{
+		{
+			name:       "single",
+			components: []string{event("a", "DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\n")},
+			start:      "20260105T100000Z",
+			end:        "20260105T110000Z",
+		},
+		{
+			name:       "all day",
+			components: []string{event("a", "DTSTART;VALUE=DATE:20260105\n")},
+			start:      "20260105T000000Z",
+			end:        "20260106T000000Z",
+		},
+		{
+			name: "count",
+			components: []string{event("a", "DTSTART:20260105T100000Z\nDURATION:PT1H\n"+
+				"RRULE:FREQ=WEEKLY;COUNT=3\n")},
+			start: "20260105T100000Z",
+			end:   "20260119T110000Z",
+		},
+		{
+			name: "until and override",
+			components: []string{
+				event("a", "DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\n"+
+					"RRULE:FREQ=DAILY;UNTIL=20260107T100000Z\n"),
+				event("a", "RECURRENCE-ID:20260107T100000Z\nDTSTART:20260110T100000Z\nDTEND:20260110T120000Z\n"),
+			},
+			start: "20260105T100000Z",
+			end:   "20260110T120000Z",
+		},
+		{
+			name:       "forever",
+			components: []string{event("a", "DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\nRRULE:FREQ=YEARLY\n")},
+			start:      "20260105T100000Z",
+		},
+		{
+			name:       "todo without start",
+			components: []string{"BEGIN:VTODO\nUID:a\nDTSTAMP:20260101T000000Z\nDUE:20260105T100000Z\nEND:VTODO\n"},
+		},
+		{
+			name: "multiple",
+			components: []string{
+				event("a", "DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\n"),
+				event("b", "DTSTART:20251231T230000Z\nDTEND:20260101T000000Z\n"),
+			},
+			start: "20251231T230000Z",
+			end:   "20260105T110000Z",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			start, end, err := TimeRange(parseCalendar(t, test.components...))
+			if err != nil {
+				t.Fatalf("error computing time range: %s", err)
+			}
+
+			format := func(t time.Time) string {
+				if t.IsZero() {
+					return ""
+				}
+
+				return t.UTC().Format(dateTimeLayout)
+			}
+
+			if format(start) != test.start || format(end) != test.end {
+				t.Errorf("GOT [%s, %s) EXPECTED [%s, %s)", format(start), format(end), test.start, test.end)
+			}
+		})
+	}
+}
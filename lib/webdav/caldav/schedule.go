@@ -0,0 +1,257 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	ics "github.com/arran4/golang-ical"
+
+	daverr "github.com/teapotovh/teapot/lib/webdav/error"
+	"github.com/teapotovh/teapot/lib/webdav/internal"
+)
+
+var ErrUnknownRecipient = errors.New("unknown recipient")
+
+// Request statuses reported for each recipient of a scheduling message, as
+// defined in RFC 5545, section 3.8.8.3.
+const (
+	requestStatusSuccess            = "2.0;Success"
+	requestStatusInvalidUser        = "3.7;Invalid calendar user"
+	requestStatusServiceUnavailable = "5.1;Service unavailable"
+)
+
+// SchedulingBackend can be implemented by a Backend to provide its principals
+// with a scheduling outbox, as defined in RFC 6638. Only free/busy requests
+// are supported, so the calendar-schedule capability is not advertised.
+type SchedulingBackend interface {
+	// ScheduleOutboxPath returns the path of the scheduling outbox of the
+	// current user principal.
+	ScheduleOutboxPath(ctx context.Context) (string, error)
+
+	// CalendarUserAddressSet returns the addresses of the current user
+	// principal, i.e., mailto URIs.
+	CalendarUserAddressSet(ctx context.Context) ([]string, error)
+
+	// ScheduleFreeBusy returns the time in which the calendar user with the
+	// given address is busy within the time range of the query, according to
+	// all of their calendars. Backends must wrap ErrUnknownRecipient when the
+	// address does not belong to any of their users.
+	ScheduleFreeBusy(ctx context.Context, address string, query *FreeBusyQuery) ([]BusyPeriod, error)
+}
+
+// isScheduleOutbox reports whether reqPath is the scheduling outbox of the
+// current user principal.
+func isScheduleOutbox(ctx context.Context, backend Backend, reqPath string) (SchedulingBackend, bool, error) {
+	sb, ok := backend.(SchedulingBackend)
+	if !ok {
+		return nil, false, nil
+	}
+
+	outboxPath, err := sb.ScheduleOutboxPath(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return sb, path.Clean(outboxPath) == path.Clean(reqPath), nil
+}
+
+// https://datatracker.ietf.org/doc/html/rfc6638#section-6.1
+//
+//nolint:gocyclo
+func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	sb, ok, err := isScheduleOutbox(ctx, h.Backend, r.URL.Path)
+	if err != nil {
+		return err
+	} else if !ok {
+		return daverr.HTTPErrorf(http.StatusMethodNotAllowed, "caldav: POST is only supported on the scheduling outbox")
+	}
+
+	t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || t != MIMEType {
+		return daverr.HTTPErrorf(http.StatusUnsupportedMediaType, "caldav: expected %s scheduling message", MIMEType)
+	}
+
+	cal, err := ics.ParseCalendar(r.Body)
+	if err != nil {
+		return daverr.HTTPErrorf(http.StatusBadRequest, "caldav: failed to parse iCalendar: %v", err)
+	}
+
+	if !strings.EqualFold(calendarMethod(cal), string(ics.MethodRequest)) || len(cal.Components) != 1 {
+		return daverr.HTTPErrorf(http.StatusForbidden, "caldav: only VFREEBUSY requests can be scheduled")
+	}
+
+	request, ok := cal.Components[0].(*ics.VBusy)
+	if !ok {
+		return daverr.HTTPErrorf(http.StatusForbidden, "caldav: only VFREEBUSY requests can be scheduled")
+	}
+
+	start, err := request.GetStartAt()
+	if err != nil {
+		return daverr.HTTPErrorf(http.StatusBadRequest, "caldav: invalid DTSTART in VFREEBUSY request: %v", err)
+	}
+
+	end, err := request.GetEndAt()
+	if err != nil || !start.Before(end) {
+		return daverr.HTTPErrorf(http.StatusBadRequest, "caldav: invalid DTEND in VFREEBUSY request")
+	}
+
+	addresses, err := sb.CalendarUserAddressSet(ctx)
+	if err != nil {
+		return err
+	}
+
+	organizer := request.GetProperty(ics.ComponentPropertyOrganizer)
+	if organizer == nil || !hasAddress(addresses, organizer.Value) {
+		return daverr.HTTPErrorf(http.StatusForbidden, "caldav: the organizer must be the authenticated user")
+	}
+
+	query := FreeBusyQuery{Start: start, End: end}
+	resp := scheduleResponse{}
+
+	for _, attendee := range request.GetProperties(ics.ComponentPropertyAttendee) {
+		entry, err := scheduleFreeBusyReply(ctx, sb, request, attendee, &query)
+		if err != nil {
+			return err
+		}
+
+		resp.Responses = append(resp.Responses, *entry)
+	}
+
+	encoder, err := internal.ServeXML(w)
+	if err != nil {
+		return err
+	}
+
+	if err := encoder.Encode(&resp); err != nil {
+		return fmt.Errorf("error while XML encoding: %w", err)
+	}
+
+	return nil
+}
+
+// calendarMethod returns the METHOD of an iTIP message, or an empty string.
+func calendarMethod(cal *ics.Calendar) string {
+	for _, prop := range cal.CalendarProperties {
+		if prop.IANAToken == string(ics.PropertyMethod) {
+			return prop.Value
+		}
+	}
+
+	return ""
+}
+
+// scheduleFreeBusyReply answers a VFREEBUSY request for a single attendee.
+func scheduleFreeBusyReply(
+	ctx context.Context,
+	sb SchedulingBackend,
+	request *ics.VBusy,
+	attendee *ics.IANAProperty,
+	query *FreeBusyQuery,
+) (*scheduleResponseEntry, error) {
+	var href internal.Href
+	if err := href.UnmarshalText([]byte(attendee.Value)); err != nil {
+		return nil, daverr.HTTPErrorf(http.StatusBadRequest, "caldav: invalid attendee %q: %v", attendee.Value, err)
+	}
+
+	entry := scheduleResponseEntry{Recipient: recipient{Href: href}}
+
+	periods, err := sb.ScheduleFreeBusy(ctx, attendee.Value, query)
+	if errors.Is(err, ErrUnknownRecipient) {
+		entry.RequestStatus = requestStatusInvalidUser
+		return &entry, nil
+	} else if err != nil {
+		entry.RequestStatus = requestStatusServiceUnavailable
+		return &entry, nil //nolint:nilerr
+	}
+
+	uid := ""
+	if prop := request.GetProperty(ics.ComponentPropertyUniqueId); prop != nil {
+		uid = prop.Value
+	}
+
+	reply := NewFreeBusy(uid, query.Start, query.End, periods)
+	reply.Properties = append(reply.Properties, *request.GetProperty(ics.ComponentPropertyOrganizer), *attendee)
+
+	cal := ics.NewCalendarFor(ProductID)
+	cal.SetMethod(ics.MethodReply)
+	cal.AddVBusy(reply)
+
+	var buf bytes.Buffer
+	if err := cal.SerializeTo(&buf); err != nil {
+		return nil, fmt.Errorf("error while serializing VFREEBUSY reply: %w", err)
+	}
+
+	entry.RequestStatus = requestStatusSuccess
+	entry.CalendarData = &calendarDataResp{Data: buf.Bytes()}
+
+	return &entry, nil
+}
+
+// propFindScheduleOutbox lists the properties of a scheduling outbox.
+func (b *backend) propFindScheduleOutbox(
+	ctx context.Context,
+	propfind *internal.PropFind,
+	outboxPath string,
+) (*internal.Response, error) {
+	principalPath, err := b.Backend.CurrentUserPrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	props := map[xml.Name]internal.PropFindFunc{
+		internal.CurrentUserPrincipalName: internal.PropFindValue(&internal.CurrentUserPrincipal{
+			Href: internal.Href{Path: principalPath},
+		}),
+		internal.ResourceTypeName: internal.PropFindValue(
+			internal.NewResourceType(internal.CollectionName, scheduleOutboxName),
+		),
+	}
+
+	return internal.NewPropFindResponse(outboxPath, propfind, props)
+}
+
+// addSchedulingProps adds the scheduling properties of the current user
+// principal to props, if the backend supports scheduling.
+func (b *backend) addSchedulingProps(ctx context.Context, props map[xml.Name]internal.PropFindFunc) error {
+	sb, ok := b.Backend.(SchedulingBackend)
+	if !ok {
+		return nil
+	}
+
+	outboxPath, err := sb.ScheduleOutboxPath(ctx)
+	if err != nil {
+		return err
+	}
+
+	addresses, err := sb.CalendarUserAddressSet(ctx)
+	if err != nil {
+		return err
+	}
+
+	hrefs := make([]internal.Href, 0, len(addresses))
+
+	for _, address := range addresses {
+		var href internal.Href
+		if err := href.UnmarshalText([]byte(address)); err != nil {
+			return fmt.Errorf("invalid calendar user address %q: %w", address, err)
+		}
+
+		hrefs = append(hrefs, href)
+	}
+
+	props[scheduleOutboxURLName] = internal.PropFindValue(&scheduleOutboxURL{
+		Href: internal.Href{Path: outboxPath},
+	})
+	props[calendarUserAddressSetName] = internal.PropFindValue(&calendarUserAddressSet{Hrefs: hrefs})
+
+	return nil
+}
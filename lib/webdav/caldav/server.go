@@ -22,8 +22,13 @@ import (
 
 const MIMEType = "text/calendar"
 
+// ProductID identifies the server in the PRODID of the iCalendar objects it
+// generates.
+const ProductID = "teapot"
+
 var (
 	ErrInvalidCalendarPath                   = errors.New("invalid calendar path (must be under calendar home-set)")
+	ErrInvalidSyncToken                      = errors.New("invalid sync token")
 	ErrTextMatchIncompatibleWithIfNotDefined = errors.New("if is-not-defined is provided, text-match can't be provided")
 	ErrMany1IncompatibleWithIfNotDefined     = errors.New(
 		"if is-not-defined is provided, text-match, time-range, or param-filter can't be provided",
@@ -51,6 +56,8 @@ type Backend interface {
 	CreateCalendar(ctx context.Context, calendar *Calendar) error
 	ListCalendars(ctx context.Context) ([]Calendar, error)
 	GetCalendar(ctx context.Context, path string) (*Calendar, error)
+	UpdateCalendar(ctx context.Context, path string, update *CalendarUpdate) error
+	DeleteCalendar(ctx context.Context, path string) error
 
 	GetCalendarObject(ctx context.Context, path string, req *CalendarCompRequest) (*CalendarObject, error)
 	ListCalendarObjects(ctx context.Context, path string, req *CalendarCompRequest) ([]CalendarObject, error)
@@ -63,6 +70,21 @@ type Backend interface {
 	) (*CalendarObject, error)
 	DeleteCalendarObject(ctx context.Context, path string) error
 
+	// CopyCalendarObject and MoveCalendarObject copy and move the object at
+	// path to dest, which may be in another calendar, reporting whether dest
+	// was created. Unless overwrite is set, an existing dest is left untouched.
+	CopyCalendarObject(ctx context.Context, path, dest string, overwrite bool) (created bool, err error)
+	MoveCalendarObject(ctx context.Context, path, dest string, overwrite bool) (created bool, err error)
+
+	// SyncCollection returns the objects of the calendar at path which changed
+	// since query.SyncToken. Backends must wrap ErrInvalidSyncToken when the
+	// token is not recognized.
+	SyncCollection(ctx context.Context, path string, query *SyncQuery) (*SyncResponse, error)
+
+	// QueryFreeBusy returns the time in which the owner of the calendar at path
+	// is busy, according to that calendar, within the time range of the query.
+	QueryFreeBusy(ctx context.Context, path string, query *FreeBusyQuery) ([]BusyPeriod, error)
+
 	webdav.UserPrincipalBackend
 }
 
@@ -97,6 +119,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "REPORT":
 		err = h.handleReport(w, r)
+	case http.MethodPost:
+		err = h.handlePost(w, r)
 	default:
 		b := backend{
 			Backend: h.Backend,
@@ -121,11 +145,16 @@ func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) error {
 		return h.handleQuery(r, w, report.Query)
 	} else if report.Multiget != nil {
 		return h.handleMultiget(r.Context(), w, report.Multiget)
+	} else if report.SyncCollection != nil {
+		return h.handleSyncCollection(r, w, report.SyncCollection)
+	} else if report.FreeBusyQuery != nil {
+		return h.handleFreeBusyQuery(r, w, report.FreeBusyQuery)
 	}
 
 	return daverr.HTTPErrorf(
 		http.StatusBadRequest,
-		"caldav: expected calendar-query or calendar-multiget element in REPORT request",
+		"caldav: expected calendar-query, calendar-multiget, sync-collection or free-busy-query element "+
+			"in REPORT request",
 	)
 }
 
@@ -253,19 +282,59 @@ func decodeComp(comp *comp) (*CalendarCompRequest, error) {
 }
 
 func decodeCalendarDataReq(calendarData *calendarDataReq) (*CalendarCompRequest, error) {
-	if calendarData.Comp == nil {
-		return &CalendarCompRequest{
-			AllProps: true,
-			AllComps: true,
-		}, nil
+	req := &CalendarCompRequest{
+		AllProps: true,
+		AllComps: true,
 	}
 
-	return decodeComp(calendarData.Comp)
+	if calendarData.Comp != nil {
+		var err error
+		if req, err = decodeComp(calendarData.Comp); err != nil {
+			return nil, err
+		}
+	}
+
+	if calendarData.Expand != nil && calendarData.LimitRecurrenceSet != nil {
+		return nil, daverr.HTTPErrorf(
+			http.StatusBadRequest,
+			"caldav: only one of expand or limit-recurrence-set can be specified in calendar-data",
+		)
+	}
+
+	if e := calendarData.Expand; e != nil {
+		req.Expand = &CalendarExpandRequest{Start: time.Time(e.Start), End: time.Time(e.End)}
+		if !req.Expand.Start.Before(req.Expand.End) {
+			return nil, daverr.HTTPErrorf(http.StatusBadRequest, "caldav: invalid time range in expand")
+		}
+	}
+
+	if l := calendarData.LimitRecurrenceSet; l != nil {
+		req.LimitRecurrenceSet = &CalendarExpandRequest{Start: time.Time(l.Start), End: time.Time(l.End)}
+		if !req.LimitRecurrenceSet.Start.Before(req.LimitRecurrenceSet.End) {
+			return nil, daverr.HTTPErrorf(http.StatusBadRequest, "caldav: invalid time range in limit-recurrence-set")
+		}
+	}
+
+	return req, nil
 }
 
 func (h *Handler) handleQuery(r *http.Request, w http.ResponseWriter, query *calendarQuery) error {
 	var q CalendarQuery
-	// TODO: calendar-data in query.Prop
+
+	if query.Prop != nil {
+		var calendarData calendarDataReq
+		if err := query.Prop.Decode(&calendarData); err != nil && !daverr.IsNotFound(err) {
+			return err
+		}
+
+		decoded, err := decodeCalendarDataReq(&calendarData)
+		if err != nil {
+			return err
+		}
+
+		q.CompRequest = *decoded
+	}
+
 	cf, err := decodeCompFilter(&query.Filter.CompFilter)
 	if err != nil {
 		return err
@@ -355,6 +424,127 @@ func (h *Handler) handleMultiget(ctx context.Context, w http.ResponseWriter, mul
 	return internal.ServeMultiStatus(w, ms)
 }
 
+func (h *Handler) handleSyncCollection(
+	r *http.Request,
+	w http.ResponseWriter,
+	sync *internal.SyncCollectionQuery,
+) error {
+	ctx := r.Context()
+	b := backend{
+		Backend: h.Backend,
+		Prefix:  strings.TrimSuffix(h.Prefix, "/"),
+	}
+
+	if b.resourceTypeAtPath(r.URL.Path) != resourceTypeCalendar {
+		return daverr.HTTPErrorf(http.StatusForbidden, "caldav: sync-collection is only supported on calendars")
+	}
+
+	// Calendars have no child collections, so both sync levels are equivalent.
+	switch sync.SyncLevel {
+	case "", "1", "infinite":
+	default:
+		return daverr.HTTPErrorf(http.StatusBadRequest, "caldav: invalid sync-level %q", sync.SyncLevel)
+	}
+
+	query := SyncQuery{SyncToken: sync.SyncToken}
+	if sync.Limit != nil {
+		query.Limit = int(sync.Limit.NResults)
+	}
+
+	if sync.Prop != nil {
+		var calendarData calendarDataReq
+		if err := sync.Prop.Decode(&calendarData); err != nil && !daverr.IsNotFound(err) {
+			return err
+		}
+
+		decoded, err := decodeCalendarDataReq(&calendarData)
+		if err != nil {
+			return err
+		}
+
+		query.CompRequest = *decoded
+	}
+
+	sr, err := h.Backend.SyncCollection(ctx, r.URL.Path, &query)
+	if errors.Is(err, ErrInvalidSyncToken) {
+		// https://datatracker.ietf.org/doc/html/rfc6578#section-3.2
+		elem := internal.NewRawXMLElement(internal.ValidSyncTokenName, nil, nil)
+
+		return &daverr.HTTPError{
+			Code: http.StatusForbidden,
+			Err:  &internal.Error{Raw: []internal.RawXMLValue{*elem}},
+		}
+	} else if err != nil {
+		return err
+	}
+
+	resps := make([]internal.Response, 0, len(sr.Updated)+len(sr.Deleted)+1)
+
+	for _, co := range sr.Updated {
+		propfind := internal.PropFind{Prop: sync.Prop}
+
+		resp, err := b.propFindCalendarObject(ctx, &propfind, &co)
+		if err != nil {
+			return err
+		}
+
+		resps = append(resps, *resp)
+	}
+
+	for _, path := range sr.Deleted {
+		resps = append(resps, internal.Response{
+			Hrefs:  []internal.Href{{Path: path}},
+			Status: &internal.Status{Code: http.StatusNotFound},
+		})
+	}
+
+	// https://datatracker.ietf.org/doc/html/rfc6578#section-3.6
+	if sr.Truncated {
+		resps = append(resps, internal.Response{
+			Hrefs:  []internal.Href{{Path: r.URL.Path}},
+			Status: &internal.Status{Code: http.StatusInsufficientStorage},
+		})
+	}
+
+	ms := internal.NewMultiStatus(resps...)
+	ms.SyncToken = sr.SyncToken
+
+	return internal.ServeMultiStatus(w, ms)
+}
+
+// https://datatracker.ietf.org/doc/html/rfc4791#section-7.10
+func (h *Handler) handleFreeBusyQuery(r *http.Request, w http.ResponseWriter, query *freeBusyQuery) error {
+	b := backend{
+		Backend: h.Backend,
+		Prefix:  strings.TrimSuffix(h.Prefix, "/"),
+	}
+
+	if b.resourceTypeAtPath(r.URL.Path) != resourceTypeCalendar {
+		return daverr.HTTPErrorf(http.StatusForbidden, "caldav: free-busy-query is only supported on calendars")
+	}
+
+	if query.TimeRange == nil {
+		return daverr.HTTPErrorf(http.StatusBadRequest, "caldav: expected time-range in free-busy-query")
+	}
+
+	q := FreeBusyQuery{Start: time.Time(query.TimeRange.Start), End: time.Time(query.TimeRange.End)}
+	if q.Start.IsZero() || !q.Start.Before(q.End) {
+		return daverr.HTTPErrorf(http.StatusBadRequest, "caldav: invalid time range in free-busy-query")
+	}
+
+	periods, err := h.Backend.QueryFreeBusy(r.Context(), r.URL.Path, &q)
+	if err != nil {
+		return err
+	}
+
+	cal := ics.NewCalendarFor(ProductID)
+	cal.AddVBusy(NewFreeBusy("", q.Start, q.End, periods))
+
+	w.Header().Set("Content-Type", MIMEType)
+
+	return cal.SerializeTo(w)
+}
+
 type backend struct {
 	Backend Backend
 	Prefix  string
@@ -372,8 +562,25 @@ const (
 
 func (b *backend) Options(r *http.Request) (caps []string, allow []string, err error) {
 	caps = []string{"calendar-access"}
+	if _, ok := b.Backend.(ACLBackend); ok {
+		caps = append(caps, "access-control")
+	}
 
-	if b.resourceTypeAtPath(r.URL.Path) != resourceTypeCalendarObject {
+	if _, ok, err := isScheduleOutbox(r.Context(), b.Backend, r.URL.Path); err != nil {
+		return nil, nil, err
+	} else if ok {
+		return caps, []string{http.MethodOptions, http.MethodPost, "PROPFIND"}, nil
+	}
+
+	resType := b.resourceTypeAtPath(r.URL.Path)
+	if resType == resourceTypeCalendar {
+		allow = []string{http.MethodOptions, "PROPFIND", "PROPPATCH", "REPORT", "DELETE", "MKCOL"}
+		if _, ok := b.Backend.(ACLBackend); ok {
+			allow = append(allow, "ACL")
+		}
+
+		return caps, allow, nil
+	} else if resType != resourceTypeCalendarObject {
 		return caps, []string{http.MethodOptions, "PROPFIND", "REPORT", "DELETE", "MKCOL"}, nil
 	}
 
@@ -395,6 +602,8 @@ func (b *backend) Options(r *http.Request) (caps []string, allow []string, err e
 		http.MethodPut,
 		http.MethodDelete,
 		"PROPFIND",
+		"COPY",
+		"MOVE",
 	}, nil
 }
 
@@ -506,6 +715,15 @@ func (b *backend) PropFind(
 
 				resps = append(resps, resps_...)
 			}
+		} else if _, ok, err := isScheduleOutbox(r.Context(), b.Backend, r.URL.Path); err != nil {
+			return nil, err
+		} else if ok {
+			resp, err := b.propFindScheduleOutbox(r.Context(), propfind, r.URL.Path)
+			if err != nil {
+				return nil, err
+			}
+
+			resps = append(resps, *resp)
 		}
 	case resourceTypeCalendar:
 		ab, err := b.Backend.GetCalendar(r.Context(), r.URL.Path)
@@ -545,8 +763,123 @@ func (b *backend) PropFind(
 	return internal.NewMultiStatus(resps...), nil
 }
 
+// patchCalendarProp applies a property of a PROPPATCH request to a calendar
+// update, reporting whether the property can be changed.
+func patchCalendarProp(update *CalendarUpdate, raw *internal.RawXMLValue, remove bool) (bool, error) {
+	xmlName, _ := raw.XMLName()
+
+	var value string
+
+	switch xmlName {
+	case internal.DisplayNameName:
+		var v internal.DisplayName
+		if !remove {
+			if err := raw.Decode(&v); err != nil {
+				return false, &daverr.HTTPError{Code: http.StatusBadRequest, Err: err}
+			}
+		}
+
+		value = v.Name
+		update.Name = &value
+	case calendarDescriptionName:
+		var v calendarDescription
+		if !remove {
+			if err := raw.Decode(&v); err != nil {
+				return false, &daverr.HTTPError{Code: http.StatusBadRequest, Err: err}
+			}
+		}
+
+		value = v.Description
+		update.Description = &value
+	case calendarColorName:
+		var v calendarColor
+		if !remove {
+			if err := raw.Decode(&v); err != nil {
+				return false, &daverr.HTTPError{Code: http.StatusBadRequest, Err: err}
+			}
+		}
+
+		value = v.Color
+		update.Color = &value
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// PropPatch updates the properties of a calendar. As required by RFC 4918,
+// section 9.2, either all properties are updated or none is: if any of them
+// cannot be changed, the others are reported as a failed dependency.
 func (b *backend) PropPatch(r *http.Request, update *internal.PropertyUpdate) (*internal.Response, error) {
-	return nil, daverr.HTTPErrorf(http.StatusNotImplemented, "caldav: PropPatch not implemented")
+	if b.resourceTypeAtPath(r.URL.Path) != resourceTypeCalendar {
+		return nil, daverr.HTTPErrorf(http.StatusForbidden, "caldav: PROPPATCH is only supported on calendars")
+	}
+
+	var (
+		calUpdate CalendarUpdate
+		names     []xml.Name
+		forbidden = map[xml.Name]struct{}{}
+	)
+
+	patch := func(props []internal.RawXMLValue, remove bool) error {
+		for _, raw := range props {
+			xmlName, ok := raw.XMLName()
+			if !ok {
+				continue
+			}
+
+			names = append(names, xmlName)
+
+			ok, err := patchCalendarProp(&calUpdate, &raw, remove)
+			if err != nil {
+				return err
+			} else if !ok {
+				forbidden[xmlName] = struct{}{}
+			}
+		}
+
+		return nil
+	}
+
+	for _, remove := range update.Remove {
+		if err := patch(remove.Prop.Raw, true); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, set := range update.Set {
+		if err := patch(set.Prop.Raw, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, daverr.HTTPErrorf(http.StatusBadRequest, "caldav: request missing properties to update")
+	}
+
+	if len(forbidden) == 0 {
+		if err := b.Backend.UpdateCalendar(r.Context(), r.URL.Path, &calUpdate); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &internal.Response{Hrefs: []internal.Href{{Path: r.URL.Path}}}
+
+	for _, xmlName := range names {
+		code := http.StatusOK
+		if _, ok := forbidden[xmlName]; ok {
+			code = http.StatusForbidden
+		} else if len(forbidden) > 0 {
+			code = http.StatusFailedDependency
+		}
+
+		if err := resp.EncodeProp(code, internal.NewRawXMLElement(xmlName, nil, nil)); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
 }
 
 func (b *backend) Put(w http.ResponseWriter, r *http.Request) (err error) {
@@ -604,6 +937,10 @@ func (b *backend) Put(w http.ResponseWriter, r *http.Request) (err error) {
 }
 
 func (b *backend) Delete(r *http.Request) error {
+	if b.resourceTypeAtPath(r.URL.Path) == resourceTypeCalendar {
+		return b.Backend.DeleteCalendar(r.Context(), r.URL.Path)
+	}
+
 	return b.Backend.DeleteCalendarObject(r.Context(), r.URL.Path)
 }
 
@@ -630,7 +967,7 @@ func (b *backend) Mkcol(r *http.Request) error {
 		cal.Name = m.DisplayName
 		cal.Description = m.Description
 		cal.MaxResourceSize = m.MaxResourceSize
-		cal.SupportedComponentSet = m.SupportedComponents
+		cal.SupportedComponentSet = compNames(m.SupportedComponents)
 	}
 
 	homeSetPath, err := b.Backend.CalendarHomeSetPath(ctx)
@@ -668,7 +1005,7 @@ func (b *backend) Mkcalendar(r *http.Request) error {
 		cal.Name = m.DisplayName
 		cal.Description = m.Description
 		cal.MaxResourceSize = m.MaxResourceSize
-		cal.SupportedComponentSet = m.SupportedComponents
+		cal.SupportedComponentSet = compNames(m.SupportedComponents)
 	}
 
 	homeSetPath, err := b.Backend.CalendarHomeSetPath(ctx)
@@ -683,12 +1020,35 @@ func (b *backend) Mkcalendar(r *http.Request) error {
 	return b.Backend.CreateCalendar(ctx, &cal)
 }
 
+// checkCopyMove ensures that a COPY or MOVE request transfers a calendar
+// object to another calendar object resource on this server.
+func (b *backend) checkCopyMove(r *http.Request, dest *internal.Href) error {
+	if dest.Host != "" && dest.Host != r.Host {
+		return daverr.HTTPErrorf(http.StatusBadGateway, "caldav: destination %q is on another server", dest.Host)
+	}
+
+	if b.resourceTypeAtPath(r.URL.Path) != resourceTypeCalendarObject ||
+		b.resourceTypeAtPath(dest.Path) != resourceTypeCalendarObject {
+		return daverr.HTTPErrorf(http.StatusForbidden, "caldav: only calendar objects can be copied or moved")
+	}
+
+	return nil
+}
+
 func (b *backend) Copy(r *http.Request, dest *internal.Href, recursive, overwrite bool) (created bool, err error) {
-	return false, daverr.HTTPErrorf(http.StatusNotImplemented, "caldav: Copy not implemented")
+	if err := b.checkCopyMove(r, dest); err != nil {
+		return false, err
+	}
+
+	return b.Backend.CopyCalendarObject(r.Context(), r.URL.Path, dest.Path, overwrite)
 }
 
 func (b *backend) Move(r *http.Request, dest *internal.Href, overwrite bool) (created bool, err error) {
-	return false, daverr.HTTPErrorf(http.StatusNotImplemented, "caldav: Move not implemented")
+	if err := b.checkCopyMove(r, dest); err != nil {
+		return false, err
+	}
+
+	return b.Backend.MoveCalendarObject(r.Context(), r.URL.Path, dest.Path, overwrite)
 }
 
 func (b *backend) resourceTypeAtPath(reqPath string) resourceType {
@@ -745,6 +1105,10 @@ func (b *backend) propFindUserPrincipal(ctx context.Context, propfind *internal.
 		),
 	}
 
+	if err := b.addSchedulingProps(ctx, props); err != nil {
+		return nil, err
+	}
+
 	return internal.NewPropFindResponse(principalPath, propfind, props)
 }
 
@@ -767,6 +1131,15 @@ func (b *backend) propFindHomeSet(ctx context.Context, propfind *internal.PropFi
 		internal.ResourceTypeName: internal.PropFindValue(internal.NewResourceType(internal.CollectionName)),
 	}
 
+	if quotaBackend, ok := b.Backend.(QuotaBackend); ok {
+		quota, err := quotaBackend.CalendarHomeSetQuota(ctx)
+		if err != nil {
+			return nil, err
+		} else if quota != nil {
+			addQuotaProps(props, quota)
+		}
+	}
+
 	return internal.NewPropFindResponse(homeSetPath, propfind, props)
 }
 
@@ -807,13 +1180,30 @@ func (b *backend) propFindCalendar(
 				Comp: components,
 			}, nil
 		},
-		// TODO: Gather UserPrivilege from backend to control read-and-write
-		internal.CurrentUserPrivilegeSetName: internal.PropFindValue(&internal.CurrentUserPrivilegeSet{
-			Privilege: []internal.Privilege{{
-				Read:  &struct{}{},
-				Write: &struct{}{},
-			}},
-		}),
+		internal.SupportedReportSetName: internal.PropFindValue(internal.NewSupportedReportSet(
+			calendarQueryName,
+			calendarMultigetName,
+			freeBusyQueryName,
+			internal.SyncCollectionName,
+		)),
+		internal.CurrentUserPrivilegeSetName: func(*internal.RawXMLValue) (any, error) {
+			privileges := cal.Privileges
+			if privileges == nil {
+				privileges = []Privilege{PrivilegeRead, PrivilegeWrite}
+			}
+
+			return &internal.CurrentUserPrivilegeSet{Privilege: encodePrivileges(privileges)}, nil
+		},
+	}
+
+	if cal.Owner != "" {
+		props[internal.OwnerName] = internal.PropFindValue(&internal.Owner{
+			Href: internal.Href{Path: cal.Owner},
+		})
+	}
+
+	if cal.ACL != nil {
+		props[internal.ACLName] = internal.PropFindValue(encodeACL(cal.ACL))
 	}
 
 	if cal.Name != "" {
@@ -840,6 +1230,12 @@ func (b *backend) propFindCalendar(
 		})
 	}
 
+	if cal.SyncToken != "" {
+		props[internal.SyncTokenName] = internal.PropFindValue(&internal.SyncToken{
+			Token: cal.SyncToken,
+		})
+	}
+
 	// TODO: CALDAV:calendar-timezone, CALDAV:supported-calendar-component-set, CALDAV:min-date-time,
 	// CALDAV:max-date-time, CALDAV:max-instances, CALDAV:max-attendees-per-instance
 
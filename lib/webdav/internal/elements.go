@@ -35,6 +35,17 @@ var (
 
 	CurrentUserPrincipalName    = xml.Name{Space: Namespace, Local: "current-user-principal"}
 	CurrentUserPrivilegeSetName = xml.Name{Space: Namespace, Local: "current-user-privilege-set"}
+	OwnerName                   = xml.Name{Space: Namespace, Local: "owner"}
+	ACLName                     = xml.Name{Space: Namespace, Local: "acl"}
+
+	SyncTokenName          = xml.Name{Space: Namespace, Local: "sync-token"}
+	SyncCollectionName     = xml.Name{Space: Namespace, Local: "sync-collection"}
+	SupportedReportSetName = xml.Name{Space: Namespace, Local: "supported-report-set"}
+	ValidSyncTokenName     = xml.Name{Space: Namespace, Local: "valid-sync-token"}
+
+	QuotaAvailableBytesName = xml.Name{Space: Namespace, Local: "quota-available-bytes"}
+	QuotaUsedBytesName      = xml.Name{Space: Namespace, Local: "quota-used-bytes"}
+	QuotaNotExceededName    = xml.Name{Space: Namespace, Local: "quota-not-exceeded"}
 )
 
 type Status struct {
@@ -485,12 +496,57 @@ type SyncCollectionQuery struct {
 	Prop      *Prop    `xml:"prop"`
 }
 
+// SyncToken implements https://tools.ietf.org/html/rfc6578#section-4
+type SyncToken struct {
+	XMLName xml.Name `xml:"DAV: sync-token"`
+	Token   string   `xml:",chardata"`
+}
+
+// QuotaAvailableBytes implements https://tools.ietf.org/html/rfc4331#section-3
+type QuotaAvailableBytes struct {
+	XMLName xml.Name `xml:"DAV: quota-available-bytes"`
+	Bytes   int64    `xml:",chardata"`
+}
+
+// QuotaUsedBytes implements https://tools.ietf.org/html/rfc4331#section-4
+type QuotaUsedBytes struct {
+	XMLName xml.Name `xml:"DAV: quota-used-bytes"`
+	Bytes   int64    `xml:",chardata"`
+}
+
 // Limit implements https://tools.ietf.org/html/rfc5323#section-5.17
 type Limit struct {
 	XMLName  xml.Name `xml:"DAV: limit"`
 	NResults uint     `xml:"nresults"`
 }
 
+// SupportedReportSet implements https://tools.ietf.org/html/rfc3253#section-3.1.5
+type SupportedReportSet struct {
+	XMLName         xml.Name          `xml:"DAV: supported-report-set"`
+	SupportedReport []SupportedReport `xml:"supported-report"`
+}
+
+func NewSupportedReportSet(names ...xml.Name) *SupportedReportSet {
+	reports := make([]SupportedReport, len(names))
+	for i, name := range names {
+		reports[i] = SupportedReport{Report: Report{Raw: xmlNamesToRaw([]xml.Name{name})}}
+	}
+
+	return &SupportedReportSet{SupportedReport: reports}
+}
+
+// SupportedReport implements https://tools.ietf.org/html/rfc3253#section-3.1.5
+type SupportedReport struct {
+	XMLName xml.Name `xml:"DAV: supported-report"`
+	Report  Report   `xml:"report"`
+}
+
+// Report implements https://tools.ietf.org/html/rfc3253#section-3.1.5
+type Report struct {
+	XMLName xml.Name      `xml:"DAV: report"`
+	Raw     []RawXMLValue `xml:",any"`
+}
+
 // CurrentUserPrivilegeSet implements https://tools.ietf.org/html/rfc3744#section-5.4
 type CurrentUserPrivilegeSet struct {
 	XMLName   xml.Name `xml:"DAV: current-user-privilege-set"`
@@ -499,7 +555,46 @@ type CurrentUserPrivilegeSet struct {
 
 // Privilege implements https://tools.ietf.org/html/rfc3744#section-5.4
 type Privilege struct {
-	XMLName xml.Name  `xml:"DAV: privilege"`
-	Read    *struct{} `xml:"DAV: read,omitempty"`
-	Write   *struct{} `xml:"DAV: write,omitempty"`
+	XMLName  xml.Name  `xml:"DAV: privilege"`
+	All      *struct{} `xml:"DAV: all,omitempty"`
+	Read     *struct{} `xml:"DAV: read,omitempty"`
+	Write    *struct{} `xml:"DAV: write,omitempty"`
+	WriteACL *struct{} `xml:"DAV: write-acl,omitempty"`
+}
+
+// Owner implements https://tools.ietf.org/html/rfc3744#section-5.1
+type Owner struct {
+	XMLName xml.Name `xml:"DAV: owner"`
+	Href    Href     `xml:"href"`
+}
+
+// ACL implements https://tools.ietf.org/html/rfc3744#section-5.5
+type ACL struct {
+	XMLName xml.Name `xml:"DAV: acl"`
+	ACEs    []ACE    `xml:"ace"`
+}
+
+// ACE implements https://tools.ietf.org/html/rfc3744#section-5.5
+type ACE struct {
+	XMLName   xml.Name  `xml:"DAV: ace"`
+	Principal Principal `xml:"principal"`
+	Grant     *Grant    `xml:"grant,omitempty"`
+	Deny      *Grant    `xml:"deny,omitempty"`
+	Protected *struct{} `xml:"protected,omitempty"`
+}
+
+// Principal implements https://tools.ietf.org/html/rfc3744#section-5.5.1
+type Principal struct {
+	XMLName         xml.Name  `xml:"DAV: principal"`
+	Href            *Href     `xml:"href,omitempty"`
+	All             *struct{} `xml:"all,omitempty"`
+	Authenticated   *struct{} `xml:"authenticated,omitempty"`
+	Unauthenticated *struct{} `xml:"unauthenticated,omitempty"`
+	Self            *struct{} `xml:"self,omitempty"`
+}
+
+// Grant implements the grant and deny elements of
+// https://tools.ietf.org/html/rfc3744#section-5.5.2
+type Grant struct {
+	Privileges []Privilege `xml:"privilege"`
 }
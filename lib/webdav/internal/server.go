@@ -100,6 +100,12 @@ type WithMkcalendar interface {
 	Mkcalendar(r *http.Request) error
 }
 
+// WithACL can be implemented by a Backend to support the ACL method, as
+// defined in RFC 3744, section 8.1.
+type WithACL interface {
+	ACL(r *http.Request, acl *ACL) error
+}
+
 type Handler struct {
 	Backend Backend
 }
@@ -141,6 +147,8 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			} else {
 				err = daverr.HTTPErrorf(http.StatusMethodNotAllowed, "webdav: server does not support MKCALENDAR")
 			}
+		case "ACL":
+			err = h.handleACL(w, r)
 		case "COPY", "MOVE":
 			err = h.handleCopyMove(w, r)
 		default:
@@ -168,6 +176,26 @@ func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (h *Handler) handleACL(w http.ResponseWriter, r *http.Request) error {
+	withACL, ok := h.Backend.(WithACL)
+	if !ok {
+		return daverr.HTTPErrorf(http.StatusMethodNotAllowed, "webdav: server does not support ACL")
+	}
+
+	var acl ACL
+	if err := DecodeXMLRequest(r, &acl); err != nil {
+		return err
+	}
+
+	if err := withACL.ACL(r, &acl); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	return nil
+}
+
 func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) error {
 	var propfind PropFind
 	if IsRequestBodyEmpty(r) {
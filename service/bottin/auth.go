@@ -11,7 +11,12 @@ import (
 	"github.com/teapotovh/teapot/service/bottin/store"
 )
 
-var ErrUnsupportedExtendedRequest = errors.New("unsupported extended request, we only support PasswordModify")
+var (
+	ErrUnsupportedExtendedRequest = errors.New(
+		"unsupported extended request, we only support PasswordModify and WhoAmI",
+	)
+	ErrUnexpectedRequestValue = errors.New("WhoAmI requests must not have a value")
+)
 
 func (server *Bottin) Bind(ctx context.Context, state State, r ldap.BindRequest) (State, error) {
 	dn, err := server.parseDN(string(r.Name()), false)
@@ -55,11 +60,19 @@ func (server *Bottin) Bind(ctx context.Context, state State, r ldap.BindRequest)
 		}
 
 		if valid {
+			// The root passwd is hashed at startup, so only entries need rehashing.
+			if !dn.Equal(server.baseDN) && needsRehash(hash, server.hashType) {
+				server.rehashPassword(ctx, entry, hash, passwd)
+			}
+
 			groups := entry.Get(AttrMemberOf)
 			state.user = &User{
 				user:   string(r.Name()),
 				groups: groups,
 			}
+			// Results of paged searches were filtered with the access rights
+			// of the previous user, so they must not be returned anymore.
+			state.paged = nil
 
 			return state, nil
 		}
@@ -69,10 +82,38 @@ func (server *Bottin) Bind(ctx context.Context, state State, r ldap.BindRequest)
 }
 
 func (server *Bottin) Extended(ctx context.Context, state State, r ldap.ExtendedRequest) (State, error) {
-	if r.RequestName() != ldapsrv.NoticeOfPasswordModify {
+	switch r.RequestName() {
+	case ldapsrv.NoticeOfPasswordModify:
+		return server.passwordModify(ctx, state, r)
+	case ldapsrv.NoticeOfWhoAmI:
+		return server.whoAmI(ctx, state, r)
+	default:
 		return state, fmt.Errorf("(%w) %w", ldapsrv.ErrUnwillingToPerform, ErrUnsupportedExtendedRequest)
 	}
+}
+
+// whoAmI implements the WhoAmI extended operation (RFC 4532), which returns
+// the authorization identity of the connection: empty for anonymous users,
+// or the DN the user is bound as.
+func (server *Bottin) whoAmI(ctx context.Context, state State, r ldap.ExtendedRequest) (State, error) {
+	if r.RequestValue() != nil {
+		return state, fmt.Errorf("(%w) %w", ldapsrv.ErrProtocolError, ErrUnexpectedRequestValue)
+	}
+
+	var authzID ldap.OCTETSTRING
+	if user := state.User().user; user != AnonymousUser {
+		authzID = ldap.OCTETSTRING("dn:" + user)
+	}
+
+	server.logger.InfoContext(ctx, "whoami", "user", state.User())
 
+	ldapsrv.SetExtendedResponse(ctx, nil, &authzID)
+
+	return state, nil
+}
+
+// passwordModify implements the PasswordModify extended operation (RFC 3062).
+func (server *Bottin) passwordModify(ctx context.Context, state State, r ldap.ExtendedRequest) (State, error) {
 	passwordModifyRequest, err := r.PasswordModifyRequest()
 	if err != nil {
 		return state, fmt.Errorf("(%w) error while parsing PasswordModify: %w", ldapsrv.ErrInvalidAttributeSyntax, err)
@@ -118,7 +159,7 @@ func (server *Bottin) Extended(ctx context.Context, state State, r ldap.Extended
 		return state, err
 	}
 
-	hash, err := ssha512Encode(string(*passwd))
+	hash, err := hashPassword(server.hashType, string(*passwd))
 	if err != nil {
 		return state, fmt.Errorf("(%w) error while hashing passwd: %w", ldapsrv.ErrOperationsError, err)
 	}
@@ -133,6 +174,9 @@ func (server *Bottin) Extended(ctx context.Context, state State, r ldap.Extended
 		return state, fmt.Errorf("(%w) error while beginning transaction: %w", ldapsrv.ErrOperationsError, err)
 	}
 
+	// Rolling back a committed transaction is a no-op
+	defer func() { _ = tx.Rollback(ctx) }()
+
 	if err = tx.Store(ctx, store.NewEntry(dn, attrs)); err != nil {
 		return state, fmt.Errorf("(%w) error while updating password: %w", ldapsrv.ErrOperationsError, err)
 	}
@@ -143,3 +187,42 @@ func (server *Bottin) Extended(ctx context.Context, state State, r ldap.Extended
 
 	return state, nil
 }
+
+// rehashPassword replaces the hash of a user password with one using the
+// configured scheme. It is called after a successful bind, which is the only
+// time the plaintext password is available. Failures are only logged, as the
+// old hash remains valid.
+func (server *Bottin) rehashPassword(ctx context.Context, entry *store.Entry, oldHash, passwd string) {
+	hash, err := hashPassword(server.hashType, passwd)
+	if err != nil {
+		server.logger.WarnContext(ctx, "error while rehashing passwd", "dn", entry.DN, "err", err)
+		return
+	}
+
+	tx, err := server.store.Begin(ctx)
+	if err != nil {
+		server.logger.WarnContext(ctx, "error while beginning transaction for rehash", "dn", entry.DN, "err", err)
+		return
+	}
+
+	// Rolling back a committed transaction is a no-op
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Only the old hash is swapped, so that concurrent changes to the entry,
+	// including to the password itself, are not overwritten.
+	err = tx.ReplaceValue(ctx, entry.DN, AttrUserPassword, oldHash, hash)
+	if err == nil {
+		err = tx.Commit(ctx)
+	}
+
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		server.logger.DebugContext(ctx, "passwd changed concurrently, skipping rehash", "dn", entry.DN)
+		return
+	case err != nil:
+		server.logger.WarnContext(ctx, "could not store rehashed passwd", "dn", entry.DN, "err", err)
+		return
+	}
+
+	server.logger.InfoContext(ctx, "rehashed passwd", "dn", entry.DN, "scheme", server.hashType)
+}
@@ -0,0 +1,69 @@
+package bottin
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+func TestRehashPassword(t *testing.T) {
+	t.Parallel()
+
+	const (
+		dn     = "uid=alice,ou=users," + testBaseDN
+		passwd = "secret"
+	)
+
+	oldHash, err := hashPassword(SSHA256, passwd)
+	if err != nil {
+		t.Fatalf("error hashing password: %s", err)
+	}
+
+	stale := testEntry(t, dn, map[string][]string{
+		"objectClass":  {"inetOrgPerson"},
+		"mail":         {"alice@teapot.ovh"},
+		"userPassword": {oldHash},
+	})
+	// The entry is modified between the bind and the rehash.
+	current := testEntry(t, dn, map[string][]string{
+		"objectClass":  {"inetOrgPerson"},
+		"mail":         {"alice@example.com"},
+		"userPassword": {oldHash},
+	})
+
+	ctx := context.Background()
+	server := newTestBottin(t, current)
+	server.rehashPassword(ctx, &stale, oldHash, passwd)
+
+	entries, err := server.store.List(ctx, stale.DN.Prefix(), true)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("error getting %q: %v", dn, err)
+	}
+
+	if mail := entries[0].Get(store.NewAttributeKey("mail")); !slices.Equal(mail, []string{"alice@example.com"}) {
+		t.Errorf("concurrent change was lost, GOT mail %v", mail)
+	}
+
+	hashes := entries[0].Get(AttrUserPassword)
+	if len(hashes) != 1 || !needsRehash(oldHash, server.hashType) || needsRehash(hashes[0], server.hashType) {
+		t.Fatalf("password was not rehashed, GOT %v", hashes)
+	}
+
+	if matched, err := matchPassword(hashes[0], passwd); err != nil || !matched {
+		t.Errorf("rehashed password does not match (err %v)", err)
+	}
+
+	// A password changed after the bind is left untouched.
+	server.rehashPassword(ctx, &stale, oldHash, passwd)
+
+	entries, err = server.store.List(ctx, stale.DN.Prefix(), true)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("error getting %q: %v", dn, err)
+	}
+
+	if got := entries[0].Get(AttrUserPassword); !slices.Equal(got, hashes) {
+		t.Errorf("GOT %v EXPECTED %v", got, hashes)
+	}
+}
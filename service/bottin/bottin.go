@@ -7,10 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/teapotovh/teapot/lib/ldapsrv"
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
 	"github.com/teapotovh/teapot/lib/run"
 	"github.com/teapotovh/teapot/service/bottin/store"
 )
@@ -25,6 +27,22 @@ const (
 	AttrModifiersName   store.AttributeKey = "modifiersname"
 	AttrModifyTimestamp store.AttributeKey = "modifytimestamp"
 
+	// Attributes published by the subschema subentry (see schema.go).
+
+	AttrSubschemaSubentry store.AttributeKey = "subschemasubentry"
+	AttrAttributeTypes    store.AttributeKey = "attributetypes"
+	AttrObjectClasses     store.AttributeKey = "objectclasses"
+	AttrLDAPSyntaxes      store.AttributeKey = "ldapsyntaxes"
+	AttrMatchingRules     store.AttributeKey = "matchingrules"
+
+	// Attributes published by the root DSE (see rootdse.go).
+
+	AttrNamingContexts       store.AttributeKey = "namingcontexts"
+	AttrSupportedControl     store.AttributeKey = "supportedcontrol"
+	AttrSupportedExtension   store.AttributeKey = "supportedextension"
+	AttrSupportedFeatures    store.AttributeKey = "supportedfeatures"
+	AttrSupportedLDAPVersion store.AttributeKey = "supportedldapversion"
+
 	// Attributes that we are interested in at various points.
 
 	AttrObjectClass  store.AttributeKey = "objectclass"
@@ -40,10 +58,15 @@ var (
 )
 
 type BottinConfig struct {
-	Store  store.StoreConfig
-	BaseDN string
-	Passwd string
-	ACL    []string
+	Store        store.StoreConfig
+	BaseDN       string
+	Passwd       string
+	PasswdScheme string
+	ACL          []string
+	Schemas      []string
+	SchemaCheck  bool
+	SizeLimit    int
+	TimeLimit    time.Duration
 }
 
 type Bottin struct {
@@ -51,8 +74,18 @@ type Bottin struct {
 
 	baseDN     store.DN
 	rootPasswd string
+	hashType   HashType
 	acl        ACL
 
+	// schema is nil when no schema has been configured, in which case
+	// entries are not validated and no subschema subentry is published.
+	schema      *Schema
+	schemaCheck bool
+	subschema   store.Entry
+
+	maxSizeLimit int
+	maxTimeLimit time.Duration
+
 	store store.Store
 }
 
@@ -67,6 +100,29 @@ func NewBottin(config BottinConfig, logger *slog.Logger) (*Bottin, error) {
 		return nil, fmt.Errorf("error while parsing baseDN: %w", err)
 	}
 
+	var (
+		schema    *Schema
+		subschema store.Entry
+	)
+
+	if len(config.Schemas) > 0 {
+		schema, err = LoadSchema(config.Schemas)
+		if err != nil {
+			return nil, fmt.Errorf("error while loading schema: %w", err)
+		}
+
+		subschema = schema.Subschema(genTimestamp())
+	}
+
+	if config.PasswdScheme == "" {
+		config.PasswdScheme = string(DefaultHashType)
+	}
+
+	hashType, err := ParseHashType(config.PasswdScheme)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing passwd scheme: %w", err)
+	}
+
 	if config.Passwd == "" {
 		adminPassBytes := make([]byte, 8)
 
@@ -79,7 +135,7 @@ func NewBottin(config BottinConfig, logger *slog.Logger) (*Bottin, error) {
 		logger.Info("using randomly generated root password", "passwd", config.Passwd)
 	}
 
-	hash, err := ssha512Encode(config.Passwd)
+	hash, err := hashPassword(hashType, config.Passwd)
 	if err != nil {
 		return nil, fmt.Errorf("error while hashing root passwd: %w", err)
 	}
@@ -94,8 +150,16 @@ func NewBottin(config BottinConfig, logger *slog.Logger) (*Bottin, error) {
 
 		baseDN:     baseDN,
 		rootPasswd: hash,
+		hashType:   hashType,
 		acl:        acl,
 
+		schema:      schema,
+		schemaCheck: config.SchemaCheck,
+		subschema:   subschema,
+
+		maxSizeLimit: config.SizeLimit,
+		maxTimeLimit: config.TimeLimit,
+
 		store: store,
 	}
 
@@ -106,8 +170,19 @@ func (server *Bottin) Store() store.Store {
 	return server.store
 }
 
+// SupportedControls implements ldapsrv.ControlsHandler.
+func (server *Bottin) SupportedControls() []ldap.LDAPOID {
+	return []ldap.LDAPOID{ldapsrv.ControlPagedResults, ldapsrv.ControlServerSideSort}
+}
+
+// SupportedExtensions implements ldapsrv.ExtensionsHandler.
+func (server *Bottin) SupportedExtensions() []ldap.LDAPOID {
+	return []ldap.LDAPOID{ldapsrv.NoticeOfPasswordModify, ldapsrv.NoticeOfWhoAmI}
+}
+
 type State struct {
-	user *User
+	user  *User
+	paged *pagedSearches
 }
 
 const AnonymousUser = "ANONYMOUS"
@@ -158,6 +233,9 @@ func (server *Bottin) initialize(ctx context.Context) error {
 		AttrCreatorsName:        store.AttributeValue{server.baseDN.String()},
 		AttrCreateTimestamp:     store.AttributeValue{genTimestamp()},
 		AttrEntryUUID:           store.AttributeValue{uuid.String()},
+
+		// The organization class requires a name, which defaults to the RDN value.
+		"o": store.AttributeValue{server.baseDN[0].Value},
 	}
 
 	tx, err := server.store.Begin(ctx)
@@ -165,6 +243,9 @@ func (server *Bottin) initialize(ctx context.Context) error {
 		return fmt.Errorf("error while beginning store transaction: %w", err)
 	}
 
+	// Rolling back a committed transaction is a no-op
+	defer func() { _ = tx.Rollback(ctx) }()
+
 	entry := store.NewEntry(server.baseDN, baseAttributes)
 	if err = tx.Store(ctx, entry); err != nil {
 		return fmt.Errorf("error while storing base entry: %w", err)
@@ -180,6 +261,11 @@ func (server *Bottin) initialize(ctx context.Context) error {
 }
 
 func (server *Bottin) parseDN(rawDN string, allowPrefix bool) (store.DN, error) {
+	// The empty DN (i.e., the root DSE) is a prefix of any DN
+	if allowPrefix && rawDN == "" {
+		return server.baseDN, nil
+	}
+
 	dn, err := store.ParseDN(rawDN)
 	if err != nil {
 		return nil, err
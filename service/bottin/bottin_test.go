@@ -0,0 +1,188 @@
+package bottin
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+const testBaseDN = "dc=teapot,dc=ovh"
+
+func mustParseDN(t *testing.T, raw string) store.DN {
+	t.Helper()
+
+	dn, err := store.ParseDN(raw)
+	if err != nil {
+		t.Fatalf("error parsing DN %q: %s", raw, err)
+	}
+
+	return dn
+}
+
+func testEntry(t *testing.T, dn string, attributes map[string][]string) store.Entry {
+	t.Helper()
+
+	attrs := store.Attributes{}
+	for key, values := range attributes {
+		attrs[store.NewAttributeKey(key)] = values
+	}
+
+	return store.NewEntry(mustParseDN(t, dn), attrs)
+}
+
+// newTestBottin returns a Bottin backed by an in-memory store containing
+// the given entries, with an ACL granting everything to everyone.
+func newTestBottin(t *testing.T, entries ...store.Entry) *Bottin {
+	t.Helper()
+
+	server, err := NewBottin(BottinConfig{
+		Store:  store.StoreConfig{Type: "mem", Timeout: time.Second},
+		BaseDN: testBaseDN,
+		Passwd: "secret",
+		ACL:    []string{"*::read add delete modify:*:*"},
+	}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("error creating bottin: %s", err)
+	}
+
+	ctx := context.Background()
+
+	tx, err := server.store.Begin(ctx)
+	if err != nil {
+		t.Fatalf("error beginning transaction: %s", err)
+	}
+
+	for _, entry := range entries {
+		if err := tx.Store(ctx, entry); err != nil {
+			t.Fatalf("error storing %q: %s", entry.DN, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("error committing entries: %s", err)
+	}
+
+	return server
+}
+
+// parseFilter compiles an RFC 4515 filter string into the ldap.Filter
+// decoded by the server.
+func parseFilter(t *testing.T, filter string) ldap.Filter {
+	t.Helper()
+
+	r := searchRequest(t, testBaseDN, ldap.SearchRequestScopeBaseObject, filter)
+
+	return r.Filter()
+}
+
+// searchRequest builds a SearchRequest by encoding it with go-ldap and
+// decoding it as the server would.
+func searchRequest(t *testing.T, base string, scope int, filter string) ldap.SearchRequest {
+	t.Helper()
+
+	compiled, err := goldap.CompileFilter(filter)
+	if err != nil {
+		t.Fatalf("error compiling filter %q: %s", filter, err)
+	}
+
+	request := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchRequest, nil, "Search")
+	request.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, base, "Base DN"))
+	request.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, scope, "Scope"))
+	request.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, 0, "Deref"))
+	request.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 0, "Size Limit"))
+	request.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 0, "Time Limit"))
+	request.AppendChild(ber.NewBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, false, "Types Only"))
+	request.AppendChild(ldapBooleans(compiled))
+	request.AppendChild(ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes"))
+
+	return decodeRequest[ldap.SearchRequest](t, request)
+}
+
+// modifyDNRequest builds a ModifyDNRequest as decoded by the server.
+func modifyDNRequest(t *testing.T, dn, newRDN string, deleteOldRDN bool, newSuperior string) ldap.ModifyDNRequest {
+	t.Helper()
+
+	request := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationModifyDNRequest, nil, "ModDN")
+	request.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "DN"))
+	request.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, newRDN, "RDN"))
+	request.AppendChild(ber.NewLDAPBoolean(ber.ClassUniversal, ber.TypePrimitive, ber.TagBoolean, deleteOldRDN, "Del"))
+
+	if newSuperior != "" {
+		request.AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 0, newSuperior, "New Superior"))
+	}
+
+	return decodeRequest[ldap.ModifyDNRequest](t, request)
+}
+
+func addRequest(t *testing.T, dn string, attributes map[string][]string) ldap.AddRequest {
+	t.Helper()
+
+	request := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationAddRequest, nil, "Add")
+	request.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "DN"))
+
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for key, values := range attributes {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, key, "Type"))
+
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Vals")
+		for _, value := range values {
+			vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Val"))
+		}
+
+		attr.AppendChild(vals)
+		attrs.AppendChild(attr)
+	}
+
+	request.AppendChild(attrs)
+
+	return decodeRequest[ldap.AddRequest](t, request)
+}
+
+// decodeRequest wraps an encoded protocol operation in a message, and
+// decodes it as the server would.
+func decodeRequest[T ldap.ProtocolOp](t *testing.T, request *ber.Packet) T {
+	t.Helper()
+
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Message")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, 1, "Message ID"))
+	packet.AppendChild(request)
+
+	message, err := ldap.ReadLDAPMessage(ldap.NewBytes(0, packet.Bytes()))
+	if err != nil {
+		t.Fatalf("error decoding request: %s", err)
+	}
+
+	r, ok := message.ProtocolOp().(T)
+	if !ok {
+		t.Fatalf("decoded %T, expected %T", message.ProtocolOp(), r)
+	}
+
+	return r
+}
+
+// ldapBooleans re-encodes the dnAttributes flags of extensible match filters,
+// which go-ldap encodes as 0x01 while RFC 4511 requires TRUE to be 0xFF.
+func ldapBooleans(packet *ber.Packet) *ber.Packet {
+	if packet.TagType != ber.TypeConstructed {
+		return packet
+	}
+
+	result := ber.Encode(packet.ClassType, packet.TagType, packet.Tag, nil, packet.Description)
+	for _, child := range packet.Children {
+		if packet.Tag == goldap.FilterExtensibleMatch && child.Tag == goldap.MatchingRuleAssertionDNAttributes {
+			child = ber.NewLDAPBoolean(child.ClassType, child.TagType, child.Tag, true, child.Description)
+		}
+
+		result.AppendChild(ldapBooleans(child))
+	}
+
+	return result
+}
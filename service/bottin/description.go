@@ -0,0 +1,281 @@
+package bottin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// token is a lexical element of a schema description: a parenthesis, a
+// dollar sign, a quoted string or a bare word (i.e., a keyword or an OID).
+type token struct {
+	value  string
+	quoted bool
+}
+
+func (t token) is(value string) bool {
+	return !t.quoted && t.value == value
+}
+
+// tokenize splits a schema description, as defined in RFC 4512, section 4.1,
+// into tokens.
+func tokenize(definition string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(definition); {
+		switch c := definition[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '$':
+			tokens = append(tokens, token{value: string(c)})
+			i++
+		case c == '\'':
+			end := strings.IndexByte(definition[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated quoted string", ErrSchemaSyntax)
+			}
+
+			tokens = append(tokens, token{value: unescapeQDString(definition[i+1 : i+1+end]), quoted: true})
+			i += end + 2
+		default:
+			end := strings.IndexAny(definition[i:], " \t\n\r()$'")
+			if end < 0 {
+				end = len(definition) - i
+			}
+
+			tokens = append(tokens, token{value: definition[i : i+end]})
+			i += end
+		}
+	}
+
+	return tokens, nil
+}
+
+// unescapeQDString decodes the \27 and \5C escapes of a quoted string.
+func unescapeQDString(value string) string {
+	return strings.NewReplacer(`\27`, `'`, `\5C`, `\`, `\5c`, `\`).Replace(value)
+}
+
+// escapeQDString encodes a value to be written as a quoted string.
+func escapeQDString(value string) string {
+	return strings.NewReplacer(`\`, `\5C`, `'`, `\27`).Replace(value)
+}
+
+// flagKeywords are the keywords of a description that take no value.
+var flagKeywords = []string{
+	"OBSOLETE",
+	"SINGLE-VALUE",
+	"COLLECTIVE",
+	"NO-USER-MODIFICATION",
+	KindAbstract,
+	KindStructural,
+	KindAuxiliary,
+}
+
+// description is a parsed schema description, mapping each of its keywords
+// to its values. Keywords that take no value are mapped to an empty slice.
+type description struct {
+	oid    string
+	fields map[string][]string
+}
+
+func (d description) value(keyword string) string {
+	if values := d.fields[keyword]; len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func (d description) has(keyword string) bool {
+	_, ok := d.fields[keyword]
+	return ok
+}
+
+// parseDescription parses a schema description, accepting only the given
+// keywords. Extensions (i.e., X-ORIGIN) are parsed and discarded.
+func parseDescription(definition string, keywords []string) (*description, error) {
+	tokens, err := tokenize(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) < 3 || !tokens[0].is("(") || !tokens[len(tokens)-1].is(")") {
+		return nil, fmt.Errorf("%w: description must be enclosed in parentheses", ErrSchemaSyntax)
+	}
+
+	tokens = tokens[1 : len(tokens)-1]
+	d := description{oid: tokens[0].value, fields: map[string][]string{}}
+
+	for i := 1; i < len(tokens); {
+		keyword := tokens[i].value
+		i++
+
+		extension := strings.HasPrefix(keyword, "X-")
+		if !extension && !slices.Contains(keywords, keyword) {
+			return nil, fmt.Errorf("%w: unexpected keyword %q in %q", ErrSchemaSyntax, keyword, d.oid)
+		}
+
+		if _, ok := d.fields[keyword]; ok {
+			return nil, fmt.Errorf("%w: duplicate keyword %q in %q", ErrSchemaSyntax, keyword, d.oid)
+		}
+
+		if slices.Contains(flagKeywords, keyword) {
+			d.fields[keyword] = []string{}
+			continue
+		}
+
+		if i >= len(tokens) {
+			return nil, fmt.Errorf("%w: missing value for %q in %q", ErrSchemaSyntax, keyword, d.oid)
+		}
+
+		var values []string
+
+		if tokens[i].is("(") {
+			end := slices.IndexFunc(tokens[i:], func(t token) bool { return t.is(")") })
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated list for %q in %q", ErrSchemaSyntax, keyword, d.oid)
+			}
+
+			for _, t := range tokens[i+1 : i+end] {
+				if !t.is("$") {
+					values = append(values, t.value)
+				}
+			}
+
+			i += end + 1
+		} else {
+			values = append(values, tokens[i].value)
+			i++
+		}
+
+		if !extension {
+			d.fields[keyword] = values
+		}
+	}
+
+	return &d, nil
+}
+
+var attributeTypeKeywords = []string{
+	"NAME", "DESC", "OBSOLETE", "SUP", "EQUALITY", "ORDERING", "SUBSTR", "SYNTAX",
+	"SINGLE-VALUE", "COLLECTIVE", "NO-USER-MODIFICATION", "USAGE",
+}
+
+// parseAttributeType parses an AttributeTypeDescription (RFC 4512, section 4.1.2).
+func parseAttributeType(definition string) (*AttributeType, error) {
+	d, err := parseDescription(definition, attributeTypeKeywords)
+	if err != nil {
+		return nil, err
+	}
+
+	at := AttributeType{
+		OID:                d.oid,
+		Names:              d.fields["NAME"],
+		Desc:               d.value("DESC"),
+		Obsolete:           d.has("OBSOLETE"),
+		Sup:                d.value("SUP"),
+		Equality:           d.value("EQUALITY"),
+		Ordering:           d.value("ORDERING"),
+		Substr:             d.value("SUBSTR"),
+		Syntax:             d.value("SYNTAX"),
+		SingleValue:        d.has("SINGLE-VALUE"),
+		Collective:         d.has("COLLECTIVE"),
+		NoUserModification: d.has("NO-USER-MODIFICATION"),
+		Usage:              d.value("USAGE"),
+	}
+
+	if at.Sup == "" && at.Syntax == "" {
+		return nil, fmt.Errorf("%w: attribute type %q has neither a supertype nor a syntax", ErrSchemaSyntax, at.OID)
+	}
+
+	switch at.Usage {
+	case "", UsageUserApplications, UsageDirectoryOperation, UsageDistributedOperation, UsageDSAOperation:
+	default:
+		return nil, fmt.Errorf("%w: attribute type %q has invalid usage %q", ErrSchemaSyntax, at.OID, at.Usage)
+	}
+
+	return &at, nil
+}
+
+var objectClassKeywords = []string{
+	"NAME", "DESC", "OBSOLETE", "SUP", KindAbstract, KindStructural, KindAuxiliary, "MUST", "MAY",
+}
+
+// parseObjectClass parses an ObjectClassDescription (RFC 4512, section 4.1.1).
+func parseObjectClass(definition string) (*ObjectClass, error) {
+	d, err := parseDescription(definition, objectClassKeywords)
+	if err != nil {
+		return nil, err
+	}
+
+	oc := ObjectClass{
+		OID:      d.oid,
+		Names:    d.fields["NAME"],
+		Desc:     d.value("DESC"),
+		Obsolete: d.has("OBSOLETE"),
+		Sup:      d.fields["SUP"],
+		Must:     d.fields["MUST"],
+		May:      d.fields["MAY"],
+	}
+
+	for _, kind := range []string{KindAbstract, KindStructural, KindAuxiliary} {
+		if !d.has(kind) {
+			continue
+		}
+
+		if oc.Kind != "" {
+			return nil, fmt.Errorf("%w: object class %q has multiple kinds", ErrSchemaSyntax, oc.OID)
+		}
+
+		oc.Kind = kind
+	}
+
+	// Object classes are structural unless stated otherwise.
+	if oc.Kind == "" {
+		oc.Kind = KindStructural
+	}
+
+	return &oc, nil
+}
+
+// optional returns a list with value, or an empty list if value is empty.
+func optional(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return []string{value}
+}
+
+func writeFlag(sb *strings.Builder, keyword string, set bool) {
+	if set {
+		sb.WriteString(" " + keyword)
+	}
+}
+
+func writeQDString(sb *strings.Builder, keyword, value string) {
+	if value != "" {
+		sb.WriteString(" " + keyword + " '" + escapeQDString(value) + "'")
+	}
+}
+
+func writeQDescrs(sb *strings.Builder, keyword string, values []string) {
+	switch len(values) {
+	case 0:
+	case 1:
+		sb.WriteString(" " + keyword + " '" + values[0] + "'")
+	default:
+		sb.WriteString(" " + keyword + " ( '" + strings.Join(values, "' '") + "' )")
+	}
+}
+
+func writeOIDs(sb *strings.Builder, keyword string, values []string) {
+	switch len(values) {
+	case 0:
+	case 1:
+		sb.WriteString(" " + keyword + " " + values[0])
+	default:
+		sb.WriteString(" " + keyword + " ( " + strings.Join(values, " $ ") + " )")
+	}
+}
@@ -1,15 +1,21 @@
 package bottin
 
 import (
+	"strings"
+	"time"
+
 	flag "github.com/spf13/pflag"
 
 	"github.com/teapotovh/teapot/service/bottin/store"
 )
 
 var (
-	DefaultAddr   = "0.0.0.0:1389"
-	DefaultBaseDN = "dc=teapot,dc=ovh"
-	DefaultACL    = []string{
+	DefaultAddr      = "0.0.0.0:1389"
+	DefaultBaseDN    = "dc=teapot,dc=ovh"
+	DefaultSizeLimit = 1000
+	DefaultTimeLimit = time.Minute
+	DefaultHashType  = ARGON2
+	DefaultACL       = []string{
 		"ANONYMOUS::bind:*,ou=users,dc=teapot,dc=ovh:",
 		"ANONYMOUS::bind:dc=teapot,dc=ovh:",
 		"*,dc=teapot,dc=ovh::read:*:* !userpassword",
@@ -24,16 +30,45 @@ func BottinFlagSet() (*flag.FlagSet, func() BottinConfig) {
 
 	baseDN := fs.String("bottin-basedn", DefaultBaseDN, "the base DN of the LDAP server")
 	passwd := fs.String("bottin-passwd", "", "the passwd for binding to the root object")
+	passwdScheme := fs.String(
+		"bottin-passwd-scheme",
+		string(DefaultHashType),
+		"the scheme used to hash new passwords, one of {ARGON2}, {CRYPT}, {PBKDF2-SHA512}, {SSHA512} or {SSHA256}",
+	)
 	acl := fs.StringArray("bottin-acl", DefaultACL, "the list of ACL rules to apply for permission checking")
+	schemas := fs.StringArray(
+		"bottin-schema",
+		BuiltinSchemas,
+		"the schemas to load, either builtin ones ("+strings.Join(BuiltinSchemas, ", ")+
+			") or paths to OpenLDAP schema files",
+	)
+	schemaCheck := fs.Bool(
+		"bottin-schema-check",
+		false,
+		"whether to validate entries against the schema on write. "+
+			"Existing entries can be checked with import --dry-run --replace first",
+	)
+	sizeLimit := fs.Int(
+		"bottin-size-limit",
+		DefaultSizeLimit,
+		"the maximum number of entries returned by a search, or by each page of a paged search (0 to disable)",
+	)
+	timeLimit := fs.Duration("bottin-time-limit", DefaultTimeLimit, "the maximum duration of a search (0 to disable)")
 
 	storeFS, getStoreConfig := store.StoreFlagSet()
 	fs.AddFlagSet(storeFS)
 
 	return fs, func() BottinConfig {
 		return BottinConfig{
-			BaseDN: *baseDN,
-			Passwd: *passwd,
-			ACL:    *acl,
+			BaseDN:       *baseDN,
+			Passwd:       *passwd,
+			PasswdScheme: *passwdScheme,
+			ACL:          *acl,
+			Schemas:      *schemas,
+			SchemaCheck:  *schemaCheck,
+
+			SizeLimit: *sizeLimit,
+			TimeLimit: *timeLimit,
 
 			Store: getStoreConfig(),
 		}
@@ -2,27 +2,88 @@ package bottin
 
 import (
 	"crypto/md5" //nolint:gosec
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/jsimonetti/pwscheme/ssha256"
 	"github.com/jsimonetti/pwscheme/ssha512"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type HashType string
 
 var (
-	ErrNoValidHash = errors.New("no valid hash found")
+	ErrNoValidHash         = errors.New("no valid hash found")
+	ErrInvalidHash         = errors.New("invalid hash format")
+	ErrUnsupportedCrypt    = errors.New("unsupported crypt(3) algorithm")
+	ErrUnsupportedHashType = errors.New("unsupported password hash scheme")
+	ErrUnsupportedArgon2   = errors.New("unsupported argon2 variant or version")
+	ErrInvalidArgon2Params = errors.New("invalid argon2 parameters")
+	ErrInvalidPBKDF2Params = errors.New("invalid PBKDF2 parameters")
 )
 
 const (
-	MD5     HashType = "{MD5}"
-	SSHA256 HashType = "{SSHA256}"
-	SSHA512 HashType = "{SSHA512}"
+	MD5          HashType = "{MD5}"
+	SSHA256      HashType = "{SSHA256}"
+	SSHA512      HashType = "{SSHA512}"
+	ARGON2       HashType = "{ARGON2}"
+	CRYPT        HashType = "{CRYPT}"
+	PBKDF2SHA512 HashType = "{PBKDF2-SHA512}"
 )
 
+// HashTypes are the schemes which can be used to hash new passwords.
+var HashTypes = []HashType{ARGON2, CRYPT, PBKDF2SHA512, SSHA512, SSHA256}
+
+// Parameters used when hashing new passwords.
+const (
+	saltLength = 16
+
+	// As recommended by RFC 9106.
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+
+	pbkdf2Iterations = 100000
+	pbkdf2KeyLen     = sha512.Size
+)
+
+// Upper bounds of the parameters of stored hashes, so that matching a password
+// against a hash cannot be made arbitrarily expensive. Hashes may be up to a
+// few times costlier than the ones generated for new passwords.
+const (
+	argon2MaxTime    = 4 * argon2Time
+	argon2MaxMemory  = 4 * argon2Memory
+	argon2MaxThreads = 4 * argon2Threads
+	argon2MaxKeyLen  = 4 * argon2KeyLen
+
+	bcryptMaxCost = bcrypt.DefaultCost + 2
+
+	pbkdf2MaxIterations = 4 * pbkdf2Iterations
+	pbkdf2MaxKeyLen     = 4 * pbkdf2KeyLen
+)
+
+// ParseHashType parses the name of a hashing scheme, with or without braces
+// (i.e., both {ARGON2} and argon2 are accepted).
+func ParseHashType(raw string) (HashType, error) {
+	name := strings.ToUpper(strings.Trim(strings.TrimSpace(raw), "{}"))
+	for _, ht := range HashTypes {
+		if string(ht) == "{"+name+"}" {
+			return ht, nil
+		}
+	}
+
+	return HashType(""), fmt.Errorf("%w: %q", ErrUnsupportedHashType, raw)
+}
+
 func ssha512Encode(passwd string) (string, error) {
 	hash, err := ssha512.Generate(passwd, 16)
 	if err != nil {
@@ -32,6 +93,34 @@ func ssha512Encode(passwd string) (string, error) {
 	return hash, nil
 }
 
+// hashPassword hashes a password with the given scheme.
+func hashPassword(hashType HashType, passwd string) (string, error) {
+	switch hashType {
+	case ARGON2:
+		return argon2Encode(passwd)
+	case CRYPT:
+		hash, err := bcrypt.GenerateFromPassword([]byte(passwd), bcrypt.DefaultCost)
+		if err != nil {
+			return "", fmt.Errorf("error while hashing password with bcrypt: %w", err)
+		}
+
+		return string(CRYPT) + string(hash), nil
+	case PBKDF2SHA512:
+		return pbkdf2Encode(passwd)
+	case SSHA512:
+		return ssha512Encode(passwd)
+	case SSHA256:
+		hash, err := ssha256.Generate(passwd, 16)
+		if err != nil {
+			return "", fmt.Errorf("error while hashing password with ssha256: %w", err)
+		}
+
+		return hash, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedHashType, hashType)
+	}
+}
+
 // Matches matches the encoded password and the raw password.
 func matchPassword(schemaHash string, passwd string) (bool, error) {
 	hashType, hash, err := parseHash(schemaHash)
@@ -50,19 +139,263 @@ func matchPassword(schemaHash string, passwd string) (bool, error) {
 		return ssha256.Validate(passwd, string(SSHA256)+hash)
 	case SSHA512:
 		return ssha512.Validate(passwd, string(SSHA512)+hash)
+	case ARGON2:
+		return argon2Validate(passwd, hash)
+	case CRYPT:
+		return cryptValidate(passwd, hash)
+	case PBKDF2SHA512:
+		return pbkdf2Validate(passwd, hash)
 	}
 
 	return false, nil // unreachable
 }
 
+// needsRehash reports whether a stored hash uses a different scheme than the
+// one new passwords are hashed with.
+func needsRehash(schemaHash string, hashType HashType) bool {
+	current, _, err := parseHash(schemaHash)
+
+	return err == nil && current != hashType
+}
+
+// validateHash checks that a hash uses a known scheme with parameters within
+// bounds, without matching it against any password.
+func validateHash(schemaHash string) error {
+	hashType, hash, err := parseHash(schemaHash)
+	if err != nil {
+		return err
+	}
+
+	switch hashType {
+	case ARGON2:
+		_, err = argon2Parse(hash)
+	case CRYPT:
+		err = cryptParse(hash)
+	case PBKDF2SHA512:
+		_, _, _, err = pbkdf2Parse(hash)
+	}
+
+	return err
+}
+
 func parseHash(hash string) (HashType, string, error) {
-	if strings.HasPrefix(hash, string(MD5)) {
-		return MD5, hash[len(MD5):], nil
-	} else if strings.HasPrefix(hash, string(SSHA256)) {
-		return SSHA256, hash[len(SSHA256):], nil
-	} else if strings.HasPrefix(hash, string(SSHA512)) {
-		return SSHA512, hash[len(SSHA512):], nil
+	for _, ht := range []HashType{MD5, SSHA256, SSHA512, ARGON2, CRYPT, PBKDF2SHA512} {
+		if strings.HasPrefix(hash, string(ht)) {
+			return ht, hash[len(ht):], nil
+		}
+	}
+
+	return HashType(""), "", ErrNoValidHash
+}
+
+func randomSalt() ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("error while generating salt: %w", err)
+	}
+
+	return salt, nil
+}
+
+// argon2Encode hashes a password with argon2id, in the PHC string format used
+// by the OpenLDAP argon2 module:
+//
+//	{ARGON2}$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+func argon2Encode(passwd string) (string, error) {
+	salt, err := randomSalt()
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(passwd), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf(
+		"%s$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		ARGON2,
+		argon2.Version,
+		argon2Memory,
+		argon2Time,
+		argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// argon2Params are the fields of an argon2 hash.
+type argon2Params struct {
+	variant      string
+	memory, time uint32
+	threads      uint8
+	salt, key    []byte
+}
+
+//nolint:gocyclo
+func argon2Parse(hash string) (argon2Params, error) {
+	var params argon2Params
+
+	// The leading $ yields an empty first part
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		return params, fmt.Errorf("%w: expected 5 argon2 fields", ErrInvalidHash)
+	}
+
+	params.variant = parts[1]
+	if params.variant != "argon2id" && params.variant != "argon2i" {
+		return params, fmt.Errorf("%w: %q", ErrUnsupportedArgon2, params.variant)
+	}
+
+	if parts[2] != "v="+strconv.Itoa(argon2.Version) {
+		return params, fmt.Errorf("%w: %q", ErrUnsupportedArgon2, parts[2])
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads)
+	if err != nil {
+		return params, fmt.Errorf("%w: %w", ErrInvalidArgon2Params, err)
+	}
+
+	if params.time == 0 || params.time > argon2MaxTime ||
+		params.memory > argon2MaxMemory ||
+		params.threads == 0 || params.threads > argon2MaxThreads {
+		return params, fmt.Errorf("%w: %q", ErrInvalidArgon2Params, parts[3])
+	}
+
+	params.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, fmt.Errorf("%w: invalid salt: %w", ErrInvalidHash, err)
+	}
+
+	params.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, fmt.Errorf("%w: invalid hash: %w", ErrInvalidHash, err)
+	}
+
+	if len(params.key) == 0 || len(params.key) > argon2MaxKeyLen {
+		return params, fmt.Errorf("%w: invalid hash length %d", ErrInvalidArgon2Params, len(params.key))
+	}
+
+	return params, nil
+}
+
+func argon2Validate(passwd, hash string) (bool, error) {
+	params, err := argon2Parse(hash)
+	if err != nil {
+		return false, err
+	}
+
+	keyLen := uint32(len(params.key)) //nolint:gosec
+
+	var key []byte
+	if params.variant == "argon2id" {
+		key = argon2.IDKey([]byte(passwd), params.salt, params.time, params.memory, params.threads, keyLen)
 	} else {
-		return HashType(""), "", ErrNoValidHash
+		key = argon2.Key([]byte(passwd), params.salt, params.time, params.memory, params.threads, keyLen)
+	}
+
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+// cryptParse checks crypt(3) hashes. Only bcrypt is supported.
+func cryptParse(hash string) error {
+	if !strings.HasPrefix(hash, "$2a$") && !strings.HasPrefix(hash, "$2b$") && !strings.HasPrefix(hash, "$2y$") {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(hash, "$"), "$")
+		return fmt.Errorf("%w: %q", ErrUnsupportedCrypt, prefix)
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidHash, err)
+	}
+
+	if cost > bcryptMaxCost {
+		return fmt.Errorf("%w: bcrypt cost %d exceeds %d", ErrInvalidHash, cost, bcryptMaxCost)
+	}
+
+	return nil
+}
+
+// cryptValidate validates crypt(3) hashes. Only bcrypt is supported.
+func cryptValidate(passwd, hash string) (bool, error) {
+	if err := cryptParse(hash); err != nil {
+		return false, err
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(passwd))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("error while validating bcrypt hash: %w", err)
+	}
+
+	return true, nil
+}
+
+// ab64 is the "adapted base64" encoding used by the OpenLDAP pbkdf2 module,
+// which replaces + with . and omits the padding.
+var ab64 = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789./").
+	WithPadding(base64.NoPadding)
+
+// pbkdf2Encode hashes a password with PBKDF2-SHA512, in the format used by
+// the OpenLDAP pbkdf2 module:
+//
+//	{PBKDF2-SHA512}<iterations>$<salt>$<hash>
+func pbkdf2Encode(passwd string) (string, error) {
+	salt, err := randomSalt()
+	if err != nil {
+		return "", err
+	}
+
+	key, err := pbkdf2.Key(sha512.New, passwd, salt, pbkdf2Iterations, pbkdf2KeyLen)
+	if err != nil {
+		return "", fmt.Errorf("error while hashing password with pbkdf2: %w", err)
 	}
+
+	return fmt.Sprintf(
+		"%s%d$%s$%s",
+		PBKDF2SHA512,
+		pbkdf2Iterations,
+		ab64.EncodeToString(salt),
+		ab64.EncodeToString(key),
+	), nil
+}
+
+func pbkdf2Parse(hash string) (iterations int, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 3 {
+		return 0, nil, nil, fmt.Errorf("%w: expected 3 PBKDF2 fields", ErrInvalidHash)
+	}
+
+	iterations, err = strconv.Atoi(parts[0])
+	if err != nil || iterations <= 0 || iterations > pbkdf2MaxIterations {
+		return 0, nil, nil, fmt.Errorf("%w: invalid iterations %q", ErrInvalidPBKDF2Params, parts[0])
+	}
+
+	salt, err = ab64.DecodeString(parts[1])
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("%w: invalid salt: %w", ErrInvalidHash, err)
+	}
+
+	key, err = ab64.DecodeString(parts[2])
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("%w: invalid hash: %w", ErrInvalidHash, err)
+	}
+
+	if len(key) == 0 || len(key) > pbkdf2MaxKeyLen {
+		return 0, nil, nil, fmt.Errorf("%w: invalid hash length %d", ErrInvalidPBKDF2Params, len(key))
+	}
+
+	return iterations, salt, key, nil
+}
+
+func pbkdf2Validate(passwd, hash string) (bool, error) {
+	iterations, salt, expected, err := pbkdf2Parse(hash)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := pbkdf2.Key(sha512.New, passwd, salt, iterations, len(expected))
+	if err != nil {
+		return false, fmt.Errorf("error while validating pbkdf2 hash: %w", err)
+	}
+
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
 }
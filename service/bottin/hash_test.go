@@ -0,0 +1,140 @@
+package bottin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	t.Parallel()
+
+	for _, hashType := range HashTypes {
+		t.Run(string(hashType), func(t *testing.T) {
+			t.Parallel()
+
+			hash, err := hashPassword(hashType, "correct horse")
+			if err != nil {
+				t.Fatalf("error hashing password: %s", err)
+			}
+
+			if current, _, err := parseHash(hash); err != nil || current != hashType {
+				t.Fatalf("hash %q has scheme %q (err %v), expected %q", hash, current, err, hashType)
+			}
+
+			for passwd, expected := range map[string]bool{"correct horse": true, "battery staple": false} {
+				// Some schemes report mismatches as errors, so these are only
+				// fatal for the right password.
+				matched, err := matchPassword(hash, passwd)
+				if err != nil && expected {
+					t.Fatalf("error matching %q: %s", passwd, err)
+				}
+
+				if matched != expected {
+					t.Errorf("matching %q: GOT %t EXPECTED %t", passwd, matched, expected)
+				}
+			}
+
+			if needsRehash(hash, hashType) {
+				t.Errorf("hash %q should not need rehashing", hash)
+			}
+		})
+	}
+}
+
+func TestMatchPassword(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		hash    string
+		passwd  string
+		matched bool
+		err     error
+	}{
+		{name: "md5", hash: "{MD5}Xr4ilOzQ4PCOq3aQ0qbuaQ==", passwd: "secret", matched: true},
+		{name: "md5 mismatch", hash: "{MD5}Xr4ilOzQ4PCOq3aQ0qbuaQ==", passwd: "Secret"},
+		{name: "no scheme", hash: "secret", passwd: "secret", err: ErrNoValidHash},
+		{name: "argon2 fields", hash: "{ARGON2}$argon2id$v=19$m=65536,t=3,p=4$c2FsdA", err: ErrInvalidHash},
+		{
+			name: "argon2 variant",
+			hash: "{ARGON2}$argon2d$v=19$m=65536,t=3,p=4$c2FsdA$aGFzaA",
+			err:  ErrUnsupportedArgon2,
+		},
+		{
+			name: "argon2 version",
+			hash: "{ARGON2}$argon2id$v=16$m=65536,t=3,p=4$c2FsdA$aGFzaA",
+			err:  ErrUnsupportedArgon2,
+		},
+		{
+			name: "argon2 params",
+			hash: "{ARGON2}$argon2id$v=19$m=65536,t=0,p=4$c2FsdA$aGFzaA",
+			err:  ErrInvalidArgon2Params,
+		},
+		{name: "crypt md5", hash: "{CRYPT}$1$salt$hash", err: ErrUnsupportedCrypt},
+		{name: "pbkdf2 fields", hash: "{PBKDF2-SHA512}10000$c2FsdA", err: ErrInvalidHash},
+		{name: "pbkdf2 iterations", hash: "{PBKDF2-SHA512}0$c2FsdA$aGFzaA", err: ErrInvalidPBKDF2Params},
+		{
+			name: "argon2 memory cap",
+			hash: "{ARGON2}$argon2id$v=19$m=4194304,t=3,p=4$c2FsdA$aGFzaA",
+			err:  ErrInvalidArgon2Params,
+		},
+		{
+			name: "argon2 time cap",
+			hash: "{ARGON2}$argon2id$v=19$m=65536,t=1000,p=4$c2FsdA$aGFzaA",
+			err:  ErrInvalidArgon2Params,
+		},
+		{
+			name: "argon2 empty hash",
+			hash: "{ARGON2}$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$",
+			err:  ErrInvalidArgon2Params,
+		},
+		{
+			name: "crypt cost cap",
+			hash: "{CRYPT}$2a$31$aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+			err:  ErrInvalidHash,
+		},
+		{
+			name: "pbkdf2 iterations cap",
+			hash: "{PBKDF2-SHA512}100000000$c2FsdA$aGFzaA",
+			err:  ErrInvalidPBKDF2Params,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			matched, err := matchPassword(test.hash, test.passwd)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("GOT error %v EXPECTED %v", err, test.err)
+			}
+
+			if matched != test.matched {
+				t.Errorf("GOT %t EXPECTED %t", matched, test.matched)
+			}
+		})
+	}
+}
+
+func TestParseHashType(t *testing.T) {
+	t.Parallel()
+
+	for raw, expected := range map[string]HashType{
+		"argon2":          ARGON2,
+		"{CRYPT}":         CRYPT,
+		" pbkdf2-sha512 ": PBKDF2SHA512,
+		"ssha512":         SSHA512,
+	} {
+		hashType, err := ParseHashType(raw)
+		if err != nil || hashType != expected {
+			t.Errorf("parsing %q: GOT %q (err %v) EXPECTED %q", raw, hashType, err, expected)
+		}
+	}
+
+	// MD5 is only accepted when validating existing hashes.
+	for _, raw := range []string{"md5", "sha1", ""} {
+		if _, err := ParseHashType(raw); !errors.Is(err, ErrUnsupportedHashType) {
+			t.Errorf("parsing %q: GOT error %v EXPECTED %v", raw, err, ErrUnsupportedHashType)
+		}
+	}
+}
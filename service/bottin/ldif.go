@@ -0,0 +1,279 @@
+package bottin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/teapotovh/teapot/service/bottin/ldif"
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+var (
+	ErrImportDuplicate = errors.New("entry is defined more than once")
+	ErrImportNoParent  = errors.New("parent entry does not exist")
+)
+
+type ImportConfig struct {
+	// BaseDN is the base DN of the directory, all imported entries must be below it.
+	BaseDN string
+	// DryRun validates the LDIF without writing any entry to the store.
+	DryRun bool
+	// Replace overwrites entries already in the store, instead of failing.
+	Replace bool
+	// Schema validates the imported entries, unless it is nil.
+	Schema *Schema
+}
+
+// Export writes all entries below baseDN to w as LDIF, including all
+// operational attributes. Parents are always written before their children,
+// so that the output can be imported again. It returns the number of
+// exported entries.
+func Export(ctx context.Context, s store.Store, baseDN string, w io.Writer) (int, error) {
+	dn, err := store.ParseDN(baseDN)
+	if err != nil {
+		return 0, fmt.Errorf("error while parsing baseDN: %w", err)
+	}
+
+	entries, err := s.List(ctx, dn.Prefix(), false)
+	if err != nil {
+		return 0, fmt.Errorf("error while listing entries: %w", err)
+	}
+
+	// The prefix of a parent is a prefix of its children's, so sorting by
+	// prefix guarantees that parents come first
+	slices.SortFunc(entries, func(a, b store.Entry) int {
+		return strings.Compare(a.DN.Prefix().String(), b.DN.Prefix().String())
+	})
+
+	writer := ldif.NewWriter(w)
+	for _, entry := range entries {
+		if err := writer.Write(entry); err != nil {
+			return 0, fmt.Errorf("error while writing entry %q: %w", entry.DN, err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return 0, fmt.Errorf("error while flushing LDIF: %w", err)
+	}
+
+	return len(entries), nil
+}
+
+// Import reads entries from r as LDIF and stores them in a single
+// transaction. Operational attributes are preserved when present, and
+// generated otherwise. The memberOf attribute of imported entries is
+// updated with the groups that list them as members. Entries are streamed
+// to the store as they are read, so that the LDIF is never fully loaded in
+// memory. It returns the number of imported entries.
+func Import(ctx context.Context, s store.Store, r io.Reader, config ImportConfig, logger *slog.Logger) (int, error) {
+	baseDN, err := store.ParseDN(config.BaseDN)
+	if err != nil {
+		return 0, fmt.Errorf("error while parsing baseDN: %w", err)
+	}
+
+	imp := importer{
+		store:    s,
+		baseDN:   baseDN,
+		schema:   config.Schema,
+		replace:  config.Replace,
+		imported: map[string]bool{},
+		groupsOf: map[string][]string{},
+	}
+
+	if !config.DryRun {
+		imp.tx, err = s.Begin(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("error while beginning transaction: %w", err)
+		}
+
+		// Rolling back a committed transaction is a no-op
+		defer func() { _ = imp.tx.Rollback(ctx) }()
+	}
+
+	reader := ldif.NewReader(r)
+
+	count := 0
+
+	for {
+		entry, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return 0, fmt.Errorf("error while reading LDIF: %w", err)
+		}
+
+		if err := imp.importEntry(ctx, entry); err != nil {
+			return 0, err
+		}
+
+		count++
+	}
+
+	if config.DryRun {
+		logger.InfoContext(ctx, "dry run, not storing entries", "count", count)
+		return count, nil
+	}
+
+	if err := imp.tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	return count, nil
+}
+
+// importer holds the state of an LDIF import. Only the DNs of the imported
+// entries are kept, along with the groups of the members which have not
+// been read yet.
+type importer struct {
+	store   store.Store
+	tx      store.Transaction
+	baseDN  store.DN
+	schema  *Schema
+	replace bool
+
+	imported map[string]bool
+	// groupsOf maps the DN of a member not imported yet to the DNs of the
+	// imported groups listing it
+	groupsOf map[string][]string
+}
+
+// importEntry validates an entry and stores it in the transaction, if any.
+func (imp *importer) importEntry(ctx context.Context, entry store.Entry) error {
+	if err := imp.validate(ctx, entry); err != nil {
+		return err
+	}
+
+	dn := entry.DN.String()
+
+	// Groups read before this entry were waiting for it to be imported
+	for _, group := range imp.groupsOf[dn] {
+		if !slices.Contains(entry.Attributes.Get(AttrMemberOf), group) {
+			entry.Attributes[AttrMemberOf] = append(entry.Attributes[AttrMemberOf], group)
+		}
+	}
+
+	delete(imp.groupsOf, dn)
+
+	if imp.schema != nil {
+		if err := imp.schema.Check(entry.Attributes); err != nil {
+			return fmt.Errorf("could not import %q: %w", entry.DN, err)
+		}
+	}
+
+	imp.imported[dn] = true
+
+	if imp.tx != nil {
+		if err := imp.tx.Store(ctx, entry); err != nil {
+			return fmt.Errorf("error while storing entry %q: %w", entry.DN, err)
+		}
+	}
+
+	// Members read before this entry have already been stored, so their
+	// memberOf attribute is updated in place
+	for _, member := range entry.Attributes.Get(AttrMember) {
+		if !imp.imported[member] {
+			imp.groupsOf[member] = append(imp.groupsOf[member], dn)
+			continue
+		}
+
+		if imp.tx == nil {
+			continue
+		}
+
+		memberDN, _ := store.ParseDN(member)
+		if err := imp.tx.AddValue(ctx, memberDN, AttrMemberOf, dn); err != nil {
+			return fmt.Errorf("error while updating memberOf of %q: %w", member, err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks that an entry can be imported, and fills in its
+// operational attributes.
+func (imp *importer) validate(ctx context.Context, entry store.Entry) error {
+	dn := entry.DN
+	if !imp.baseDN.Prefix().IsPrefixOf(dn.Prefix()) {
+		return fmt.Errorf("could not import %q (base %q): %w", dn, imp.baseDN, ErrDNNotPrefix)
+	}
+
+	if imp.imported[dn.String()] {
+		return fmt.Errorf("could not import %q: %w", dn, ErrImportDuplicate)
+	}
+
+	existing, err := imp.store.List(ctx, dn.Prefix(), true)
+	if err != nil {
+		return fmt.Errorf("error while fetching entry %q from store: %w", dn, err)
+	}
+
+	if len(existing) > 0 && !imp.replace {
+		return fmt.Errorf("could not import %q: %w", dn, ErrAlreadyExists)
+	}
+
+	if !dn.Equal(imp.baseDN) && !imp.imported[dn.Parent().String()] {
+		parent := dn.Parent()
+
+		parents, err := imp.store.List(ctx, parent.Prefix(), true)
+		if err != nil {
+			return fmt.Errorf("error while fetching entry %q from store: %w", parent, err)
+		}
+
+		if len(parents) < 1 {
+			return fmt.Errorf("could not import %q: %w", dn, ErrImportNoParent)
+		}
+	}
+
+	if err := fillOperationalAttributes(entry.Attributes, imp.baseDN); err != nil {
+		return fmt.Errorf("could not import %q: %w", dn, err)
+	}
+
+	// Use the canonical DN syntax for members and groups, as done by Add, so
+	// that they can be compared as strings
+	for _, key := range []store.AttributeKey{AttrMember, AttrMemberOf} {
+		values := entry.Attributes.Get(key)
+		for i, value := range values {
+			valueDN, err := store.ParseDN(value)
+			if err != nil {
+				return fmt.Errorf("could not import %q, invalid %s %q: %w", dn, key, value, err)
+			}
+
+			values[i] = valueDN.String()
+		}
+	}
+
+	return nil
+}
+
+// fillOperationalAttributes sets the system attributes which are missing
+// from an imported entry, as Add would.
+func fillOperationalAttributes(attrs store.Attributes, baseDN store.DN) error {
+	if len(attrs.Get(AttrObjectClass)) == 0 {
+		attrs[AttrObjectClass] = store.AttributeValue{"top"}
+	}
+
+	if len(attrs.Get(AttrEntryUUID)) == 0 {
+		uuid, err := uuid.NewRandom()
+		if err != nil {
+			return fmt.Errorf("error while generating random uuid: %w", err)
+		}
+
+		attrs[AttrEntryUUID] = store.AttributeValue{uuid.String()}
+	}
+
+	if len(attrs.Get(AttrCreatorsName)) == 0 {
+		attrs[AttrCreatorsName] = store.AttributeValue{baseDN.String()}
+	}
+
+	if len(attrs.Get(AttrCreateTimestamp)) == 0 {
+		attrs[AttrCreateTimestamp] = store.AttributeValue{genTimestamp()}
+	}
+
+	return nil
+}
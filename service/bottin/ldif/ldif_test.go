@@ -0,0 +1,140 @@
+package ldif
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+func readAll(t *testing.T, input string) ([]store.Entry, error) {
+	t.Helper()
+
+	var entries []store.Entry
+
+	reader := NewReader(strings.NewReader(input))
+
+	for {
+		entry, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		} else if err != nil {
+			return entries, err
+		}
+
+		entries = append(entries, entry)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dn, err := store.ParseDN("cn=Ünïcode user,ou=users,dc=teapot,dc=ovh")
+	if err != nil {
+		t.Fatalf("error parsing DN: %s", err)
+	}
+
+	entries := []store.Entry{
+		store.NewEntry(dn, store.Attributes{
+			"objectclass": {"top", "inetOrgPerson"},
+			"description": {
+				" leading space",
+				"trailing space ",
+				":colon",
+				"<angle",
+				"multi\nline",
+				"",
+				strings.Repeat("a very long value, ", 20),
+			},
+			"jpegphoto": {"\x00\xff\xd8binary"},
+		}),
+		store.NewEntry(dn.Parent(), store.Attributes{"objectclass": {"organizationalUnit"}}),
+	}
+
+	var sb strings.Builder
+
+	writer := NewWriter(&sb)
+	for _, entry := range entries {
+		if err := writer.Write(entry); err != nil {
+			t.Fatalf("error writing %q: %s", entry.DN, err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("error flushing: %s", err)
+	}
+
+	for line := range strings.SplitSeq(sb.String(), "\n") {
+		if len(line) > lineLength {
+			t.Errorf("line is not folded: %q", line)
+		}
+	}
+
+	read, err := readAll(t, sb.String())
+	if err != nil {
+		t.Fatalf("error reading back LDIF: %s\n%s", err, sb.String())
+	}
+
+	if len(read) != len(entries) {
+		t.Fatalf("GOT %d entries EXPECTED %d", len(read), len(entries))
+	}
+
+	for i, entry := range entries {
+		if !read[i].DN.Equal(entry.DN) {
+			t.Errorf("GOT dn %q EXPECTED %q", read[i].DN, entry.DN)
+		}
+
+		if !maps.EqualFunc(read[i].Attributes, entry.Attributes, slices.Equal) {
+			t.Errorf("GOT %q EXPECTED %q", read[i].Attributes, entry.Attributes)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		count int
+		err   error
+	}{
+		{
+			name: "comments and folding",
+			input: "version: 1\n\n# a comment\ndn: uid=alice,dc=teapot,dc=ovh\n" +
+				"cn: Ali\n ce\nmail:: YWxpY2VAdGVhcG90Lm92aA==\n\n\ndn: uid=bob,dc=teapot,dc=ovh\n",
+			count: 2,
+		},
+		{
+			name:  "changetype add",
+			input: "dn: uid=alice,dc=teapot,dc=ovh\nchangetype: add\ncn: Alice\n",
+			count: 1,
+		},
+		{name: "version", input: "version: 2\n\ndn: dc=teapot,dc=ovh\n", err: ErrUnsupportedVersion},
+		{name: "missing dn", input: "cn: Alice\n", err: ErrMissingDN},
+		{name: "continuation", input: " cn: Alice\n", err: ErrUnexpectedContinue},
+		{name: "invalid line", input: "dn: dc=teapot,dc=ovh\ncn\n", err: ErrInvalidLine},
+		{name: "url", input: "dn: dc=teapot,dc=ovh\njpegPhoto:< file:///photo.jpg\n", err: ErrUnsupportedURL},
+		{name: "control", input: "dn: dc=teapot,dc=ovh\ncontrol: 1.2.3\n", err: ErrUnsupportedControl},
+		{name: "modify", input: "dn: dc=teapot,dc=ovh\nchangetype: modify\n", err: ErrUnsupportedChangeType},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			entries, err := readAll(t, test.input)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("GOT error %v EXPECTED %v", err, test.err)
+			}
+
+			if len(entries) != test.count {
+				t.Errorf("GOT %d entries EXPECTED %d", len(entries), test.count)
+			}
+		})
+	}
+}
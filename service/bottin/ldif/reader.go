@@ -0,0 +1,237 @@
+package ldif
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+var (
+	ErrInvalidLine           = errors.New("invalid line, expected attribute: value")
+	ErrUnexpectedContinue    = errors.New("continuation line without a preceding line")
+	ErrMissingDN             = errors.New("record does not start with a dn")
+	ErrUnsupportedVersion    = errors.New("unsupported LDIF version")
+	ErrUnsupportedChangeType = errors.New("unsupported changetype, only add is supported")
+	ErrUnsupportedURL        = errors.New("values referenced by URL are not supported")
+	ErrUnsupportedControl    = errors.New("controls are not supported")
+)
+
+// Reader reads entries from an LDIF stream, as defined in RFC 2849.
+//
+// Only content records and change records with changetype add are
+// supported, as both describe complete entries.
+type Reader struct {
+	r *bufio.Reader
+
+	// The line number of the last line read, used for error messages
+	line int
+	// A physical line that has been read but not consumed yet
+	peeked *string
+	// Whether the first record has been read, as only it may be preceded by a version
+	started bool
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// readLine reads a physical line, without the line terminator.
+func (r *Reader) readLine() (string, error) {
+	if r.peeked != nil {
+		line := *r.peeked
+		r.peeked = nil
+		r.line++
+
+		return line, nil
+	}
+
+	line, err := r.r.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+
+	r.line++
+
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// readLogical reads a logical line, joining folded lines. Empty lines are
+// returned as-is, as they separate records.
+func (r *Reader) readLogical() (string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(line, " ") {
+		return "", fmt.Errorf("line %d: %w", r.line, ErrUnexpectedContinue)
+	}
+
+	if line == "" {
+		return line, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(line)
+
+	for {
+		next, err := r.readLine()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+
+		if !strings.HasPrefix(next, " ") {
+			r.peeked = &next
+			r.line--
+
+			break
+		}
+
+		sb.WriteString(next[1:])
+	}
+
+	return sb.String(), nil
+}
+
+// readRecord reads the lines of the next record, skipping comments.
+func (r *Reader) readRecord() ([]string, int, error) {
+	var (
+		lines []string
+		start int
+	)
+
+	for {
+		line, err := r.readLogical()
+		if errors.Is(err, io.EOF) {
+			if len(lines) > 0 {
+				return lines, start, nil
+			}
+
+			return nil, 0, io.EOF
+		} else if err != nil {
+			return nil, 0, err
+		}
+
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if line == "" {
+			if len(lines) > 0 {
+				return lines, start, nil
+			}
+
+			continue
+		}
+
+		if len(lines) == 0 {
+			start = r.line
+		}
+
+		lines = append(lines, line)
+	}
+}
+
+// parseLine parses an attrval-spec into its attribute description and value.
+func parseLine(line string) (string, string, error) {
+	attr, value, found := strings.Cut(line, ":")
+	if !found || attr == "" {
+		return "", "", ErrInvalidLine
+	}
+
+	switch {
+	case strings.HasPrefix(value, ":"):
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+		if err != nil {
+			return "", "", fmt.Errorf("invalid base64 value for %q: %w", attr, err)
+		}
+
+		return attr, string(decoded), nil
+	case strings.HasPrefix(value, "<"):
+		return "", "", ErrUnsupportedURL
+	default:
+		return attr, strings.TrimLeft(value, " "), nil
+	}
+}
+
+// Read reads the next entry from the stream. It returns io.EOF when there
+// are no more entries.
+//
+//nolint:gocyclo
+func (r *Reader) Read() (store.Entry, error) {
+	lines, start, err := r.readRecord()
+	if err != nil {
+		return store.Entry{}, err
+	}
+
+	wrap := func(err error) error {
+		return fmt.Errorf("record at line %d: %w", start, err)
+	}
+
+	if !r.started {
+		r.started = true
+
+		attr, value, err := parseLine(lines[0])
+		if err != nil {
+			return store.Entry{}, wrap(err)
+		}
+
+		if strings.EqualFold(attr, "version") {
+			if strings.TrimSpace(value) != "1" {
+				return store.Entry{}, wrap(fmt.Errorf("%w: %q", ErrUnsupportedVersion, value))
+			}
+
+			lines = lines[1:]
+			// The version may be followed by an empty line
+			if len(lines) == 0 {
+				return r.Read()
+			}
+		}
+	}
+
+	attr, value, err := parseLine(lines[0])
+	if err != nil {
+		return store.Entry{}, wrap(err)
+	}
+
+	if !strings.EqualFold(attr, "dn") {
+		return store.Entry{}, wrap(ErrMissingDN)
+	}
+
+	dn, err := store.ParseDN(value)
+	if err != nil {
+		return store.Entry{}, wrap(fmt.Errorf("invalid dn %q: %w", value, err))
+	}
+
+	attrs := make(store.Attributes)
+
+	for _, line := range lines[1:] {
+		attr, value, err := parseLine(line)
+		if err != nil {
+			return store.Entry{}, wrap(err)
+		}
+
+		switch strings.ToLower(attr) {
+		case "control":
+			return store.Entry{}, wrap(ErrUnsupportedControl)
+		case "changetype":
+			if !strings.EqualFold(strings.TrimSpace(value), "add") {
+				return store.Entry{}, wrap(fmt.Errorf("%w: %q", ErrUnsupportedChangeType, value))
+			}
+
+			continue
+		}
+
+		key := store.NewAttributeKey(attr)
+		attrs[key] = append(attrs[key], value)
+	}
+
+	return store.NewEntry(dn, attrs), nil
+}
@@ -0,0 +1,132 @@
+package ldif
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+// The maximum length of a line before it gets folded. RFC 2849 recommends
+// not exceeding 76 characters.
+const lineLength = 76
+
+const attrObjectClass store.AttributeKey = "objectclass"
+
+// Writer writes entries to an LDIF stream, as defined in RFC 2849.
+type Writer struct {
+	w *bufio.Writer
+
+	// Whether the version line and the first record have been written
+	started bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// isSafe checks whether a value can be written as-is (i.e., a SAFE-STRING),
+// or needs to be base64-encoded instead. Values ending with a space are also
+// encoded, as the trailing space would be lost by many tools.
+func isSafe(value string) bool {
+	if value == "" {
+		return true
+	}
+
+	switch value[0] {
+	case ' ', ':', '<':
+		return false
+	}
+
+	if value[len(value)-1] == ' ' {
+		return false
+	}
+
+	for i := range len(value) {
+		c := value[i]
+		if c == 0 || c == '\n' || c == '\r' || c >= 0x80 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeLine writes a line, folding it if it is too long.
+func (w *Writer) writeLine(line string) error {
+	n := min(len(line), lineLength)
+	if _, err := w.w.WriteString(line[:n] + "\n"); err != nil {
+		return err
+	}
+
+	// Continuation lines start with a space, so they carry one character less
+	for line = line[n:]; len(line) > 0; line = line[n:] {
+		n = min(len(line), lineLength-1)
+		if _, err := w.w.WriteString(" " + line[:n] + "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) writeAttribute(attr, value string) error {
+	if isSafe(value) {
+		return w.writeLine(attr + ": " + value)
+	}
+
+	return w.writeLine(attr + ":: " + base64.StdEncoding.EncodeToString([]byte(value)))
+}
+
+// Write writes an entry to the stream. The objectclass attribute is written
+// first, followed by all other attributes in alphabetical order.
+func (w *Writer) Write(entry store.Entry) error {
+	if !w.started {
+		w.started = true
+
+		if _, err := w.w.WriteString("version: 1\n"); err != nil {
+			return fmt.Errorf("error while writing version: %w", err)
+		}
+	}
+
+	if _, err := w.w.WriteString("\n"); err != nil {
+		return fmt.Errorf("error while writing record separator: %w", err)
+	}
+
+	if err := w.writeAttribute("dn", entry.DN.String()); err != nil {
+		return fmt.Errorf("error while writing dn: %w", err)
+	}
+
+	keys := slices.SortedFunc(maps.Keys(entry.Attributes), func(a, b store.AttributeKey) int {
+		switch {
+		case a == b:
+			return 0
+		case a == attrObjectClass:
+			return -1
+		case b == attrObjectClass:
+			return 1
+		default:
+			return strings.Compare(string(a), string(b))
+		}
+	})
+
+	for _, key := range keys {
+		for _, value := range entry.Attributes[key] {
+			if err := w.writeAttribute(string(key), value); err != nil {
+				return fmt.Errorf("error while writing attribute %q: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,187 @@
+package bottin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+const testLDIF = `version: 1
+
+dn: ou=groups,dc=teapot,dc=ovh
+objectClass: organizationalUnit
+ou: groups
+
+dn: cn=admins,ou=groups,dc=teapot,dc=ovh
+objectClass: groupOfNames
+cn: admins
+member: UID=alice,ou=users,dc=teapot,dc=ovh
+
+dn: ou=users,dc=teapot,dc=ovh
+objectClass: organizationalUnit
+ou: users
+
+dn: uid=alice,ou=users,dc=teapot,dc=ovh
+objectClass: inetOrgPerson
+cn: Alice
+sn: Liddell
+uid: alice
+
+dn: uid=bob,ou=users,dc=teapot,dc=ovh
+objectClass: inetOrgPerson
+cn: Bob
+sn: Builder
+uid: bob
+
+dn: cn=users,ou=groups,dc=teapot,dc=ovh
+objectClass: groupOfNames
+cn: users
+member: uid=alice,ou=users,dc=teapot,dc=ovh
+member: uid=bob,ou=users,dc=teapot,dc=ovh
+`
+
+func testBaseEntry(t *testing.T) store.Entry {
+	t.Helper()
+
+	return testEntry(t, testBaseDN, map[string][]string{"objectClass": {"organization", "dcObject"}, "o": {"teapot"}})
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := newTestBottin(t, testBaseEntry(t))
+
+	schema, err := LoadSchema(BuiltinSchemas)
+	if err != nil {
+		t.Fatalf("error loading schema: %s", err)
+	}
+
+	config := ImportConfig{BaseDN: testBaseDN, Schema: schema}
+
+	count, err := Import(ctx, server.store, strings.NewReader(testLDIF), config, slog.New(slog.DiscardHandler))
+	if err != nil || count != 6 {
+		t.Fatalf("GOT %d entries (err %v) EXPECTED 6", count, err)
+	}
+
+	// Groups are linked both when they precede and when they follow members
+	memberOf := map[string][]string{
+		"uid=alice,ou=users,dc=teapot,dc=ovh": {
+			"cn=admins,ou=groups,dc=teapot,dc=ovh",
+			"cn=users,ou=groups,dc=teapot,dc=ovh",
+		},
+		"uid=bob,ou=users,dc=teapot,dc=ovh": {"cn=users,ou=groups,dc=teapot,dc=ovh"},
+	}
+	for dn, expected := range memberOf {
+		entries, err := server.store.List(ctx, mustParseDN(t, dn).Prefix(), true)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("error getting %q: %v", dn, err)
+		}
+
+		if got := entries[0].Get(AttrMemberOf); !slices.Equal(got, expected) {
+			t.Errorf("memberOf of %q: GOT %v EXPECTED %v", dn, got, expected)
+		}
+	}
+
+	// Exporting and importing again in a new directory yields the same entries
+	var sb strings.Builder
+	if _, err := Export(ctx, server.store, testBaseDN, &sb); err != nil {
+		t.Fatalf("error exporting: %s", err)
+	}
+
+	other := newTestBottin(t)
+	_, err = Import(ctx, other.store, strings.NewReader(sb.String()), config, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("error importing export: %s", err)
+	}
+
+	for dn := range memberOf {
+		entries, _ := server.store.List(ctx, mustParseDN(t, dn).Prefix(), true)
+		imported, _ := other.store.List(ctx, mustParseDN(t, dn).Prefix(), true)
+
+		if len(imported) != 1 || !slices.Equal(imported[0].Get(AttrEntryUUID), entries[0].Get(AttrEntryUUID)) {
+			t.Errorf("entry %q was not preserved by the round-trip", dn)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	t.Parallel()
+
+	schema, err := LoadSchema(BuiltinSchemas)
+	if err != nil {
+		t.Fatalf("error loading schema: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		ldif string
+		err  error
+	}{
+		{
+			name: "outside base",
+			ldif: "dn: ou=users,dc=example,dc=com\nobjectClass: organizationalUnit\n",
+			err:  ErrDNNotPrefix,
+		},
+		{
+			name: "duplicate",
+			ldif: "dn: ou=users,dc=teapot,dc=ovh\nobjectClass: organizationalUnit\n\n" +
+				"dn: ou=users,dc=teapot,dc=ovh\nobjectClass: organizationalUnit\n",
+			err: ErrImportDuplicate,
+		},
+		{
+			name: "no parent",
+			ldif: "dn: uid=alice,ou=users,dc=teapot,dc=ovh\nobjectClass: account\nuid: alice\n",
+			err:  ErrImportNoParent,
+		},
+		{
+			name: "schema",
+			ldif: "dn: uid=alice,dc=teapot,dc=ovh\nobjectClass: inetOrgPerson\ncn: Alice\nuid: alice\n",
+			err:  ErrMissingAttribute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			server := newTestBottin(t, testBaseEntry(t))
+			config := ImportConfig{BaseDN: testBaseDN, Schema: schema}
+
+			_, err := Import(ctx, server.store, strings.NewReader(test.ldif), config, slog.New(slog.DiscardHandler))
+			if !errors.Is(err, test.err) {
+				t.Fatalf("GOT error %v EXPECTED %v", err, test.err)
+			}
+
+			// Failed imports leave the store untouched
+			entries, err := server.store.List(ctx, mustParseDN(t, testBaseDN).Prefix(), false)
+			if err != nil || len(entries) != 1 {
+				t.Errorf("GOT %d entries (err %v) EXPECTED 1", len(entries), err)
+			}
+		})
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := newTestBottin(t, testBaseEntry(t))
+	config := ImportConfig{BaseDN: testBaseDN, DryRun: true}
+
+	count, err := Import(ctx, server.store, strings.NewReader(testLDIF), config, slog.New(slog.DiscardHandler))
+	if err != nil || count != 6 {
+		t.Fatalf("GOT %d entries (err %v) EXPECTED 6", count, err)
+	}
+
+	entries, err := server.store.List(ctx, mustParseDN(t, testBaseDN).Prefix(), false)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("GOT %d entries (err %v) EXPECTED 1", len(entries), err)
+	}
+}
@@ -0,0 +1,60 @@
+package bottin
+
+import (
+	"errors"
+	"time"
+
+	"github.com/teapotovh/teapot/lib/ldapsrv"
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+)
+
+// minLimit returns the strictest of two limits, where zero means no limit.
+func minLimit[T int | time.Duration](a, b T) T {
+	switch {
+	case a == 0:
+		return b
+	case b == 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
+
+// timeLimit returns the time a search may take, combining the limit requested
+// by the client with the one configured on the server. Zero means no limit.
+func (server *Bottin) timeLimit(r ldap.SearchRequest) time.Duration {
+	return minLimit(time.Duration(r.TimeLimit().Int())*time.Second, server.maxTimeLimit)
+}
+
+// sizeLimit returns the number of entries a search may return, combining the
+// limit requested by the client with the one configured on the server. Zero
+// means no limit.
+//
+// The server limit does not apply to paged searches as a whole, as they are
+// meant to retrieve large result sets. It is enforced on each page instead,
+// see pageSize.
+func (server *Bottin) sizeLimit(r ldap.SearchRequest, paged bool) int {
+	if paged {
+		return r.SizeLimit().Int()
+	}
+
+	return minLimit(r.SizeLimit().Int(), server.maxSizeLimit)
+}
+
+// pageSize returns the size of the pages of a paged search, capping the size
+// requested by the client to the size limit configured on the server.
+func (server *Bottin) pageSize(paged *ldap.PagedResultsControl) int {
+	size := paged.Size().Int()
+	if size == 0 {
+		return 0
+	}
+
+	return minLimit(size, server.maxSizeLimit)
+}
+
+// isLimitExceeded reports whether err signals that a search was interrupted
+// by either its size or time limit, in which case the results collected so
+// far are still returned to the client.
+func isLimitExceeded(err error) bool {
+	return errors.Is(err, ldapsrv.ErrSizeLimitExceeded) || errors.Is(err, ldapsrv.ErrTimeLimitExceeded)
+}
@@ -0,0 +1,522 @@
+package bottin
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+var (
+	ErrUnknownMatchingRule = errors.New("unknown matching rule")
+	ErrInvalidTime         = errors.New("invalid generalized time")
+)
+
+// MatchingRule describes how the values of an attribute are compared against
+// assertion values, following the equality, ordering and substrings matching
+// rules from RFC 4517.
+type MatchingRule struct {
+	Name string
+	OID  string
+	// Syntax is the OID of the syntax of the assertion values.
+	Syntax string
+
+	// compare returns -1, 0 or +1 when value is respectively less than, equal
+	// to or greater than assertion. It returns false if either of the two
+	// cannot be interpreted in the rule's syntax, which must be considered an
+	// Undefined match.
+	compare func(value, assertion string) (int, bool)
+	// normalize prepares a value for substring and approximate matching.
+	// It is nil for rules that do not support substring matching.
+	normalize func(value string) string
+	// match overrides the equality defined via compare, and is used for rules
+	// that do not define an ordering (i.e., bitwise matching rules).
+	match func(value, assertion string) bool
+
+	// ordering and substrings mark ordering and substrings rules, which
+	// evaluate differently when used in extensible match filters.
+	ordering   bool
+	substrings bool
+}
+
+// String formats the rule as a MatchingRuleDescription.
+func (rule *MatchingRule) String() string {
+	return "( " + rule.OID + " NAME '" + rule.Name + "' SYNTAX " + rule.Syntax + " )"
+}
+
+// Equal reports whether value matches assertion according to the rule.
+func (rule *MatchingRule) Equal(value, assertion string) bool {
+	if rule.match != nil {
+		return rule.match(value, assertion)
+	}
+
+	res, ok := rule.compare(value, assertion)
+
+	return ok && res == 0
+}
+
+// GreaterOrEqual reports whether value is greater or equal than assertion.
+func (rule *MatchingRule) GreaterOrEqual(value, assertion string) bool {
+	if rule.compare == nil {
+		return false
+	}
+
+	res, ok := rule.compare(value, assertion)
+
+	return ok && res >= 0
+}
+
+// LessOrEqual reports whether value is less or equal than assertion.
+func (rule *MatchingRule) LessOrEqual(value, assertion string) bool {
+	if rule.compare == nil {
+		return false
+	}
+
+	res, ok := rule.compare(value, assertion)
+
+	return ok && res <= 0
+}
+
+// Substrings reports whether value matches the substring assertion made of
+// an optional initial, any number of middle parts and an optional final part.
+func (rule *MatchingRule) Substrings(value string, initial *string, any []string, final *string) bool {
+	if rule.normalize == nil {
+		return false
+	}
+
+	value = rule.normalize(value)
+
+	if initial != nil {
+		prefix := rule.normalize(*initial)
+		if !strings.HasPrefix(value, prefix) {
+			return false
+		}
+
+		value = value[len(prefix):]
+	}
+
+	if final != nil {
+		suffix := rule.normalize(*final)
+		if !strings.HasSuffix(value, suffix) {
+			return false
+		}
+
+		value = value[:len(value)-len(suffix)]
+	}
+
+	for _, part := range any {
+		part = rule.normalize(part)
+
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+
+		value = value[i+len(part):]
+	}
+
+	return true
+}
+
+// Approx reports whether value approximately matches assertion. We do not
+// implement any phonetic algorithm, instead we compare the normalized values
+// ignoring all whitespace and punctuation, falling back to equality for
+// rules that cannot be normalized.
+func (rule *MatchingRule) Approx(value, assertion string) bool {
+	if rule.normalize == nil {
+		return rule.Equal(value, assertion)
+	}
+
+	strip := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) || unicode.IsPunct(r) {
+				return -1
+			}
+
+			return r
+		}, rule.normalize(s))
+	}
+
+	return strip(value) == strip(assertion)
+}
+
+// Extensible evaluates the rule as requested via an extensible match filter.
+// As per RFC 4517, ordering rules match values strictly less than the
+// assertion, while substrings rules take an assertion in the same
+// initial*any*final syntax used by string filters.
+func (rule *MatchingRule) Extensible(value, assertion string) bool {
+	switch {
+	case rule.ordering:
+		res, ok := rule.compare(value, assertion)
+		return ok && res < 0
+	case rule.substrings:
+		parts := strings.Split(assertion, "*")
+		if len(parts) < 2 {
+			return false
+		}
+
+		var initial, final *string
+		if parts[0] != "" {
+			initial = &parts[0]
+		}
+
+		if last := parts[len(parts)-1]; last != "" {
+			final = &last
+		}
+
+		return rule.Substrings(value, initial, parts[1:len(parts)-1], final)
+	default:
+		return rule.Equal(value, assertion)
+	}
+}
+
+// prepareString implements a simplified version of the string preparation
+// algorithm from RFC 4518: leading and trailing spaces are removed and inner
+// sequences of spaces are collapsed into a single one.
+func prepareString(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func caseIgnoreNormalize(value string) string {
+	return strings.ToLower(prepareString(value))
+}
+
+func compareStrings(normalize func(string) string) func(string, string) (int, bool) {
+	return func(value, assertion string) (int, bool) {
+		return strings.Compare(normalize(value), normalize(assertion)), true
+	}
+}
+
+func compareIntegers(value, assertion string) (int, bool) {
+	v, ok := new(big.Int).SetString(strings.TrimSpace(value), 10)
+	if !ok {
+		return 0, false
+	}
+
+	a, ok := new(big.Int).SetString(strings.TrimSpace(assertion), 10)
+	if !ok {
+		return 0, false
+	}
+
+	return v.Cmp(a), true
+}
+
+func compareGeneralizedTimes(value, assertion string) (int, bool) {
+	v, err := parseGeneralizedTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	a, err := parseGeneralizedTime(assertion)
+	if err != nil {
+		return 0, false
+	}
+
+	return v.Compare(a), true
+}
+
+func compareDNs(value, assertion string) (int, bool) {
+	v, err := store.ParseDN(value)
+	if err != nil {
+		return 0, false
+	}
+
+	a, err := store.ParseDN(assertion)
+	if err != nil {
+		return 0, false
+	}
+
+	return strings.Compare(v.String(), a.String()), true
+}
+
+func bitwise(and bool) func(string, string) bool {
+	return func(value, assertion string) bool {
+		v, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return false
+		}
+
+		a, err := strconv.ParseUint(strings.TrimSpace(assertion), 10, 64)
+		if err != nil {
+			return false
+		}
+
+		if and {
+			return v&a == a
+		}
+
+		return v&a != 0
+	}
+}
+
+// parseGeneralizedTime parses a GeneralizedTime as defined in RFC 4517,
+// section 3.3.13. Minutes, seconds and fractions are optional, and the time
+// zone is either Z or a numeric offset.
+//
+//nolint:gocyclo
+func parseGeneralizedTime(raw string) (time.Time, error) {
+	s := strings.TrimSpace(raw)
+	invalid := fmt.Errorf("could not parse %q: %w", raw, ErrInvalidTime)
+
+	digits := func(n int) (int, bool) {
+		if len(s) < n {
+			return 0, false
+		}
+
+		v, err := strconv.Atoi(s[:n])
+		if err != nil || strings.ContainsAny(s[:n], "+-") {
+			return 0, false
+		}
+
+		s = s[n:]
+
+		return v, true
+	}
+
+	year, ok := digits(4)
+	if !ok {
+		return time.Time{}, invalid
+	}
+
+	month, ok := digits(2)
+	if !ok {
+		return time.Time{}, invalid
+	}
+
+	day, ok := digits(2)
+	if !ok {
+		return time.Time{}, invalid
+	}
+
+	hour, ok := digits(2)
+	if !ok {
+		return time.Time{}, invalid
+	}
+
+	var (
+		minute, second int
+		fraction       time.Duration
+		// unit is the duration represented by the last component that was
+		// parsed, and is used to compute the value of fractions.
+		unit = time.Hour
+	)
+
+	if m, ok := digits(2); ok {
+		minute, unit = m, time.Minute
+
+		if sec, ok := digits(2); ok {
+			second, unit = sec, time.Second
+		}
+	}
+
+	if len(s) > 0 && (s[0] == '.' || s[0] == ',') {
+		s = s[1:]
+
+		n := 0
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+
+		if n == 0 {
+			return time.Time{}, invalid
+		}
+
+		frac, err := strconv.ParseFloat("0."+s[:n], 64)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+
+		fraction = time.Duration(frac * float64(unit))
+		s = s[n:]
+	}
+
+	var loc *time.Location
+
+	switch {
+	case s == "Z":
+		loc = time.UTC
+	case len(s) == 3 || len(s) == 5:
+		sign := 1
+
+		switch s[0] {
+		case '+':
+		case '-':
+			sign = -1
+		default:
+			return time.Time{}, invalid
+		}
+
+		s = s[1:]
+
+		offHour, ok := digits(2)
+		if !ok {
+			return time.Time{}, invalid
+		}
+
+		offMinute := 0
+		if len(s) > 0 {
+			if offMinute, ok = digits(2); !ok {
+				return time.Time{}, invalid
+			}
+		}
+
+		loc = time.FixedZone("", sign*(offHour*3600+offMinute*60))
+	default:
+		return time.Time{}, invalid
+	}
+
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 60 {
+		return time.Time{}, invalid
+	}
+
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+
+	return t.Add(fraction), nil
+}
+
+var (
+	CaseIgnoreMatch = &MatchingRule{
+		Name:      "caseIgnoreMatch",
+		OID:       "2.5.13.2",
+		Syntax:    syntaxPrefix + "15",
+		compare:   compareStrings(caseIgnoreNormalize),
+		normalize: caseIgnoreNormalize,
+	}
+	CaseExactMatch = &MatchingRule{
+		Name:      "caseExactMatch",
+		OID:       "2.5.13.5",
+		Syntax:    syntaxPrefix + "15",
+		compare:   compareStrings(prepareString),
+		normalize: prepareString,
+	}
+	OctetStringMatch = &MatchingRule{
+		Name:    "octetStringMatch",
+		OID:     "2.5.13.17",
+		Syntax:  syntaxPrefix + "40",
+		compare: func(value, assertion string) (int, bool) { return strings.Compare(value, assertion), true },
+	}
+	IntegerMatch = &MatchingRule{
+		Name:    "integerMatch",
+		OID:     "2.5.13.14",
+		Syntax:  syntaxPrefix + "27",
+		compare: compareIntegers,
+	}
+	GeneralizedTimeMatch = &MatchingRule{
+		Name:    "generalizedTimeMatch",
+		OID:     "2.5.13.27",
+		Syntax:  syntaxPrefix + "24",
+		compare: compareGeneralizedTimes,
+	}
+	DistinguishedNameMatch = &MatchingRule{
+		Name:    "distinguishedNameMatch",
+		OID:     "2.5.13.1",
+		Syntax:  syntaxPrefix + "12",
+		compare: compareDNs,
+	}
+	BitAndMatch = &MatchingRule{
+		Name:   "bitAndMatch",
+		OID:    "1.2.840.113556.1.4.803",
+		Syntax: syntaxPrefix + "27",
+		match:  bitwise(true),
+	}
+	BitOrMatch = &MatchingRule{
+		Name:   "bitOrMatch",
+		OID:    "1.2.840.113556.1.4.804",
+		Syntax: syntaxPrefix + "27",
+		match:  bitwise(false),
+	}
+
+	// MatchingRules lists all matching rules that can be requested by
+	// clients via extensible match filters.
+	MatchingRules = []*MatchingRule{
+		CaseIgnoreMatch,
+		orderingOf(CaseIgnoreMatch, "caseIgnoreOrderingMatch", "2.5.13.3"),
+		substringsOf(CaseIgnoreMatch, "caseIgnoreSubstringsMatch", "2.5.13.4"),
+		CaseExactMatch,
+		orderingOf(CaseExactMatch, "caseExactOrderingMatch", "2.5.13.6"),
+		substringsOf(CaseExactMatch, "caseExactSubstringsMatch", "2.5.13.7"),
+		OctetStringMatch,
+		orderingOf(OctetStringMatch, "octetStringOrderingMatch", "2.5.13.18"),
+		IntegerMatch,
+		orderingOf(IntegerMatch, "integerOrderingMatch", "2.5.13.15"),
+		GeneralizedTimeMatch,
+		orderingOf(GeneralizedTimeMatch, "generalizedTimeOrderingMatch", "2.5.13.28"),
+		DistinguishedNameMatch,
+		BitAndMatch,
+		BitOrMatch,
+	}
+
+	// attributeMatchingRules maps attributes to their equality matching rule.
+	// Attributes not listed here are treated as directory strings and use
+	// caseIgnoreMatch.
+	attributeMatchingRules = map[store.AttributeKey]*MatchingRule{
+		AttrUserPassword: OctetStringMatch,
+
+		AttrCreateTimestamp: GeneralizedTimeMatch,
+		AttrModifyTimestamp: GeneralizedTimeMatch,
+
+		AttrMember:          DistinguishedNameMatch,
+		AttrMemberOf:        DistinguishedNameMatch,
+		AttrCreatorsName:    DistinguishedNameMatch,
+		AttrModifiersName:   DistinguishedNameMatch,
+		"uniquemember":      DistinguishedNameMatch,
+		"owner":             DistinguishedNameMatch,
+		"manager":           DistinguishedNameMatch,
+		"seealso":           DistinguishedNameMatch,
+		"secretary":         DistinguishedNameMatch,
+		"roleoccupant":      DistinguishedNameMatch,
+		"distinguishedname": DistinguishedNameMatch,
+
+		"uidnumber":        IntegerMatch,
+		"gidnumber":        IntegerMatch,
+		"shadowlastchange": IntegerMatch,
+		"shadowmin":        IntegerMatch,
+		"shadowmax":        IntegerMatch,
+		"shadowwarning":    IntegerMatch,
+		"shadowinactive":   IntegerMatch,
+		"shadowexpire":     IntegerMatch,
+		"shadowflag":       IntegerMatch,
+	}
+)
+
+// orderingOf derives an ordering rule from an equality rule.
+func orderingOf(rule *MatchingRule, name, oid string) *MatchingRule {
+	return &MatchingRule{Name: name, OID: oid, Syntax: rule.Syntax, compare: rule.compare, ordering: true}
+}
+
+// substringsOf derives a substrings rule from an equality rule.
+func substringsOf(rule *MatchingRule, name, oid string) *MatchingRule {
+	return &MatchingRule{
+		Name:       name,
+		OID:        oid,
+		Syntax:     syntaxPrefix + "58",
+		compare:    rule.compare,
+		normalize:  rule.normalize,
+		substrings: true,
+	}
+}
+
+// matchingRuleFor returns the equality matching rule for an attribute.
+func matchingRuleFor(attr store.AttributeKey) *MatchingRule {
+	if rule, ok := attributeMatchingRules[store.NewAttributeKey(string(attr))]; ok {
+		return rule
+	}
+
+	return CaseIgnoreMatch
+}
+
+// findMatchingRule looks up a matching rule by either its name or OID.
+func findMatchingRule(id string) (*MatchingRule, error) {
+	for _, rule := range MatchingRules {
+		if rule.OID == id || strings.EqualFold(rule.Name, id) {
+			return rule, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not find matching rule %q: %w", id, ErrUnknownMatchingRule)
+}
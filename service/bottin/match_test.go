@@ -0,0 +1,79 @@
+package bottin
+
+import (
+	"testing"
+)
+
+func TestMatchFilter(t *testing.T) {
+	t.Parallel()
+
+	entry := testEntry(t, "uid=alice,ou=users,dc=teapot,dc=ovh", map[string][]string{
+		"objectClass":     {"inetOrgPerson", "posixAccount"},
+		"cn":              {"Alice  Liddell"},
+		"mail":            {"alice@teapot.ovh"},
+		"uidNumber":       {"1000"},
+		"memberOf":        {"cn=Admins,ou=groups,dc=teapot,dc=ovh"},
+		"createTimestamp": {"20240101120000Z"},
+	})
+
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{"(objectClass=*)", true},
+		{"(sn=*)", false},
+		{"(uid=ALICE)", true},
+		{"(cn=alice liddell)", true},
+		{"(cn=alice)", false},
+		{"(mail=alice@*)", true},
+		{"(mail=*@teapot.ovh)", true},
+		{"(cn=a*ce*dell)", true},
+		{"(cn=*bob*)", false},
+		{"(uidNumber>=999)", true},
+		{"(uidNumber>=1001)", false},
+		{"(uidNumber<=1000)", true},
+		{"(uidNumber<=0999)", false},
+		{"(createTimestamp>=20231231000000Z)", true},
+		{"(createTimestamp<=20231231000000Z)", false},
+		{"(memberOf=CN=admins, OU=groups, DC=teapot, DC=ovh)", true},
+		{"(cn~=alice-liddell)", true},
+		{"(&(uid=alice)(uidNumber>=1000))", true},
+		{"(&(uid=alice)(uidNumber>=1001))", false},
+		{"(|(uid=bob)(mail=alice@teapot.ovh))", true},
+		{"(|(uid=bob)(uid=carol))", false},
+		{"(!(uid=bob))", true},
+		{"(!(uid=alice))", false},
+		{"(uid:caseExactMatch:=alice)", true},
+		{"(cn:caseExactMatch:=alice liddell)", false},
+		{"(uidNumber:integerOrderingMatch:=1001)", true},
+		{"(uidNumber:1.2.840.113556.1.4.803:=8)", true},
+		{"(uidNumber:1.2.840.113556.1.4.803:=16)", false},
+		{"(uidNumber:1.2.840.113556.1.4.804:=24)", true},
+		{"(:caseIgnoreSubstringsMatch:=*teapot*)", true},
+		{"(ou:dn:=users)", true},
+		{"(ou:=users)", false},
+
+		// Unknown matching rules are Undefined, which is not a match even
+		// when negated, and only overridden by OR with TRUE or AND with FALSE.
+		{"(cn:unknownMatch:=alice)", false},
+		{"(!(cn:unknownMatch:=alice))", false},
+		{"(|(cn:unknownMatch:=alice)(uid=alice))", true},
+		{"(!(&(cn:unknownMatch:=alice)(uid=bob)))", true},
+		{"(!(|(cn:unknownMatch:=alice)(uid=bob)))", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Parallel()
+
+			matched, err := matchFilter(entry, parseFilter(t, test.filter))
+			if err != nil {
+				t.Fatalf("error applying filter: %s", err)
+			}
+
+			if matched != test.expected {
+				t.Errorf("GOT %t, EXPECTED %t", matched, test.expected)
+			}
+		})
+	}
+}
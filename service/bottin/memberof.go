@@ -75,3 +75,21 @@ func (server *Bottin) membershipRemove(
 
 	return nil
 }
+
+// rewriteReferences replaces all DNs in values that appear in renames with
+// their new DN, leaving the others untouched.
+func rewriteReferences(values store.AttributeValue, renames map[string]store.DN) store.AttributeValue {
+	rewritten := make(store.AttributeValue, 0, len(values))
+
+	for _, value := range values {
+		if dn, err := store.ParseDN(value); err == nil {
+			if renamed, ok := renames[dn.String()]; ok {
+				value = renamed.String()
+			}
+		}
+
+		rewritten = append(rewritten, value)
+	}
+
+	return rewritten
+}
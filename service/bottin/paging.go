@@ -0,0 +1,158 @@
+package bottin
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/teapotovh/teapot/lib/ldapsrv"
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+)
+
+var (
+	ErrInvalidCookie   = errors.New("invalid paged results cookie")
+	ErrCookieMismatch  = errors.New("paged results cookie was issued for a different search")
+	ErrCookieGenerator = errors.New("could not generate paged results cookie")
+)
+
+const (
+	// maxPagedSearches is the number of paged searches kept for each
+	// connection. Starting more paged searches drops the oldest ones, whose
+	// cookies become invalid.
+	maxPagedSearches = 8
+	// cookieLength is the number of random bytes in a paged results cookie.
+	cookieLength = 16
+)
+
+// pagedSearch holds the results of a paged search that have not been
+// returned to the client yet.
+type pagedSearch struct {
+	// request identifies the search the results belong to, as all requests
+	// in a paged search must be equal except for the paging control.
+	request string
+	results []ldap.SearchResultEntry
+	// err is returned along with the last page, and reports whether the
+	// search was interrupted by its size or time limit.
+	err error
+}
+
+// pagedSearches tracks the paged searches in progress on a connection.
+// Results are computed on the first request and kept until the client has
+// fetched all of them, so that pages stay consistent even if entries change
+// in the meantime. Cookies are opaque random tokens referencing them.
+type pagedSearches struct {
+	searches map[string]*pagedSearch
+	// cookies holds the cookies of the searches in progress, oldest first.
+	cookies []string
+}
+
+func newPagedSearches() *pagedSearches {
+	return &pagedSearches{searches: map[string]*pagedSearch{}}
+}
+
+func (p *pagedSearches) remove(cookie string) {
+	delete(p.searches, cookie)
+	p.cookies = slices.DeleteFunc(p.cookies, func(c string) bool { return c == cookie })
+}
+
+// start begins a paged search over results, returning the first page and the
+// cookie to fetch the next one. The cookie is empty if there are no more
+// results. The limitErr error, if any, is returned with the last page.
+func (p *pagedSearches) start(
+	request string,
+	results []ldap.SearchResultEntry,
+	size int,
+	limitErr error,
+) ([]ldap.SearchResultEntry, string, error) {
+	if size == 0 {
+		return nil, "", nil
+	}
+
+	if len(results) <= size {
+		return results, "", limitErr
+	}
+
+	raw := make([]byte, cookieLength)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("(%w) %w: %w", ldapsrv.ErrOperationsError, ErrCookieGenerator, err)
+	}
+
+	cookie := string(raw)
+
+	if len(p.cookies) >= maxPagedSearches {
+		p.remove(p.cookies[0])
+	}
+
+	p.searches[cookie] = &pagedSearch{request: request, results: results[size:], err: limitErr}
+	p.cookies = append(p.cookies, cookie)
+
+	return results[:size], cookie, nil
+}
+
+// next returns the next page of the search referenced by cookie. A size of
+// zero abandons the search.
+func (p *pagedSearches) next(request, cookie string, size int) ([]ldap.SearchResultEntry, string, error) {
+	search, ok := p.searches[cookie]
+	if !ok {
+		return nil, "", fmt.Errorf("(%w) %w", ldapsrv.ErrUnwillingToPerform, ErrInvalidCookie)
+	}
+
+	if search.request != request {
+		return nil, "", fmt.Errorf("(%w) %w", ldapsrv.ErrUnwillingToPerform, ErrCookieMismatch)
+	}
+
+	if size == 0 || len(search.results) <= size {
+		p.remove(cookie)
+
+		if size == 0 {
+			return nil, "", nil
+		}
+
+		return search.results, "", search.err
+	}
+
+	page := search.results[:size]
+	search.results = search.results[size:]
+
+	return page, cookie, nil
+}
+
+// remaining returns the number of results left in the search referenced
+// by cookie, to be sent to the client as an estimate of the result set size.
+func (p *pagedSearches) remaining(cookie string) int {
+	if search, ok := p.searches[cookie]; ok {
+		return len(search.results)
+	}
+
+	return 0
+}
+
+// pagedResultsControl returns the simple paged results control (RFC 2696)
+// sent along with the request, if any.
+func pagedResultsControl(ctx context.Context) (*ldap.PagedResultsControl, error) {
+	control, ok := ldapsrv.RequestControl(ctx, ldapsrv.ControlPagedResults)
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	paged, err := control.PagedResultsControl()
+	if err != nil {
+		return nil, fmt.Errorf("(%w) error while parsing paged results control: %w", ldapsrv.ErrProtocolError, err)
+	}
+
+	return paged, nil
+}
+
+// addPagedResults attaches the simple paged results control to the response.
+func addPagedResults(ctx context.Context, size int, cookie string) error {
+	value, err := ldap.NewPagedResultsControl(ldap.INTEGER(size), ldap.OCTETSTRING(cookie)).Encode()
+	if err != nil {
+		return fmt.Errorf("(%w) error while encoding paged results control: %w", ldapsrv.ErrOperationsError, err)
+	}
+
+	ldapsrv.AddResponseControl(ctx, ldap.NewControl(ldapsrv.ControlPagedResults, false, value.Pointer()))
+
+	return nil
+}
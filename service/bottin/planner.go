@@ -0,0 +1,79 @@
+package bottin
+
+import (
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+// planQuery translates a search filter into a store query which can be
+// answered by the store indexes. Parts of the filter that cannot be indexed
+// are translated into store.QueryAll, and the store only narrows the set of
+// candidate entries: the full filter is always applied on the results.
+//
+//nolint:gocyclo
+func planQuery(filter ldap.Filter) store.Query {
+	switch f := filter.(type) {
+	case ldap.FilterAnd:
+		query := make(store.QueryAnd, 0, len(f))
+		for _, sub := range f {
+			query = append(query, planQuery(sub))
+		}
+
+		return query
+	case ldap.FilterOr:
+		query := make(store.QueryOr, 0, len(f))
+		for _, sub := range f {
+			query = append(query, planQuery(sub))
+		}
+
+		return query
+	case ldap.FilterPresent:
+		return store.QueryPresent{Attribute: filterAttributeKey(ldap.AttributeDescription(f))}
+	case ldap.FilterEqualityMatch:
+		attr := filterAttributeKey(f.AttributeDesc())
+		value := string(f.AssertionValue())
+
+		switch matchingRuleFor(attr) {
+		case CaseIgnoreMatch, CaseExactMatch, OctetStringMatch:
+			// Indexes compare values case-insensitively, which yields a superset
+			// of the matches for all string rules.
+		case DistinguishedNameMatch:
+			// DN values are stored in their canonical form, so the assertion
+			// has to be canonicalized as well before looking it up.
+			dn, err := store.ParseDN(value)
+			if err != nil {
+				return store.QueryAll{}
+			}
+
+			value = dn.String()
+		default:
+			return store.QueryAll{}
+		}
+
+		return store.QueryEquality{Attribute: attr, Value: value}
+	case ldap.FilterSubstrings:
+		attr := filterAttributeKey(f.Type_())
+		if matchingRuleFor(attr).normalize == nil {
+			return store.QueryAll{}
+		}
+
+		query := store.QuerySubstrings{Attribute: attr}
+
+		for _, substring := range f.Substrings() {
+			switch s := substring.(type) {
+			case ldap.SubstringInitial:
+				query.Initial = string(s)
+			case ldap.SubstringAny:
+				query.Any = append(query.Any, string(s))
+			case ldap.SubstringFinal:
+				query.Final = string(s)
+			}
+		}
+
+		return query
+	default:
+		// Negations, ordering, approximate and extensible matches cannot
+		// be answered by the indexes.
+		return store.QueryAll{}
+	}
+}
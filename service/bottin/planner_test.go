@@ -0,0 +1,58 @@
+package bottin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+func TestPlanQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		filter   string
+		expected store.Query
+	}{
+		{"(objectClass=*)", store.QueryPresent{Attribute: "objectclass"}},
+		{"(uid=Alice)", store.QueryEquality{Attribute: "uid", Value: "Alice"}},
+		{"(cn;lang-en=Alice)", store.QueryEquality{Attribute: "cn", Value: "Alice"}},
+		{
+			"(member=UID=Alice, OU=users,dc=teapot,dc=ovh)",
+			store.QueryEquality{Attribute: "member", Value: "uid=alice,ou=users,dc=teapot,dc=ovh"},
+		},
+		{"(member=not a dn)", store.QueryAll{}},
+		{"(uidNumber=1000)", store.QueryAll{}},
+		{
+			"(mail=ali*@*.ovh)",
+			store.QuerySubstrings{Attribute: "mail", Initial: "ali", Any: []string{"@"}, Final: ".ovh"},
+		},
+		{"(uidNumber=10*)", store.QueryAll{}},
+		{"(uidNumber>=1000)", store.QueryAll{}},
+		{"(cn~=alice)", store.QueryAll{}},
+		{"(!(uid=alice))", store.QueryAll{}},
+		{"(uid:caseExactMatch:=alice)", store.QueryAll{}},
+		{
+			"(&(objectClass=person)(!(uid=alice)))",
+			store.QueryAnd{store.QueryEquality{Attribute: "objectclass", Value: "person"}, store.QueryAll{}},
+		},
+		{
+			"(|(uid=alice)(mail=*@teapot.ovh))",
+			store.QueryOr{
+				store.QueryEquality{Attribute: "uid", Value: "alice"},
+				store.QuerySubstrings{Attribute: "mail", Final: "@teapot.ovh"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			t.Parallel()
+
+			query := planQuery(parseFilter(t, test.filter))
+			if !reflect.DeepEqual(query, test.expected) {
+				t.Errorf("GOT %#v, EXPECTED %#v", query, test.expected)
+			}
+		})
+	}
+}
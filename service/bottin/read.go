@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/teapotovh/teapot/lib/ldapsrv"
@@ -13,6 +15,10 @@ import (
 
 var (
 	ErrUnsupportedFilter = errors.New("unsupported filter")
+
+	// errUndefined is returned by applyFilter for filters evaluating to
+	// Undefined, as described in RFC 4511, section 4.5.1.7.
+	errUndefined = errors.New("filter evaluates to undefined")
 )
 
 func (server *Bottin) getEntry(ctx context.Context, dn store.DN) (*store.Entry, error) {
@@ -91,11 +97,134 @@ func (server *Bottin) Compare(ctx context.Context, state State, r ldap.CompareRe
 	return false, state, nil
 }
 
-//nolint:all
+// searchIdentity identifies a search request, so that the requests of
+// a paged search can be checked to be the same search.
+func searchIdentity(r ldap.SearchRequest, sortControl ldap.Control) string {
+	var sortKeys []byte
+	if sortControl.ControlValue() != nil {
+		sortKeys = sortControl.ControlValue().Bytes()
+	}
+
+	return fmt.Sprintf(
+		"%s\x00%d\x00%s\x00%v\x00%t\x00%x",
+		r.BaseObject(),
+		r.Scope(),
+		r.FilterString(),
+		r.Attributes(),
+		r.TypesOnly(),
+		sortKeys,
+	)
+}
+
 func (server *Bottin) Search(ctx context.Context, state State, r ldap.SearchRequest) ([]ldap.SearchResultEntry, State, error) {
+	paged, err := pagedResultsControl(ctx)
+	if err != nil {
+		return nil, state, err
+	}
+
+	sortControl, sorting := ldapsrv.RequestControl(ctx, ldapsrv.ControlServerSideSort)
+
+	var request string
+
+	if paged != nil {
+		if state.paged == nil {
+			state.paged = newPagedSearches()
+		}
+
+		request = searchIdentity(r, sortControl)
+
+		// Subsequent pages are served from the results computed by the
+		// first request, which have already been sorted.
+		if cookie := string(paged.Cookie()); cookie != "" {
+			results, next, err := state.paged.next(request, cookie, server.pageSize(paged))
+			if err != nil && !isLimitExceeded(err) {
+				return nil, state, err
+			}
+
+			if sorting {
+				if err := addSortResult(ctx, ldap.NewSortResult(ldap.ResultCodeSuccess, nil)); err != nil {
+					return nil, state, err
+				}
+			}
+
+			if err := addPagedResults(ctx, state.paged.remaining(next), next); err != nil {
+				return nil, state, err
+			}
+
+			return results, state, err
+		}
+	}
+
+	// The time limit applies to the whole search, including the store query.
+	if limit := server.timeLimit(r); limit > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, limit)
+		defer cancel()
+	}
+
+	var keys []sortKey
+
+	if sorting {
+		var result *ldap.SortResult
+
+		keys, result, err = parseSortKeys(sortControl)
+		if err != nil && result == nil {
+			return nil, state, err
+		}
+
+		if err != nil {
+			if err := addSortResult(ctx, *result); err != nil {
+				return nil, state, err
+			}
+
+			// Non critical sort requests are served unsorted, as per RFC 2891.
+			if sortControl.Criticality().Bool() {
+				return nil, state, fmt.Errorf("(%w) %w", ldapsrv.ErrUnavailableCriticalExtension, err)
+			}
+		} else if err := addSortResult(ctx, ldap.NewSortResult(ldap.ResultCodeSuccess, nil)); err != nil {
+			return nil, state, err
+		}
+	}
+
+	// Searches interrupted by their limits still return the partial results.
+	results, err := server.search(ctx, state, r, keys, server.sizeLimit(r, paged != nil))
+	if paged == nil || (err != nil && !isLimitExceeded(err)) {
+		return results, state, err
+	}
+
+	page, cookie, err := state.paged.start(request, results, server.pageSize(paged), err)
+	if err != nil && !isLimitExceeded(err) {
+		return nil, state, err
+	}
+
+	if err := addPagedResults(ctx, state.paged.remaining(cookie), cookie); err != nil {
+		return nil, state, err
+	}
+
+	return page, state, err
+}
+
+//nolint:all
+func (server *Bottin) search(
+	ctx context.Context,
+	state State,
+	r ldap.SearchRequest,
+	keys []sortKey,
+	limit int,
+) ([]ldap.SearchResultEntry, error) {
+	// The root DSE and the subschema subentry live outside of the base DN,
+	// and are not stored.
+	switch {
+	case r.BaseObject() == "" && r.Scope() == ldap.SearchRequestScopeBaseObject:
+		return searchVirtualEntry(server.rootDSE(ctx), r)
+	case server.schema != nil && isSubschemaDN(string(r.BaseObject())):
+		return searchVirtualEntry(server.subschema, r)
+	}
+
 	baseObject, err := server.parseDN(string(r.BaseObject()), true)
 	if err != nil {
-		return nil, state, fmt.Errorf("(%w) %w", ldapsrv.ErrInvalidDNSyntax, err)
+		return nil, fmt.Errorf("(%w) %w", ldapsrv.ErrInvalidDNSyntax, err)
 	}
 
 	server.logger.InfoContext(
@@ -107,12 +236,14 @@ func (server *Bottin) Search(ctx context.Context, state State, r ldap.SearchRequ
 		r.FilterString(),
 		"attributes",
 		r.Attributes(),
-		"deadline",
-		r.TimeLimit(),
+		"sizelimit",
+		limit,
+		"timelimit",
+		server.timeLimit(r),
 	)
 
 	if !server.acl.Check(state.User(), "read", baseObject, []store.AttributeKey{}) {
-		return nil, state, fmt.Errorf(
+		return nil, fmt.Errorf(
 			"could not read %q: %w",
 			baseObject,
 			ldapsrv.ErrInsufficientAccessRights,
@@ -121,17 +252,27 @@ func (server *Bottin) Search(ctx context.Context, state State, r ldap.SearchRequ
 
 	baseObjectLevel := baseObject.Level()
 	exact := r.Scope() == ldap.SearchRequestScopeBaseObject
-	entries, err := server.store.List(ctx, baseObject.Prefix(), exact)
-	if err != nil {
-		return nil, state, fmt.Errorf("(%w), error while listing objects: %w", ldapsrv.ErrOperationsError, err)
+	// When the time limit is reached while querying the store, the entries
+	// retrieved so far are still filtered and returned.
+	var limitErr error
+	entries, err := server.store.Search(ctx, baseObject.Prefix(), exact, planQuery(r.Filter()))
+	if errors.Is(err, context.DeadlineExceeded) {
+		limitErr = fmt.Errorf("(%w) error while listing objects: %w", ldapsrv.ErrTimeLimitExceeded, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("(%w), error while listing objects: %w", ldapsrv.ErrOperationsError, err)
 	}
 
 	server.logger.DebugContext(ctx, "retrieved entries", "entries", entries, "base", baseObject)
 
-	var results []ldap.SearchResultEntry
+	var selected []store.Entry
 	for _, entry := range entries {
+		if limitErr == nil && ctx.Err() != nil {
+			limitErr = fmt.Errorf("(%w) error while filtering objects: %w", ldapsrv.ErrTimeLimitExceeded, ctx.Err())
+			break
+		}
+
 		if r.Scope() == ldap.SearchRequestScopeBaseObject {
-			if entry.DN.Equal(baseObject) {
+			if !entry.DN.Equal(baseObject) {
 				continue
 			}
 		} else if r.Scope() == ldap.SearchRequestSingleLevel {
@@ -140,12 +281,11 @@ func (server *Bottin) Search(ctx context.Context, state State, r ldap.SearchRequ
 			}
 		}
 		// Filter out if we don't match requested filter
-		matched, err := applyFilter(entry, r.Filter())
+		matched, err := matchFilter(entry, r.Filter())
 		if err != nil {
-			return nil, state, fmt.Errorf(
-				"(%w), error while applying filter %q on %q: %w",
-				ldapsrv.ErrUnwillingToPerform,
-				r.Filter(),
+			return nil, fmt.Errorf(
+				"error while applying filter %q on %q: %w",
+				r.FilterString(),
 				entry.DN.String(),
 				err,
 			)
@@ -159,53 +299,165 @@ func (server *Bottin) Search(ctx context.Context, state State, r ldap.SearchRequ
 			continue
 		}
 
-		e := ldapsrv.NewSearchResultEntry(entry.DN.String())
-		for attr, val := range entry.Attributes {
-			// If attribute is not in request, exclude it from returned entry
-			if len(r.Attributes()) > 0 {
-				found := false
-				for _, requested := range r.Attributes() {
-					if string(requested) == "1.1" && len(r.Attributes()) == 1 {
-						found = false
-						break
-					}
-					if (string(requested) == "*" && !isOperationalAttribute(attr)) ||
-						(string(requested) == "+" && isOperationalAttribute(attr)) ||
-						strings.EqualFold(string(requested), string(attr)) {
-						found = true
-						break
-					}
-				}
-				if !found {
-					continue
-				}
-			}
+		selected = append(selected, entry)
+	}
+
+	server.sortEntries(state.User(), selected, keys)
+
+	// Entries are truncated after sorting, so that the first ones in the
+	// requested order are returned.
+	if limit > 0 && len(selected) > limit {
+		selected = selected[:limit]
+		if limitErr == nil {
+			limitErr = fmt.Errorf("(%w) search returned more than %d entries", ldapsrv.ErrSizeLimitExceeded, limit)
+		}
+	}
 
+	results := make([]ldap.SearchResultEntry, 0, len(selected))
+	for _, entry := range selected {
+		if server.schema != nil {
+			entry.Attributes = maps.Clone(entry.Attributes)
+			entry.Attributes[AttrSubschemaSubentry] = store.AttributeValue{SubschemaDN}
+		}
+
+		results = append(results, searchResultEntry(entry, r.Attributes(), func(attr store.AttributeKey) bool {
 			// If we are not allowed to read attribute, exclude it from returned entry
-			if !server.acl.Check(state.User(), "read", entry.DN, []store.AttributeKey{attr}) {
-				continue
-			}
+			return server.acl.Check(state.User(), "read", entry.DN, []store.AttributeKey{attr})
+		}))
+	}
 
-			// Send result
-			resultVals := []ldap.AttributeValue{}
-			for _, v := range val {
-				resultVals = append(resultVals, ldap.AttributeValue(v))
-			}
-			e.AddAttribute(ldap.AttributeDescription(attr), resultVals...)
+	return results, limitErr
+}
+
+// searchVirtualEntry serves a search for an entry synthesized by the server,
+// such as the root DSE or the subschema subentry. These entries are readable
+// by anyone, and only returned for base object searches.
+func searchVirtualEntry(entry store.Entry, r ldap.SearchRequest) ([]ldap.SearchResultEntry, error) {
+	if r.Scope() != ldap.SearchRequestScopeBaseObject {
+		return nil, nil
+	}
+
+	matched, err := matchFilter(entry, r.Filter())
+	if err != nil {
+		return nil, fmt.Errorf("error while applying filter %q on %q: %w", r.FilterString(), entry.DN.String(), err)
+	}
+
+	if !matched {
+		return nil, nil
+	}
+
+	readable := func(store.AttributeKey) bool { return true }
+
+	return []ldap.SearchResultEntry{searchResultEntry(entry, r.Attributes(), readable)}, nil
+}
+
+// attributeRequested reports whether attr is part of the attributes requested
+// by a search, as described in RFC 4511, section 4.5.1.8.
+func attributeRequested(requested ldap.AttributeSelection, attr store.AttributeKey) bool {
+	if len(requested) == 0 {
+		return true
+	}
+
+	for _, req := range requested {
+		if string(req) == "1.1" && len(requested) == 1 {
+			return false
+		}
+
+		if (string(req) == "*" && !isOperationalAttribute(attr)) ||
+			(string(req) == "+" && isOperationalAttribute(attr)) ||
+			strings.EqualFold(string(req), string(attr)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// searchResultEntry builds the search result for an entry, with the requested
+// attributes that the readable function allows.
+func searchResultEntry(
+	entry store.Entry,
+	requested ldap.AttributeSelection,
+	readable func(attr store.AttributeKey) bool,
+) ldap.SearchResultEntry {
+	e := ldapsrv.NewSearchResultEntry(entry.DN.String())
+
+	for attr, val := range entry.Attributes {
+		// If attribute is not in request, exclude it from returned entry
+		if !attributeRequested(requested, attr) || !readable(attr) {
+			continue
+		}
+
+		resultVals := []ldap.AttributeValue{}
+		for _, v := range val {
+			resultVals = append(resultVals, ldap.AttributeValue(v))
+		}
+
+		e.AddAttribute(ldap.AttributeDescription(attr), resultVals...)
+	}
+
+	return e
+}
+
+// filterAttributeKey converts an attribute description from a filter into
+// an attribute key, dropping any attribute options (i.e., cn;lang-en).
+func filterAttributeKey(desc ldap.AttributeDescription) store.AttributeKey {
+	name, _, _ := strings.Cut(string(desc), ";")
+	return store.NewAttributeKey(name)
+}
+
+// entryValues returns the values for the attribute key with a case insensitive lookup.
+func entryValues(entry store.Entry, key store.AttributeKey) (store.AttributeValue, bool) {
+	if values, ok := entry.Attributes[key]; ok {
+		return values, true
+	}
+
+	for desc, values := range entry.Attributes {
+		if desc.EqualFold(key) {
+			return values, true
 		}
+	}
 
-		results = append(results, e)
+	return nil, false
+}
+
+// anyValue reports whether any of the values of attr in entry satisfies pred.
+func anyValue(entry store.Entry, attr store.AttributeKey, pred func(rule *MatchingRule, value string) bool) bool {
+	values, _ := entryValues(entry, attr)
+	rule := matchingRuleFor(attr)
+
+	return slices.ContainsFunc(values, func(value string) bool {
+		return pred(rule, value)
+	})
+}
+
+// matchFilter reports whether entry matches filter. Entries for which the
+// filter evaluates to Undefined do not match.
+func matchFilter(entry store.Entry, filter ldap.Filter) (bool, error) {
+	matched, err := applyFilter(entry, filter)
+	if errors.Is(err, errUndefined) {
+		return false, nil
 	}
 
-	return results, state, nil
+	return matched, err
 }
 
+// applyFilter evaluates filter on entry, returning errUndefined when the
+// result is Undefined. Undefined is propagated through NOT, and only
+// overridden in AND and OR by a FALSE or TRUE item respectively.
+//
 //nolint:gocyclo
 func applyFilter(entry store.Entry, filter ldap.Filter) (bool, error) {
-	if fAnd, ok := filter.(ldap.FilterAnd); ok {
-		for _, cond := range fAnd {
+	switch f := filter.(type) {
+	case ldap.FilterAnd:
+		var undefined error
+
+		for _, cond := range f {
 			res, err := applyFilter(entry, cond)
-			if err != nil {
+			if errors.Is(err, errUndefined) {
+				undefined = err
+				continue
+			} else if err != nil {
 				return false, err
 			}
 
@@ -214,11 +466,16 @@ func applyFilter(entry store.Entry, filter ldap.Filter) (bool, error) {
 			}
 		}
 
-		return true, nil
-	} else if fOr, ok := filter.(ldap.FilterOr); ok {
-		for _, cond := range fOr {
+		return undefined == nil, undefined
+	case ldap.FilterOr:
+		var undefined error
+
+		for _, cond := range f {
 			res, err := applyFilter(entry, cond)
-			if err != nil {
+			if errors.Is(err, errUndefined) {
+				undefined = err
+				continue
+			} else if err != nil {
 				return false, err
 			}
 
@@ -227,47 +484,129 @@ func applyFilter(entry store.Entry, filter ldap.Filter) (bool, error) {
 			}
 		}
 
-		return false, nil
-	} else if fNot, ok := filter.(ldap.FilterNot); ok {
-		res, err := applyFilter(entry, fNot.Filter)
+		return false, undefined
+	case ldap.FilterNot:
+		res, err := applyFilter(entry, f.Filter)
 		if err != nil {
 			return false, err
 		}
 
 		return !res, nil
-	} else if fPresent, ok := filter.(ldap.FilterPresent); ok {
-		what := string(fPresent)
-		// Case insensitive search
-		for desc, values := range entry.Attributes {
-			if strings.EqualFold(what, string(desc)) {
-				return len(values) > 0, nil
-			}
-		}
-
-		return false, nil
-	} else if fEquality, ok := filter.(ldap.FilterEqualityMatch); ok {
-		desc := string(fEquality.AttributeDesc())
-		target := string(fEquality.AssertionValue())
-		// Case insensitive attribute search
-		for entryDesc, value := range entry.Attributes {
-			if strings.EqualFold(string(entryDesc), desc) {
-				for _, val := range value {
-					if valueMatch(entryDesc, val, target) {
-						return true, nil
-					}
-				}
+	case ldap.FilterPresent:
+		values, _ := entryValues(entry, filterAttributeKey(ldap.AttributeDescription(f)))
+		return len(values) > 0, nil
+	case ldap.FilterEqualityMatch:
+		target := string(f.AssertionValue())
+
+		return anyValue(entry, filterAttributeKey(f.AttributeDesc()), func(rule *MatchingRule, value string) bool {
+			return rule.Equal(value, target)
+		}), nil
+	case ldap.FilterGreaterOrEqual:
+		target := string(f.AssertionValue())
+
+		return anyValue(entry, filterAttributeKey(f.AttributeDesc()), func(rule *MatchingRule, value string) bool {
+			return rule.GreaterOrEqual(value, target)
+		}), nil
+	case ldap.FilterLessOrEqual:
+		target := string(f.AssertionValue())
+
+		return anyValue(entry, filterAttributeKey(f.AttributeDesc()), func(rule *MatchingRule, value string) bool {
+			return rule.LessOrEqual(value, target)
+		}), nil
+	case ldap.FilterApproxMatch:
+		target := string(f.AssertionValue())
+
+		return anyValue(entry, filterAttributeKey(f.AttributeDesc()), func(rule *MatchingRule, value string) bool {
+			return rule.Approx(value, target)
+		}), nil
+	case ldap.FilterSubstrings:
+		var (
+			initial, final *string
+			middle         []string
+		)
 
-				return false, nil
+		for _, substring := range f.Substrings() {
+			switch s := substring.(type) {
+			case ldap.SubstringInitial:
+				str := string(s)
+				initial = &str
+			case ldap.SubstringAny:
+				middle = append(middle, string(s))
+			case ldap.SubstringFinal:
+				str := string(s)
+				final = &str
 			}
 		}
 
-		return false, nil
-	} else {
+		return anyValue(entry, filterAttributeKey(f.Type_()), func(rule *MatchingRule, value string) bool {
+			return rule.Substrings(value, initial, middle, final)
+		}), nil
+	case ldap.FilterExtensibleMatch:
+		return applyExtensibleMatch(entry, f)
+	default:
 		return false, fmt.Errorf(
-			"error while applying filter %#v (of type %T): %w",
+			"(%w) error while applying filter %#v (of type %T): %w",
+			ldapsrv.ErrUnwillingToPerform,
 			filter,
 			filter,
 			ErrUnsupportedFilter,
 		)
 	}
 }
+
+// applyExtensibleMatch evaluates an extensible match filter as described in
+// RFC 4511, section 4.5.1.7.7. When no matching rule is provided, the
+// equality rule of the attribute is used. When no attribute type is provided,
+// the rule is applied to all attributes of the entry. If dnAttributes is set,
+// the components of the entry's DN are considered as well. Unknown matching
+// rules make the filter Undefined.
+func applyExtensibleMatch(entry store.Entry, f ldap.FilterExtensibleMatch) (bool, error) {
+	target := string(f.MatchValue())
+
+	var rule *MatchingRule
+
+	if id := f.MatchingRule(); id != nil {
+		var err error
+
+		rule, err = findMatchingRule(string(*id))
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", errUndefined, err)
+		}
+	}
+
+	var attr *store.AttributeKey
+	if desc := f.Type_(); desc != nil {
+		key := filterAttributeKey(*desc)
+		attr = &key
+	}
+
+	match := func(key store.AttributeKey, value string) bool {
+		if attr != nil && !attr.EqualFold(key) {
+			return false
+		}
+
+		if rule != nil {
+			return rule.Extensible(value, target)
+		}
+
+		return matchingRuleFor(key).Equal(value, target)
+	}
+
+	for key, values := range entry.Attributes {
+		for _, value := range values {
+			if match(key, value) {
+				return true, nil
+			}
+		}
+	}
+
+	if f.DnAttributes() {
+		for _, component := range entry.DN {
+			if match(store.NewAttributeKey(component.Type), component.Value) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,77 @@
+package bottin
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+)
+
+func TestSearchScope(t *testing.T) {
+	t.Parallel()
+
+	server := newTestBottin(t,
+		testEntry(t, "ou=users,dc=teapot,dc=ovh", map[string][]string{"objectclass": {"organizationalUnit"}}),
+		testEntry(t, "uid=alice,ou=users,dc=teapot,dc=ovh", map[string][]string{"objectclass": {"person"}}),
+		testEntry(t, "uid=bob,ou=users,dc=teapot,dc=ovh", map[string][]string{"objectclass": {"person"}}),
+		testEntry(t, "cn=keys,uid=bob,ou=users,dc=teapot,dc=ovh", map[string][]string{"objectclass": {"device"}}),
+	)
+	state := State{user: &User{user: testBaseDN}}
+
+	tests := []struct {
+		name     string
+		base     string
+		scope    int
+		expected []string
+	}{
+		{
+			name:     "base",
+			base:     "uid=bob,ou=users,dc=teapot,dc=ovh",
+			scope:    ldap.SearchRequestScopeBaseObject,
+			expected: []string{"uid=bob,ou=users,dc=teapot,dc=ovh"},
+		},
+		{
+			name:  "one",
+			base:  "ou=users,dc=teapot,dc=ovh",
+			scope: ldap.SearchRequestSingleLevel,
+			expected: []string{
+				"uid=alice,ou=users,dc=teapot,dc=ovh",
+				"uid=bob,ou=users,dc=teapot,dc=ovh",
+			},
+		},
+		{
+			name:  "sub",
+			base:  "uid=bob,ou=users,dc=teapot,dc=ovh",
+			scope: ldap.SearchRequestHomeSubtree,
+			expected: []string{
+				"cn=keys,uid=bob,ou=users,dc=teapot,dc=ovh",
+				"uid=bob,ou=users,dc=teapot,dc=ovh",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := searchRequest(t, test.base, test.scope, "(objectClass=*)")
+
+			results, _, err := server.Search(context.Background(), state, r)
+			if err != nil {
+				t.Fatalf("error searching: %s", err)
+			}
+
+			var dns []string
+			for _, result := range results {
+				dns = append(dns, string(result.ObjectName()))
+			}
+
+			slices.Sort(dns)
+
+			if !slices.Equal(dns, test.expected) {
+				t.Errorf("GOT %v, EXPECTED %v", dns, test.expected)
+			}
+		})
+	}
+}
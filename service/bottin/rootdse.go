@@ -0,0 +1,41 @@
+package bottin
+
+import (
+	"context"
+
+	"github.com/teapotovh/teapot/lib/ldapsrv"
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+// featureAllOperationalAttributes advertises support for requesting all
+// operational attributes with "+" (RFC 3673).
+const featureAllOperationalAttributes = "1.3.6.1.4.1.4203.1.5.1"
+
+// rootDSE builds the root DSE (RFC 4512, section 5.1), through which clients
+// discover the naming context and the capabilities of the server.
+func (server *Bottin) rootDSE(ctx context.Context) store.Entry {
+	attrs := store.Attributes{
+		AttrObjectClass:          store.AttributeValue{"top"},
+		AttrNamingContexts:       store.AttributeValue{server.baseDN.String()},
+		AttrSupportedLDAPVersion: store.AttributeValue{"3"},
+		AttrSupportedFeatures:    store.AttributeValue{featureAllOperationalAttributes},
+		AttrSupportedControl:     oidValues(ldapsrv.SupportedControls(ctx)),
+		AttrSupportedExtension:   oidValues(ldapsrv.SupportedExtensions(ctx)),
+	}
+
+	if server.schema != nil {
+		attrs[AttrSubschemaSubentry] = store.AttributeValue{SubschemaDN}
+	}
+
+	return store.Entry{DN: store.DN{}, Attributes: attrs}
+}
+
+func oidValues(oids []ldap.LDAPOID) store.AttributeValue {
+	values := make(store.AttributeValue, 0, len(oids))
+	for _, oid := range oids {
+		values = append(values, string(oid))
+	}
+
+	return values
+}
@@ -0,0 +1,651 @@
+package bottin
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/teapotovh/teapot/lib/ldapsrv"
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+//go:embed schema/*.schema
+var builtinSchemas embed.FS
+
+var (
+	ErrSchemaSyntax          = errors.New("invalid schema definition")
+	ErrUnknownSchema         = errors.New("unknown schema")
+	ErrDuplicateDefinition   = errors.New("duplicate schema definition")
+	ErrUnknownAttributeType  = errors.New("unknown attribute type")
+	ErrUnknownObjectClass    = errors.New("unknown object class")
+	ErrMissingObjectClass    = errors.New("entry has no object class")
+	ErrNoStructuralClass     = errors.New("entry has no structural object class")
+	ErrMultipleStructural    = errors.New("entry has multiple structural object class chains")
+	ErrMissingAttribute      = errors.New("required attribute is missing")
+	ErrAttributeNotAllowed   = errors.New("attribute is not allowed by the object classes of the entry")
+	ErrSingleValueAttribute  = errors.New("attribute is single-valued")
+	ErrInvalidAttributeValue = errors.New("value does not conform to the attribute syntax")
+)
+
+const (
+	// SubschemaDN is the DN of the subschema subentry, through which clients
+	// can discover the schema (RFC 4512, section 4.2).
+	SubschemaDN = "cn=subschema"
+
+	// systemSchema is always loaded, as it defines the attributes managed by
+	// the server itself.
+	systemSchema = "system"
+
+	oidExtensibleObject = "1.3.6.1.4.1.1466.101.120.111"
+)
+
+// BuiltinSchemas are the schemas shipped with bottin, which can be loaded
+// by name rather than by path.
+var BuiltinSchemas = []string{"core", "cosine", "inetorgperson", "nis", "openssh-lpk", "teapot"}
+
+// Attribute usages, as defined in RFC 4512, section 4.1.2.
+const (
+	UsageUserApplications     = "userApplications"
+	UsageDirectoryOperation   = "directoryOperation"
+	UsageDistributedOperation = "distributedOperation"
+	UsageDSAOperation         = "dSAOperation"
+)
+
+// AttributeType is an attribute type definition, as described in RFC 4512,
+// section 4.1.2.
+type AttributeType struct {
+	OID                string
+	Names              []string
+	Desc               string
+	Obsolete           bool
+	Sup                string
+	Equality           string
+	Ordering           string
+	Substr             string
+	Syntax             string
+	SingleValue        bool
+	Collective         bool
+	NoUserModification bool
+	Usage              string
+
+	sup *AttributeType
+}
+
+// Name returns the primary name of the attribute type, or its OID if unnamed.
+func (at *AttributeType) Name() string {
+	if len(at.Names) > 0 {
+		return at.Names[0]
+	}
+
+	return at.OID
+}
+
+// IsOperational reports whether the attribute is managed by the server.
+func (at *AttributeType) IsOperational() bool {
+	return at.Usage != "" && at.Usage != UsageUserApplications
+}
+
+// syntax returns the syntax OID of the attribute type, which may be
+// inherited from its supertype, without any length bound.
+func (at *AttributeType) syntax() string {
+	for t := at; t != nil; t = t.sup {
+		if t.Syntax != "" {
+			oid, _, _ := strings.Cut(t.Syntax, "{")
+			return oid
+		}
+	}
+
+	return ""
+}
+
+// String formats the attribute type as an AttributeTypeDescription.
+func (at *AttributeType) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("( " + at.OID)
+	writeQDescrs(&sb, "NAME", at.Names)
+	writeQDString(&sb, "DESC", at.Desc)
+	writeFlag(&sb, "OBSOLETE", at.Obsolete)
+	writeOIDs(&sb, "SUP", optional(at.Sup))
+	writeOIDs(&sb, "EQUALITY", optional(at.Equality))
+	writeOIDs(&sb, "ORDERING", optional(at.Ordering))
+	writeOIDs(&sb, "SUBSTR", optional(at.Substr))
+	writeOIDs(&sb, "SYNTAX", optional(at.Syntax))
+	writeFlag(&sb, "SINGLE-VALUE", at.SingleValue)
+	writeFlag(&sb, "COLLECTIVE", at.Collective)
+	writeFlag(&sb, "NO-USER-MODIFICATION", at.NoUserModification)
+	writeOIDs(&sb, "USAGE", optional(at.Usage))
+	sb.WriteString(" )")
+
+	return sb.String()
+}
+
+// Object class kinds, as defined in RFC 4512, section 2.4.
+const (
+	KindAbstract   = "ABSTRACT"
+	KindStructural = "STRUCTURAL"
+	KindAuxiliary  = "AUXILIARY"
+)
+
+// ObjectClass is an object class definition, as described in RFC 4512,
+// section 4.1.1.
+type ObjectClass struct {
+	OID      string
+	Names    []string
+	Desc     string
+	Obsolete bool
+	Sup      []string
+	Kind     string
+	Must     []string
+	May      []string
+
+	sup  []*ObjectClass
+	must []*AttributeType
+	may  []*AttributeType
+}
+
+// Name returns the primary name of the object class, or its OID if unnamed.
+func (oc *ObjectClass) Name() string {
+	if len(oc.Names) > 0 {
+		return oc.Names[0]
+	}
+
+	return oc.OID
+}
+
+// inherits reports whether oc is other or one of its subclasses.
+func (oc *ObjectClass) inherits(other *ObjectClass) bool {
+	if oc == other {
+		return true
+	}
+
+	return slices.ContainsFunc(oc.sup, func(sup *ObjectClass) bool {
+		return sup.inherits(other)
+	})
+}
+
+// String formats the object class as an ObjectClassDescription.
+func (oc *ObjectClass) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("( " + oc.OID)
+	writeQDescrs(&sb, "NAME", oc.Names)
+	writeQDString(&sb, "DESC", oc.Desc)
+	writeFlag(&sb, "OBSOLETE", oc.Obsolete)
+	writeOIDs(&sb, "SUP", oc.Sup)
+	sb.WriteString(" " + oc.Kind)
+	writeOIDs(&sb, "MUST", oc.Must)
+	writeOIDs(&sb, "MAY", oc.May)
+	sb.WriteString(" )")
+
+	return sb.String()
+}
+
+// Schema holds the attribute types and object classes known to the server.
+type Schema struct {
+	attributeTypes []*AttributeType
+	objectClasses  []*ObjectClass
+
+	// Definitions are indexed by their lowercase names and OIDs
+	attributes map[string]*AttributeType
+	classes    map[string]*ObjectClass
+	// macros are the OID macros defined with objectidentifier
+	macros map[string]string
+}
+
+// LoadSchema loads the system schema and all the given schemas, which are
+// either the names of builtin schemas or paths to schema files in the
+// OpenLDAP format. Definitions may reference those of any other schema.
+func LoadSchema(schemas []string) (*Schema, error) {
+	schema := Schema{
+		attributes: map[string]*AttributeType{},
+		classes:    map[string]*ObjectClass{},
+		macros:     map[string]string{},
+	}
+
+	for _, name := range append([]string{systemSchema}, schemas...) {
+		var (
+			content []byte
+			err     error
+		)
+
+		if name == systemSchema || slices.Contains(BuiltinSchemas, name) {
+			content, err = builtinSchemas.ReadFile("schema/" + name + ".schema")
+		} else if strings.ContainsRune(name, os.PathSeparator) || strings.HasSuffix(name, ".schema") {
+			content, err = os.ReadFile(name)
+		} else {
+			err = fmt.Errorf("%w: %q", ErrUnknownSchema, name)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error while reading schema %q: %w", name, err)
+		}
+
+		if err := schema.parse(string(content)); err != nil {
+			return nil, fmt.Errorf("error while parsing schema %q: %w", name, err)
+		}
+	}
+
+	if err := schema.resolve(); err != nil {
+		return nil, fmt.Errorf("error while resolving schema: %w", err)
+	}
+
+	return &schema, nil
+}
+
+// parse parses a schema file in the OpenLDAP format, made of attributetype,
+// objectclass and objectidentifier statements. Statements may span multiple
+// lines, as long as continuation lines start with whitespace.
+func (schema *Schema) parse(content string) error {
+	type statement struct {
+		line int
+		text string
+	}
+
+	var statements []statement
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		switch {
+		case strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#"):
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			if len(statements) == 0 {
+				return fmt.Errorf("line %d: %w: continuation without a statement", i+1, ErrSchemaSyntax)
+			}
+
+			statements[len(statements)-1].text += " " + strings.TrimSpace(line)
+		default:
+			statements = append(statements, statement{line: i + 1, text: line})
+		}
+	}
+
+	for _, stmt := range statements {
+		keyword, definition, _ := strings.Cut(stmt.text, " ")
+
+		var err error
+
+		switch strings.ToLower(keyword) {
+		case "attributetype", "attributetypes:":
+			err = schema.addAttributeType(definition)
+		case "objectclass", "objectclasses:":
+			err = schema.addObjectClass(definition)
+		case "objectidentifier":
+			fields := strings.Fields(definition)
+			if len(fields) != 2 {
+				err = fmt.Errorf("%w: expected a name and an OID", ErrSchemaSyntax)
+			} else {
+				schema.macros[strings.ToLower(fields[0])] = schema.expand(fields[1])
+			}
+		default:
+			err = fmt.Errorf("%w: unknown statement %q", ErrSchemaSyntax, keyword)
+		}
+
+		if err != nil {
+			return fmt.Errorf("line %d: %w", stmt.line, err)
+		}
+	}
+
+	return nil
+}
+
+// expand replaces an OID macro (i.e., name or name:suffix) with its value.
+func (schema *Schema) expand(oid string) string {
+	name, suffix, found := strings.Cut(oid, ":")
+	if value, ok := schema.macros[strings.ToLower(name)]; ok {
+		if found {
+			return value + "." + suffix
+		}
+
+		return value
+	}
+
+	return oid
+}
+
+func (schema *Schema) addAttributeType(definition string) error {
+	at, err := parseAttributeType(definition)
+	if err != nil {
+		return err
+	}
+
+	at.OID = schema.expand(at.OID)
+	at.Syntax = schema.expand(at.Syntax)
+
+	for _, key := range append([]string{at.OID}, at.Names...) {
+		key = strings.ToLower(key)
+		if _, ok := schema.attributes[key]; ok {
+			return fmt.Errorf("%w: attribute type %q", ErrDuplicateDefinition, key)
+		}
+
+		schema.attributes[key] = at
+	}
+
+	schema.attributeTypes = append(schema.attributeTypes, at)
+
+	return nil
+}
+
+func (schema *Schema) addObjectClass(definition string) error {
+	oc, err := parseObjectClass(definition)
+	if err != nil {
+		return err
+	}
+
+	oc.OID = schema.expand(oc.OID)
+
+	for _, key := range append([]string{oc.OID}, oc.Names...) {
+		key = strings.ToLower(key)
+		if _, ok := schema.classes[key]; ok {
+			return fmt.Errorf("%w: object class %q", ErrDuplicateDefinition, key)
+		}
+
+		schema.classes[key] = oc
+	}
+
+	schema.objectClasses = append(schema.objectClasses, oc)
+
+	return nil
+}
+
+// resolve links all definitions to the ones they reference, once all
+// schemas have been loaded.
+func (schema *Schema) resolve() error {
+	for _, at := range schema.attributeTypes {
+		if at.Sup == "" {
+			continue
+		}
+
+		sup, ok := schema.attributes[strings.ToLower(at.Sup)]
+		if !ok {
+			return fmt.Errorf("supertype of %q: %w: %q", at.Name(), ErrUnknownAttributeType, at.Sup)
+		}
+
+		at.sup = sup
+	}
+
+	for _, at := range schema.attributeTypes {
+		seen := map[*AttributeType]bool{}
+		for t := at; t != nil; t = t.sup {
+			if seen[t] {
+				return fmt.Errorf("%w: attribute type %q has a cyclic supertype", ErrSchemaSyntax, at.Name())
+			}
+
+			seen[t] = true
+		}
+	}
+
+	for _, oc := range schema.objectClasses {
+		for _, name := range oc.Sup {
+			sup, ok := schema.classes[strings.ToLower(name)]
+			if !ok {
+				return fmt.Errorf("superclass of %q: %w: %q", oc.Name(), ErrUnknownObjectClass, name)
+			}
+
+			oc.sup = append(oc.sup, sup)
+		}
+	}
+
+	resolving := map[*ObjectClass]bool{}
+
+	var resolveClass func(oc *ObjectClass) error
+
+	resolveClass = func(oc *ObjectClass) error {
+		if oc.must != nil || oc.may != nil {
+			return nil
+		}
+
+		if resolving[oc] {
+			return fmt.Errorf("%w: object class %q has a cyclic superclass", ErrSchemaSyntax, oc.Name())
+		}
+
+		resolving[oc] = true
+		defer delete(resolving, oc)
+
+		// Attributes are inherited from all superclasses
+		must := map[*AttributeType]bool{}
+		may := map[*AttributeType]bool{}
+
+		for _, sup := range oc.sup {
+			if err := resolveClass(sup); err != nil {
+				return err
+			}
+
+			for _, at := range sup.must {
+				must[at] = true
+			}
+
+			for _, at := range sup.may {
+				may[at] = true
+			}
+		}
+
+		if err := schema.lookupAttributes(oc, oc.Must, must); err != nil {
+			return err
+		}
+
+		if err := schema.lookupAttributes(oc, oc.May, may); err != nil {
+			return err
+		}
+
+		oc.must = slices.Collect(maps.Keys(must))
+		oc.may = slices.Collect(maps.Keys(may))
+
+		return nil
+	}
+
+	for _, oc := range schema.objectClasses {
+		if err := resolveClass(oc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lookupAttributes adds the attribute types with the given names, as
+// referenced by oc, to set.
+func (schema *Schema) lookupAttributes(oc *ObjectClass, names []string, set map[*AttributeType]bool) error {
+	for _, name := range names {
+		at, ok := schema.attributes[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("attribute of %q: %w: %q", oc.Name(), ErrUnknownAttributeType, name)
+		}
+
+		set[at] = true
+	}
+
+	return nil
+}
+
+// AttributeType looks up an attribute type by name or OID, ignoring any
+// attribute options (i.e., cn;lang-en).
+func (schema *Schema) AttributeType(name string) (*AttributeType, bool) {
+	name, _, _ = strings.Cut(name, ";")
+	at, ok := schema.attributes[strings.ToLower(name)]
+
+	return at, ok
+}
+
+// ObjectClass looks up an object class by name or OID.
+func (schema *Schema) ObjectClass(name string) (*ObjectClass, bool) {
+	oc, ok := schema.classes[strings.ToLower(name)]
+	return oc, ok
+}
+
+// Check validates the attributes of an entry against the schema: its object
+// classes must be known and form a single structural chain, all attributes
+// required by them must be present, only allowed attributes may be set,
+// single-valued attributes may have one value at most and all values must
+// conform to the syntax of their attribute. Operational attributes are
+// managed by the server, and are thus not checked.
+//
+//nolint:gocyclo
+func (schema *Schema) Check(attrs store.Attributes) error {
+	names := attrs.Get(AttrObjectClass)
+	if len(names) == 0 {
+		return fmt.Errorf("(%w) %w", ldapsrv.ErrObjectClassViolation, ErrMissingObjectClass)
+	}
+
+	var (
+		classes    []*ObjectClass
+		structural *ObjectClass
+		extensible bool
+	)
+
+	for _, name := range names {
+		oc, ok := schema.ObjectClass(name)
+		if !ok {
+			return fmt.Errorf("(%w) %w: %q", ldapsrv.ErrObjectClassViolation, ErrUnknownObjectClass, name)
+		}
+
+		classes = append(classes, oc)
+		extensible = extensible || oc.OID == oidExtensibleObject
+
+		if oc.Kind != KindStructural {
+			continue
+		}
+
+		// All structural classes must belong to the chain of the most specific one
+		switch {
+		case structural == nil || oc.inherits(structural):
+			structural = oc
+		case !structural.inherits(oc):
+			return fmt.Errorf(
+				"(%w) %w: %q and %q",
+				ldapsrv.ErrObjectClassViolation,
+				ErrMultipleStructural,
+				structural.Name(),
+				oc.Name(),
+			)
+		}
+	}
+
+	if structural == nil {
+		return fmt.Errorf("(%w) %w", ldapsrv.ErrObjectClassViolation, ErrNoStructuralClass)
+	}
+
+	must := map[*AttributeType]bool{}
+	allowed := map[*AttributeType]bool{}
+
+	for _, oc := range classes {
+		for _, at := range oc.must {
+			must[at] = true
+			allowed[at] = true
+		}
+
+		for _, at := range oc.may {
+			allowed[at] = true
+		}
+	}
+
+	present := map[*AttributeType]bool{}
+
+	for _, key := range slices.Sorted(maps.Keys(attrs)) {
+		values := attrs[key]
+		if len(values) == 0 {
+			continue
+		}
+
+		at, ok := schema.AttributeType(string(key))
+		if !ok {
+			return fmt.Errorf("(%w) %w: %q", ldapsrv.ErrUndefinedAttributeType, ErrUnknownAttributeType, key)
+		}
+
+		present[at] = true
+
+		if at.IsOperational() {
+			continue
+		}
+
+		if !allowed[at] && !extensible {
+			return fmt.Errorf("(%w) %w: %q", ldapsrv.ErrObjectClassViolation, ErrAttributeNotAllowed, key)
+		}
+
+		if at.SingleValue && len(values) > 1 {
+			return fmt.Errorf("(%w) %w: %q", ldapsrv.ErrConstraintViolation, ErrSingleValueAttribute, key)
+		}
+
+		syntax := findSyntax(at.syntax())
+		for _, value := range values {
+			if !syntax.Validate(value) {
+				return fmt.Errorf(
+					"(%w) %w: %q is not a valid %s",
+					ldapsrv.ErrInvalidAttributeSyntax,
+					ErrInvalidAttributeValue,
+					key,
+					syntax.Desc,
+				)
+			}
+		}
+	}
+
+	for at := range must {
+		if !present[at] {
+			return fmt.Errorf("(%w) %w: %q", ldapsrv.ErrObjectClassViolation, ErrMissingAttribute, at.Name())
+		}
+	}
+
+	return nil
+}
+
+// Subschema returns the subschema subentry, which publishes the schema.
+func (schema *Schema) Subschema(timestamp string) store.Entry {
+	dn, _ := store.ParseDN(SubschemaDN)
+
+	attrs := store.Attributes{
+		AttrObjectClass:     store.AttributeValue{"top", "subschema", "extensibleObject"},
+		"cn":                store.AttributeValue{"subschema"},
+		AttrCreateTimestamp: store.AttributeValue{timestamp},
+		AttrModifyTimestamp: store.AttributeValue{timestamp},
+	}
+
+	for _, at := range schema.attributeTypes {
+		attrs[AttrAttributeTypes] = append(attrs[AttrAttributeTypes], at.String())
+	}
+
+	for _, oc := range schema.objectClasses {
+		attrs[AttrObjectClasses] = append(attrs[AttrObjectClasses], oc.String())
+	}
+
+	for _, syntax := range Syntaxes {
+		attrs[AttrLDAPSyntaxes] = append(attrs[AttrLDAPSyntaxes], syntax.String())
+	}
+
+	for _, rule := range MatchingRules {
+		attrs[AttrMatchingRules] = append(attrs[AttrMatchingRules], rule.String())
+	}
+
+	return store.Entry{DN: dn, Attributes: attrs}
+}
+
+// checkSchema validates an entry about to be written against the schema,
+// unless no schema is configured or schema checking has been disabled.
+func (server *Bottin) checkSchema(entry store.Entry) error {
+	if server.schema == nil || !server.schemaCheck {
+		return nil
+	}
+
+	if err := server.schema.Check(entry.Attributes); err != nil {
+		return fmt.Errorf("entry %q violates the schema: %w", entry.DN, err)
+	}
+
+	return nil
+}
+
+// isSubschemaDN reports whether the raw DN refers to the subschema subentry.
+func isSubschemaDN(rawDN string) bool {
+	dn, err := store.ParseDN(rawDN)
+	if err != nil {
+		return false
+	}
+
+	subschema, _ := store.ParseDN(SubschemaDN)
+
+	return dn.Equal(subschema)
+}
@@ -0,0 +1,215 @@
+package bottin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+func TestLoadSchema(t *testing.T) {
+	t.Parallel()
+
+	schema, err := LoadSchema(BuiltinSchemas)
+	if err != nil {
+		t.Fatalf("error loading builtin schemas: %s", err)
+	}
+
+	// Names are case insensitive, and options are ignored
+	if at, ok := schema.AttributeType("CN;lang-en"); !ok || at.OID != "2.5.4.3" {
+		t.Errorf("GOT %#v", at)
+	}
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("error writing %q: %s", path, err)
+		}
+
+		return path
+	}
+
+	// OID macros are expanded
+	macros, err := LoadSchema([]string{"core", write("macros.schema", "objectidentifier Base 1.2.3\n"+
+		"objectidentifier Attrs Base:1\n"+
+		"attributetype ( Attrs:4 NAME 'foo'\n\tSUP name )")})
+	if err != nil {
+		t.Fatalf("error loading schema with macros: %s", err)
+	}
+
+	if at, ok := macros.AttributeType("foo"); !ok || at.OID != "1.2.3.1.4" {
+		t.Errorf("GOT %#v", at)
+	}
+
+	tests := []struct {
+		name    string
+		schemas []string
+		err     error
+	}{
+		{name: "unknown builtin", schemas: []string{"samba"}, err: ErrUnknownSchema},
+		{
+			name:    "statement",
+			schemas: []string{write("statement.schema", "ditcontentrule ( 1.2.3 )")},
+			err:     ErrSchemaSyntax,
+		},
+		{name: "continuation", schemas: []string{write("continuation.schema", " NAME 'foo' )")}, err: ErrSchemaSyntax},
+		{name: "duplicate", schemas: []string{"core", "core"}, err: ErrDuplicateDefinition},
+		{
+			name:    "unknown superior",
+			schemas: []string{write("sup.schema", "objectclass ( 1.2.3 NAME 'foo' SUP bar AUXILIARY )")},
+			err:     ErrUnknownObjectClass,
+		},
+		{
+			name:    "custom",
+			schemas: []string{"core", write("custom.schema", "attributetype ( 1.2.3 NAME 'foo'\n\tSUP name )")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := LoadSchema(test.schemas); !errors.Is(err, test.err) {
+				t.Errorf("GOT error %v EXPECTED %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	t.Parallel()
+
+	schema, err := LoadSchema(BuiltinSchemas)
+	if err != nil {
+		t.Fatalf("error loading builtin schemas: %s", err)
+	}
+
+	person := func(extra map[string][]string) store.Attributes {
+		attrs := store.Attributes{
+			"objectclass": {"top", "inetOrgPerson"},
+			"cn":          {"Alice"},
+			"sn":          {"Liddell"},
+			"uid":         {"alice"},
+		}
+		for key, values := range extra {
+			attrs[store.NewAttributeKey(key)] = values
+		}
+
+		return attrs
+	}
+
+	tests := []struct {
+		name  string
+		attrs store.Attributes
+		err   error
+	}{
+		{name: "valid", attrs: person(nil)},
+		{name: "operational", attrs: person(map[string][]string{"entryUUID": {"not checked"}})},
+		{name: "no object class", attrs: store.Attributes{"cn": {"Alice"}}, err: ErrMissingObjectClass},
+		{
+			name:  "unknown class",
+			attrs: person(map[string][]string{"objectClass": {"wizard"}}),
+			err:   ErrUnknownObjectClass,
+		},
+		{name: "no structural", attrs: store.Attributes{"objectclass": {"top"}}, err: ErrNoStructuralClass},
+		{
+			name:  "multiple structural",
+			attrs: person(map[string][]string{"objectClass": {"inetOrgPerson", "organizationalUnit"}, "ou": {"users"}}),
+			err:   ErrMultipleStructural,
+		},
+		{
+			name:  "missing attribute",
+			attrs: store.Attributes{"objectclass": {"inetOrgPerson"}, "cn": {"Alice"}},
+			err:   ErrMissingAttribute,
+		},
+		{
+			name:  "unknown attribute",
+			attrs: person(map[string][]string{"shoeSize": {"42"}}),
+			err:   ErrUnknownAttributeType,
+		},
+		{name: "not allowed", attrs: person(map[string][]string{"uidNumber": {"1000"}}), err: ErrAttributeNotAllowed},
+		{
+			name:  "single value",
+			attrs: person(map[string][]string{"preferredLanguage": {"en", "it"}}),
+			err:   ErrSingleValueAttribute,
+		},
+		{
+			name: "syntax",
+			attrs: person(map[string][]string{
+				"objectClass":   {"inetOrgPerson", "posixAccount"},
+				"uidNumber":     {"one"},
+				"gidNumber":     {"1000"},
+				"homeDirectory": {"/home/alice"},
+			}),
+			err: ErrInvalidAttributeValue,
+		},
+		{
+			name: "extensible",
+			attrs: person(map[string][]string{
+				"objectClass": {"inetOrgPerson", "extensibleObject"},
+				"uidNumber":   {"1"},
+			}),
+		},
+		{
+			name: "ssh keys",
+			attrs: person(map[string][]string{
+				"objectClass":  {"inetOrgPerson", "ldapPublicKey"},
+				"sshPublicKey": {"ssh-ed25519 AAAA alice@teapot"},
+			}),
+		},
+		{
+			name:  "ssh keys without class",
+			attrs: person(map[string][]string{"sshPublicKey": {"ssh-ed25519 AAAA alice@teapot"}}),
+			err:   ErrAttributeNotAllowed,
+		},
+		{
+			name:  "calendar quota without class",
+			attrs: person(map[string][]string{"calendarQuotaBytes": {"1024"}}),
+			err:   ErrAttributeNotAllowed,
+		},
+		{
+			name: "calendar quota",
+			attrs: person(map[string][]string{
+				"objectClass":          {"inetOrgPerson", "calendarUser"},
+				"calendarQuotaBytes":   {"1024"},
+				"calendarQuotaObjects": {"10"},
+			}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := schema.Check(test.attrs); !errors.Is(err, test.err) {
+				t.Errorf("GOT error %v EXPECTED %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestCheckSchemaOptIn(t *testing.T) {
+	t.Parallel()
+
+	schema, err := LoadSchema(BuiltinSchemas)
+	if err != nil {
+		t.Fatalf("error loading builtin schemas: %s", err)
+	}
+
+	server := newTestBottin(t)
+	server.schema = schema
+	invalid := testEntry(t, "uid=alice,"+testBaseDN, map[string][]string{"objectClass": {"wizard"}})
+
+	// A loaded schema is only enforced when checking is enabled
+	if err := server.checkSchema(invalid); err != nil {
+		t.Errorf("GOT error %v with schema checking disabled", err)
+	}
+
+	server.schemaCheck = true
+	if err := server.checkSchema(invalid); !errors.Is(err, ErrUnknownObjectClass) {
+		t.Errorf("GOT error %v EXPECTED %v", err, ErrUnknownObjectClass)
+	}
+}
@@ -0,0 +1,157 @@
+package bottin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/teapotovh/teapot/lib/ldapsrv"
+	ldap "github.com/teapotovh/teapot/lib/ldapsrv/goldap"
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+// sortKey is a key of a server side sorting request (RFC 2891).
+type sortKey struct {
+	attr    store.AttributeKey
+	rule    *MatchingRule
+	reverse bool
+}
+
+// parseSortKeys parses the keys of a server side sorting request control.
+// If a key cannot be used to sort the results, the returned SortResult
+// describes the failure and is to be sent back to the client.
+func parseSortKeys(control ldap.Control) ([]sortKey, *ldap.SortResult, error) {
+	list, err := control.SortKeyList()
+	if err != nil {
+		return nil, nil, fmt.Errorf("(%w) error while parsing sort control: %w", ldapsrv.ErrProtocolError, err)
+	}
+
+	keys := make([]sortKey, 0, len(list))
+
+	for _, key := range list {
+		attr := filterAttributeKey(key.AttributeType())
+		rule := matchingRuleFor(attr)
+
+		if key.OrderingRule() != nil {
+			rule, err = findMatchingRule(string(*key.OrderingRule()))
+			if err != nil {
+				result := ldap.NewSortResult(ldap.ResultCodeInappropriateMatching, key.AttributeType().Pointer())
+				return nil, &result, fmt.Errorf("(%w) %w", ldapsrv.ErrInappropriateMatching, err)
+			}
+		}
+
+		if rule.compare == nil {
+			result := ldap.NewSortResult(ldap.ResultCodeInappropriateMatching, key.AttributeType().Pointer())
+
+			return nil, &result, fmt.Errorf(
+				"(%w) matching rule %q cannot be used for sorting",
+				ldapsrv.ErrInappropriateMatching,
+				rule.Name,
+			)
+		}
+
+		keys = append(keys, sortKey{attr: attr, rule: rule, reverse: key.ReverseOrder().Bool()})
+	}
+
+	return keys, nil, nil
+}
+
+// addSortResult attaches the server side sorting response control to the
+// response.
+func addSortResult(ctx context.Context, result ldap.SortResult) error {
+	value, err := result.Encode()
+	if err != nil {
+		return fmt.Errorf("(%w) error while encoding sort response control: %w", ldapsrv.ErrOperationsError, err)
+	}
+
+	ldapsrv.AddResponseControl(ctx, ldap.NewControl(ldapsrv.ControlServerSideSortResponse, false, value.Pointer()))
+
+	return nil
+}
+
+// sortValue returns the value an entry is sorted by for the given key: the
+// lowest value in ascending order, the highest one in reverse order.
+// Values that cannot be interpreted in the rule's syntax are ignored.
+func (key sortKey) sortValue(entry store.Entry) *string {
+	var res *string
+
+	for _, value := range entry.Get(key.attr) {
+		if _, ok := key.rule.compare(value, value); !ok {
+			continue
+		}
+
+		if res == nil {
+			res = &value
+			continue
+		}
+
+		cmp, _ := key.rule.compare(value, *res)
+		if (cmp < 0 && !key.reverse) || (cmp > 0 && key.reverse) {
+			res = &value
+		}
+	}
+
+	return res
+}
+
+// sortEntries sorts entries according to the given keys. As per RFC 2891,
+// entries without a value for a key are treated as larger than all others.
+// Values the user is not allowed to read are treated as missing, so that the
+// order does not disclose them.
+func (server *Bottin) sortEntries(user User, entries []store.Entry, keys []sortKey) {
+	if len(keys) == 0 {
+		return
+	}
+
+	type sortable struct {
+		entry  store.Entry
+		values []*string
+	}
+
+	sortables := make([]sortable, 0, len(entries))
+
+	for _, entry := range entries {
+		s := sortable{entry: entry, values: make([]*string, len(keys))}
+
+		for i, key := range keys {
+			if server.acl.Check(user, "read", entry.DN, []store.AttributeKey{key.attr}) {
+				s.values[i] = key.sortValue(entry)
+			}
+		}
+
+		sortables = append(sortables, s)
+	}
+
+	slices.SortStableFunc(sortables, func(a, b sortable) int {
+		for i, key := range keys {
+			va, vb := a.values[i], b.values[i]
+
+			var cmp int
+
+			switch {
+			case va == nil && vb == nil:
+				cmp = 0
+			case va == nil:
+				cmp = 1
+			case vb == nil:
+				cmp = -1
+			default:
+				cmp, _ = key.rule.compare(*va, *vb)
+			}
+
+			if key.reverse {
+				cmp = -cmp
+			}
+
+			if cmp != 0 {
+				return cmp
+			}
+		}
+
+		return 0
+	})
+
+	for i, s := range sortables {
+		entries[i] = s.entry
+	}
+}
@@ -6,6 +6,19 @@ import (
 	flag "github.com/spf13/pflag"
 )
 
+// DefaultIndexes are the indexes maintained unless configured otherwise.
+// Integer attributes, such as uidNumber, are not indexed: their equality
+// matching does not compare strings, so searches on them are never planned
+// against an index.
+var DefaultIndexes = []string{
+	"objectclass:eq",
+	"uid:eq,sub",
+	"cn:eq,sub",
+	"mail:eq,sub",
+	"member:eq",
+	"memberof:eq",
+}
+
 func StoreFlagSet() (*flag.FlagSet, func() StoreConfig) {
 	fs := flag.NewFlagSet("bottin/store", flag.ExitOnError)
 
@@ -17,11 +30,18 @@ func StoreFlagSet() (*flag.FlagSet, func() StoreConfig) {
 		"the URL connection string to connect to the store. mandatory for the psql backend",
 	)
 
+	indexes := fs.StringArray(
+		"bottin-store-index",
+		DefaultIndexes,
+		"the attribute indexes to maintain, in the form attr:kind,kind. Kinds: eq, pres, sub",
+	)
+
 	return fs, func() StoreConfig {
 		return StoreConfig{
 			Timeout: *timeout,
 			Type:    *typ,
 			URL:     *url,
+			Indexes: *indexes,
 		}
 	}
 }
@@ -0,0 +1,375 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/teapotovh/teapot/lib/pgcache"
+)
+
+var (
+	ErrInvalidIndex     = errors.New("invalid index definition")
+	ErrInvalidIndexKind = errors.New("invalid index kind")
+)
+
+type IndexKind string
+
+const (
+	// IndexEquality indexes all values of an attribute for equality lookups.
+	IndexEquality IndexKind = "eq"
+	// IndexPresence indexes which entries have at least one value for an attribute.
+	IndexPresence IndexKind = "pres"
+	// IndexSubstrings indexes the trigrams of all values of an attribute
+	// for substring lookups.
+	IndexSubstrings IndexKind = "sub"
+)
+
+// trigramLength is the length of the n-grams used for substring indexes.
+const trigramLength = 3
+
+const (
+	trigramStart = "\x00"
+	trigramEnd   = "\x01"
+)
+
+// IndexConfig describes which kinds of indexes are kept for an attribute.
+type IndexConfig struct {
+	Attribute AttributeKey
+	Kinds     []IndexKind
+}
+
+// ParseIndexConfig parses an index definition in the form attr:kind,kind
+// (i.e., uid:eq,sub).
+func ParseIndexConfig(raw string) (IndexConfig, error) {
+	attr, rawKinds, found := strings.Cut(raw, ":")
+	if !found || strings.TrimSpace(attr) == "" {
+		return IndexConfig{}, fmt.Errorf("could not parse index %q: %w", raw, ErrInvalidIndex)
+	}
+
+	config := IndexConfig{Attribute: NewAttributeKey(strings.TrimSpace(attr))}
+
+	for rawKind := range strings.SplitSeq(rawKinds, ",") {
+		kind := IndexKind(strings.TrimSpace(rawKind))
+		switch kind {
+		case IndexEquality, IndexPresence, IndexSubstrings:
+			config.Kinds = append(config.Kinds, kind)
+		default:
+			return IndexConfig{}, fmt.Errorf("could not parse index %q: %w: %q", raw, ErrInvalidIndexKind, kind)
+		}
+	}
+
+	return config, nil
+}
+
+type set map[string]struct{}
+
+func (s set) add(key string) {
+	s[key] = struct{}{}
+}
+
+func intersect(a, b set) set {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+
+	res := set{}
+
+	for key := range a {
+		if _, ok := b[key]; ok {
+			res.add(key)
+		}
+	}
+
+	return res
+}
+
+func union(a, b set) set {
+	res := maps.Clone(a)
+	maps.Copy(res, b)
+
+	return res
+}
+
+// attributeIndex holds the indexes for a single attribute.
+type attributeIndex struct {
+	equality   map[string]set
+	presence   set
+	substrings map[string]set
+}
+
+// Indexes maintains the configured attribute indexes over a set of entries
+// and is used by stores to answer queries without scanning all entries.
+// Indexes are kept up to date by notifying them of every stored and deleted
+// entry, and implement pgcache.Listener for this purpose.
+type Indexes struct {
+	mu         sync.RWMutex
+	attributes map[AttributeKey]*attributeIndex
+	// keys tracks the index keys each entry was indexed with, so that they
+	// can be removed when the entry changes or is deleted.
+	keys     map[string]map[AttributeKey][]string
+	prefixes map[string]Prefix
+}
+
+func NewIndexes(configs []IndexConfig) *Indexes {
+	idx := Indexes{
+		attributes: map[AttributeKey]*attributeIndex{},
+		keys:       map[string]map[AttributeKey][]string{},
+		prefixes:   map[string]Prefix{},
+	}
+
+	for _, config := range configs {
+		ai, ok := idx.attributes[config.Attribute]
+		if !ok {
+			ai = &attributeIndex{}
+			idx.attributes[config.Attribute] = ai
+		}
+
+		for _, kind := range config.Kinds {
+			switch kind {
+			case IndexEquality:
+				ai.equality = map[string]set{}
+			case IndexPresence:
+				ai.presence = set{}
+			case IndexSubstrings:
+				ai.substrings = map[string]set{}
+			}
+		}
+	}
+
+	return &idx
+}
+
+// normalizeIndexValue normalizes values so that they are compared
+// case-insensitively and ignoring insignificant spaces.
+func normalizeIndexValue(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+func trigrams(value string) []string {
+	runes := []rune(value)
+	if len(runes) < trigramLength {
+		return nil
+	}
+
+	grams := make([]string, 0, len(runes)-trigramLength+1)
+	for i := 0; i+trigramLength <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+trigramLength]))
+	}
+
+	return grams
+}
+
+func (ai *attributeIndex) add(key string, values AttributeValue) (keys []string) {
+	if ai.presence != nil && len(values) > 0 {
+		ai.presence.add(key)
+	}
+
+	for _, value := range values {
+		value = normalizeIndexValue(value)
+
+		if ai.equality != nil {
+			if ai.equality[value] == nil {
+				ai.equality[value] = set{}
+			}
+
+			ai.equality[value].add(key)
+		}
+
+		if ai.substrings != nil {
+			for _, gram := range trigrams(trigramStart + value + trigramEnd) {
+				if ai.substrings[gram] == nil {
+					ai.substrings[gram] = set{}
+				}
+
+				ai.substrings[gram].add(key)
+				keys = append(keys, gram)
+			}
+		}
+
+		keys = append(keys, value)
+	}
+
+	return keys
+}
+
+func (ai *attributeIndex) remove(key string, values []string) {
+	if ai.presence != nil {
+		delete(ai.presence, key)
+	}
+
+	for _, value := range values {
+		if s, ok := ai.equality[value]; ok {
+			delete(s, key)
+
+			if len(s) == 0 {
+				delete(ai.equality, value)
+			}
+		}
+
+		if s, ok := ai.substrings[value]; ok {
+			delete(s, key)
+
+			if len(s) == 0 {
+				delete(ai.substrings, value)
+			}
+		}
+	}
+}
+
+func (idx *Indexes) remove(key string) {
+	for attr, values := range idx.keys[key] {
+		idx.attributes[attr].remove(key, values)
+	}
+
+	delete(idx.keys, key)
+	delete(idx.prefixes, key)
+}
+
+// Stored updates the indexes with the values of the given entry,
+// replacing any values previously indexed for the same key.
+func (idx *Indexes) Stored(prefix Prefix, entry Entry) {
+	key := prefix.String()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(key)
+
+	indexed := map[AttributeKey][]string{}
+
+	for attr, values := range entry.Attributes {
+		ai, ok := idx.attributes[NewAttributeKey(string(attr))]
+		if !ok {
+			continue
+		}
+
+		indexed[NewAttributeKey(string(attr))] = ai.add(key, values)
+	}
+
+	idx.keys[key] = indexed
+	idx.prefixes[key] = prefix
+}
+
+// Deleted removes an entry from the indexes.
+func (idx *Indexes) Deleted(prefix Prefix) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(prefix.String())
+}
+
+// Candidates returns the prefixes of the entries that may match the query,
+// sorted in the same order as List returns them. The boolean result is false
+// if the indexes cannot answer the query, in which case the caller has to
+// fall back to a full scan.
+func (idx *Indexes) Candidates(query Query) ([]Prefix, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	keys, ok := idx.plan(query)
+	if !ok {
+		return nil, false
+	}
+
+	prefixes := make([]Prefix, 0, len(keys))
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		prefixes = append(prefixes, idx.prefixes[key])
+	}
+
+	return prefixes, true
+}
+
+//nolint:gocyclo
+func (idx *Indexes) plan(query Query) (set, bool) {
+	switch q := query.(type) {
+	case QueryAnd:
+		// An intersection can be answered as long as one of the subqueries
+		// can, as the others can only restrict the results further.
+		var (
+			res   set
+			found bool
+		)
+
+		for _, sub := range q {
+			keys, ok := idx.plan(sub)
+			if !ok {
+				continue
+			}
+
+			if !found {
+				res, found = keys, true
+			} else {
+				res = intersect(res, keys)
+			}
+		}
+
+		return res, found
+	case QueryOr:
+		res := set{}
+
+		for _, sub := range q {
+			keys, ok := idx.plan(sub)
+			if !ok {
+				return nil, false
+			}
+
+			res = union(res, keys)
+		}
+
+		return res, true
+	case QueryPresent:
+		ai, ok := idx.attributes[NewAttributeKey(string(q.Attribute))]
+		if !ok || ai.presence == nil {
+			return nil, false
+		}
+
+		return ai.presence, true
+	case QueryEquality:
+		ai, ok := idx.attributes[NewAttributeKey(string(q.Attribute))]
+		if !ok || ai.equality == nil {
+			return nil, false
+		}
+
+		return ai.equality[normalizeIndexValue(q.Value)], true
+	case QuerySubstrings:
+		ai, ok := idx.attributes[NewAttributeKey(string(q.Attribute))]
+		if !ok || ai.substrings == nil {
+			return nil, false
+		}
+
+		var grams []string
+
+		if q.Initial != "" {
+			grams = append(grams, trigrams(trigramStart+normalizeIndexValue(q.Initial))...)
+		}
+
+		for _, part := range q.Any {
+			grams = append(grams, trigrams(normalizeIndexValue(part))...)
+		}
+
+		if q.Final != "" {
+			grams = append(grams, trigrams(normalizeIndexValue(q.Final)+trigramEnd)...)
+		}
+
+		// If all parts are too short, we have no trigrams to look up
+		if len(grams) == 0 {
+			return nil, false
+		}
+
+		res := ai.substrings[grams[0]]
+		for _, gram := range grams[1:] {
+			res = intersect(res, ai.substrings[gram])
+		}
+
+		return res, true
+	default:
+		return nil, false
+	}
+}
+
+// Ensure *Indexes implements pgcache.Listener.
+var _ pgcache.Listener[Prefix, Entry] = &Indexes{}
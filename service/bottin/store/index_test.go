@@ -0,0 +1,170 @@
+package store
+
+import (
+	"slices"
+	"testing"
+)
+
+func mustParseDN(t *testing.T, raw string) DN {
+	t.Helper()
+
+	dn, err := ParseDN(raw)
+	if err != nil {
+		t.Fatalf("error parsing DN %q: %s", raw, err)
+	}
+
+	return dn
+}
+
+func TestParseIndexConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := ParseIndexConfig("Mail:eq, sub")
+	if err != nil {
+		t.Fatalf("error parsing index: %s", err)
+	}
+
+	if config.Attribute != "mail" || !slices.Equal(config.Kinds, []IndexKind{IndexEquality, IndexSubstrings}) {
+		t.Errorf("GOT %#v", config)
+	}
+
+	for _, raw := range []string{"mail", ":eq", "mail:eq,fuzzy"} {
+		if _, err := ParseIndexConfig(raw); err == nil {
+			t.Errorf("expected an error parsing %q", raw)
+		}
+	}
+}
+
+func TestIndexesCandidates(t *testing.T) {
+	t.Parallel()
+
+	idx := NewIndexes([]IndexConfig{
+		{Attribute: "uid", Kinds: []IndexKind{IndexEquality}},
+		{Attribute: "mail", Kinds: []IndexKind{IndexEquality, IndexSubstrings}},
+		{Attribute: "objectclass", Kinds: []IndexKind{IndexEquality, IndexPresence}},
+	})
+
+	store := func(dn string, attrs Attributes) {
+		entry := NewEntry(mustParseDN(t, dn), attrs)
+		idx.Stored(entry.DN.Prefix(), entry)
+	}
+
+	store("uid=alice,ou=users,dc=teapot,dc=ovh", Attributes{
+		"objectclass": {"person"},
+		"mail":        {"Alice@Teapot.ovh"},
+	})
+	store("uid=bob,ou=users,dc=teapot,dc=ovh", Attributes{
+		"objectclass": {"person"},
+		"mail":        {"bob@example.com"},
+	})
+	store("uid=carol,ou=users,dc=teapot,dc=ovh", Attributes{
+		"objectclass": {"person"},
+		"mail":        {"carol@teapot.ovh"},
+	})
+	store("ou=users,dc=teapot,dc=ovh", Attributes{})
+
+	// Updates replace the previously indexed values, and deletions remove them.
+	store("uid=carol,ou=users,dc=teapot,dc=ovh", Attributes{
+		"objectclass": {"person"},
+		"mail":        {"carol@example.com"},
+	})
+	idx.Deleted(mustParseDN(t, "uid=bob,ou=users,dc=teapot,dc=ovh").Prefix())
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected []string
+		ok       bool
+	}{
+		{
+			name:     "equality",
+			query:    QueryEquality{Attribute: "UID", Value: "ALICE"},
+			expected: []string{"uid=alice,ou=users,dc=teapot,dc=ovh"},
+			ok:       true,
+		},
+		{
+			name:     "equality deleted",
+			query:    QueryEquality{Attribute: "uid", Value: "bob"},
+			expected: []string{},
+			ok:       true,
+		},
+		{
+			name:  "presence",
+			query: QueryPresent{Attribute: "objectclass"},
+			expected: []string{
+				"uid=alice,ou=users,dc=teapot,dc=ovh",
+				"uid=carol,ou=users,dc=teapot,dc=ovh",
+			},
+			ok: true,
+		},
+		{
+			name:     "substrings",
+			query:    QuerySubstrings{Attribute: "mail", Final: "@teapot.ovh"},
+			expected: []string{"uid=alice,ou=users,dc=teapot,dc=ovh"},
+			ok:       true,
+		},
+		{
+			name:     "substrings updated",
+			query:    QuerySubstrings{Attribute: "mail", Initial: "carol", Any: []string{"example"}},
+			expected: []string{"uid=carol,ou=users,dc=teapot,dc=ovh"},
+			ok:       true,
+		},
+		{
+			name:  "substrings too short",
+			query: QuerySubstrings{Attribute: "mail", Any: []string{"@"}},
+			ok:    false,
+		},
+		{
+			name:  "not indexed",
+			query: QueryEquality{Attribute: "cn", Value: "alice"},
+			ok:    false,
+		},
+		{
+			name:     "and with unindexed",
+			query:    QueryAnd{QueryAll{}, QueryEquality{Attribute: "uid", Value: "alice"}},
+			expected: []string{"uid=alice,ou=users,dc=teapot,dc=ovh"},
+			ok:       true,
+		},
+		{
+			name: "or",
+			query: QueryOr{
+				QueryEquality{Attribute: "uid", Value: "alice"},
+				QueryEquality{Attribute: "uid", Value: "carol"},
+			},
+			expected: []string{
+				"uid=alice,ou=users,dc=teapot,dc=ovh",
+				"uid=carol,ou=users,dc=teapot,dc=ovh",
+			},
+			ok: true,
+		},
+		{
+			name:  "or with unindexed",
+			query: QueryOr{QueryAll{}, QueryEquality{Attribute: "uid", Value: "alice"}},
+			ok:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			candidates, ok := idx.Candidates(test.query)
+			if ok != test.ok {
+				t.Fatalf("GOT ok %t, EXPECTED %t", ok, test.ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			dns := []string{}
+			for _, candidate := range candidates {
+				dns = append(dns, candidate.DN().String())
+			}
+
+			if !slices.Equal(dns, test.expected) {
+				t.Errorf("GOT %v, EXPECTED %v", dns, test.expected)
+			}
+		})
+	}
+}
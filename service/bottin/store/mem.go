@@ -3,6 +3,9 @@ package store
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -45,11 +48,15 @@ func mementryLess(a, b mementry) bool {
 type Mem struct {
 	tr      *btree.BTreeG[mementry]
 	mu      sync.RWMutex
+	indexes *Indexes
 	metrics metrics
 }
 
-func NewMem() *Mem {
-	m := Mem{tr: btree.NewG(2, mementryLess)}
+func NewMem(indexes []IndexConfig) *Mem {
+	m := Mem{
+		tr:      btree.NewG(2, mementryLess),
+		indexes: NewIndexes(indexes),
+	}
 	m.metrics.initMetrics("mem")
 
 	return &m
@@ -72,6 +79,10 @@ func (m *Mem) List(ctx context.Context, prefix Prefix, exact bool) (entries []En
 	end := mementryFromPrefix(prefixEnd(prefix))
 
 	m.tr.AscendRange(start, end, func(entry mementry) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+
 		// For non-exact matches, continue looping and collect all results
 		if !exact {
 			entries = append(entries, entry.entry)
@@ -87,6 +98,38 @@ func (m *Mem) List(ctx context.Context, prefix Prefix, exact bool) (entries []En
 		return true
 	})
 
+	return entries, err
+}
+
+// Search implements Store.
+func (m *Mem) Search(ctx context.Context, prefix Prefix, exact bool, query Query) ([]Entry, error) {
+	candidates, ok := m.indexes.Candidates(query)
+	if !ok {
+		m.metrics.queries.WithLabelValues("mem", planScan).Inc()
+		return m.List(ctx, prefix, exact)
+	}
+
+	m.metrics.queries.WithLabelValues("mem", planIndex).Inc()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []Entry
+
+	for _, candidate := range candidates {
+		if err := ctx.Err(); err != nil {
+			return entries, err
+		}
+
+		if !matchesPrefix(prefix, exact, candidate) {
+			continue
+		}
+
+		if entry, found := m.tr.Get(mementryFromPrefix(candidate)); found {
+			entries = append(entries, entry.entry)
+		}
+	}
+
 	return entries, nil
 }
 
@@ -116,11 +159,18 @@ type changekind uint8
 const (
 	changekindStore changekind = iota
 	changekindDelete
+	changekindReplace
+	changekindAdd
 )
 
 type change struct {
 	entry mementry
 	kind  changekind
+
+	// attr, oldValue and newValue describe the value swapped by a
+	// changekindReplace change, or added by a changekindAdd one.
+	attr               AttributeKey
+	oldValue, newValue string
 }
 
 // Store implements Transaction.
@@ -159,6 +209,122 @@ func (m *MemTransaction) Delete(ctx context.Context, dn DN) (err error) {
 	return nil
 }
 
+// ReplaceValue implements Transaction.
+func (m *MemTransaction) ReplaceValue(
+	ctx context.Context,
+	dn DN,
+	attr AttributeKey,
+	oldValue, newValue string,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.committed {
+		return ErrCommitted
+	}
+
+	c := change{
+		kind:     changekindReplace,
+		entry:    mementryFromPrefix(dn.Prefix()),
+		attr:     attr,
+		oldValue: oldValue,
+		newValue: newValue,
+	}
+	m.changes = append(m.changes, c)
+
+	return nil
+}
+
+// AddValue implements Transaction.
+func (m *MemTransaction) AddValue(ctx context.Context, dn DN, attr AttributeKey, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.committed {
+		return ErrCommitted
+	}
+
+	c := change{
+		kind:     changekindAdd,
+		entry:    mementryFromPrefix(dn.Prefix()),
+		attr:     attr,
+		newValue: value,
+	}
+	m.changes = append(m.changes, c)
+
+	return nil
+}
+
+// resolve turns the replace and add changes into store changes, based on the
+// current contents of the btree and on the previous changes of the
+// transaction. The caller must hold the btree lock.
+func (m *MemTransaction) resolve() ([]change, error) {
+	pending := map[string]*mementry{}
+	changes := make([]change, 0, len(m.changes))
+
+	for _, c := range m.changes {
+		if c.kind == changekindReplace || c.kind == changekindAdd {
+			current, found := m.mem.tr.Get(c.entry)
+			if p, ok := pending[c.entry.prefix.String()]; ok {
+				current, found = mementry{}, p != nil
+				if found {
+					current = *p
+				}
+			}
+
+			if !found {
+				return nil, fmt.Errorf("could not update %q in %q: %w", c.attr, c.entry.entry.DN, ErrConflict)
+			}
+
+			values, err := c.apply(current.entry.Get(c.attr))
+			if err != nil {
+				return nil, err
+			}
+
+			attrs := maps.Clone(current.entry.Attributes)
+			attrs[c.attr] = values
+
+			c = change{
+				kind:  changekindStore,
+				entry: mementry{prefix: current.prefix, entry: Entry{DN: current.entry.DN, Attributes: attrs}},
+			}
+		}
+
+		// A nil pending entry marks a deletion.
+		var entry *mementry
+		if c.kind == changekindStore {
+			entry = &c.entry
+		}
+
+		pending[c.entry.prefix.String()] = entry
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// apply returns a copy of the values of an attribute updated by a replace or
+// add change.
+func (c change) apply(values AttributeValue) (AttributeValue, error) {
+	if c.kind == changekindAdd {
+		if slices.Contains(values, c.newValue) {
+			return values, nil
+		}
+
+		return append(slices.Clone(values), c.newValue), nil
+	}
+
+	i := slices.Index(values, c.oldValue)
+	if i < 0 {
+		return nil, fmt.Errorf("could not replace value of %q in %q: %w", c.attr, c.entry.entry.DN, ErrConflict)
+	}
+
+	values = slices.Clone(values)
+	values[i] = c.newValue
+
+	return values, nil
+}
+
 func (m *MemTransaction) Commit(ctx context.Context) error {
 	if err := ctx.Err(); err != nil {
 		return err
@@ -176,12 +342,21 @@ func (m *MemTransaction) Commit(ctx context.Context) error {
 	m.mem.mu.Lock()
 	defer m.mem.mu.Unlock()
 
-	for _, change := range m.changes {
+	// All changes are resolved before touching the btree, so that a conflict
+	// leaves the store as it was.
+	changes, err := m.resolve()
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
 		switch change.kind {
 		case changekindStore:
 			m.mem.tr.ReplaceOrInsert(change.entry)
+			m.mem.indexes.Stored(change.entry.prefix, change.entry.entry)
 		case changekindDelete:
 			m.mem.tr.Delete(change.entry)
+			m.mem.indexes.Deleted(change.entry.prefix)
 		}
 	}
 
@@ -190,6 +365,18 @@ func (m *MemTransaction) Commit(ctx context.Context) error {
 	return nil
 }
 
+// Rollback implements Transaction. A rolled back transaction accepts no
+// further changes, as if it was committed.
+func (m *MemTransaction) Rollback(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.changes = nil
+	m.committed = true
+
+	return nil
+}
+
 // Run implements run.Runnable
 //
 // This is a no-op.
@@ -200,7 +387,7 @@ func (m *Mem) Run(ctx context.Context, notify run.Notify) error {
 
 // Metrics implements observability.Metrics.
 func (m *Mem) Metrics() []prometheus.Collector {
-	return []prometheus.Collector{m.metrics.backend}
+	return []prometheus.Collector{m.metrics.backend, m.metrics.queries}
 }
 
 // ReadinessChecks implements run.ReadinessChecks
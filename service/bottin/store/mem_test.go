@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMemReplaceValue(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mem := NewMem(nil)
+	dn := mustParseDN(t, "uid=alice,ou=users,dc=teapot,dc=ovh")
+
+	run := func(fn func(tx Transaction) error) error {
+		tx, err := mem.Begin(ctx)
+		if err != nil {
+			t.Fatalf("error beginning transaction: %s", err)
+		}
+
+		if err := fn(tx); err != nil {
+			t.Fatalf("error in transaction: %s", err)
+		}
+
+		return tx.Commit(ctx)
+	}
+
+	get := func() Entry {
+		entries, err := mem.List(ctx, dn.Prefix(), true)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("error getting %q: %v", dn, err)
+		}
+
+		return entries[0]
+	}
+
+	err := run(func(tx Transaction) error {
+		return tx.Store(ctx, NewEntry(dn, Attributes{"mail": {"a@teapot.ovh", "b@teapot.ovh"}, "cn": {"Alice"}}))
+	})
+	if err != nil {
+		t.Fatalf("error storing entry: %s", err)
+	}
+
+	err = run(func(tx Transaction) error {
+		return tx.ReplaceValue(ctx, dn, "mail", "a@teapot.ovh", "c@teapot.ovh")
+	})
+	if err != nil {
+		t.Fatalf("error replacing value: %s", err)
+	}
+
+	entry := get()
+	if mail := entry.Get("mail"); !slices.Equal(mail, []string{"c@teapot.ovh", "b@teapot.ovh"}) {
+		t.Errorf("GOT mail %v", mail)
+	}
+
+	if cn := entry.Get("cn"); !slices.Equal(cn, []string{"Alice"}) {
+		t.Errorf("GOT cn %v", cn)
+	}
+
+	// The value is gone, so the whole transaction is rejected.
+	err = run(func(tx Transaction) error {
+		if err := tx.Store(ctx, NewEntry(dn, Attributes{"mail": {"d@teapot.ovh"}})); err != nil {
+			return err
+		}
+
+		return tx.ReplaceValue(ctx, dn, "mail", "a@teapot.ovh", "e@teapot.ovh")
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("GOT error %v EXPECTED %v", err, ErrConflict)
+	}
+
+	if mail := get().Attributes.Get("mail"); !slices.Equal(mail, []string{"c@teapot.ovh", "b@teapot.ovh"}) {
+		t.Errorf("conflicting transaction was applied, GOT mail %v", mail)
+	}
+
+	// Replaces see the earlier changes of the same transaction.
+	err = run(func(tx Transaction) error {
+		if err := tx.ReplaceValue(ctx, dn, "mail", "b@teapot.ovh", "f@teapot.ovh"); err != nil {
+			return err
+		}
+
+		return tx.ReplaceValue(ctx, dn, "mail", "f@teapot.ovh", "g@teapot.ovh")
+	})
+	if err != nil {
+		t.Fatalf("error replacing values: %s", err)
+	}
+
+	if mail := get().Attributes.Get("mail"); !slices.Equal(mail, []string{"c@teapot.ovh", "g@teapot.ovh"}) {
+		t.Errorf("GOT mail %v", mail)
+	}
+
+	err = run(func(tx Transaction) error {
+		if err := tx.Delete(ctx, dn); err != nil {
+			return err
+		}
+
+		return tx.ReplaceValue(ctx, dn, "mail", "c@teapot.ovh", "h@teapot.ovh")
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("GOT error %v EXPECTED %v", err, ErrConflict)
+	}
+}
+
+func TestMemRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	mem := NewMem(nil)
+	dn := mustParseDN(t, "uid=alice,ou=users,dc=teapot,dc=ovh")
+
+	tx, err := mem.Begin(ctx)
+	if err != nil {
+		t.Fatalf("error beginning transaction: %s", err)
+	}
+
+	if err := tx.Store(ctx, NewEntry(dn, Attributes{"cn": {"Alice"}})); err != nil {
+		t.Fatalf("error storing entry: %s", err)
+	}
+
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("error rolling back: %s", err)
+	}
+
+	if err := tx.Commit(ctx); !errors.Is(err, ErrCommitted) {
+		t.Errorf("GOT error %v EXPECTED %v", err, ErrCommitted)
+	}
+
+	if entries, err := mem.List(ctx, dn.Prefix(), true); err != nil || len(entries) != 0 {
+		t.Errorf("rolled back entry was stored, GOT %v (err %v)", entries, err)
+	}
+}
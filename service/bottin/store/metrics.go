@@ -4,8 +4,14 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+const (
+	planIndex = "index"
+	planScan  = "scan"
+)
+
 type metrics struct {
 	backend *prometheus.CounterVec
+	queries *prometheus.CounterVec
 }
 
 func (m *metrics) initMetrics(backend string) {
@@ -18,4 +24,12 @@ func (m *metrics) initMetrics(backend string) {
 	)
 	// This is used purely to track which backend the current bottin instance is running
 	m.backend.WithLabelValues(backend).Add(1)
+
+	m.queries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bottin_store_queries_total",
+			Help: "Number of search queries, by whether they were answered using indexes or a full scan",
+		},
+		[]string{"backend", "plan"},
+	)
 }
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"slices"
 
 	"git.sr.ht/~bitfehler/brant"
 	"git.sr.ht/~bitfehler/brant/database/dialect"
@@ -144,17 +143,91 @@ func deletePSQL(ctx context.Context, tx pgx.Tx, prefixes []Prefix) error {
 	return nil
 }
 
+// replaceValueQuery swaps a single value in the array of an attribute,
+// preserving the order of the other values. The entry is only updated if it
+// still contains the old value.
+var replaceValueQuery = `
+		UPDATE entries
+		SET attributes = jsonb_set(attributes, ARRAY[$2::text], (
+			SELECT jsonb_agg(CASE WHEN value = to_jsonb($3::text) THEN to_jsonb($4::text) ELSE value END ORDER BY idx)
+			FROM jsonb_array_elements(attributes->$2::text) WITH ORDINALITY AS elements(value, idx)
+		))
+		WHERE dn = $1 AND attributes->$2::text @> jsonb_build_array($3::text)
+		RETURNING dn, attributes;
+`
+
+func replaceValuePSQL(
+	ctx context.Context,
+	tx pgx.Tx,
+	dn DN,
+	attr AttributeKey,
+	oldValue, newValue string,
+) ([]Entry, error) {
+	prefix := dn.Prefix()
+
+	rows, err := tx.Query(ctx, replaceValueQuery, prefix.String(), string(attr), oldValue, newValue)
+	if err != nil {
+		return nil, fmt.Errorf("error while replacing attribute value with psql: %w", err)
+	}
+
+	entries, err := parseRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("could not replace value of %q in %q: %w", attr, dn, ErrConflict)
+	}
+
+	return entries, nil
+}
+
+// addValueQuery appends a value to the array of an attribute, unless it is
+// already present. The entry is returned even if it was not changed, so that
+// missing entries can be told apart.
+var addValueQuery = `
+		UPDATE entries
+		SET attributes = CASE
+			WHEN attributes->$2::text @> jsonb_build_array($3::text) THEN attributes
+			ELSE jsonb_set(attributes, ARRAY[$2::text],
+				COALESCE(attributes->$2::text, '[]'::jsonb) || jsonb_build_array($3::text))
+		END
+		WHERE dn = $1
+		RETURNING dn, attributes;
+`
+
+func addValuePSQL(ctx context.Context, tx pgx.Tx, dn DN, attr AttributeKey, value string) ([]Entry, error) {
+	prefix := dn.Prefix()
+
+	rows, err := tx.Query(ctx, addValueQuery, prefix.String(), string(attr), value)
+	if err != nil {
+		return nil, fmt.Errorf("error while adding attribute value with psql: %w", err)
+	}
+
+	entries, err := parseRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("could not add value of %q in %q: %w", attr, dn, ErrConflict)
+	}
+
+	return entries, nil
+}
+
 //go:embed migrations/*.sql
 var migraitions embed.FS
 
 type PSQL struct {
-	pool  *pgxpool.Pool
-	table *pgcache.Table[Prefix, Entry]
+	pool    *pgxpool.Pool
+	table   *pgcache.Table[Prefix, Entry]
+	indexes *Indexes
 
 	metrics metrics
 }
 
-func NewPSQL(ctx context.Context, url string, logger *slog.Logger) (*PSQL, error) {
+func NewPSQL(ctx context.Context, url string, indexes []IndexConfig, logger *slog.Logger) (*PSQL, error) {
 	options := brant.DefaultOptions().WithTableName("_version").WithFilesystem(migraitions).WithDataSourceName(url)
 
 	provider, err := brant.NewProvider(logger, dialect.Postgres, options)
@@ -199,9 +272,15 @@ func NewPSQL(ctx context.Context, url string, logger *slog.Logger) (*PSQL, error
 		return nil, fmt.Errorf("error while bulding cachnig table: %w", err)
 	}
 
+	// The indexes are kept up to date with the cache, including the changes
+	// made by other replicas that we receive via notifications.
+	idx := NewIndexes(indexes)
+	table.AddListener(idx)
+
 	p := PSQL{
-		pool:  pool,
-		table: table,
+		pool:    pool,
+		table:   table,
+		indexes: idx,
 	}
 	p.metrics.initMetrics("psql")
 
@@ -226,7 +305,42 @@ func (p *PSQL) List(ctx context.Context, prefix Prefix, exact bool) (entries []E
 		}
 	} else {
 		end := prefixEnd(prefix)
-		entries = slices.Collect(p.table.Between(prefix, end))
+		for entry := range p.table.Between(prefix, end) {
+			if err := ctx.Err(); err != nil {
+				return entries, err
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// Search implements Store.
+func (p *PSQL) Search(ctx context.Context, prefix Prefix, exact bool, query Query) ([]Entry, error) {
+	candidates, ok := p.indexes.Candidates(query)
+	if !ok {
+		p.metrics.queries.WithLabelValues("psql", planScan).Inc()
+		return p.List(ctx, prefix, exact)
+	}
+
+	p.metrics.queries.WithLabelValues("psql", planIndex).Inc()
+
+	var entries []Entry
+
+	for _, candidate := range candidates {
+		if err := ctx.Err(); err != nil {
+			return entries, err
+		}
+
+		if !matchesPrefix(prefix, exact, candidate) {
+			continue
+		}
+
+		if entry, found := p.table.Get(candidate); found {
+			entries = append(entries, entry)
+		}
 	}
 
 	return entries, nil
@@ -257,11 +371,35 @@ func (p *PSQLTransaction) Delete(ctx context.Context, dn DN) (err error) {
 	return p.tx.Delete(ctx, []Prefix{prefix})
 }
 
+// ReplaceValue implements Transaction.
+func (p *PSQLTransaction) ReplaceValue(
+	ctx context.Context,
+	dn DN,
+	attr AttributeKey,
+	oldValue, newValue string,
+) error {
+	return p.tx.Update(ctx, func(ctx context.Context, tx pgx.Tx) ([]Entry, error) {
+		return replaceValuePSQL(ctx, tx, dn, attr, oldValue, newValue)
+	})
+}
+
+// AddValue implements Transaction.
+func (p *PSQLTransaction) AddValue(ctx context.Context, dn DN, attr AttributeKey, value string) error {
+	return p.tx.Update(ctx, func(ctx context.Context, tx pgx.Tx) ([]Entry, error) {
+		return addValuePSQL(ctx, tx, dn, attr, value)
+	})
+}
+
 // Commit implements Transaction.
 func (p *PSQLTransaction) Commit(ctx context.Context) (err error) {
 	return p.tx.Commit(ctx)
 }
 
+// Rollback implements Transaction.
+func (p *PSQLTransaction) Rollback(ctx context.Context) error {
+	return p.tx.Rollback(ctx)
+}
+
 // Run implements run.Runnable.
 func (p *PSQL) Run(ctx context.Context, notify run.Notify) error {
 	return p.table.Run(ctx, notify)
@@ -269,7 +407,7 @@ func (p *PSQL) Run(ctx context.Context, notify run.Notify) error {
 
 // Metrics implements observability.Metrics.
 func (p *PSQL) Metrics() []prometheus.Collector {
-	return append(p.table.Metrics(), p.metrics.backend)
+	return append(p.table.Metrics(), p.metrics.backend, p.metrics.queries)
 }
 
 // ReadinessChecks implements run.ReadinessChecks.
@@ -0,0 +1,49 @@
+package store
+
+// Query describes a set of entries in a way that can be answered by the
+// store indexes. Queries are always evaluated conservatively: the store may
+// return more entries than the ones matching the query, so callers must still
+// apply their own filtering on the returned entries.
+type Query interface {
+	isQuery()
+}
+
+type (
+	// QueryAll matches all entries and can only be answered with a full scan.
+	QueryAll struct{}
+
+	// QueryAnd matches entries matching all of the subqueries.
+	QueryAnd []Query
+
+	// QueryOr matches entries matching any of the subqueries.
+	QueryOr []Query
+
+	// QueryPresent matches entries that have at least one value for Attribute.
+	QueryPresent struct {
+		Attribute AttributeKey
+	}
+
+	// QueryEquality matches entries that have Value among the values of
+	// Attribute. Values are compared case-insensitively.
+	QueryEquality struct {
+		Attribute AttributeKey
+		Value     string
+	}
+
+	// QuerySubstrings matches entries with a value of Attribute starting with
+	// Initial, containing all Any parts in order and ending with Final.
+	// Values are compared case-insensitively.
+	QuerySubstrings struct {
+		Attribute AttributeKey
+		Initial   string
+		Any       []string
+		Final     string
+	}
+)
+
+func (QueryAll) isQuery()        {}
+func (QueryAnd) isQuery()        {}
+func (QueryOr) isQuery()         {}
+func (QueryPresent) isQuery()    {}
+func (QueryEquality) isQuery()   {}
+func (QuerySubstrings) isQuery() {}
@@ -13,6 +13,7 @@ import (
 
 var (
 	ErrInvalidBackend = errors.New("invalid backend")
+	ErrConflict       = errors.New("entry was modified concurrently")
 )
 
 // Store provides an interface implemented by all types of stores for LDAP entries.
@@ -28,6 +29,14 @@ type Store interface {
 	// entries exactly matching this DN.
 	List(ctx context.Context, prefix Prefix, exact bool) ([]Entry, error)
 
+	// Search behaves like List, but uses the configured indexes to only return
+	// the entries that may match the query. The result is a superset of the
+	// matching entries, and falls back to List if no index can be used.
+	//
+	// Both List and Search stop when ctx is done, returning the entries
+	// collected so far along with the context error.
+	Search(ctx context.Context, prefix Prefix, exact bool, query Query) ([]Entry, error)
+
 	// Begin starts a transaction through which the contents on the store can be modified.
 	Begin(ctx context.Context) (Transaction, error)
 }
@@ -40,25 +49,51 @@ type Transaction interface {
 	// Delete deletes the entry with the specified DN.
 	Delete(ctx context.Context, dn DN) error
 
+	// ReplaceValue replaces the old value of an attribute with a new one,
+	// leaving the rest of the entry untouched. It fails with ErrConflict if the
+	// entry does not contain the old value anymore.
+	ReplaceValue(ctx context.Context, dn DN, attr AttributeKey, oldValue, newValue string) error
+
+	// AddValue adds a value to an attribute, leaving the rest of the entry
+	// untouched. Adding a value which is already present is a no-op. It fails
+	// with ErrConflict if the entry does not exist.
+	AddValue(ctx context.Context, dn DN, attr AttributeKey, value string) error
+
 	// Commit permanently saves the changes made through this transaction to the store.
 	Commit(ctx context.Context) error
+
+	// Rollback discards the changes made through this transaction. Rolling back
+	// a committed transaction is a no-op, so that Rollback can be deferred.
+	Rollback(ctx context.Context) error
 }
 
 type StoreConfig struct {
 	Timeout time.Duration
 	Type    string
 	URL     string
+	Indexes []string
 }
 
 func NewStore(config StoreConfig, logger *slog.Logger) (Store, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
 	defer cancel()
 
+	var indexes []IndexConfig
+
+	for _, raw := range config.Indexes {
+		index, err := ParseIndexConfig(raw)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing index definition: %w", err)
+		}
+
+		indexes = append(indexes, index)
+	}
+
 	switch config.Type {
 	case "mem":
-		return NewMem(), nil
+		return NewMem(indexes), nil
 	case "psql":
-		return NewPSQL(ctx, config.URL, logger.With("store", "psql"))
+		return NewPSQL(ctx, config.URL, indexes, logger.With("store", "psql"))
 	default:
 		return nil, fmt.Errorf("error instantiating store of type %q: %w", config.Type, ErrInvalidBackend)
 	}
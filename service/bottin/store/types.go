@@ -231,3 +231,13 @@ func prefixEnd(prefix Prefix) Prefix {
 
 	return cpy
 }
+
+// matchesPrefix reports whether the entry at candidate would be returned by
+// a List call with the given prefix and exact arguments.
+func matchesPrefix(prefix Prefix, exact bool, candidate Prefix) bool {
+	if exact {
+		return prefix.Equal(candidate)
+	}
+
+	return prefix.IsPrefixOf(candidate)
+}
@@ -0,0 +1,180 @@
+package bottin
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+// Syntax describes the values allowed for an attribute, following the
+// syntaxes defined in RFC 4517.
+type Syntax struct {
+	OID  string
+	Desc string
+
+	// validate reports whether a value conforms to the syntax. It is nil for
+	// syntaxes that accept any value (i.e., binary ones).
+	validate func(value string) bool
+}
+
+// Validate reports whether value conforms to the syntax.
+func (syntax *Syntax) Validate(value string) bool {
+	return syntax.validate == nil || syntax.validate(value)
+}
+
+// String formats the syntax as an LDAPSyntaxDescription.
+func (syntax *Syntax) String() string {
+	return "( " + syntax.OID + " DESC '" + escapeQDString(syntax.Desc) + "' )"
+}
+
+func nonEmpty(value string) bool {
+	return value != ""
+}
+
+func isDirectoryString(value string) bool {
+	return value != "" && utf8.ValidString(value)
+}
+
+func isIA5String(value string) bool {
+	for i := range len(value) {
+		if value[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isInteger(value string) bool {
+	digits := strings.TrimPrefix(value, "-")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return false
+	}
+
+	// Leading zeros are only allowed for 0 itself, which has no sign.
+	return digits[0] != '0' || value == "0"
+}
+
+func isNumericString(value string) bool {
+	return value != "" && strings.Trim(value, "0123456789 ") == ""
+}
+
+const printableCharacters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789'()+,-./:? "
+
+func isPrintableString(value string) bool {
+	return value != "" && strings.Trim(value, printableCharacters) == ""
+}
+
+func isCountryString(value string) bool {
+	return len(value) == 2 && isPrintableString(value)
+}
+
+func isBoolean(value string) bool {
+	return value == "TRUE" || value == "FALSE"
+}
+
+func isBitString(value string) bool {
+	bits, ok := strings.CutPrefix(value, "'")
+	if !ok {
+		return false
+	}
+
+	bits, ok = strings.CutSuffix(bits, "'B")
+
+	return ok && strings.Trim(bits, "01") == ""
+}
+
+func isDN(value string) bool {
+	_, err := store.ParseDN(value)
+	return err == nil
+}
+
+func isNameAndOptionalUID(value string) bool {
+	if i := strings.LastIndex(value, "#'"); i >= 0 && isBitString(value[i+1:]) {
+		value = value[:i]
+	}
+
+	return isDN(value)
+}
+
+func isGeneralizedTime(value string) bool {
+	_, err := parseGeneralizedTime(value)
+	return err == nil
+}
+
+func isOID(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	// A descriptor starts with a letter and contains letters, digits and hyphens
+	if c := value[0]; (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return strings.Trim(strings.ToLower(value), "abcdefghijklmnopqrstuvwxyz0123456789-") == ""
+	}
+
+	for part := range strings.SplitSeq(value, ".") {
+		if part == "" || strings.Trim(part, "0123456789") != "" || (len(part) > 1 && part[0] == '0') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isUUID(value string) bool {
+	_, err := uuid.Parse(value)
+	return len(value) == 36 && err == nil
+}
+
+const syntaxPrefix = "1.3.6.1.4.1.1466.115.121.1."
+
+// Syntaxes lists the syntaxes known to the server. Values of attributes
+// with a syntax not listed here are not validated.
+var Syntaxes = []*Syntax{
+	{OID: syntaxPrefix + "3", Desc: "Attribute Type Description", validate: nonEmpty},
+	{OID: syntaxPrefix + "5", Desc: "Binary"},
+	{OID: syntaxPrefix + "6", Desc: "Bit String", validate: isBitString},
+	{OID: syntaxPrefix + "7", Desc: "Boolean", validate: isBoolean},
+	{OID: syntaxPrefix + "8", Desc: "Certificate"},
+	{OID: syntaxPrefix + "11", Desc: "Country String", validate: isCountryString},
+	{OID: syntaxPrefix + "12", Desc: "DN", validate: isDN},
+	{OID: syntaxPrefix + "14", Desc: "Delivery Method", validate: nonEmpty},
+	{OID: syntaxPrefix + "15", Desc: "Directory String", validate: isDirectoryString},
+	{OID: syntaxPrefix + "21", Desc: "Enhanced Guide", validate: nonEmpty},
+	{OID: syntaxPrefix + "22", Desc: "Facsimile Telephone Number", validate: nonEmpty},
+	{OID: syntaxPrefix + "23", Desc: "Fax"},
+	{OID: syntaxPrefix + "24", Desc: "Generalized Time", validate: isGeneralizedTime},
+	{OID: syntaxPrefix + "25", Desc: "Guide", validate: nonEmpty},
+	{OID: syntaxPrefix + "26", Desc: "IA5 String", validate: isIA5String},
+	{OID: syntaxPrefix + "27", Desc: "INTEGER", validate: isInteger},
+	{OID: syntaxPrefix + "28", Desc: "JPEG"},
+	{OID: syntaxPrefix + "30", Desc: "Matching Rule Description", validate: nonEmpty},
+	{OID: syntaxPrefix + "34", Desc: "Name And Optional UID", validate: isNameAndOptionalUID},
+	{OID: syntaxPrefix + "36", Desc: "Numeric String", validate: isNumericString},
+	{OID: syntaxPrefix + "37", Desc: "Object Class Description", validate: nonEmpty},
+	{OID: syntaxPrefix + "38", Desc: "OID", validate: isOID},
+	{OID: syntaxPrefix + "39", Desc: "Other Mailbox", validate: nonEmpty},
+	{OID: syntaxPrefix + "40", Desc: "Octet String"},
+	{OID: syntaxPrefix + "41", Desc: "Postal Address", validate: isDirectoryString},
+	{OID: syntaxPrefix + "44", Desc: "Printable String", validate: isPrintableString},
+	{OID: syntaxPrefix + "50", Desc: "Telephone Number", validate: isPrintableString},
+	{OID: syntaxPrefix + "51", Desc: "Teletex Terminal Identifier", validate: nonEmpty},
+	{OID: syntaxPrefix + "52", Desc: "Telex Number", validate: nonEmpty},
+	{OID: syntaxPrefix + "54", Desc: "LDAP Syntax Description", validate: nonEmpty},
+	{OID: syntaxPrefix + "58", Desc: "Substring Assertion", validate: nonEmpty},
+	{OID: "1.3.6.1.1.16.1", Desc: "UUID", validate: isUUID},
+}
+
+// findSyntax looks up a syntax by OID. Unknown syntaxes accept any value.
+func findSyntax(oid string) *Syntax {
+	for _, syntax := range Syntaxes {
+		if syntax.OID == oid {
+			return syntax
+		}
+	}
+
+	return &Syntax{OID: oid, Desc: oid}
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"slices"
-	"strings"
 	"time"
 
 	"github.com/teapotovh/teapot/service/bottin/store"
@@ -18,6 +17,16 @@ var (
 		AttrModifiersName,
 		AttrModifyTimestamp,
 		"entrycsn",
+		AttrSubschemaSubentry,
+		AttrAttributeTypes,
+		AttrObjectClasses,
+		AttrLDAPSyntaxes,
+		AttrMatchingRules,
+		AttrNamingContexts,
+		AttrSupportedControl,
+		AttrSupportedExtension,
+		AttrSupportedFeatures,
+		AttrSupportedLDAPVersion,
 	}
 
 	ErrMememberOfDefinition = errors.New(
@@ -43,13 +52,9 @@ func canUpdateAttribute(attr store.AttributeKey) error {
 }
 
 func genTimestamp() string {
-	return time.Now().Format("20060102150405Z")
+	return time.Now().UTC().Format("20060102150405Z")
 }
 
 func valueMatch(attr store.AttributeKey, val1, val2 string) bool {
-	if attr.EqualFold(AttrUserPassword) {
-		return val1 == val2
-	} else {
-		return strings.EqualFold(val1, val2)
-	}
+	return matchingRuleFor(attr).Equal(val1, val2)
 }
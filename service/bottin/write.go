@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"slices"
 
 	"github.com/google/uuid"
@@ -14,11 +15,26 @@ import (
 )
 
 var (
-	ErrHasChildren   = errors.New("has children")
-	ErrNotFound      = errors.New("not found")
-	ErrAlreadyExists = errors.New("already exists")
+	ErrHasChildren       = errors.New("has children")
+	ErrNotFound          = errors.New("not found")
+	ErrAlreadyExists     = errors.New("already exists")
+	ErrRenameBase        = errors.New("the base entry cannot be renamed")
+	ErrRenameUnderItself = errors.New("an entry cannot be moved under itself")
 )
 
+// checkPasswordHashes rejects password hashes which binds would refuse to
+// match, as they are malformed or their parameters are out of bounds. Values
+// without a known scheme are stored as they are, as they match no password.
+func checkPasswordHashes(values []string) error {
+	for _, value := range values {
+		if err := validateHash(value); err != nil && !errors.Is(err, ErrNoValidHash) {
+			return fmt.Errorf("(%w) invalid password hash: %w", ldapsrv.ErrInvalidAttributeSyntax, err)
+		}
+	}
+
+	return nil
+}
+
 //nolint:gocyclo
 func (server *Bottin) Add(ctx context.Context, state State, r ldap.AddRequest) (State, error) {
 	dn, err := server.parseDN(string(r.Entry()), false)
@@ -87,6 +103,12 @@ func (server *Bottin) Add(ctx context.Context, state State, r ldap.AddRequest) (
 			return state, fmt.Errorf("(%w) %w", ldapsrv.ErrObjectClassViolation, err)
 		}
 
+		if key.EqualFold(AttrUserPassword) {
+			if err := checkPasswordHashes(vals); err != nil {
+				return state, err
+			}
+		}
+
 		if key.EqualFold(AttrMember) {
 			// If they are writing a member list, we have to check they are adding valid members
 			// Also, rewrite member list to use canonical DN syntax (no spaces, all lowercase)
@@ -136,13 +158,20 @@ func (server *Bottin) Add(ctx context.Context, state State, r ldap.AddRequest) (
 	attrs[AttrCreateTimestamp] = []string{genTimestamp()}
 	attrs[AttrEntryUUID] = []string{uuid.String()}
 
+	// This ensures the dn[].Type attribute is set to the appropriate value
+	entry := store.NewEntry(dn, attrs)
+	if err := server.checkSchema(entry); err != nil {
+		return state, err
+	}
+
 	tx, err := server.store.Begin(ctx)
 	if err != nil {
 		return state, fmt.Errorf("(%w) error while beginning transaction: %w", ldapsrv.ErrOperationsError, err)
 	}
 
-	// This ensures the dn[].Type attribute is set to the appropriate value
-	entry := store.NewEntry(dn, attrs)
+	// Rolling back a committed transaction is a no-op
+	defer func() { _ = tx.Rollback(ctx) }()
+
 	if err = tx.Store(ctx, entry); err != nil {
 		return state, fmt.Errorf("(%w) error while storing entry: %w", ldapsrv.ErrOperationsError, err)
 	}
@@ -218,6 +247,9 @@ func (server *Bottin) Del(ctx context.Context, state State, r ldap.DelRequest) (
 		return state, fmt.Errorf("(%w) error while beginning transaction: %w", ldapsrv.ErrOperationsError, err)
 	}
 
+	// Rolling back a committed transaction is a no-op
+	defer func() { _ = tx.Rollback(ctx) }()
+
 	// Delete the LDAP entry
 	if err = tx.Delete(ctx, dn); err != nil {
 		return state, fmt.Errorf("(%w) error while deleting entry: %w", ldapsrv.ErrOperationsError, err)
@@ -297,8 +329,12 @@ func (server *Bottin) Modify(ctx context.Context, state State, r ldap.ModifyRequ
 		delMembers []store.DN
 	)
 
-	// Produce new entry values to be saved
-	attrs := make(store.Attributes)
+	// Produce new entry values to be saved, starting from a copy of the
+	// current ones so that the changes can be applied in place
+	attrs := make(store.Attributes, len(prevEntry.Attributes))
+	for attr, values := range prevEntry.Attributes {
+		attrs[attr] = slices.Clone(values)
+	}
 
 	for _, change := range r.Changes() {
 		attr := store.NewAttributeKey(string(change.Modification().Type_()))
@@ -341,6 +377,12 @@ func (server *Bottin) Modify(ctx context.Context, state State, r ldap.ModifyRequ
 			)
 		}
 
+		if attr.EqualFold(AttrUserPassword) && change.Operation() != ldapsrv.ModifyRequestChangeOperationDelete {
+			if err := checkPasswordHashes(changeValues); err != nil {
+				return state, err
+			}
+		}
+
 		// If we are changing ATTR_MEMBER, rewrite all values to canonical form
 		if attr.EqualFold(AttrMember) {
 			for i := range changeValues {
@@ -353,16 +395,6 @@ func (server *Bottin) Modify(ctx context.Context, state State, r ldap.ModifyRequ
 			}
 		}
 
-		// If we don't yet have a new value for this attr,
-		// but one existed before, initialize entry[attr] to the old value
-		// so that later on what we do is simply modify entry[attr] in place
-		// (this allows to handle sequences of several changes on the same attr)
-		if _, ok := attrs[attr]; !ok {
-			if _, ok := prevEntry.Attributes[attr]; ok {
-				attrs[attr] = prevEntry.Attributes[attr]
-			}
-		}
-
 		// Apply effective modification on entry[attr]
 		if change.Operation() == ldapsrv.ModifyRequestChangeOperationAdd {
 			for _, val := range changeValues {
@@ -464,19 +496,31 @@ func (server *Bottin) Modify(ctx context.Context, state State, r ldap.ModifyRequ
 			return state, fmt.Errorf(
 				"(%w) cannot remove all objectclass values", ldapsrv.ErrInsufficientAccessRights)
 		}
+
+		// Attributes without values are removed from the entry
+		if len(v) == 0 {
+			delete(attrs, k)
+		}
 	}
 
 	// Now, the modification has been processed and accepted and we want to commit it
 	attrs[AttrModifiersName] = []string{state.User().user}
 	attrs[AttrModifyTimestamp] = []string{genTimestamp()}
 
+	entry := store.NewEntry(prevEntry.DN, attrs)
+	if err := server.checkSchema(entry); err != nil {
+		return state, err
+	}
+
 	tx, err := server.store.Begin(ctx)
 	if err != nil {
 		return state, fmt.Errorf("(%w) error while beginning transaction: %w", ldapsrv.ErrOperationsError, err)
 	}
 
+	// Rolling back a committed transaction is a no-op
+	defer func() { _ = tx.Rollback(ctx) }()
+
 	// Save the edited values
-	entry := store.NewEntry(prevEntry.DN, attrs)
 	if err = tx.Store(ctx, entry); err != nil {
 		return state, fmt.Errorf("(%w) error while storing updated entry: %w", ldapsrv.ErrOperationsError, err)
 	}
@@ -500,3 +544,213 @@ func (server *Bottin) Modify(ctx context.Context, state State, r ldap.ModifyRequ
 
 	return state, nil
 }
+
+// ModifyDN renames and/or moves an entry together with its whole subtree.
+// The RDN attribute is updated to the new RDN value, while all entryUUID and
+// other operational attributes are preserved. All member/memberOf references
+// to the moved entries are rewritten in the same transaction.
+//
+//nolint:gocyclo
+func (server *Bottin) ModifyDN(ctx context.Context, state State, r ldap.ModifyDNRequest) (State, error) {
+	dn, err := server.parseDN(string(r.Entry()), false)
+	if err != nil {
+		return state, fmt.Errorf("(%w) %w", ldapsrv.ErrInvalidDNSyntax, err)
+	}
+
+	newRDN, err := store.ParseComponent(string(r.NewRDN()))
+	if err != nil {
+		return state, fmt.Errorf("(%w) %w", ldapsrv.ErrInvalidDNSyntax, err)
+	}
+
+	if dn.Equal(server.baseDN) {
+		return state, fmt.Errorf("(%w) cannot rename the base entry: %w", ldapsrv.ErrUnwillingToPerform, ErrRenameBase)
+	}
+
+	newParent := dn.Parent()
+	if r.NewSuperior() != nil {
+		newParent, err = server.parseDN(string(*r.NewSuperior()), false)
+		if err != nil {
+			return state, fmt.Errorf("(%w) %w", ldapsrv.ErrInvalidDNSyntax, err)
+		}
+	}
+
+	newDN := newParent.Sub(newRDN)
+
+	// Check permissions: a rename is equivalent to deleting the old entry
+	// and adding the new one.
+	if !server.acl.Check(state.User(), "delete", dn, []store.AttributeKey{}) ||
+		!server.acl.Check(state.User(), "add", newDN, []store.AttributeKey{}) {
+		return state, fmt.Errorf(
+			"could not rename %q to %q: %w",
+			dn,
+			newDN,
+			ldapsrv.ErrInsufficientAccessRights,
+		)
+	}
+
+	server.logger.InfoContext(ctx, "renaming entry", "dn", dn, "newdn", newDN)
+
+	if newDN.Equal(dn) {
+		return state, nil
+	}
+
+	if dn.Prefix().IsPrefixOf(newDN.Prefix()) {
+		return state, fmt.Errorf(
+			"(%w) cannot move %q under itself: %w",
+			ldapsrv.ErrUnwillingToPerform,
+			dn,
+			ErrRenameUnderItself,
+		)
+	}
+
+	exists, err := server.existsEntry(ctx, newDN)
+	if err != nil {
+		return state, fmt.Errorf("(%w) %w", ldapsrv.ErrOperationsError, err)
+	}
+
+	if exists {
+		return state, fmt.Errorf("(%w) %q: %w", ldapsrv.ErrEntryAlreadyExists, newDN, ErrAlreadyExists)
+	}
+
+	parentExists, err := server.existsEntry(ctx, newParent)
+	if err != nil {
+		return state, fmt.Errorf("(%w) %w", ldapsrv.ErrOperationsError, err)
+	}
+
+	if !parentExists {
+		return state, fmt.Errorf(
+			"(%w) parent object with DN %q does not exist",
+			ldapsrv.ErrNoSuchObject,
+			newParent)
+	}
+
+	// Collect the entry with its whole subtree, and compute the new DN of each.
+	subtree, err := server.store.List(ctx, dn.Prefix(), false)
+	if err != nil {
+		return state, fmt.Errorf(
+			"(%w) error while fetching subtree of %q from store: %w",
+			ldapsrv.ErrOperationsError,
+			dn.String(),
+			err,
+		)
+	}
+
+	if len(subtree) == 0 {
+		return state, fmt.Errorf("(%w) error fetching entry: %w", ldapsrv.ErrNoSuchObject, ErrNotFound)
+	}
+
+	renames := map[string]store.DN{}
+	// affected holds all entries that need to be written, keyed by their old DN.
+	affected := map[string]store.Entry{}
+
+	for _, entry := range subtree {
+		relative := slices.Clone(entry.DN[:len(entry.DN)-len(dn)])
+		renamed := newDN.Sub(relative...)
+
+		renames[entry.DN.String()] = renamed
+		affected[entry.DN.String()] = entry
+	}
+
+	// Fetch all entries outside of the subtree that reference a renamed entry.
+	// Dangling references do not point back to the renamed entries, and are
+	// left untouched.
+	for _, entry := range subtree {
+		for _, attr := range []store.AttributeKey{AttrMember, AttrMemberOf} {
+			for _, ref := range entry.Get(attr) {
+				refDN, err := server.parseDN(ref, false)
+				if err != nil {
+					server.logger.WarnContext(ctx, "skipping invalid reference", "dn", entry.DN, "ref", ref, "err", err)
+					continue
+				}
+
+				if _, ok := affected[refDN.String()]; ok {
+					continue
+				}
+
+				refEntry, err := server.getEntry(ctx, refDN)
+				if errors.Is(err, ldapsrv.ErrNoSuchObject) {
+					server.logger.WarnContext(ctx, "skipping dangling reference", "dn", entry.DN, "ref", ref)
+					continue
+				} else if err != nil {
+					return state, err
+				}
+
+				affected[refDN.String()] = *refEntry
+			}
+		}
+	}
+
+	// Compute and validate all renamed entries before touching the store, so
+	// that a schema violation does not leave a transaction behind.
+	renamedEntries := make([]store.Entry, 0, len(affected))
+
+	for old, entry := range affected {
+		attrs := maps.Clone(entry.Attributes)
+
+		for _, attr := range []store.AttributeKey{AttrMember, AttrMemberOf} {
+			values := attrs.Get(attr)
+			if len(values) == 0 {
+				continue
+			}
+
+			attrs[attr] = rewriteReferences(values, renames)
+		}
+
+		entryDN := entry.DN
+		if renamed, ok := renames[old]; ok {
+			entryDN = renamed
+		}
+
+		if entry.DN.Equal(dn) {
+			// Unless requested otherwise, keep the old RDN value as a regular
+			// attribute. Note that RDN attributes are single-valued in bottin,
+			// so an old value of the same type as the new RDN is always replaced.
+			oldRDN := dn[0]
+			if r.DeleteOldRDN() {
+				delete(attrs, store.NewAttributeKey(oldRDN.Type))
+			}
+
+			attrs[AttrModifiersName] = []string{state.User().user}
+			attrs[AttrModifyTimestamp] = []string{genTimestamp()}
+		}
+
+		renamedEntry := store.NewEntry(entryDN, attrs)
+
+		// Only the renamed entry changes its attributes, as the new RDN
+		// attribute must be allowed by its object classes
+		if entry.DN.Equal(dn) {
+			if err := server.checkSchema(renamedEntry); err != nil {
+				return state, err
+			}
+		}
+
+		renamedEntries = append(renamedEntries, renamedEntry)
+	}
+
+	tx, err := server.store.Begin(ctx)
+	if err != nil {
+		return state, fmt.Errorf("(%w) error while beginning transaction: %w", ldapsrv.ErrOperationsError, err)
+	}
+
+	// Rolling back a committed transaction is a no-op
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Delete the old entries first, so that the new ones can be stored afterwards.
+	for _, entry := range subtree {
+		if err := tx.Delete(ctx, entry.DN); err != nil {
+			return state, fmt.Errorf("(%w) error while deleting entry: %w", ldapsrv.ErrOperationsError, err)
+		}
+	}
+
+	for _, entry := range renamedEntries {
+		if err := tx.Store(ctx, entry); err != nil {
+			return state, fmt.Errorf("(%w) error while storing renamed entry: %w", ldapsrv.ErrOperationsError, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return state, fmt.Errorf("(%w) could not commit transaction: %w", ldapsrv.ErrOperationsError, err)
+	}
+
+	return state, nil
+}
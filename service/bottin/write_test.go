@@ -0,0 +1,111 @@
+package bottin
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/teapotovh/teapot/service/bottin/store"
+)
+
+func TestModifyDN(t *testing.T) {
+	t.Parallel()
+
+	server := newTestBottin(t,
+		testEntry(t, "ou=users,dc=teapot,dc=ovh", nil),
+		testEntry(t, "ou=groups,dc=teapot,dc=ovh", nil),
+		testEntry(t, "uid=alice,ou=users,dc=teapot,dc=ovh", map[string][]string{
+			"memberOf": {
+				"cn=admins,ou=groups,dc=teapot,dc=ovh",
+				"cn=removed,ou=groups,dc=teapot,dc=ovh",
+				"cn=elsewhere,dc=example,dc=com",
+			},
+		}),
+		testEntry(t, "cn=admins,ou=groups,dc=teapot,dc=ovh", map[string][]string{
+			"member": {"uid=alice,ou=users,dc=teapot,dc=ovh"},
+		}),
+	)
+	state := State{user: &User{user: testBaseDN}}
+	ctx := context.Background()
+
+	r := modifyDNRequest(t, "uid=alice,ou=users,dc=teapot,dc=ovh", "uid=alicia", true, "")
+	if _, err := server.ModifyDN(ctx, state, r); err != nil {
+		t.Fatalf("error renaming entry: %s", err)
+	}
+
+	if _, err := server.getEntry(ctx, mustParseDN(t, "uid=alice,ou=users,dc=teapot,dc=ovh")); err == nil {
+		t.Errorf("the old entry still exists")
+	}
+
+	renamed, err := server.getEntry(ctx, mustParseDN(t, "uid=alicia,ou=users,dc=teapot,dc=ovh"))
+	if err != nil {
+		t.Fatalf("error fetching renamed entry: %s", err)
+	}
+
+	if uid := renamed.Get("uid"); !slices.Equal(uid, store.AttributeValue{"alicia"}) {
+		t.Errorf("GOT uid %v, EXPECTED [alicia]", uid)
+	}
+
+	// Dangling references are kept as they are.
+	if memberOf := renamed.Get(AttrMemberOf); len(memberOf) != 3 {
+		t.Errorf("GOT memberOf %v, EXPECTED 3 values", memberOf)
+	}
+
+	group, err := server.getEntry(ctx, mustParseDN(t, "cn=admins,ou=groups,dc=teapot,dc=ovh"))
+	if err != nil {
+		t.Fatalf("error fetching group: %s", err)
+	}
+
+	expected := store.AttributeValue{"uid=alicia,ou=users,dc=teapot,dc=ovh"}
+	if member := group.Get(AttrMember); !slices.Equal(member, expected) {
+		t.Errorf("GOT member %v, EXPECTED %v", member, expected)
+	}
+}
+
+func TestAddPasswordHash(t *testing.T) {
+	t.Parallel()
+
+	server := newTestBottin(t, testEntry(t, "ou=users,dc=teapot,dc=ovh", nil))
+	state := State{user: &User{user: testBaseDN}}
+	ctx := context.Background()
+
+	hash, err := hashPassword(ARGON2, "secret")
+	if err != nil {
+		t.Fatalf("error hashing password: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		err  error
+	}{
+		{name: "valid", hash: hash},
+		{name: "no scheme", hash: "secret"},
+		{name: "malformed", hash: "{PBKDF2-SHA512}c2FsdA", err: ErrInvalidHash},
+		{
+			name: "over cap",
+			hash: "{PBKDF2-SHA512}100000000$c2FsdA$aGFzaA",
+			err:  ErrInvalidPBKDF2Params,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := addRequest(t, "uid=user"+strconv.Itoa(i)+",ou=users,dc=teapot,dc=ovh", map[string][]string{
+				"objectClass":  {"inetOrgPerson"},
+				"sn":           {"user"},
+				"cn":           {"user"},
+				"userPassword": {test.hash},
+			})
+
+			_, err := server.Add(ctx, state, r)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("GOT error %v EXPECTED %v", err, test.err)
+			}
+		})
+	}
+}
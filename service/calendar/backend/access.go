@@ -0,0 +1,283 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/teapotovh/teapot/lib/observability"
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	daverr "github.com/teapotovh/teapot/lib/webdav/error"
+	"github.com/teapotovh/teapot/service/calendar/store"
+)
+
+// groupPrincipalPrefix is the prefix of the paths of group principals, which
+// are used to refer to LDAP groups in access control lists.
+const groupPrincipalPrefix = "/groups/"
+
+// access is the level of access of a user to a calendar.
+type access int
+
+const (
+	accessNone access = iota
+	accessRead
+	accessReadWrite
+	accessOwner
+)
+
+func shareAccess(a store.Access) access {
+	switch a {
+	case store.AccessRead:
+		return accessRead
+	case store.AccessReadWrite:
+		return accessReadWrite
+	default:
+		return accessNone
+	}
+}
+
+// privileges returns the WebDAV privileges granted by an access level.
+func (a access) privileges() []caldav.Privilege {
+	switch a {
+	case accessOwner:
+		return []caldav.Privilege{caldav.PrivilegeRead, caldav.PrivilegeWrite, caldav.PrivilegeWriteACL}
+	case accessReadWrite:
+		return []caldav.Privilege{caldav.PrivilegeRead, caldav.PrivilegeWrite}
+	case accessRead:
+		return []caldav.Privilege{caldav.PrivilegeRead}
+	default:
+		return []caldav.Privilege{}
+	}
+}
+
+// grantee is a user whose access to calendars is being checked. Their LDAP
+// groups are only looked up once a calendar shared with a group is found.
+type grantee struct {
+	username string
+	groups   []string
+	resolved bool
+}
+
+func (b *Backend) currentGrantee(ctx context.Context) (*grantee, error) {
+	up, err := b.CurrentUserPrincipal(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get user principal: %w", err)
+	}
+
+	return &grantee{username: strings.TrimPrefix(up, "/")}, nil
+}
+
+// groupName returns the name of an LDAP group, i.e., the value of the first
+// RDN of its DN.
+func groupName(dn string) string {
+	rdn, _, _ := strings.Cut(dn, ",")
+	_, name, _ := strings.Cut(rdn, "=")
+
+	return name
+}
+
+func (b *Backend) granteeGroups(ctx context.Context, g *grantee) ([]string, error) {
+	if g.resolved {
+		return g.groups, nil
+	}
+
+	user, err := b.lookupUser(ctx, g.username)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dn := range user.Groups {
+		g.groups = append(g.groups, groupName(dn))
+	}
+
+	g.resolved = true
+
+	return g.groups, nil
+}
+
+// calendarAccess returns the level of access of a grantee to a calendar. The
+// owner has full access, while other users get the highest level of access
+// granted to them or any of their groups.
+func (b *Backend) calendarAccess(ctx context.Context, g *grantee, cal *store.Calendar) (access, error) {
+	owner, err := calendarOwner(string(cal.Path))
+	if err != nil {
+		return accessNone, err
+	} else if owner == g.username {
+		return accessOwner, nil
+	}
+
+	result := accessNone
+
+	for _, share := range cal.Metadata.Shares {
+		switch share.Kind {
+		case store.ShareKindUser:
+			if share.Name != g.username {
+				continue
+			}
+		case store.ShareKindGroup:
+			groups, err := b.granteeGroups(ctx, g)
+			if err != nil {
+				return accessNone, err
+			}
+
+			if !slices.Contains(groups, share.Name) {
+				continue
+			}
+		default:
+			continue
+		}
+
+		result = max(result, shareAccess(share.Access))
+	}
+
+	return result, nil
+}
+
+// authorize fetches the calendar at path, ensuring that the current user has
+// at least the wanted level of access to it.
+func (b *Backend) authorize(ctx context.Context, path store.Path, want access) (*store.Calendar, access, error) {
+	g, err := b.currentGrantee(ctx)
+	if err != nil {
+		return nil, accessNone, err
+	}
+
+	cal, err := b.store.GetCalendar(ctx, path)
+	if err != nil {
+		return nil, accessNone, fmt.Errorf("error while getting calendar at path %q in storage: %w", path, err)
+	}
+
+	got, err := b.calendarAccess(ctx, g, cal)
+	if err != nil {
+		return nil, accessNone, fmt.Errorf("error while checking access to calendar at path %q: %w", path, err)
+	}
+
+	if got < want {
+		return nil, accessNone, daverr.HTTPErrorf(http.StatusForbidden, "calendar: insufficient privileges on %q", path)
+	}
+
+	return cal, got, nil
+}
+
+// calendarACL returns the access control list of a calendar, which grants
+// all privileges to its owner and the shared ones to other principals.
+func calendarACL(cal *store.Calendar) ([]caldav.ACE, error) {
+	owner, err := calendarOwner(string(cal.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	acl := []caldav.ACE{{
+		Principal:  "/" + owner,
+		Privileges: []caldav.Privilege{caldav.PrivilegeAll},
+		Protected:  true,
+	}}
+
+	for _, share := range cal.Metadata.Shares {
+		principal := "/" + share.Name
+		if share.Kind == store.ShareKindGroup {
+			principal = groupPrincipalPrefix + share.Name
+		}
+
+		acl = append(acl, caldav.ACE{Principal: principal, Privileges: shareAccess(share.Access).privileges()})
+	}
+
+	return acl, nil
+}
+
+// aceShare turns an access control entry into a share. Write privileges
+// imply read ones, as calendars cannot be written blindly.
+func (b *Backend) aceShare(ctx context.Context, ace caldav.ACE) (*store.Share, error) {
+	var share store.Share
+
+	for _, privilege := range ace.Privileges {
+		switch privilege {
+		case caldav.PrivilegeRead:
+			if share.Access == "" {
+				share.Access = store.AccessRead
+			}
+		case caldav.PrivilegeWrite, caldav.PrivilegeAll:
+			share.Access = store.AccessReadWrite
+		default:
+			return nil, caldav.NewACLPreconditionError(caldav.ACLPreconditionNotSupportedPrivilege)
+		}
+	}
+
+	principal := strings.TrimSuffix(ace.Principal, "/")
+	if name, ok := strings.CutPrefix(principal, groupPrincipalPrefix); ok {
+		if name == "" || strings.Contains(name, "/") {
+			return nil, caldav.NewACLPreconditionError(caldav.ACLPreconditionRecognizedPrincipal)
+		}
+
+		group, err := b.lookupGroup(ctx, name)
+		if err != nil {
+			b.logger.WarnContext(ctx, "could not resolve ACL principal", "principal", ace.Principal, "err", err)
+			return nil, caldav.NewACLPreconditionError(caldav.ACLPreconditionRecognizedPrincipal)
+		}
+
+		share.Kind = store.ShareKindGroup
+		share.Name = group.Name
+
+		return &share, nil
+	}
+
+	name, ok := strings.CutPrefix(principal, "/")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return nil, caldav.NewACLPreconditionError(caldav.ACLPreconditionRecognizedPrincipal)
+	}
+
+	user, err := b.lookupUser(ctx, name)
+	if err != nil {
+		b.logger.WarnContext(ctx, "could not resolve ACL principal", "principal", ace.Principal, "err", err)
+		return nil, caldav.NewACLPreconditionError(caldav.ACLPreconditionRecognizedPrincipal)
+	}
+
+	share.Kind = store.ShareKindUser
+	share.Name = user.Username
+
+	return &share, nil
+}
+
+func (b *Backend) SetCalendarACL(ctx context.Context, path string, acl []caldav.ACE) (err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "SetCalendarACL")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	cal, _, err := b.authorize(ctx, normalizePath(path), accessOwner)
+	if err != nil {
+		return err
+	}
+
+	owner, err := calendarOwner(string(cal.Path))
+	if err != nil {
+		return err
+	}
+
+	shares := make([]store.Share, 0, len(acl))
+
+	for _, ace := range acl {
+		share, err := b.aceShare(ctx, ace)
+		if err != nil {
+			return err
+		} else if share.Access == "" {
+			continue
+		}
+
+		// The owner is granted all privileges by a protected entry
+		if share.Kind == store.ShareKindUser && share.Name == owner {
+			return caldav.NewACLPreconditionError(caldav.ACLPreconditionNoProtectedACEConflict)
+		}
+
+		shares = append(shares, *share)
+	}
+
+	cal.Metadata.Shares = shares
+	if err := b.store.UpdateCalendar(ctx, *cal); err != nil {
+		return fmt.Errorf("error while updating calendar at path %q in storage: %w", path, err)
+	}
+
+	return nil
+}
+
+// Ensure Backend implements caldav.ACLBackend.
+var _ caldav.ACLBackend = &Backend{}
@@ -8,12 +8,14 @@ import (
 	"net/http"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	ics "github.com/arran4/golang-ical"
 	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/teapotovh/teapot/lib/ldap"
 	"github.com/teapotovh/teapot/lib/observability"
 	"github.com/teapotovh/teapot/lib/webdav/caldav"
 	daverr "github.com/teapotovh/teapot/lib/webdav/error"
@@ -35,15 +37,33 @@ type Backend struct {
 
 	logger *slog.Logger
 
-	store store.Store
+	store       store.Store
+	ldapFactory *ldap.Factory
+	quota       QuotaConfig
+
+	quotaCacheMu sync.Mutex
+	quotaCache   map[string]cachedQuota
+
+	metrics metrics
+}
+
+type BackendConfig struct {
+	Quota QuotaConfig
 }
 
-func NewBackend(store store.Store, logger *slog.Logger) *Backend {
-	return &Backend{
+func NewBackend(config BackendConfig, store store.Store, ldapFactory *ldap.Factory, logger *slog.Logger) *Backend {
+	backend := Backend{
 		logger: logger,
 
-		store: store,
+		store:       store,
+		ldapFactory: ldapFactory,
+		quota:       config.Quota,
+		quotaCache:  map[string]cachedQuota{},
 	}
+
+	backend.initMetrics()
+
+	return &backend
 }
 
 func (b *Backend) CalendarHomeSetPath(ctx context.Context) (path string, err error) {
@@ -95,6 +115,8 @@ func (b *Backend) CreateCalendar(ctx context.Context, calendar *caldav.Calendar)
 
 	if len(calendar.SupportedComponentSet) <= 0 {
 		calendar.SupportedComponentSet = SupportedComponentSet
+	} else if err := checkSupportedComponentSet(calendar.SupportedComponentSet); err != nil {
+		return err
 	}
 
 	err = b.store.CreateCalendar(ctx, caldavCalendarToStoreCalendar(calendar))
@@ -109,35 +131,93 @@ func (b *Backend) ListCalendars(ctx context.Context) (calendars []caldav.Calenda
 	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "ListCalendars")
 	defer func() { observability.SpanEnd(span, err) }()
 
-	path, err := b.CalendarHomeSetPath(ctx)
+	g, err := b.currentGrantee(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calendars shared with the user are listed in their home set along with
+	// the ones they own, at their original paths. They are found through the
+	// shares naming the user or their groups, without scanning all calendars.
+	home := "/" + g.username + "/"
+
+	cals, err := b.store.ListCalendars(ctx, store.Path(home))
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching calendars from storage: %w", err)
+	}
+
+	groups, err := b.granteeGroups(ctx, g)
 	if err != nil {
-		return nil, fmt.Errorf("could not get home-set path: %w", err)
+		return nil, err
 	}
 
-	cals, err := b.store.ListCalendars(ctx, normalizePath(path))
+	shared, err := b.store.ListSharedCalendars(ctx, g.username, groups)
 	if err != nil {
-		return nil, fmt.Errorf("error while fetching calendars at path %q from storage: %w", path, err)
+		return nil, fmt.Errorf("error while fetching shared calendars from storage: %w", err)
+	}
+
+	for _, cal := range shared {
+		if !strings.HasPrefix(string(cal.Path), home) {
+			cals = append(cals, cal)
+		}
 	}
 
 	for _, cal := range cals {
-		calendars = append(calendars, storeCalendarToCaldavCalendar(cal))
+		a, err := b.calendarAccess(ctx, g, &cal)
+		if err != nil {
+			return nil, fmt.Errorf("error while checking access to calendar at path %q: %w", cal.Path, err)
+		} else if a == accessNone {
+			continue
+		}
+
+		calendar, err := b.caldavCalendar(ctx, &cal, a)
+		if err != nil {
+			return nil, err
+		}
+
+		calendars = append(calendars, *calendar)
 	}
 
 	return calendars, nil
 }
 
+// caldavCalendar converts a calendar from the store, advertising the
+// privileges of the current user on it. Only owners can read its ACL.
+func (b *Backend) caldavCalendar(ctx context.Context, cal *store.Calendar, a access) (*caldav.Calendar, error) {
+	owner, err := calendarOwner(string(cal.Path))
+	if err != nil {
+		return nil, err
+	}
+
+	c := storeCalendarToCaldavCalendar(*cal)
+	c.Owner = "/" + owner
+	c.Privileges = a.privileges()
+
+	if a == accessOwner {
+		c.ACL, err = calendarACL(cal)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.SyncToken, err = b.syncToken(ctx, cal.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
 func (b *Backend) GetCalendar(ctx context.Context, path string) (calendar *caldav.Calendar, err error) {
 	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "GetCalendar")
 	defer func() { observability.SpanEnd(span, err) }()
 
-	cal, err := b.store.GetCalendar(ctx, normalizePath(path))
+	cal, a, err := b.authorize(ctx, normalizePath(path), accessRead)
 	if err != nil {
-		return nil, fmt.Errorf("error while getting calendar at path %q in storage: %w", path, err)
+		return nil, err
 	}
 
-	c := storeCalendarToCaldavCalendar(*cal)
-
-	return &c, nil
+	return b.caldavCalendar(ctx, cal, a)
 }
 
 func caldavObjectToStoreObject(
@@ -190,7 +270,28 @@ func (b *Backend) PutCalendarObject(
 		return nil, ErrUnexpectedNilObject
 	}
 
-	if opts != nil {
+	cal, _, err := b.authorize(ctx, normalizePath(path).Dir(), accessReadWrite)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, daverr.HTTPErrorf(
+			http.StatusConflict,
+			"calendar: no calendar at path %q",
+			normalizePath(path).Dir(),
+		)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := checkSupportedComponents(cal, calendar); err != nil {
+		return nil, err
+	}
+
+	prev, err := b.store.GetCalendarObject(ctx, normalizePath(path))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("error while checking for previous object at path %q: %w", path, err)
+	}
+	// We ignore NotFound errors, insertion is safe on the first insertion of an object
+
+	if opts != nil && prev != nil {
 		matchers := make([]ETagMatcher, 0, 2)
 		if opts.IfMatch.IsSet() {
 			matchers = append(matchers, ETagMatcher(opts.IfMatch.MatchETag))
@@ -202,26 +303,16 @@ func (b *Backend) PutCalendarObject(
 
 		matcher := AndETagMatch(matchers...)
 
-		obj, err := b.store.GetCalendarObject(ctx, normalizePath(path))
+		match, err := matcher(prev.ETag)
 		if err != nil {
-			if !errors.Is(err, store.ErrNotFound) {
-				return nil, fmt.Errorf("error while checking for previous object at path %q: %w", path, err)
-			}
-			// We ignore NotFound errors, insertion is safe on the first insertion of an object
-		} else {
-			etag := obj.ETag
-
-			match, err := matcher(etag)
-			if err != nil {
-				return nil, &daverr.HTTPError{
-					Code: http.StatusPreconditionFailed,
-					Err:  fmt.Errorf("error while matching etag: %w", err),
-				}
+			return nil, &daverr.HTTPError{
+				Code: http.StatusPreconditionFailed,
+				Err:  fmt.Errorf("error while matching etag: %w", err),
 			}
+		}
 
-			if !match {
-				return nil, ErrETagDidNotMatch
-			}
+		if !match {
+			return nil, ErrETagDidNotMatch
 		}
 	}
 
@@ -230,11 +321,22 @@ func (b *Backend) PutCalendarObject(
 		return nil, fmt.Errorf("could not convert caldav Object at path %q into store Object: %w", path, err)
 	}
 
+	var objects, bytes int64 = 1, objPtr.Size()
+	if prev != nil {
+		objects, bytes = 0, bytes-prev.Size()
+	}
+
+	if err := b.checkQuota(ctx, cal.Path, objects, bytes); err != nil {
+		return nil, err
+	}
+
 	obj := *objPtr
 	if err := b.store.CreateCalendarObject(ctx, obj); err != nil {
 		return nil, fmt.Errorf("error while creating calendar object at path %q in storage: %w", path, err)
 	}
 
+	b.updateUsage(ctx, obj.Path)
+
 	object, err = storeObjectToCaldavObject(ctx, obj)
 	if err != nil {
 		return nil, fmt.Errorf("error while converting stored object back to a caldav CalendarObject: %w", err)
@@ -251,6 +353,10 @@ func (b *Backend) GetCalendarObject(
 	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "GetCalendarObject")
 	defer func() { observability.SpanEnd(span, err) }()
 
+	if _, _, err := b.authorize(ctx, normalizePath(path).Dir(), accessRead); err != nil {
+		return nil, err
+	}
+
 	obj, err := b.store.GetCalendarObject(ctx, normalizePath(path))
 	if err != nil {
 		return nil, fmt.Errorf("error while fetching calendar object at path %q from storage: %w", path, err)
@@ -263,10 +369,10 @@ func (b *Backend) GetCalendarObject(
 		return nil, fmt.Errorf("error while converting object at path %q to a caldav CalendarObject: %w", obj.Path, err)
 	}
 
-	// object, err = mapCalendarObject(object, req)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("error while applying filters and maps to calendar object: %w", err)
-	// }
+	object, err = mapCalendarObject(object, req)
+	if err != nil {
+		return nil, fmt.Errorf("error while applying maps to calendar object at path %q: %w", obj.Path, err)
+	}
 
 	return object, nil
 }
@@ -279,6 +385,10 @@ func (b *Backend) ListCalendarObjects(
 	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "ListCalendarObjects")
 	defer func() { observability.SpanEnd(span, err) }()
 
+	if _, _, err := b.authorize(ctx, normalizePath(path), accessRead); err != nil {
+		return nil, err
+	}
+
 	return b.listCalendarObjects(ctx, path, req)
 }
 
@@ -290,9 +400,24 @@ func (b *Backend) QueryCalendarObjects(
 	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "QueryCalendarObjects")
 	defer func() { observability.SpanEnd(span, err) }()
 
-	objects, err = b.listCalendarObjects(ctx, path, &query.CompRequest)
+	if _, _, err := b.authorize(ctx, normalizePath(path), accessRead); err != nil {
+		return nil, err
+	}
+
+	// Candidates are narrowed down through the index of the store, so that
+	// only the objects which may match are fetched and parsed.
+	objs, err := b.store.QueryCalendarObjects(ctx, normalizePath(path)+"/", objectQuery(&query.CompFilter))
+	if err != nil {
+		return nil, fmt.Errorf("error while querying calendar objects at path %q from storage: %w", path, err)
+	}
+
+	span.SetAttributes(attribute.Int("candidates", len(objs)))
+
+	// Objects are filtered before being mapped, as expanding recurrences
+	// would otherwise affect matching.
+	objects, err = decodeCalendarObjects(ctx, objs, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error while listing all calendar objects for query: %w", err)
+		return nil, fmt.Errorf("error while decoding calendar objects for query: %w", err)
 	}
 
 	objects, err = caldav.Filter(&query.CompFilter, objects)
@@ -300,6 +425,15 @@ func (b *Backend) QueryCalendarObjects(
 		return nil, fmt.Errorf("error while filtering down calendar objects: %w", err)
 	}
 
+	for i := range objects {
+		object, err := mapCalendarObject(&objects[i], &query.CompRequest)
+		if err != nil {
+			return nil, fmt.Errorf("error while applying maps to calendar object %q: %w", objects[i].Path, err)
+		}
+
+		objects[i] = *object
+	}
+
 	return objects, nil
 }
 
@@ -307,10 +441,16 @@ func (b *Backend) DeleteCalendarObject(ctx context.Context, path string) (err er
 	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "DeleteCalendarObject")
 	defer func() { observability.SpanEnd(span, err) }()
 
+	if _, _, err := b.authorize(ctx, normalizePath(path).Dir(), accessReadWrite); err != nil {
+		return err
+	}
+
 	if err := b.store.DeleteCalendarObject(ctx, normalizePath(path)); err != nil {
 		return fmt.Errorf("error while deleting calendar object at path %q in storage: %w", path, err)
 	}
 
+	b.updateUsage(ctx, normalizePath(path))
+
 	return nil
 }
 
@@ -319,7 +459,7 @@ func (b *Backend) DeleteCalendarObject(ctx context.Context, path string) (err er
 func (b *Backend) listCalendarObjects(
 	ctx context.Context,
 	path string,
-	_ *caldav.CalendarCompRequest,
+	req *caldav.CalendarCompRequest,
 ) (objects []caldav.CalendarObject, err error) {
 	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "listCalendarObjects")
 	defer func() { observability.SpanEnd(span, err) }()
@@ -329,7 +469,17 @@ func (b *Backend) listCalendarObjects(
 		return nil, fmt.Errorf("error while fetching calendar objects at path %q from storage: %w", path, err)
 	}
 
-	objects = make([]caldav.CalendarObject, len(objs))
+	return decodeCalendarObjects(ctx, objs, req)
+}
+
+// decodeCalendarObjects converts objects from the store in parallel, applying
+// the calendar-data request to each of them.
+func decodeCalendarObjects(
+	ctx context.Context,
+	objs []store.Object,
+	req *caldav.CalendarCompRequest,
+) ([]caldav.CalendarObject, error) {
+	objects := make([]caldav.CalendarObject, len(objs))
 	eg, ctx := errgroup.WithContext(ctx)
 	eg.SetLimit(MaxDecodesInParallel)
 
@@ -345,10 +495,10 @@ func (b *Backend) listCalendarObjects(
 				)
 			}
 
-			// object, err = mapCalendarObject(object, req)
-			// if err != nil {
-			// 	return nil, fmt.Errorf("error while applying filters and maps to calendar object %q: %w", obj.Path, err)
-			// }
+			object, err = mapCalendarObject(object, req)
+			if err != nil {
+				return fmt.Errorf("error while applying maps to calendar object %q: %w", obj.Path, err)
+			}
 
 			objects[i] = *object
 
@@ -0,0 +1,194 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/teapotovh/teapot/lib/observability"
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	daverr "github.com/teapotovh/teapot/lib/webdav/error"
+	"github.com/teapotovh/teapot/service/calendar/store"
+)
+
+// UpdateCalendar changes the properties of a calendar. Only its owner may do
+// so, as they are shared by everyone the calendar is shared with.
+func (b *Backend) UpdateCalendar(ctx context.Context, path string, update *caldav.CalendarUpdate) (err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "UpdateCalendar")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	cal, _, err := b.authorize(ctx, normalizePath(path), accessOwner)
+	if err != nil {
+		return err
+	}
+
+	if update.Name != nil {
+		cal.Metadata.Name = *update.Name
+	}
+
+	if update.Description != nil {
+		cal.Metadata.Description = *update.Description
+	}
+
+	if update.Color != nil {
+		cal.Metadata.Color = *update.Color
+	}
+
+	if err := b.store.UpdateCalendar(ctx, *cal); err != nil {
+		return fmt.Errorf("error while updating calendar at path %q in storage: %w", path, err)
+	}
+
+	return nil
+}
+
+func (b *Backend) DeleteCalendar(ctx context.Context, path string) (err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "DeleteCalendar")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	cal, _, err := b.authorize(ctx, normalizePath(path), accessOwner)
+	if err != nil {
+		return err
+	}
+
+	if err := b.store.DeleteCalendar(ctx, cal.Path); err != nil {
+		return fmt.Errorf("error while deleting calendar at path %q in storage: %w", path, err)
+	}
+
+	b.updateUsage(ctx, cal.Path)
+
+	return nil
+}
+
+// transferCalendarObject copies the object at path to dest, removing the
+// original if move is set. The user needs write access to the calendar of
+// dest, and to the one of path when moving.
+//
+//nolint:gocyclo
+func (b *Backend) transferCalendarObject(
+	ctx context.Context,
+	path, dest string,
+	overwrite, move bool,
+) (created bool, err error) {
+	src, dst := normalizePath(path), normalizePath(dest)
+	if src == dst {
+		return false, daverr.HTTPErrorf(http.StatusForbidden, "calendar: source and destination are the same")
+	}
+
+	want := accessRead
+	if move {
+		want = accessReadWrite
+	}
+
+	if _, _, err := b.authorize(ctx, src.Dir(), want); err != nil {
+		return false, err
+	}
+
+	cal, _, err := b.authorize(ctx, dst.Dir(), accessReadWrite)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, daverr.HTTPErrorf(http.StatusConflict, "calendar: no calendar at path %q", dst.Dir())
+	} else if err != nil {
+		return false, err
+	}
+
+	obj, err := b.store.GetCalendarObject(ctx, src)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, daverr.HTTPErrorf(http.StatusNotFound, "calendar: no calendar object at path %q", src)
+	} else if err != nil {
+		return false, fmt.Errorf("error while fetching calendar object at path %q from storage: %w", src, err)
+	}
+
+	data, err := obj.Calendar()
+	if err != nil {
+		return false, fmt.Errorf("error while parsing calendar object at path %q: %w", src, err)
+	}
+
+	if err := checkSupportedComponents(cal, data); err != nil {
+		return false, err
+	}
+
+	prev, err := b.store.GetCalendarObject(ctx, dst)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return false, fmt.Errorf("error while checking for previous object at path %q: %w", dst, err)
+	}
+
+	created = prev == nil
+	if !created && !overwrite {
+		return false, daverr.HTTPErrorf(http.StatusPreconditionFailed, "calendar: object at path %q exists", dst)
+	}
+
+	var objects, bytes int64 = 1, obj.Size()
+	if !created {
+		objects, bytes = 0, bytes-prev.Size()
+	}
+
+	// Moving an object between the calendars of the same user frees up the
+	// space it used at the source.
+	srcOwner, _ := calendarOwner(string(src))
+	dstOwner, _ := calendarOwner(string(dst))
+	if move && srcOwner == dstOwner {
+		objects, bytes = objects-1, bytes-obj.Size()
+	}
+
+	if err := b.checkQuota(ctx, dst, objects, bytes); err != nil {
+		return false, err
+	}
+
+	if !created {
+		if err := b.store.DeleteCalendarObject(ctx, dst); err != nil {
+			return false, fmt.Errorf("error while deleting calendar object at path %q in storage: %w", dst, err)
+		}
+	}
+
+	object := store.Object{
+		Path:    dst,
+		ModTime: time.Now(),
+		Data:    obj.Data,
+		ETag:    obj.ETag,
+		Index:   obj.Index,
+	}
+	if err := b.store.CreateCalendarObject(ctx, object); err != nil {
+		return false, fmt.Errorf("error while creating calendar object at path %q in storage: %w", dst, err)
+	}
+
+	if move {
+		if err := b.store.DeleteCalendarObject(ctx, src); err != nil {
+			return false, fmt.Errorf("error while deleting calendar object at path %q in storage: %w", src, err)
+		}
+
+		b.updateUsage(ctx, src)
+	}
+
+	b.updateUsage(ctx, dst)
+
+	return created, nil
+}
+
+func (b *Backend) CopyCalendarObject(
+	ctx context.Context,
+	path, dest string,
+	overwrite bool,
+) (created bool, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "CopyCalendarObject")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	span.SetAttributes(attribute.String("dest", dest))
+
+	return b.transferCalendarObject(ctx, path, dest, overwrite, false)
+}
+
+func (b *Backend) MoveCalendarObject(
+	ctx context.Context,
+	path, dest string,
+	overwrite bool,
+) (created bool, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "MoveCalendarObject")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	span.SetAttributes(attribute.String("dest", dest))
+
+	return b.transferCalendarObject(ctx, path, dest, overwrite, true)
+}
@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"net/http"
+	"slices"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	daverr "github.com/teapotovh/teapot/lib/webdav/error"
+	"github.com/teapotovh/teapot/service/calendar/store"
+)
+
+// checkSupportedComponentSet verifies that a supported-calendar-component-set
+// requested for a new calendar only lists components we support.
+func checkSupportedComponentSet(names []string) error {
+	for _, name := range names {
+		if !slices.Contains(SupportedComponentSet, name) {
+			return daverr.HTTPErrorf(http.StatusForbidden, "calendar: unsupported calendar component %q", name)
+		}
+	}
+
+	return nil
+}
+
+// checkSupportedComponents verifies that an object only holds components in
+// the supported-calendar-component-set of the calendar it is stored into.
+// Timezones are always allowed, as they are referenced by other components.
+func checkSupportedComponents(cal *store.Calendar, calendar *ics.Calendar) error {
+	supported := cal.Metadata.SupportedComponentSet
+	if len(supported) <= 0 {
+		supported = SupportedComponentSet
+	}
+
+	for _, comp := range calendar.Components {
+		name := caldav.ComponentName(comp)
+		if name != string(ics.ComponentVTimezone) && !slices.Contains(supported, name) {
+			return caldav.NewPreconditionError(caldav.PreconditionSupportedCalendarComponent)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/teapotovh/teapot/lib/observability"
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	"github.com/teapotovh/teapot/service/calendar/store"
+)
+
+// FeedTokenSize is the number of random bytes in a feed token.
+const FeedTokenSize = 32
+
+var ErrFeedNotFound = errors.New("feed not found")
+
+// CreateFeedToken enables the subscription feed of the calendar at path,
+// returning its new token. Any previous token is revoked.
+func (b *Backend) CreateFeedToken(ctx context.Context, path string) (token string, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "CreateFeedToken")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	cal, _, err := b.authorize(ctx, normalizePath(path), accessOwner)
+	if err != nil {
+		return "", err
+	}
+
+	raw := make([]byte, FeedTokenSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("error while generating feed token: %w", err)
+	}
+
+	token = hex.EncodeToString(raw)
+
+	cal.Metadata.FeedTokenHash = store.HashFeedToken(token)
+	if err := b.store.UpdateCalendar(ctx, *cal); err != nil {
+		return "", fmt.Errorf("error while updating calendar at path %q in storage: %w", path, err)
+	}
+
+	return token, nil
+}
+
+// RevokeFeedToken disables the subscription feed of the calendar at path.
+func (b *Backend) RevokeFeedToken(ctx context.Context, path string) (err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "RevokeFeedToken")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	cal, _, err := b.authorize(ctx, normalizePath(path), accessOwner)
+	if err != nil {
+		return err
+	}
+
+	cal.Metadata.FeedTokenHash = ""
+	if err := b.store.UpdateCalendar(ctx, *cal); err != nil {
+		return fmt.Errorf("error while updating calendar at path %q in storage: %w", path, err)
+	}
+
+	return nil
+}
+
+// feedCalendar finds the calendar whose subscription feed has the given token.
+// Only the hash of the token is stored, so the lookup does not leak the
+// tokens of other feeds through timing.
+func (b *Backend) feedCalendar(ctx context.Context, token string) (*store.Calendar, error) {
+	cal, err := b.store.GetCalendarByFeedToken(ctx, store.HashFeedToken(token))
+	if errors.Is(err, store.ErrCalendarNotFound) {
+		return nil, ErrFeedNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("error while fetching calendar from storage: %w", err)
+	}
+
+	return cal, nil
+}
+
+// confidentialProperties are the properties of a confidential component
+// which are published in a feed, so that subscribers only see when its owner
+// is busy.
+var confidentialProperties = []ics.ComponentProperty{
+	ics.ComponentPropertyUniqueId,
+	ics.ComponentPropertyDtstamp,
+	ics.ComponentPropertyDtStart,
+	ics.ComponentPropertyDtEnd,
+	ics.ComponentPropertyDue,
+	ics.ComponentPropertyDuration,
+	ics.ComponentPropertyRrule,
+	ics.ComponentPropertyRdate,
+	ics.ComponentPropertyExdate,
+	ics.ComponentPropertyRecurrenceId,
+	ics.ComponentPropertySequence,
+	ics.ComponentPropertyStatus,
+	ics.ComponentPropertyTransp,
+	ics.ComponentPropertyClass,
+}
+
+// confidentialSummary replaces the summary of confidential components.
+const confidentialSummary = "Busy"
+
+// feedComponentBase returns a copy of a component to be published in a feed,
+// without its alarms. Private components are left out, while confidential ones
+// are reduced to their times.
+func feedComponentBase(base *ics.ComponentBase) (ics.ComponentBase, bool) {
+	var class string
+	if prop := base.GetProperty(ics.ComponentPropertyClass); prop != nil {
+		class = strings.ToUpper(prop.Value)
+	}
+
+	var feed ics.ComponentBase
+
+	switch class {
+	case string(ics.ClassificationPrivate):
+		return feed, false
+	case string(ics.ClassificationConfidential):
+		for _, prop := range base.Properties {
+			if slices.Contains(confidentialProperties, ics.ComponentProperty(prop.IANAToken)) {
+				feed.Properties = append(feed.Properties, prop)
+			}
+		}
+
+		feed.SetSummary(confidentialSummary)
+	default:
+		feed.Properties = slices.Clone(base.Properties)
+	}
+
+	for _, comp := range base.Components {
+		if _, ok := comp.(*ics.VAlarm); !ok {
+			feed.Components = append(feed.Components, comp)
+		}
+	}
+
+	return feed, true
+}
+
+// feedComponent returns the version of a component to be published in a feed,
+// as computed by feedComponentBase. Components other than events, to-dos and
+// journal entries are published as they are.
+func feedComponent(comp ics.Component) (ics.Component, bool) {
+	switch c := comp.(type) {
+	case *ics.VEvent:
+		base, ok := feedComponentBase(&c.ComponentBase)
+		return &ics.VEvent{ComponentBase: base}, ok
+	case *ics.VTodo:
+		base, ok := feedComponentBase(&c.ComponentBase)
+		return &ics.VTodo{ComponentBase: base}, ok
+	case *ics.VJournal:
+		base, ok := feedComponentBase(&c.ComponentBase)
+		return &ics.VJournal{ComponentBase: base}, ok
+	default:
+		return comp, true
+	}
+}
+
+// Feed serializes the whole calendar whose subscription feed has the given
+// token into a single VCALENDAR. Timezones shared by multiple objects are
+// only included once, and components are filtered by feedComponent, as
+// subscribers are not supposed to see alarms nor private details.
+func (b *Backend) Feed(ctx context.Context, token string) (feed *ics.Calendar, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "Feed")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	cal, err := b.feedCalendar(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := b.listCalendarObjects(ctx, string(cal.Path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	feed = ics.NewCalendarFor(caldav.ProductID)
+	if cal.Metadata.Name != "" {
+		feed.SetXWRCalName(cal.Metadata.Name)
+	}
+
+	if cal.Metadata.Description != "" {
+		feed.SetXWRCalDesc(cal.Metadata.Description)
+	}
+
+	timezones := map[string]struct{}{}
+
+	for _, object := range objects {
+		for _, comp := range object.Data.Components {
+			if tz, ok := comp.(*ics.VTimezone); ok {
+				tzid := ""
+				if prop := tz.GetProperty(ics.ComponentPropertyTzid); prop != nil {
+					tzid = prop.Value
+				}
+
+				if _, seen := timezones[tzid]; seen {
+					continue
+				}
+
+				timezones[tzid] = struct{}{}
+			}
+
+			if comp, ok := feedComponent(comp); ok {
+				feed.Components = append(feed.Components, comp)
+			}
+		}
+	}
+
+	return feed, nil
+}
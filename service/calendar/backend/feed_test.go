@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+func parseEvent(t *testing.T, props string) *ics.VEvent {
+	t.Helper()
+
+	raw := "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:test\nBEGIN:VEVENT\n" +
+		"UID:event\nDTSTAMP:20240101T000000Z\nDTSTART:20240101T100000Z\nDTEND:20240101T110000Z\n" +
+		"SUMMARY:Dentist\nDESCRIPTION:Root canal\nLOCATION:Downtown\n" + props +
+		"BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER:-PT15M\nDESCRIPTION:Reminder\nEND:VALARM\n" +
+		"END:VEVENT\nEND:VCALENDAR\n"
+
+	cal, err := ics.ParseCalendar(strings.NewReader(strings.ReplaceAll(raw, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("could not parse calendar: %v", err)
+	}
+
+	return cal.Events()[0]
+}
+
+func TestFeedComponent(t *testing.T) {
+	cases := []struct {
+		name      string
+		props     string
+		published bool
+		summary   string
+		details   bool
+	}{
+		{name: "public", published: true, summary: "Dentist", details: true},
+		{name: "explicitly public", props: "CLASS:PUBLIC\n", published: true, summary: "Dentist", details: true},
+		{name: "private", props: "CLASS:PRIVATE\n"},
+		{name: "confidential", props: "CLASS:CONFIDENTIAL\n", published: true, summary: confidentialSummary},
+		{name: "lower case", props: "CLASS:confidential\n", published: true, summary: confidentialSummary},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			event := parseEvent(t, c.props)
+
+			comp, ok := feedComponent(event)
+			if ok != c.published {
+				t.Fatalf("expected published to be %t, got %t", c.published, ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			feed, isEvent := comp.(*ics.VEvent)
+			if !isEvent {
+				t.Fatalf("expected a VEVENT, got %T", comp)
+			}
+
+			if len(feed.Alarms()) != 0 {
+				t.Errorf("expected alarms to be stripped, got %d", len(feed.Alarms()))
+			}
+
+			if len(event.Alarms()) != 1 {
+				t.Errorf("expected the stored event to keep its alarm")
+			}
+
+			summary := feed.GetProperty(ics.ComponentPropertySummary)
+			if summary == nil || summary.Value != c.summary {
+				t.Errorf("expected summary %q, got %v", c.summary, summary)
+			}
+
+			for _, prop := range []ics.ComponentProperty{
+				ics.ComponentPropertyDescription,
+				ics.ComponentPropertyLocation,
+			} {
+				if hasDetail := feed.GetProperty(prop) != nil; hasDetail != c.details {
+					t.Errorf("expected %s to be published %t, got %t", prop, c.details, hasDetail)
+				}
+			}
+
+			for _, prop := range []ics.ComponentProperty{
+				ics.ComponentPropertyUniqueId,
+				ics.ComponentPropertyDtStart,
+				ics.ComponentPropertyDtEnd,
+			} {
+				if feed.GetProperty(prop) == nil {
+					t.Errorf("expected %s to be published", prop)
+				}
+			}
+		})
+	}
+}
+
+func TestFeedComponentTimezone(t *testing.T) {
+	tz := ics.NewTimezone("Europe/Rome")
+
+	comp, ok := feedComponent(tz)
+	if !ok || comp != tz {
+		t.Errorf("expected timezones to be published as they are")
+	}
+}
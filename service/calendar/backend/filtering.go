@@ -1,165 +1,50 @@
 package backend
 
-/*
 import (
-	"maps"
-	"time"
-
-	"github.com/emersion/go-ical"
+	"fmt"
 
 	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	"github.com/teapotovh/teapot/service/calendar/store"
 )
 
-func mapCalendarObject(object *caldav.CalendarObject, req *caldav.CalendarCompRequest) (*caldav.CalendarObject, error) {
-	if req == nil {
-		return object, nil
-	}
-
-	if object.Data == nil {
-		return object, nil
-	}
-
-	if err := filterComponent(object.Data.Component, req); err != nil {
-		return nil, err
-	}
-
-	return object, nil
-}
-
-//nolint:gocyclo
-func filterComponent(comp *ical.Component, req *caldav.CalendarCompRequest) error {
-	// No filter specified at all means "return everything unfiltered".
-	if !req.AllProps && len(req.Props) == 0 && !req.AllComps && len(req.Comps) == 0 {
-		return nil
-	}
-
-	//
-	// Filter properties
-	//
-	if !req.AllProps {
-		// Keep all
-	} else if len(req.Props) > 0 {
-		wanted := make(map[string]struct{}, len(req.Props))
-		for _, p := range req.Props {
-			wanted[p] = struct{}{}
-		}
-
-		for name := range comp.Props {
-			if _, ok := wanted[name]; !ok {
-				delete(comp.Props, name)
-			}
-		}
-	} else {
-		// No props requested
-		for name := range comp.Props {
-			delete(comp.Props, name)
-		}
-	}
-
-	//
-	// Filter and recurse into sub-components
-	//
-	if req.AllComps {
-		snapshot := append([]*ical.Component{}, comp.Children...)
-		for _, child := range snapshot {
-			if err := applyExpand(comp, child, req.Expand); err != nil {
-				return err
-			}
-		}
-		// Recurse into each child with a permissive request
-		passthroughReq := &caldav.CalendarCompRequest{AllProps: true, AllComps: true}
-		for _, child := range comp.Children {
-			if err := filterComponent(child, passthroughReq); err != nil {
-				return err
-			}
-		}
-	} else if len(req.Comps) > 0 {
-		subReqs := make(map[string]*caldav.CalendarCompRequest, len(req.Comps))
-		for i := range req.Comps {
-			sr := req.Comps[i]
-			subReqs[sr.Name] = &sr
-		}
-
-		snapshot := append([]*ical.Component{}, comp.Children...)
-		for _, child := range snapshot {
-			sr, ok := subReqs[child.Name]
-			if !ok {
-				continue
-			}
-
-			if err := applyExpand(comp, child, sr.Expand); err != nil {
-				return err
-			}
-		}
-
-		filtered := comp.Children[:0]
-		for _, child := range comp.Children {
-			sr, ok := subReqs[child.Name]
-			if !ok {
-				continue
-			}
-
-			if err := filterComponent(child, sr); err != nil {
-				return err
-			}
-
-			filtered = append(filtered, child)
+// objectQuery narrows down a calendar query to the objects of the store
+// which may match it, according to the first comp-filter requiring a
+// component, along with its time range.
+func objectQuery(filter *caldav.CompFilter) store.ObjectQuery {
+	for _, comp := range filter.Comps {
+		if !comp.IsNotDefined {
+			return store.ObjectQuery{Component: comp.Name, Start: comp.Start, End: comp.End}
 		}
-
-		comp.Children = filtered
-	} else {
-		comp.Children = nil
 	}
 
-	return nil
+	return store.ObjectQuery{}
 }
 
-// applyExpand expands a recurring child component into individual instances
-// within the requested time range, updating parent.Children in place.
-// For non-recurring components it is a no-op.
-func applyExpand(parent *ical.Component, comp *ical.Component, expand *caldav.CalendarExpandRequest) error {
-	if expand == nil {
-		return nil
+// mapCalendarObject applies the recurrence related parts of a calendar-data
+// request to an object, expanding or limiting its recurrence set.
+func mapCalendarObject(object *caldav.CalendarObject, req *caldav.CalendarCompRequest) (*caldav.CalendarObject, error) {
+	if req == nil || object.Data == nil {
+		return object, nil
 	}
 
-	switch comp.Name {
-	case ical.CompEvent, ical.CompToDo, ical.CompJournal, ical.CompFreeBusy:
-	default:
-		return nil
-	}
+	var err error
 
-	set, err := comp.RecurrenceSet(time.UTC)
-	if err != nil || set == nil {
-		return nil //nolint:nilerr
-	}
+	mapped := *object
 
-	occurrences := set.Between(expand.Start, expand.End, true)
-
-	// Replace the single recurring component with expanded instances
-	newChildren := make([]*ical.Component, 0, len(parent.Children)-1+len(occurrences))
-	for _, child := range parent.Children {
-		if child != comp {
-			newChildren = append(newChildren, child)
+	switch {
+	case req.Expand != nil:
+		mapped.Data, err = caldav.ExpandCalendar(object.Data, req.Expand.Start, req.Expand.End)
+		if err != nil {
+			return nil, fmt.Errorf("error while expanding recurrences: %w", err)
 		}
-	}
+	case req.LimitRecurrenceSet != nil:
+		limit := req.LimitRecurrenceSet
 
-	for _, t := range occurrences {
-		instance := &ical.Component{
-			Name:     comp.Name,
-			Props:    make(ical.Props),
-			Children: comp.Children,
+		mapped.Data, err = caldav.LimitRecurrenceSet(object.Data, limit.Start, limit.End)
+		if err != nil {
+			return nil, fmt.Errorf("error while limiting the recurrence set: %w", err)
 		}
-		maps.Copy(instance.Props, comp.Props)
-
-		dtProp := ical.NewProp(ical.PropDateTimeStart)
-		dtProp.SetDateTime(t)
-		instance.Props[ical.PropDateTimeStart] = []ical.Prop{*dtProp}
-		delete(instance.Props, ical.PropRecurrenceRule)
-		newChildren = append(newChildren, instance)
 	}
 
-	parent.Children = newChildren
-
-	return nil
+	return &mapped, nil
 }
-*/
@@ -0,0 +1,49 @@
+package backend
+
+import (
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+func BackendFlagSet() (*flag.FlagSet, func() BackendConfig) {
+	fs := flag.NewFlagSet("calendar/backend", flag.ExitOnError)
+
+	quotaBytes := fs.Int64(
+		"calendar-quota-bytes",
+		64<<20,
+		"the default maximum total size in bytes of the calendar objects owned by a user. 0 disables the limit",
+	)
+	quotaObjects := fs.Int64(
+		"calendar-quota-objects",
+		0,
+		"the default maximum number of calendar objects owned by a user. 0 disables the limit",
+	)
+	quotaBytesAttribute := fs.String(
+		"calendar-quota-bytes-attribute",
+		"calendarQuotaBytes",
+		"the LDAP attribute overriding the default size quota of a user. empty disables overrides",
+	)
+	quotaObjectsAttribute := fs.String(
+		"calendar-quota-objects-attribute",
+		"calendarQuotaObjects",
+		"the LDAP attribute overriding the default object quota of a user. empty disables overrides",
+	)
+	quotaCacheTTL := fs.Duration(
+		"calendar-quota-cache-ttl",
+		5*time.Minute,
+		"how long the quota of a user read from LDAP is cached. 0 disables caching",
+	)
+
+	return fs, func() BackendConfig {
+		return BackendConfig{
+			Quota: QuotaConfig{
+				Bytes:            *quotaBytes,
+				Objects:          *quotaObjects,
+				BytesAttribute:   *quotaBytesAttribute,
+				ObjectsAttribute: *quotaObjectsAttribute,
+				CacheTTL:         *quotaCacheTTL,
+			},
+		}
+	}
+}
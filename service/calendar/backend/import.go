@@ -0,0 +1,224 @@
+package backend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/teapotovh/teapot/lib/observability"
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	daverr "github.com/teapotovh/teapot/lib/webdav/error"
+	"github.com/teapotovh/teapot/service/calendar/store"
+)
+
+// ImportResult reports how many objects were created by an import, and how
+// many were skipped as a calendar object with the same UID already existed.
+type ImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+}
+
+// objectName returns the name of the object holding the components with the
+// given UID, which is the UID itself whenever it is safe to use in a path.
+func objectName(uid string) string {
+	safe := strings.IndexFunc(uid, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && !strings.ContainsRune("-_.@", r)
+	}) < 0
+	if safe && uid != "" && uid[0] != '.' {
+		return uid
+	}
+
+	sum := sha256.Sum256([]byte(uid))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// componentUID returns the UID of a component, or an empty string.
+func componentUID(comp ics.Component) string {
+	for _, prop := range comp.UnknownPropertiesIANAProperties() {
+		if prop.IANAToken == string(ics.ComponentPropertyUniqueId) {
+			return prop.Value
+		}
+	}
+
+	return ""
+}
+
+// objectUIDs returns the UIDs of the components of a calendar object.
+func objectUIDs(cal *ics.Calendar) []string {
+	var uids []string
+
+	for _, comp := range cal.Components {
+		if uid := componentUID(comp); uid != "" {
+			uids = append(uids, uid)
+		}
+	}
+
+	return uids
+}
+
+// referencedTimezones returns the timezones referenced by the TZID parameters
+// of the properties of a component.
+func referencedTimezones(comp ics.Component, timezones map[string]*ics.VTimezone) []*ics.VTimezone {
+	var result []*ics.VTimezone
+
+	for _, prop := range comp.UnknownPropertiesIANAProperties() {
+		for _, tzid := range prop.ICalParameters[string(ics.ParameterTzid)] {
+			if tz, ok := timezones[tzid]; ok {
+				result = append(result, tz)
+			}
+		}
+	}
+
+	return result
+}
+
+// splitCalendar splits a calendar into one calendar per UID, each holding
+// all the components with that UID along with the timezones they reference.
+// Calendar properties other than CALSCALE are dropped, as they either describe
+// the exported calendar or, like METHOD, are not allowed in calendar objects.
+func splitCalendar(cal *ics.Calendar) (uids []string, objects map[string]*ics.Calendar, err error) {
+	timezones := map[string]*ics.VTimezone{}
+
+	for _, comp := range cal.Components {
+		if tz, ok := comp.(*ics.VTimezone); ok {
+			if prop := tz.GetProperty(ics.ComponentPropertyTzid); prop != nil {
+				timezones[prop.Value] = tz
+			}
+		}
+	}
+
+	objects = map[string]*ics.Calendar{}
+	included := map[string]map[*ics.VTimezone]struct{}{}
+
+	for _, comp := range cal.Components {
+		if _, ok := comp.(*ics.VTimezone); ok {
+			continue
+		}
+
+		uid := componentUID(comp)
+		if uid == "" {
+			return nil, nil, daverr.HTTPErrorf(
+				http.StatusBadRequest,
+				"calendar: cannot import %s without UID",
+				caldav.ComponentName(comp),
+			)
+		}
+
+		object, ok := objects[uid]
+		if !ok {
+			object = ics.NewCalendarFor(caldav.ProductID)
+			for _, prop := range cal.CalendarProperties {
+				if prop.IANAToken == string(ics.PropertyCalscale) {
+					object.CalendarProperties = append(object.CalendarProperties, prop)
+				}
+			}
+
+			objects[uid] = object
+			included[uid] = map[*ics.VTimezone]struct{}{}
+			uids = append(uids, uid)
+		}
+
+		for _, tz := range referencedTimezones(comp, timezones) {
+			if _, ok := included[uid][tz]; !ok {
+				included[uid][tz] = struct{}{}
+				object.Components = append(object.Components, tz)
+			}
+		}
+
+		object.Components = append(object.Components, comp)
+	}
+
+	return uids, objects, nil
+}
+
+// ImportCalendar imports all the components of an iCalendar file into the
+// calendar at path, creating one calendar object per UID. Components whose
+// UID is already used by an object of the calendar are skipped.
+//
+//nolint:gocyclo
+func (b *Backend) ImportCalendar(
+	ctx context.Context,
+	path string,
+	data *ics.Calendar,
+) (result *ImportResult, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "ImportCalendar")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	cal, _, err := b.authorize(ctx, normalizePath(path), accessReadWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	uids, objects, err := splitCalendar(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects are validated before any of them is stored, so that imports
+	// are not left halfway through.
+	for _, uid := range uids {
+		if err := checkSupportedComponents(cal, objects[uid]); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := b.listCalendarObjects(ctx, string(cal.Path), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing calendar objects for import: %w", err)
+	}
+
+	knownPaths := map[string]struct{}{}
+	knownUIDs := map[string]struct{}{}
+
+	for _, object := range existing {
+		knownPaths[object.Path] = struct{}{}
+		for _, uid := range objectUIDs(object.Data) {
+			knownUIDs[uid] = struct{}{}
+		}
+	}
+
+	result = &ImportResult{}
+	created := []store.Object{}
+
+	var bytes int64
+
+	for _, uid := range uids {
+		objectPath := string(cal.Path) + "/" + objectName(uid) + ".ics"
+
+		_, knownUID := knownUIDs[uid]
+		if _, knownPath := knownPaths[objectPath]; knownUID || knownPath {
+			result.Skipped++
+			continue
+		}
+
+		obj, err := caldavObjectToStoreObject(objectPath, objects[uid])
+		if err != nil {
+			return nil, fmt.Errorf("could not convert imported object with UID %q into store Object: %w", uid, err)
+		}
+
+		created = append(created, *obj)
+		bytes += obj.Size()
+	}
+
+	if err := b.checkQuota(ctx, cal.Path, int64(len(created)), bytes); err != nil {
+		return nil, err
+	}
+
+	for _, obj := range created {
+		if err := b.store.CreateCalendarObject(ctx, obj); err != nil {
+			return nil, fmt.Errorf("error while creating calendar object at path %q in storage: %w", obj.Path, err)
+		}
+
+		result.Imported++
+	}
+
+	b.updateUsage(ctx, cal.Path)
+
+	return result, nil
+}
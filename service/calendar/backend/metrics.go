@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	usedBytes   *prometheus.GaugeVec
+	usedObjects *prometheus.GaugeVec
+	exceeded    *prometheus.CounterVec
+}
+
+func (b *Backend) initMetrics() {
+	b.metrics.usedBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "calendar_quota_used_bytes",
+			Help: "Total size of the calendar objects owned by a user",
+		},
+		[]string{"user"},
+	)
+
+	b.metrics.usedObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "calendar_quota_used_objects",
+			Help: "Number of calendar objects owned by a user",
+		},
+		[]string{"user"},
+	)
+
+	b.metrics.exceeded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "calendar_quota_exceeded_total",
+			Help: "Number of writes rejected as they would exceed the quota of a user",
+		},
+		[]string{"user"},
+	)
+}
+
+// Metrics implements observability.Metrics.
+func (b *Backend) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{b.metrics.usedBytes, b.metrics.usedObjects, b.metrics.exceeded}
+}
@@ -0,0 +1,205 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/teapotovh/teapot/lib/observability"
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	"github.com/teapotovh/teapot/service/calendar/store"
+)
+
+// QuotaConfig holds the default quota of each user, which can be overridden
+// through attributes of their LDAP entry. Zero limits are disabled.
+type QuotaConfig struct {
+	Bytes   int64
+	Objects int64
+
+	BytesAttribute   string
+	ObjectsAttribute string
+
+	// CacheTTL is how long the quota of a user read from LDAP is reused.
+	CacheTTL time.Duration
+}
+
+// cachedQuota is the quota of a user along with the time it was fetched at.
+type cachedQuota struct {
+	quota   QuotaConfig
+	fetched time.Time
+}
+
+// userQuota returns the quota of a user, which is fetched from LDAP at most
+// once per CacheTTL.
+func (b *Backend) userQuota(ctx context.Context, username string) (QuotaConfig, error) {
+	b.quotaCacheMu.Lock()
+	cached, ok := b.quotaCache[username]
+	b.quotaCacheMu.Unlock()
+
+	if ok && time.Since(cached.fetched) < b.quota.CacheTTL {
+		return cached.quota, nil
+	}
+
+	fetched := time.Now()
+
+	quota, err := b.fetchUserQuota(ctx, username)
+	if err != nil {
+		return quota, err
+	}
+
+	b.quotaCacheMu.Lock()
+	defer b.quotaCacheMu.Unlock()
+
+	// Drop the expired entries, so that the cache does not grow with users
+	// which are no longer active.
+	for name, cached := range b.quotaCache {
+		if time.Since(cached.fetched) >= b.quota.CacheTTL {
+			delete(b.quotaCache, name)
+		}
+	}
+
+	b.quotaCache[username] = cachedQuota{quota: quota, fetched: fetched}
+
+	return quota, nil
+}
+
+// fetchUserQuota fetches the quota of a user, i.e., the default one unless it
+// is overridden by their LDAP entry.
+func (b *Backend) fetchUserQuota(ctx context.Context, username string) (QuotaConfig, error) {
+	quota := b.quota
+
+	var names []string
+
+	for _, name := range []string{quota.BytesAttribute, quota.ObjectsAttribute} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return quota, nil
+	}
+
+	client, err := b.ldapFactory.NewClient(ctx)
+	if err != nil {
+		return quota, fmt.Errorf("error while creating LDAP client: %w", err)
+	}
+	defer client.Close()
+
+	attributes, err := client.UserAttributes(ctx, username, names...)
+	if err != nil {
+		return quota, fmt.Errorf("error while looking up quota of user %q: %w", username, err)
+	}
+
+	override := func(name string, limit *int64) {
+		values := attributes[name]
+		if name == "" || len(values) == 0 {
+			return
+		}
+
+		value, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil || value < 0 {
+			b.logger.WarnContext(ctx, "ignoring invalid quota", "user", username, "attribute", name, "value", values[0])
+			return
+		}
+
+		*limit = value
+	}
+
+	override(quota.BytesAttribute, &quota.Bytes)
+	override(quota.ObjectsAttribute, &quota.Objects)
+
+	return quota, nil
+}
+
+// userUsage returns the storage used by all the calendars owned by a user,
+// exporting it as metrics.
+func (b *Backend) userUsage(ctx context.Context, username string) (store.Usage, error) {
+	usage, err := b.store.CalendarUsage(ctx, store.Path("/"+username+"/"))
+	if err != nil {
+		return usage, fmt.Errorf("error while fetching usage of user %q from storage: %w", username, err)
+	}
+
+	b.metrics.usedBytes.WithLabelValues(username).Set(float64(usage.Bytes))
+	b.metrics.usedObjects.WithLabelValues(username).Set(float64(usage.Objects))
+
+	return usage, nil
+}
+
+// updateUsage refreshes the usage metrics of the owner of the resource at
+// path after it has been changed. Failures are only logged, as the change
+// has already been made.
+func (b *Backend) updateUsage(ctx context.Context, path store.Path) {
+	owner, err := calendarOwner(string(path))
+	if err == nil {
+		_, err = b.userUsage(ctx, owner)
+	}
+
+	if err != nil {
+		b.logger.WarnContext(ctx, "could not update usage metrics", "path", path, "err", err)
+	}
+}
+
+// checkQuota ensures that adding the given number of objects and bytes to the
+// calendars of the owner of path does not exceed their quota. Changes which do
+// not increase usage are always allowed, even if the owner is above quota.
+func (b *Backend) checkQuota(ctx context.Context, path store.Path, objects, bytes int64) error {
+	if objects <= 0 && bytes <= 0 {
+		return nil
+	}
+
+	username, err := calendarOwner(string(path))
+	if err != nil {
+		return err
+	}
+
+	quota, err := b.userQuota(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	usage, err := b.userUsage(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if (quota.Bytes > 0 && bytes > 0 && usage.Bytes+bytes > quota.Bytes) ||
+		(quota.Objects > 0 && objects > 0 && usage.Objects+objects > quota.Objects) {
+		b.metrics.exceeded.WithLabelValues(username).Inc()
+
+		return caldav.NewQuotaExceededError()
+	}
+
+	return nil
+}
+
+func (b *Backend) CalendarHomeSetQuota(ctx context.Context) (quota *caldav.Quota, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "CalendarHomeSetQuota")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	g, err := b.currentGrantee(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := b.userQuota(ctx, g.username)
+	if err != nil {
+		return nil, err
+	} else if limits.Bytes == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	usage, err := b.userUsage(ctx, g.username)
+	if err != nil {
+		return nil, err
+	}
+
+	return &caldav.Quota{
+		UsedBytes:      usage.Bytes,
+		AvailableBytes: max(limits.Bytes-usage.Bytes, 0),
+	}, nil
+}
+
+// Ensure Backend implements caldav.QuotaBackend.
+var _ caldav.QuotaBackend = &Backend{}
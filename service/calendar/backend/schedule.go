@@ -0,0 +1,222 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	ics "github.com/arran4/golang-ical"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/teapotovh/teapot/lib/ldap"
+	"github.com/teapotovh/teapot/lib/observability"
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+)
+
+const mailtoScheme = "mailto:"
+
+var ErrInvalidCalendarPath = errors.New("invalid calendar path")
+
+// calendarOwner returns the username of the owner of the resource at path,
+// which is always below the principal of its owner.
+func calendarOwner(path string) (string, error) {
+	owner, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if owner == "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCalendarPath, path)
+	}
+
+	return owner, nil
+}
+
+// userAddresses returns the calendar user addresses of an LDAP user, i.e.,
+// their principal and their mail address.
+func userAddresses(user *ldap.User) []string {
+	addresses := []string{"/" + user.Username}
+	if user.Mail != "" {
+		addresses = append(addresses, mailtoScheme+user.Mail)
+	}
+
+	return addresses
+}
+
+// lookupUser fetches a user from LDAP by their username.
+func (b *Backend) lookupUser(ctx context.Context, username string) (*ldap.User, error) {
+	client, err := b.ldapFactory.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating LDAP client: %w", err)
+	}
+	defer client.Close()
+
+	user, err := client.User(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("error while looking up user %q: %w", username, err)
+	}
+
+	return user, nil
+}
+
+// lookupGroup fetches a group from LDAP by its name.
+func (b *Backend) lookupGroup(ctx context.Context, name string) (*ldap.Group, error) {
+	client, err := b.ldapFactory.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating LDAP client: %w", err)
+	}
+	defer client.Close()
+
+	group, err := client.Group(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("error while looking up group %q: %w", name, err)
+	}
+
+	return group, nil
+}
+
+// lookupUserByAddress fetches the user with the given mailto calendar user
+// address from LDAP.
+func (b *Backend) lookupUserByAddress(ctx context.Context, address string) (*ldap.User, error) {
+	if len(address) < len(mailtoScheme) || !strings.EqualFold(address[:len(mailtoScheme)], mailtoScheme) {
+		return nil, fmt.Errorf("%w: %q", caldav.ErrUnknownRecipient, address)
+	}
+
+	mail := address[len(mailtoScheme):]
+
+	client, err := b.ldapFactory.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating LDAP client: %w", err)
+	}
+	defer client.Close()
+
+	users, err := client.Users(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing users: %w", err)
+	}
+
+	for _, user := range users {
+		if user.Mail != "" && strings.EqualFold(user.Mail, mail) {
+			return user, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", caldav.ErrUnknownRecipient, address)
+}
+
+// objectsData returns the iCalendar data of a list of calendar objects.
+func objectsData(objects []caldav.CalendarObject) []*ics.Calendar {
+	cals := make([]*ics.Calendar, 0, len(objects))
+	for _, object := range objects {
+		cals = append(cals, object.Data)
+	}
+
+	return cals
+}
+
+func (b *Backend) QueryFreeBusy(
+	ctx context.Context,
+	path string,
+	query *caldav.FreeBusyQuery,
+) (periods []caldav.BusyPeriod, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "QueryFreeBusy")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	if _, _, err := b.authorize(ctx, normalizePath(path), accessRead); err != nil {
+		return nil, err
+	}
+
+	owner, err := calendarOwner(path)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := b.lookupUser(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := b.listCalendarObjects(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	periods, err = caldav.FreeBusy(objectsData(objects), query.Start, query.End, userAddresses(user))
+	if err != nil {
+		return nil, fmt.Errorf("error while computing free/busy time of calendar %q: %w", path, err)
+	}
+
+	return periods, nil
+}
+
+func (b *Backend) ScheduleOutboxPath(ctx context.Context) (path string, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "ScheduleOutboxPath")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	up, err := b.CurrentUserPrincipal(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not get user principal: %w", err)
+	}
+
+	return up + "/outbox/", nil
+}
+
+func (b *Backend) CalendarUserAddressSet(ctx context.Context) (addresses []string, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "CalendarUserAddressSet")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	up, err := b.CurrentUserPrincipal(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get user principal: %w", err)
+	}
+
+	user, err := b.lookupUser(ctx, strings.TrimPrefix(up, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	return userAddresses(user), nil
+}
+
+// ScheduleFreeBusy aggregates the busy time of all the calendars owned by
+// the user with the given address.
+func (b *Backend) ScheduleFreeBusy(
+	ctx context.Context,
+	address string,
+	query *caldav.FreeBusyQuery,
+) (periods []caldav.BusyPeriod, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "ScheduleFreeBusy")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	span.SetAttributes(attribute.String("address", address))
+
+	user, err := b.lookupUserByAddress(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	homeSetPath := "/" + user.Username + "/calendars"
+
+	cals, err := b.store.ListCalendars(ctx, normalizePath(homeSetPath))
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching calendars at path %q from storage: %w", homeSetPath, err)
+	}
+
+	var data []*ics.Calendar
+
+	for _, cal := range cals {
+		objects, err := b.listCalendarObjects(ctx, string(cal.Path), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, objectsData(objects)...)
+	}
+
+	periods, err = caldav.FreeBusy(data, query.Start, query.End, userAddresses(user))
+	if err != nil {
+		return nil, fmt.Errorf("error while computing free/busy time of %q: %w", address, err)
+	}
+
+	return periods, nil
+}
+
+// Ensure Backend implements caldav.SchedulingBackend.
+var _ caldav.SchedulingBackend = &Backend{}
@@ -0,0 +1,164 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/teapotovh/teapot/lib/observability"
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	"github.com/teapotovh/teapot/service/calendar/store"
+)
+
+// syncTokenPrefix turns calendar revisions into URIs, as RFC 6578 requires
+// sync tokens to be.
+const syncTokenPrefix = "http://teapot.ovh/ns/sync/"
+
+func formatSyncToken(revision uint64) string {
+	return syncTokenPrefix + strconv.FormatUint(revision, 10)
+}
+
+func parseSyncToken(token string) (uint64, error) {
+	// An empty token requests the initial sync of the whole calendar
+	if token == "" {
+		return 0, nil
+	}
+
+	raw, ok := strings.CutPrefix(token, syncTokenPrefix)
+	if !ok {
+		return 0, fmt.Errorf("sync token %q has an unexpected prefix: %w", token, caldav.ErrInvalidSyncToken)
+	}
+
+	revision, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("sync token %q has an invalid revision: %w", token, caldav.ErrInvalidSyncToken)
+	}
+
+	return revision, nil
+}
+
+// syncToken returns the current sync token of a calendar.
+func (b *Backend) syncToken(ctx context.Context, path store.Path) (string, error) {
+	revision, err := b.store.CalendarRevision(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("error while fetching revision of calendar at path %q: %w", path, err)
+	}
+
+	return formatSyncToken(revision), nil
+}
+
+func (b *Backend) SyncCollection(
+	ctx context.Context,
+	path string,
+	query *caldav.SyncQuery,
+) (response *caldav.SyncResponse, err error) {
+	ctx, span := observability.TracerFromContext(ctx).Start(ctx, "SyncCollection")
+	defer func() { observability.SpanEnd(span, err) }()
+
+	if _, _, err := b.authorize(ctx, normalizePath(path), accessRead); err != nil {
+		return nil, err
+	}
+
+	since, err := parseSyncToken(query.SyncToken)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, revision, err := b.store.ListCalendarChanges(ctx, normalizePath(path), since)
+	if errors.Is(err, store.ErrInvalidRevision) {
+		return nil, fmt.Errorf(
+			"error while listing changes of calendar at path %q: %w",
+			path,
+			caldav.ErrInvalidSyncToken,
+		)
+	} else if err != nil {
+		return nil, fmt.Errorf("error while listing changes of calendar at path %q from storage: %w", path, err)
+	}
+
+	// On the initial sync the client holds no objects, so there is no need to
+	// report the ones that have been deleted.
+	if since == 0 {
+		changes = slices.DeleteFunc(changes, func(change store.Change) bool { return change.Deleted })
+	}
+
+	response = &caldav.SyncResponse{}
+	if query.Limit > 0 && len(changes) > query.Limit {
+		changes = changes[:query.Limit]
+		revision = changes[len(changes)-1].Revision
+		response.Truncated = true
+	}
+
+	response.SyncToken = formatSyncToken(revision)
+
+	updated, err := b.getChangedObjects(ctx, changes, &query.CompRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, change := range changes {
+		if object := updated[i]; object != nil {
+			response.Updated = append(response.Updated, *object)
+		} else {
+			response.Deleted = append(response.Deleted, change.Path.String())
+		}
+	}
+
+	return response, nil
+}
+
+// getChangedObjects fetches the objects updated by the given changes. The
+// result is nil for deleted objects, including those deleted after the
+// changes were listed, which the client may as well learn about now.
+func (b *Backend) getChangedObjects(
+	ctx context.Context,
+	changes []store.Change,
+	req *caldav.CalendarCompRequest,
+) ([]*caldav.CalendarObject, error) {
+	updated := make([]*caldav.CalendarObject, len(changes))
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(MaxDecodesInParallel)
+
+	for i, change := range changes {
+		if change.Deleted {
+			continue
+		}
+
+		eg.Go(func() error {
+			obj, err := b.store.GetCalendarObject(ctx, change.Path)
+			if errors.Is(err, store.ErrNotFound) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("error while fetching changed object at path %q from storage: %w", change.Path, err)
+			}
+
+			object, err := storeObjectToCaldavObject(ctx, *obj)
+			if err != nil {
+				return fmt.Errorf(
+					"error while converting object at path %q to a caldav CalendarObject: %w",
+					obj.Path,
+					err,
+				)
+			}
+
+			object, err = mapCalendarObject(object, req)
+			if err != nil {
+				return fmt.Errorf("error while applying maps to calendar object %q: %w", obj.Path, err)
+			}
+
+			updated[i] = object
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
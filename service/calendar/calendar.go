@@ -13,6 +13,7 @@ import (
 	"github.com/teapotovh/teapot/lib/ldap"
 	"github.com/teapotovh/teapot/lib/webdav/caldav"
 	"github.com/teapotovh/teapot/service/calendar/backend"
+	"github.com/teapotovh/teapot/service/calendar/reminder"
 	"github.com/teapotovh/teapot/service/calendar/store"
 )
 
@@ -24,14 +25,17 @@ type Calendar struct {
 	ldapFactory *ldap.Factory
 	httpAuth    *httpauth.BasicAuth
 
-	store   store.Store
-	backend *backend.Backend
+	store    store.Store
+	backend  *backend.Backend
+	reminder *reminder.Reminder
 }
 
 type CalendarConfig struct {
-	HTTPLog httplog.HTTPLogConfig
-	LDAP    ldap.LDAPConfig
-	Store   store.StoreConfig
+	HTTPLog  httplog.HTTPLogConfig
+	LDAP     ldap.LDAPConfig
+	Store    store.StoreConfig
+	Backend  backend.BackendConfig
+	Reminder reminder.ReminderConfig
 }
 
 func NewCalendar(config CalendarConfig, logger *slog.Logger) (*Calendar, error) {
@@ -61,7 +65,12 @@ func NewCalendar(config CalendarConfig, logger *slog.Logger) (*Calendar, error)
 		return nil, fmt.Errorf("error while initializing calendar store: %w", err)
 	}
 
-	backend := backend.NewBackend(store, logger.With("component", "backend"))
+	backend := backend.NewBackend(config.Backend, store, ldapFactory, logger.With("component", "backend"))
+
+	reminder, err := reminder.NewReminder(config.Reminder, store, ldapFactory, logger.With("component", "reminder"))
+	if err != nil {
+		return nil, fmt.Errorf("error while initializing calendar reminders: %w", err)
+	}
 
 	calendar := Calendar{
 		logger: logger,
@@ -71,8 +80,9 @@ func NewCalendar(config CalendarConfig, logger *slog.Logger) (*Calendar, error)
 		ldapFactory: ldapFactory,
 		httpAuth:    httpAuth,
 
-		store:   store,
-		backend: backend,
+		store:    store,
+		backend:  backend,
+		reminder: reminder,
 	}
 
 	return &calendar, nil
@@ -82,17 +92,28 @@ func (c *Calendar) Store() store.Store {
 	return c.store
 }
 
+func (c *Calendar) Reminder() *reminder.Reminder {
+	return c.reminder
+}
+
 func (c *Calendar) LDAPFactory() *ldap.Factory {
 	return c.ldapFactory
 }
 
 func (c *Calendar) Handler(prefix string) http.Handler {
-	var handler http.Handler = &caldav.Handler{
+	mux := http.NewServeMux()
+
+	mux.Handle(http.MethodGet+" "+PathFeed, c.adapt(c.handleFeed))
+	mux.Handle(http.MethodPost+" "+PathFeedToken, c.httpAuth.Required(c.adapt(c.handleFeedToken(prefix))))
+	mux.Handle(http.MethodDelete+" "+PathFeedToken, c.httpAuth.Required(c.adapt(c.handleFeedToken(prefix))))
+	mux.Handle(http.MethodPost+" "+PathImport, c.httpAuth.Required(c.adapt(c.handleImport)))
+	mux.Handle("/", c.httpAuth.Required(&caldav.Handler{
 		Prefix:  prefix,
 		Backend: c.backend,
-	}
+	}))
+
+	var handler http.Handler = mux
 
-	handler = c.httpAuth.Required(handler)
 	handler = c.httpAuth.Middleware(handler)
 	handler = c.httpLog.LogMiddleware(handler)
 	handler = c.httpLog.ExtractMiddleware(handler)
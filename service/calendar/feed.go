@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	pathpkg "path"
+	"strings"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	daverr "github.com/teapotovh/teapot/lib/webdav/error"
+	"github.com/teapotovh/teapot/service/calendar/backend"
+)
+
+const (
+	// PathFeed serves the read-only subscription feed of a calendar. It is
+	// only authenticated by the secret token in its path.
+	PathFeed = "/.feed/{token}"
+	// PathFeedToken enables (POST) or disables (DELETE) the subscription
+	// feed of the calendar at path.
+	PathFeedToken = "/.feed-token/{path...}"
+	// PathImport imports an iCalendar file into the calendar at path.
+	PathImport = "/.import/{path...}"
+
+	// MaxImportSize is the maximum size of an imported iCalendar file.
+	MaxImportSize = 32 << 20
+)
+
+type calendarHandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (c *Calendar) adapt(fn calendarHandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := fn(w, r); err != nil {
+			httpErr := daverr.HTTPErrorFromError(err)
+			if httpErr.Code == http.StatusInternalServerError {
+				c.logger.ErrorContext(r.Context(), "error while handling request", "err", err)
+			}
+
+			http.Error(w, httpErr.Error(), httpErr.Code)
+		}
+	})
+}
+
+func (c *Calendar) handleFeed(w http.ResponseWriter, r *http.Request) error {
+	// Some clients expect subscription URLs to end with an extension
+	token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+
+	feed, err := c.backend.Feed(r.Context(), token)
+	if errors.Is(err, backend.ErrFeedNotFound) {
+		return daverr.HTTPErrorf(http.StatusNotFound, "calendar: %w", err)
+	} else if err != nil {
+		return fmt.Errorf("error while building feed: %w", err)
+	}
+
+	w.Header().Set("Content-Type", caldav.MIMEType)
+
+	return feed.SerializeTo(w)
+}
+
+func (c *Calendar) handleFeedToken(prefix string) calendarHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		path := "/" + r.PathValue("path")
+
+		if r.Method == http.MethodDelete {
+			if err := c.backend.RevokeFeedToken(r.Context(), path); err != nil {
+				return err
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+
+			return nil
+		}
+
+		token, err := c.backend.CreateFeedToken(r.Context(), path)
+		if err != nil {
+			return err
+		}
+
+		feedPath := pathpkg.Join(prefix, strings.Replace(PathFeed, "{token}", token+".ics", 1))
+
+		w.Header().Set("Location", feedPath)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+
+		if _, err := fmt.Fprintln(w, feedPath); err != nil {
+			return fmt.Errorf("error while writing response: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func (c *Calendar) handleImport(w http.ResponseWriter, r *http.Request) error {
+	t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || t != caldav.MIMEType {
+		return daverr.HTTPErrorf(http.StatusUnsupportedMediaType, "calendar: expected %s body", caldav.MIMEType)
+	}
+
+	data, err := ics.ParseCalendar(http.MaxBytesReader(w, r.Body, MaxImportSize))
+	if err != nil {
+		return daverr.HTTPErrorf(http.StatusBadRequest, "calendar: failed to parse iCalendar: %w", err)
+	}
+
+	result, err := c.backend.ImportCalendar(r.Context(), "/"+r.PathValue("path"), data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		return fmt.Errorf("error while encoding import result: %w", err)
+	}
+
+	return nil
+}
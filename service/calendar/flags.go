@@ -5,6 +5,8 @@ import (
 
 	"github.com/teapotovh/teapot/lib/httplog"
 	"github.com/teapotovh/teapot/lib/ldap"
+	"github.com/teapotovh/teapot/service/calendar/backend"
+	"github.com/teapotovh/teapot/service/calendar/reminder"
 	"github.com/teapotovh/teapot/service/calendar/store"
 )
 
@@ -20,11 +22,19 @@ func CalendarFlagSet() (*flag.FlagSet, func() CalendarConfig) {
 	storeFS, getStoreConfig := store.StoreFlagSet()
 	fs.AddFlagSet(storeFS)
 
+	backendFS, getBackendConfig := backend.BackendFlagSet()
+	fs.AddFlagSet(backendFS)
+
+	reminderFS, getReminderConfig := reminder.ReminderFlagSet()
+	fs.AddFlagSet(reminderFS)
+
 	return fs, func() CalendarConfig {
 		return CalendarConfig{
-			HTTPLog: getHTTPLogConfig(),
-			LDAP:    getLDAPConfig(),
-			Store:   getStoreConfig(),
+			HTTPLog:  getHTTPLogConfig(),
+			LDAP:     getLDAPConfig(),
+			Store:    getStoreConfig(),
+			Backend:  getBackendConfig(),
+			Reminder: getReminderConfig(),
 		}
 	}
 }
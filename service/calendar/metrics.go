@@ -10,6 +10,8 @@ func (c *Calendar) Metrics() []prometheus.Collector {
 	collectors := []prometheus.Collector{}
 
 	collectors = append(collectors, c.httpAuth.Metrics()...)
+	collectors = append(collectors, c.backend.Metrics()...)
+	collectors = append(collectors, c.reminder.Metrics()...)
 
 	return collectors
 }
@@ -0,0 +1,72 @@
+package reminder
+
+import (
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+func ReminderFlagSet() (*flag.FlagSet, func() ReminderConfig) {
+	fs := flag.NewFlagSet("calendar/reminder", flag.ExitOnError)
+
+	notifiers := fs.StringSlice(
+		"calendar-reminder-notifiers",
+		[]string{},
+		"the notifiers delivering alarms, any of smtp and webhook. reminders are disabled if empty",
+	)
+	interval := fs.Duration("calendar-reminder-interval", time.Minute, "how often to scan for alarms to be delivered")
+	grace := fs.Duration(
+		"calendar-reminder-grace",
+		time.Hour,
+		"how long after their trigger alarms which could not be delivered are retried",
+	)
+	horizon := fs.Duration(
+		"calendar-reminder-horizon",
+		7*24*time.Hour,
+		"the maximum distance between an alarm and its event. farther alarms are not delivered",
+	)
+
+	smtpAddr := fs.String("calendar-reminder-smtp-addr", "", "the address of the SMTP server, as host:port")
+	smtpUsername := fs.String(
+		"calendar-reminder-smtp-username",
+		"",
+		"the username to authenticate with the SMTP server. authentication is disabled if empty",
+	)
+	smtpPassword := fs.String(
+		"calendar-reminder-smtp-password",
+		"",
+		"the password to authenticate with the SMTP server",
+	)
+	smtpFrom := fs.String("calendar-reminder-smtp-from", "", "the sender address of reminder emails")
+
+	webhookURL := fs.String(
+		"calendar-reminder-webhook-url",
+		"",
+		"the URL reminders are posted to, i.e., alertd's webhook",
+	)
+	webhookTimeout := fs.Duration(
+		"calendar-reminder-webhook-timeout",
+		10*time.Second,
+		"the timeout of webhook requests",
+	)
+
+	return fs, func() ReminderConfig {
+		return ReminderConfig{
+			Notifiers: *notifiers,
+			Interval:  *interval,
+			Grace:     *grace,
+			Horizon:   *horizon,
+
+			SMTP: SMTPConfig{
+				Addr:     *smtpAddr,
+				Username: *smtpUsername,
+				Password: *smtpPassword,
+				From:     *smtpFrom,
+			},
+			Webhook: WebhookConfig{
+				URL:     *webhookURL,
+				Timeout: *webhookTimeout,
+			},
+		}
+	}
+}
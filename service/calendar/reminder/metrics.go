@@ -0,0 +1,41 @@
+package reminder
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	scans         *prometheus.CounterVec
+	notifications *prometheus.CounterVec
+}
+
+const (
+	metricsStatusSuccess = "success"
+	metricsStatusFailed  = "failed"
+)
+
+func (r *Reminder) initMetrics() {
+	r.metrics.scans = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "calendar_reminder_scans_total",
+			Help: "Total number of scans for alarms to be delivered",
+		},
+		[]string{"status"},
+	)
+
+	r.metrics.notifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "calendar_reminder_notifications_total",
+			Help: "Total number of alarm deliveries grouped by notifier",
+		},
+		[]string{"notifier", "status"},
+	)
+}
+
+// Metrics implements observability.Metrics.
+func (r *Reminder) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		r.metrics.scans,
+		r.metrics.notifications,
+	}
+}
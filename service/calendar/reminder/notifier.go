@@ -0,0 +1,71 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/teapotovh/teapot/lib/ldap"
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+)
+
+const (
+	NotifierSMTP    = "smtp"
+	NotifierWebhook = "webhook"
+)
+
+var ErrInvalidNotifier = errors.New("invalid notifier")
+
+// Notification is an alarm to be delivered to the owner of its calendar.
+type Notification struct {
+	// Path is the path of the calendar object the alarm belongs to.
+	Path  string
+	Alarm caldav.Alarm
+	Owner *ldap.User
+}
+
+// Title is a short summary of the notification, suitable as an email subject.
+func (n Notification) Title() string {
+	summary := n.Alarm.Summary
+	if summary == "" {
+		summary = "Untitled event"
+	}
+
+	return "Reminder: " + summary
+}
+
+// Body is the plain text content of the notification.
+func (n Notification) Body() string {
+	var builder strings.Builder
+
+	builder.WriteString(n.Title() + "\n\n")
+	builder.WriteString("Starts: " + n.Alarm.Start.Format("Mon, 02 Jan 2006 15:04 MST") + "\n")
+
+	if !n.Alarm.End.IsZero() && !n.Alarm.End.Equal(n.Alarm.Start) {
+		builder.WriteString("Ends: " + n.Alarm.End.Format("Mon, 02 Jan 2006 15:04 MST") + "\n")
+	}
+
+	if n.Alarm.Description != "" {
+		builder.WriteString("\n" + n.Alarm.Description + "\n")
+	}
+
+	return builder.String()
+}
+
+// Notifier delivers notifications for alarms.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+func newNotifier(name string, config ReminderConfig, logger *slog.Logger) (Notifier, error) {
+	switch name {
+	case NotifierSMTP:
+		return NewSMTP(config.SMTP, logger.With("notifier", name))
+	case NotifierWebhook:
+		return NewWebhook(config.Webhook, logger.With("notifier", name))
+	default:
+		return nil, fmt.Errorf("error instantiating notifier %q: %w", name, ErrInvalidNotifier)
+	}
+}
@@ -0,0 +1,252 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/teapotovh/teapot/lib/ldap"
+	"github.com/teapotovh/teapot/lib/run"
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+	"github.com/teapotovh/teapot/service/calendar/store"
+)
+
+// The actions of the alarms which are delivered. Others, like AUDIO, are
+// left to clients.
+const (
+	ActionDisplay = "DISPLAY"
+	ActionEmail   = "EMAIL"
+)
+
+type ReminderConfig struct {
+	Notifiers []string
+	Interval  time.Duration
+	Grace     time.Duration
+	Horizon   time.Duration
+
+	SMTP    SMTPConfig
+	Webhook WebhookConfig
+}
+
+// Reminder periodically scans the calendars in the store for alarms, and
+// delivers them to the owners of the calendars through the configured
+// notifiers. Deliveries are recorded in the store, so that each alarm is
+// delivered once by each notifier, even across restarts.
+type Reminder struct {
+	logger *slog.Logger
+
+	store       store.Store
+	ldapFactory *ldap.Factory
+	notifiers   map[string]Notifier
+
+	interval time.Duration
+	grace    time.Duration
+	horizon  time.Duration
+
+	metrics metrics
+}
+
+func NewReminder(
+	config ReminderConfig,
+	store store.Store,
+	ldapFactory *ldap.Factory,
+	logger *slog.Logger,
+) (*Reminder, error) {
+	notifiers := map[string]Notifier{}
+
+	for _, name := range config.Notifiers {
+		notifier, err := newNotifier(name, config, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		notifiers[name] = notifier
+	}
+
+	reminder := Reminder{
+		logger: logger,
+
+		store:       store,
+		ldapFactory: ldapFactory,
+		notifiers:   notifiers,
+
+		interval: config.Interval,
+		grace:    config.Grace,
+		horizon:  config.Horizon,
+	}
+
+	reminder.initMetrics()
+
+	return &reminder, nil
+}
+
+// Run implements run.Runnable.
+func (r *Reminder) Run(ctx context.Context, notify run.Notify) error {
+	notify.Notify()
+
+	if len(r.notifiers) == 0 {
+		r.logger.Info("no notifiers configured, reminders are disabled")
+		<-ctx.Done()
+
+		return nil
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.scan(ctx, time.Now()); err != nil {
+			r.metrics.scans.WithLabelValues(metricsStatusFailed).Inc()
+			r.logger.ErrorContext(ctx, "error while scanning for alarms", "err", err)
+		} else {
+			r.metrics.scans.WithLabelValues(metricsStatusSuccess).Inc()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan delivers the alarms which triggered before now. Alarms which could not
+// be delivered, i.e., while calendard was not running, are retried until they
+// are older than the grace period.
+func (r *Reminder) scan(ctx context.Context, now time.Time) error {
+	from := now.Add(-r.grace)
+	if err := r.store.PruneDeliveredAlarms(ctx, from); err != nil {
+		return fmt.Errorf("error while pruning delivered alarms: %w", err)
+	}
+
+	// Alarms may trigger well before or after their event, up to the horizon.
+	query := store.ObjectQuery{Start: from.Add(-r.horizon), End: now.Add(r.horizon), Alarms: true}
+
+	objects, err := r.store.QueryCalendarObjects(ctx, "/", query)
+	if err != nil {
+		return fmt.Errorf("error while querying calendar objects: %w", err)
+	}
+
+	owners := map[string]*ldap.User{}
+
+	for _, object := range objects {
+		cal, err := object.Calendar()
+		if err != nil {
+			r.logger.WarnContext(ctx, "skipping invalid calendar object", "path", object.Path, "err", err)
+			continue
+		}
+
+		alarms, err := caldav.Alarms(cal, from, now)
+		if err != nil {
+			r.logger.WarnContext(ctx, "skipping calendar object with invalid alarms", "path", object.Path, "err", err)
+			continue
+		}
+
+		for _, alarm := range alarms {
+			if alarm.Action != ActionDisplay && alarm.Action != ActionEmail {
+				continue
+			}
+
+			owner, err := r.owner(ctx, object.Path, owners)
+			if err != nil {
+				r.logger.WarnContext(ctx, "skipping alarm without owner", "path", object.Path, "err", err)
+				continue
+			}
+
+			r.deliver(ctx, Notification{Path: string(object.Path), Alarm: alarm, Owner: owner})
+		}
+	}
+
+	return nil
+}
+
+// owner looks up the owner of the calendar of an object in LDAP, caching the
+// result in owners.
+func (r *Reminder) owner(ctx context.Context, path store.Path, owners map[string]*ldap.User) (*ldap.User, error) {
+	username, _, _ := strings.Cut(strings.TrimPrefix(string(path), "/"), "/")
+	if owner, ok := owners[username]; ok {
+		return owner, nil
+	}
+
+	client, err := r.ldapFactory.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating LDAP client: %w", err)
+	}
+	defer client.Close()
+
+	owner, err := client.User(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("error while looking up owner of calendar object %q: %w", path, err)
+	}
+
+	owners[username] = owner
+
+	return owner, nil
+}
+
+// deliver sends a notification through each notifier which has not yet
+// delivered it. Failures are logged, and the delivery is retried on the next
+// scan, so that a single alarm cannot hold back all the others.
+func (r *Reminder) deliver(ctx context.Context, notification Notification) {
+	for name, notifier := range r.notifiers {
+		alarm := store.DeliveredAlarm{
+			Path:     store.Path(notification.Path),
+			Trigger:  notification.Alarm.Trigger,
+			Action:   notification.Alarm.Action,
+			Notifier: name,
+		}
+
+		delivered, err := r.store.AlarmDelivered(ctx, alarm)
+		if err != nil {
+			r.logger.WarnContext(
+				ctx,
+				"could not check delivery of alarm",
+				"notifier",
+				name,
+				"path",
+				alarm.Path,
+				"err",
+				err,
+			)
+			continue
+		} else if delivered {
+			continue
+		}
+
+		if err := notifier.Notify(ctx, notification); err != nil {
+			r.metrics.notifications.WithLabelValues(name, metricsStatusFailed).Inc()
+			r.logger.WarnContext(
+				ctx,
+				"could not deliver alarm",
+				"notifier",
+				name,
+				"path",
+				notification.Path,
+				"trigger",
+				notification.Alarm.Trigger,
+				"err",
+				err,
+			)
+
+			continue
+		}
+
+		r.metrics.notifications.WithLabelValues(name, metricsStatusSuccess).Inc()
+
+		// The alarm may be delivered again by the next scan
+		if err := r.store.MarkAlarmDelivered(ctx, alarm); err != nil {
+			r.logger.ErrorContext(
+				ctx,
+				"could not record delivery of alarm",
+				"notifier",
+				name,
+				"path",
+				alarm.Path,
+				"err",
+				err,
+			)
+		}
+	}
+}
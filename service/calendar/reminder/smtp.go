@@ -0,0 +1,91 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingSMTPAddr = errors.New("SMTP server address not provided")
+	ErrMissingSMTPFrom = errors.New("SMTP sender address not provided")
+)
+
+type SMTPConfig struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP delivers notifications by email to the address of the owner of the
+// calendar. The attendees of EMAIL alarms are ignored, so that calendars
+// cannot be used to send email to arbitrary addresses.
+type SMTP struct {
+	logger *slog.Logger
+
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTP(config SMTPConfig, logger *slog.Logger) (*SMTP, error) {
+	if config.Addr == "" {
+		return nil, ErrMissingSMTPAddr
+	}
+
+	if config.From == "" {
+		return nil, ErrMissingSMTPFrom
+	}
+
+	var auth smtp.Auth
+
+	if config.Username != "" {
+		host, _, err := net.SplitHostPort(config.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing SMTP server address %q: %w", config.Addr, err)
+		}
+
+		auth = smtp.PlainAuth("", config.Username, config.Password, host)
+	}
+
+	return &SMTP{
+		logger: logger,
+
+		addr: config.Addr,
+		auth: auth,
+		from: config.From,
+	}, nil
+}
+
+// Notify implements Notifier.
+func (s *SMTP) Notify(ctx context.Context, notification Notification) error {
+	to := notification.Owner.Mail
+	if to == "" {
+		s.logger.WarnContext(ctx, "skipping reminder for user without email", "user", notification.Owner.Username)
+		return nil
+	}
+
+	var message strings.Builder
+
+	message.WriteString("From: " + s.from + "\r\n")
+	message.WriteString("To: " + to + "\r\n")
+	message.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", notification.Title()) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(notification.Body(), "\n", "\r\n"))
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{to}, []byte(message.String())); err != nil {
+		return fmt.Errorf("error while sending email to %q: %w", to, err)
+	}
+
+	return nil
+}
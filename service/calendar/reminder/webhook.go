@@ -0,0 +1,134 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/alertmanager/template"
+)
+
+// The status, labels and annotations of AlertManager alerts used by alertd.
+const (
+	StatusFiring          = "firing"
+	LabelAlertName        = "alertname"
+	LabelSeverity         = "severity"
+	AnnotationDescription = "description"
+
+	WebhookReceiver = "calendar"
+	WebhookSeverity = "reminder"
+)
+
+var (
+	ErrMissingWebhookURL      = errors.New("webhook URL not provided")
+	ErrInvalidWebhookResponse = errors.New("invalid webhook response")
+)
+
+type WebhookConfig struct {
+	URL     string
+	Timeout time.Duration
+}
+
+// Webhook delivers notifications as AlertManager webhook payloads, which can
+// be consumed by alertd.
+type Webhook struct {
+	logger *slog.Logger
+
+	httpClient http.Client
+	url        string
+}
+
+func NewWebhook(config WebhookConfig, logger *slog.Logger) (*Webhook, error) {
+	if config.URL == "" {
+		return nil, ErrMissingWebhookURL
+	}
+
+	return &Webhook{
+		logger: logger,
+
+		httpClient: http.Client{Timeout: config.Timeout},
+		url:        config.URL,
+	}, nil
+}
+
+// fingerprint identifies the alarm of a notification.
+func fingerprint(notification Notification) string {
+	hash := sha256.Sum256(fmt.Appendf(
+		nil,
+		"%s\x00%s\x00%s",
+		notification.Path,
+		notification.Alarm.Trigger.UTC().Format(time.RFC3339),
+		notification.Alarm.Action,
+	))
+
+	return hex.EncodeToString(hash[:8])
+}
+
+func webhookData(notification Notification) template.Data {
+	labels := template.KV{
+		LabelAlertName: notification.Title(),
+		LabelSeverity:  WebhookSeverity,
+		"user":         notification.Owner.Username,
+		"action":       notification.Alarm.Action,
+	}
+	annotations := template.KV{
+		AnnotationDescription: notification.Body(),
+		"path":                notification.Path,
+		"uid":                 notification.Alarm.UID,
+	}
+
+	return template.Data{
+		Receiver: WebhookReceiver,
+		Status:   StatusFiring,
+		Alerts: template.Alerts{{
+			Status:      StatusFiring,
+			Labels:      labels,
+			Annotations: annotations,
+			StartsAt:    notification.Alarm.Trigger,
+			EndsAt:      notification.Alarm.End,
+			Fingerprint: fingerprint(notification),
+		}},
+		GroupLabels:       template.KV{"user": notification.Owner.Username},
+		CommonLabels:      labels,
+		CommonAnnotations: annotations,
+	}
+}
+
+// Notify implements Notifier.
+func (w *Webhook) Notify(ctx context.Context, notification Notification) (err error) {
+	body, err := json.Marshal(webhookData(notification))
+	if err != nil {
+		return fmt.Errorf("error while encoding webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error performing request: %w", err)
+	}
+
+	defer func() {
+		if e := resp.Body.Close(); e != nil && err == nil {
+			err = fmt.Errorf("error while closing response body: %w", e)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error in response (code: %d): %w", resp.StatusCode, ErrInvalidWebhookResponse)
+	}
+
+	return nil
+}
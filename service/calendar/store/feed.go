@@ -0,0 +1,73 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/teapotovh/teapot/lib/pgcache"
+)
+
+// HashFeedToken returns the hash of a feed token, which is stored in place of
+// the token itself and used to look up the calendar of a feed.
+func HashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// feedIndex maps the feed token hashes to the paths of their calendars, so
+// that feeds can be served without scanning all calendars.
+type feedIndex struct {
+	mu     sync.RWMutex
+	paths  map[string]Path
+	hashes map[Path]string
+}
+
+func newFeedIndex() *feedIndex {
+	return &feedIndex{
+		paths:  map[string]Path{},
+		hashes: map[Path]string{},
+	}
+}
+
+// Lookup returns the path of the calendar whose feed has the given token hash.
+func (idx *feedIndex) Lookup(hash string) (Path, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	path, ok := idx.paths[hash]
+
+	return path, ok
+}
+
+// Stored implements pgcache.Listener.
+func (idx *feedIndex) Stored(path Path, calendar Calendar) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(path)
+
+	if hash := calendar.Metadata.FeedTokenHash; hash != "" {
+		idx.paths[hash] = path
+		idx.hashes[path] = hash
+	}
+}
+
+// Deleted implements pgcache.Listener.
+func (idx *feedIndex) Deleted(path Path) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(path)
+}
+
+// remove drops the token hash of a calendar. The caller must hold the lock.
+func (idx *feedIndex) remove(path Path) {
+	if hash, ok := idx.hashes[path]; ok {
+		delete(idx.paths, hash)
+		delete(idx.hashes, path)
+	}
+}
+
+// Ensure *feedIndex implements pgcache.Listener.
+var _ pgcache.Listener[Path, Calendar] = &feedIndex{}
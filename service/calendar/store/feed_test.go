@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemGetCalendarByFeedToken(t *testing.T) {
+	ctx := context.Background()
+	m := NewMem()
+
+	first, second := HashFeedToken("first"), HashFeedToken("second")
+	if first == second {
+		t.Fatalf("expected different tokens to have different hashes")
+	}
+
+	cal := Calendar{Path: "/alice/calendar", Metadata: CalendarMetadata{FeedTokenHash: first}}
+	if err := m.CreateCalendar(ctx, cal); err != nil {
+		t.Fatalf("could not create calendar: %v", err)
+	}
+
+	if err := m.CreateCalendar(ctx, Calendar{Path: "/alice/other"}); err != nil {
+		t.Fatalf("could not create calendar: %v", err)
+	}
+
+	found, err := m.GetCalendarByFeedToken(ctx, first)
+	if err != nil {
+		t.Fatalf("could not get calendar by feed token: %v", err)
+	}
+
+	if found.Path != cal.Path {
+		t.Errorf("expected calendar %q, got %q", cal.Path, found.Path)
+	}
+
+	cal.Metadata.FeedTokenHash = second
+	if err := m.UpdateCalendar(ctx, cal); err != nil {
+		t.Fatalf("could not update calendar: %v", err)
+	}
+
+	if _, err := m.GetCalendarByFeedToken(ctx, first); !errors.Is(err, ErrCalendarNotFound) {
+		t.Errorf("expected the replaced token to be revoked, got %v", err)
+	}
+
+	if _, err := m.GetCalendarByFeedToken(ctx, second); err != nil {
+		t.Errorf("could not get calendar by new feed token: %v", err)
+	}
+
+	if err := m.DeleteCalendar(ctx, cal.Path); err != nil {
+		t.Fatalf("could not delete calendar: %v", err)
+	}
+
+	if _, err := m.GetCalendarByFeedToken(ctx, second); !errors.Is(err, ErrCalendarNotFound) {
+		t.Errorf("expected the token of a deleted calendar to be revoked, got %v", err)
+	}
+
+	if _, err := m.GetCalendarByFeedToken(ctx, ""); !errors.Is(err, ErrCalendarNotFound) {
+		t.Errorf("expected the empty hash to match no calendar, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/teapotovh/teapot/lib/webdav/caldav"
+)
+
+// ObjectIndex summarizes a calendar object, so that queries can be narrowed
+// down without fetching and parsing it. Zero times are unbounded, and objects
+// without a component always match, as they were stored before indexing.
+type ObjectIndex struct {
+	// Component is the type of the components of the object, i.e., VEVENT.
+	Component string
+	UID       string
+	// Start and End bound all the instances of the components of the object.
+	Start time.Time
+	End   time.Time
+	// Alarms tells whether any component of the object has a VALARM.
+	Alarms bool
+}
+
+// ObjectQuery narrows down the calendar objects listed by a query to the ones
+// with components of a type overlapping the time range. Empty fields do not
+// restrict the query.
+type ObjectQuery struct {
+	Component string
+	Start     time.Time
+	End       time.Time
+	// Alarms restricts the query to objects with alarms.
+	Alarms bool
+}
+
+// NewObjectIndex computes the index of a calendar object. Objects whose time
+// range cannot be computed are considered unbounded, so that they are never
+// left out of a query.
+func NewObjectIndex(cal *ics.Calendar) ObjectIndex {
+	var index ObjectIndex
+
+	for _, comp := range cal.Components {
+		if _, ok := comp.(*ics.VTimezone); ok {
+			continue
+		}
+
+		if index.Component == "" {
+			index.Component = caldav.ComponentName(comp)
+			index.UID = componentUID(comp)
+		}
+
+		index.Alarms = index.Alarms || hasAlarms(comp)
+	}
+
+	if start, end, err := caldav.TimeRange(cal); err == nil {
+		index.Start, index.End = start, end
+	}
+
+	return index
+}
+
+// componentUID returns the UID of a component, if any.
+func componentUID(comp ics.Component) string {
+	for _, prop := range comp.UnknownPropertiesIANAProperties() {
+		if prop.IANAToken == string(ics.ComponentPropertyUniqueId) {
+			return prop.Value
+		}
+	}
+
+	return ""
+}
+
+// hasAlarms reports whether a component has any VALARM.
+func hasAlarms(comp ics.Component) bool {
+	for _, child := range comp.SubComponents() {
+		if _, ok := child.(*ics.VAlarm); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Matches reports whether an object with this index may match the query. It
+// may match objects which do not, but never leaves out any which does.
+func (index ObjectIndex) Matches(query ObjectQuery) bool {
+	if index.Component != "" && query.Component != "" && index.Component != query.Component {
+		return false
+	}
+
+	if !index.Alarms && query.Alarms && index.Component != "" {
+		return false
+	}
+
+	if !index.Start.IsZero() && !query.End.IsZero() && !index.Start.Before(query.End) {
+		return false
+	}
+
+	// Objects taking no time match ranges starting right at their end
+	if !index.End.IsZero() && !query.Start.IsZero() && index.End.Before(query.Start) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+func parseIndexCalendar(t *testing.T, components string) *ics.Calendar {
+	t.Helper()
+
+	raw := "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:test\n" + components + "END:VCALENDAR\n"
+
+	cal, err := ics.ParseCalendar(strings.NewReader(strings.ReplaceAll(raw, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("could not parse calendar: %v", err)
+	}
+
+	return cal
+}
+
+func mustParseIndexTime(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	if value == "" {
+		return time.Time{}
+	}
+
+	parsed, err := time.Parse("20060102T150405Z", value)
+	if err != nil {
+		t.Fatalf("could not parse time %q: %v", value, err)
+	}
+
+	return parsed
+}
+
+func TestNewObjectIndex(t *testing.T) {
+	tests := []struct {
+		name       string
+		components string
+		component  string
+		uid        string
+		start, end string
+		alarms     bool
+	}{
+		{
+			name: "event",
+			components: "BEGIN:VEVENT\nUID:a\nDTSTAMP:20260101T000000Z\n" +
+				"DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\nEND:VEVENT\n",
+			component: "VEVENT",
+			uid:       "a",
+			start:     "20260105T100000Z",
+			end:       "20260105T110000Z",
+		},
+		{
+			name: "timezone first",
+			components: "BEGIN:VTIMEZONE\nTZID:UTC\nBEGIN:STANDARD\nDTSTART:19700101T000000\n" +
+				"TZOFFSETFROM:+0000\nTZOFFSETTO:+0000\nEND:STANDARD\nEND:VTIMEZONE\n" +
+				"BEGIN:VTODO\nUID:b\nDTSTAMP:20260101T000000Z\nDTSTART:20260105T100000Z\n" +
+				"DUE:20260106T100000Z\nEND:VTODO\n",
+			component: "VTODO",
+			uid:       "b",
+			start:     "20260105T100000Z",
+			end:       "20260106T100000Z",
+		},
+		{
+			name: "recurring forever",
+			components: "BEGIN:VEVENT\nUID:c\nDTSTAMP:20260101T000000Z\n" +
+				"DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\nRRULE:FREQ=DAILY\nEND:VEVENT\n",
+			component: "VEVENT",
+			uid:       "c",
+			start:     "20260105T100000Z",
+		},
+		{
+			name: "alarm in override",
+			components: "BEGIN:VEVENT\nUID:e\nDTSTAMP:20260101T000000Z\n" +
+				"DTSTART:20260105T100000Z\nDTEND:20260105T110000Z\nRRULE:FREQ=DAILY;COUNT=2\nEND:VEVENT\n" +
+				"BEGIN:VEVENT\nUID:e\nDTSTAMP:20260101T000000Z\nRECURRENCE-ID:20260106T100000Z\n" +
+				"DTSTART:20260106T120000Z\nDTEND:20260106T130000Z\n" +
+				"BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER:-PT15M\nDESCRIPTION:Reminder\nEND:VALARM\nEND:VEVENT\n",
+			component: "VEVENT",
+			uid:       "e",
+			start:     "20260105T100000Z",
+			end:       "20260106T130000Z",
+			alarms:    true,
+		},
+		{
+			name:       "invalid time",
+			components: "BEGIN:VEVENT\nUID:d\nDTSTAMP:20260101T000000Z\nDTSTART:tomorrow\nEND:VEVENT\n",
+			component:  "VEVENT",
+			uid:        "d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := NewObjectIndex(parseIndexCalendar(t, tt.components))
+
+			if index.Component != tt.component {
+				t.Errorf("expected component %q, got %q", tt.component, index.Component)
+			}
+
+			if index.UID != tt.uid {
+				t.Errorf("expected uid %q, got %q", tt.uid, index.UID)
+			}
+
+			if start := mustParseIndexTime(t, tt.start); !index.Start.Equal(start) {
+				t.Errorf("expected start %v, got %v", start, index.Start)
+			}
+
+			if end := mustParseIndexTime(t, tt.end); !index.End.Equal(end) {
+				t.Errorf("expected end %v, got %v", end, index.End)
+			}
+
+			if index.Alarms != tt.alarms {
+				t.Errorf("expected alarms to be %t, got %t", tt.alarms, index.Alarms)
+			}
+		})
+	}
+}
+
+func TestObjectIndexMatches(t *testing.T) {
+	index := ObjectIndex{
+		Component: "VEVENT",
+		Start:     mustParseIndexTime(t, "20260105T100000Z"),
+		End:       mustParseIndexTime(t, "20260105T110000Z"),
+	}
+
+	tests := []struct {
+		name       string
+		index      ObjectIndex
+		component  string
+		start, end string
+		alarms     bool
+		matches    bool
+	}{
+		{name: "unrestricted", index: index, matches: true},
+		{name: "component", index: index, component: "VEVENT", matches: true},
+		{name: "other component", index: index, component: "VTODO"},
+		{name: "overlapping", index: index, start: "20260105T103000Z", end: "20260105T120000Z", matches: true},
+		{name: "before", index: index, start: "20260104T000000Z", end: "20260105T100000Z"},
+		{name: "after", index: index, start: "20260105T110001Z", end: "20260106T000000Z"},
+		{name: "starting at the end", index: index, start: "20260105T110000Z", matches: true},
+		{name: "open start", index: index, end: "20260105T103000Z", matches: true},
+		{name: "open end", index: index, start: "20260105T103000Z", matches: true},
+		{name: "unbounded", index: ObjectIndex{Component: "VEVENT"}, start: "20300101T000000Z", matches: true},
+		{name: "not indexed", index: ObjectIndex{}, component: "VTODO", alarms: true, matches: true},
+		{name: "without alarms", index: index, alarms: true},
+		{name: "with alarms", index: ObjectIndex{Component: "VEVENT", Alarms: true}, alarms: true, matches: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := ObjectQuery{
+				Component: tt.component,
+				Start:     mustParseIndexTime(t, tt.start),
+				End:       mustParseIndexTime(t, tt.end),
+				Alarms:    tt.alarms,
+			}
+
+			if matches := tt.index.Matches(query); matches != tt.matches {
+				t.Errorf("expected match to be %t, got %t", tt.matches, matches)
+			}
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package store
 
 import (
+	"cmp"
 	"context"
+	"fmt"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/trace"
@@ -16,6 +20,11 @@ type Mem struct {
 	mu        sync.RWMutex
 	calendars map[Path]Calendar
 	objects   map[Path]Object
+	revisions map[Path]uint64
+	changes   map[Path]Change
+	delivered map[DeliveredAlarm]struct{}
+	feeds     *feedIndex
+	shares    *shareIndex
 
 	metrics metrics
 }
@@ -24,6 +33,11 @@ func NewMem() *Mem {
 	m := Mem{
 		calendars: map[Path]Calendar{},
 		objects:   map[Path]Object{},
+		revisions: map[Path]uint64{},
+		changes:   map[Path]Change{},
+		delivered: map[DeliveredAlarm]struct{}{},
+		feeds:     newFeedIndex(),
+		shares:    newShareIndex(),
 	}
 	m.metrics.initMetrics("mem")
 
@@ -48,6 +62,8 @@ func (m *Mem) CreateCalendar(ctx context.Context, calendar Calendar) error {
 	}
 
 	m.calendars[calendar.Path] = calendar
+	m.feeds.Stored(calendar.Path, calendar)
+	m.shares.Stored(calendar.Path, calendar)
 
 	return nil
 }
@@ -67,6 +83,22 @@ func (m *Mem) ListCalendars(ctx context.Context, basePath Path) ([]Calendar, err
 	return calendars, nil
 }
 
+// ListSharedCalendars implements Store.
+func (m *Mem) ListSharedCalendars(ctx context.Context, username string, groups []string) ([]Calendar, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var calendars []Calendar
+
+	for _, path := range m.shares.Lookup(username, groups) {
+		if calendar, exists := m.calendars[path]; exists {
+			calendars = append(calendars, calendar)
+		}
+	}
+
+	return calendars, nil
+}
+
 // GetCalendar implements Store.
 func (m *Mem) GetCalendar(ctx context.Context, path Path) (*Calendar, error) {
 	m.mu.Lock()
@@ -79,6 +111,67 @@ func (m *Mem) GetCalendar(ctx context.Context, path Path) (*Calendar, error) {
 	return nil, ErrCalendarNotFound
 }
 
+// GetCalendarByFeedToken implements Store.
+func (m *Mem) GetCalendarByFeedToken(ctx context.Context, tokenHash string) (*Calendar, error) {
+	path, ok := m.feeds.Lookup(tokenHash)
+	if !ok {
+		return nil, ErrCalendarNotFound
+	}
+
+	return m.GetCalendar(ctx, path)
+}
+
+// UpdateCalendar implements Store.
+func (m *Mem) UpdateCalendar(ctx context.Context, calendar Calendar) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.calendars[calendar.Path]; !exists {
+		return ErrCalendarNotFound
+	}
+
+	m.calendars[calendar.Path] = calendar
+	m.feeds.Stored(calendar.Path, calendar)
+	m.shares.Stored(calendar.Path, calendar)
+
+	return nil
+}
+
+// DeleteCalendar implements Store.
+func (m *Mem) DeleteCalendar(ctx context.Context, path Path) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.calendars[path]; !exists {
+		return ErrCalendarNotFound
+	}
+
+	for objectPath := range m.objects {
+		if objectPath.Dir() == path {
+			delete(m.objects, objectPath)
+		}
+	}
+
+	for changePath := range m.changes {
+		if changePath.Dir() == path {
+			delete(m.changes, changePath)
+		}
+	}
+
+	for alarm := range m.delivered {
+		if alarm.Path.Dir() == path {
+			delete(m.delivered, alarm)
+		}
+	}
+
+	delete(m.revisions, path)
+	delete(m.calendars, path)
+	m.feeds.Deleted(path)
+	m.shares.Deleted(path)
+
+	return nil
+}
+
 // CreateCalendarObject implements Store.
 func (m *Mem) CreateCalendarObject(ctx context.Context, object Object) error {
 	m.mu.Lock()
@@ -89,6 +182,7 @@ func (m *Mem) CreateCalendarObject(ctx context.Context, object Object) error {
 	}
 
 	m.objects[object.Path] = object
+	m.recordChange(object.Path, false)
 
 	return nil
 }
@@ -108,6 +202,37 @@ func (m *Mem) ListCalendarObjects(ctx context.Context, path Path) ([]Object, err
 	return objects, nil
 }
 
+// QueryCalendarObjects implements Store.
+func (m *Mem) QueryCalendarObjects(ctx context.Context, path Path, query ObjectQuery) ([]Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var objects []Object
+	for _, object := range m.objects {
+		if strings.HasPrefix(string(object.Path), string(path)) && object.Index.Matches(query) {
+			objects = append(objects, object)
+		}
+	}
+
+	return objects, nil
+}
+
+// CalendarUsage implements Store.
+func (m *Mem) CalendarUsage(ctx context.Context, basePath Path) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var usage Usage
+	for _, object := range m.objects {
+		if strings.HasPrefix(string(object.Path), string(basePath)) {
+			usage.Objects++
+			usage.Bytes += object.Size()
+		}
+	}
+
+	return usage, nil
+}
+
 // GetCalendarObject implements Store.
 func (m *Mem) GetCalendarObject(ctx context.Context, path Path) (*Object, error) {
 	m.mu.Lock()
@@ -130,6 +255,94 @@ func (m *Mem) DeleteCalendarObject(ctx context.Context, path Path) error {
 	}
 
 	delete(m.objects, path)
+	m.recordChange(path, true)
+
+	return nil
+}
+
+// recordChange must be called with the lock held.
+func (m *Mem) recordChange(path Path, deleted bool) {
+	calendar := path.Dir()
+	m.revisions[calendar]++
+	m.changes[path] = Change{
+		Path:     path,
+		Revision: m.revisions[calendar],
+		Deleted:  deleted,
+	}
+}
+
+// CalendarRevision implements Store.
+func (m *Mem) CalendarRevision(ctx context.Context, path Path) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.calendars[path]; !exists {
+		return 0, ErrCalendarNotFound
+	}
+
+	return m.revisions[path], nil
+}
+
+// ListCalendarChanges implements Store.
+func (m *Mem) ListCalendarChanges(ctx context.Context, path Path, since uint64) ([]Change, uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.calendars[path]; !exists {
+		return nil, 0, ErrCalendarNotFound
+	}
+
+	revision := m.revisions[path]
+	if since > revision {
+		return nil, 0, fmt.Errorf("revision %d is ahead of calendar %q: %w", since, path, ErrInvalidRevision)
+	}
+
+	var changes []Change
+	for _, change := range m.changes {
+		if change.Path.Dir() == path && change.Revision > since {
+			changes = append(changes, change)
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Compare(a.Revision, b.Revision)
+	})
+
+	return changes, revision, nil
+}
+
+// AlarmDelivered implements Store.
+func (m *Mem) AlarmDelivered(ctx context.Context, alarm DeliveredAlarm) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alarm.Trigger = alarm.Trigger.UTC()
+	_, delivered := m.delivered[alarm]
+
+	return delivered, nil
+}
+
+// MarkAlarmDelivered implements Store.
+func (m *Mem) MarkAlarmDelivered(ctx context.Context, alarm DeliveredAlarm) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alarm.Trigger = alarm.Trigger.UTC()
+	m.delivered[alarm] = struct{}{}
+
+	return nil
+}
+
+// PruneDeliveredAlarms implements Store.
+func (m *Mem) PruneDeliveredAlarms(ctx context.Context, before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for alarm := range m.delivered {
+		if alarm.Trigger.Before(before) {
+			delete(m.delivered, alarm)
+		}
+	}
 
 	return nil
 }
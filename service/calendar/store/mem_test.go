@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemDeleteCalendar(t *testing.T) {
+	ctx := context.Background()
+	m := NewMem()
+
+	for _, path := range []Path{"/alice/work", "/alice/home"} {
+		if err := m.CreateCalendar(ctx, Calendar{Path: path}); err != nil {
+			t.Fatalf("could not create calendar %q: %v", path, err)
+		}
+
+		object := Object{Path: path + "/event.ics", ModTime: time.Now()}
+		if err := m.CreateCalendarObject(ctx, object); err != nil {
+			t.Fatalf("could not create object in %q: %v", path, err)
+		}
+
+		alarm := DeliveredAlarm{Path: object.Path, Trigger: time.Now(), Action: "DISPLAY", Notifier: "log"}
+		if err := m.MarkAlarmDelivered(ctx, alarm); err != nil {
+			t.Fatalf("could not mark alarm in %q as delivered: %v", path, err)
+		}
+	}
+
+	if err := m.DeleteCalendar(ctx, "/alice/work"); err != nil {
+		t.Fatalf("could not delete calendar: %v", err)
+	}
+
+	// A calendar created at the same path must not inherit anything from the
+	// deleted one.
+	if err := m.CreateCalendar(ctx, Calendar{Path: "/alice/work"}); err != nil {
+		t.Fatalf("could not recreate calendar: %v", err)
+	}
+
+	objects, err := m.ListCalendarObjects(ctx, "/alice/work/")
+	if err != nil {
+		t.Fatalf("could not list objects: %v", err)
+	}
+
+	if len(objects) != 0 {
+		t.Errorf("expected no objects in the recreated calendar, got %d", len(objects))
+	}
+
+	changes, revision, err := m.ListCalendarChanges(ctx, "/alice/work", 0)
+	if err != nil {
+		t.Fatalf("could not list changes: %v", err)
+	}
+
+	if len(changes) != 0 || revision != 0 {
+		t.Errorf("expected an empty change log, got %d changes at revision %d", len(changes), revision)
+	}
+
+	delivered := 0
+
+	for alarm := range m.delivered {
+		if alarm.Path.Dir() == "/alice/work" {
+			t.Errorf("expected delivered alarm %q to be removed", alarm.Path)
+		}
+
+		delivered++
+	}
+
+	if delivered != 1 {
+		t.Errorf("expected the alarms of other calendars to be kept, got %d", delivered)
+	}
+
+	if revision, err := m.CalendarRevision(ctx, "/alice/home"); err != nil || revision != 1 {
+		t.Errorf("expected other calendars to keep their revision, got %d (%v)", revision, err)
+	}
+}
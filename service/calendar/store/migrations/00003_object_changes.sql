@@ -0,0 +1,26 @@
+-- +brant Up
+CREATE TABLE calendar_revisions (
+  calendar TEXT PRIMARY KEY,
+  revision BIGINT NOT NULL
+);
+
+CREATE TABLE object_changes (
+  path TEXT PRIMARY KEY,
+  calendar TEXT NOT NULL,
+  revision BIGINT NOT NULL,
+  deleted BOOLEAN NOT NULL
+);
+
+CREATE INDEX object_changes_calendar_revision ON object_changes (calendar, revision);
+
+INSERT INTO object_changes (path, calendar, revision, deleted)
+SELECT path, regexp_replace(path, '/[^/]*$', ''), 1, FALSE
+FROM object_refs;
+
+INSERT INTO calendar_revisions (calendar, revision)
+SELECT DISTINCT calendar, 1
+FROM object_changes;
+
+-- +brant Down
+DROP TABLE object_changes;
+DROP TABLE calendar_revisions;
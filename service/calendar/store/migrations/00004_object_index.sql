@@ -0,0 +1,17 @@
+-- +brant Up
+ALTER TABLE object_refs
+  ADD COLUMN component TEXT NOT NULL DEFAULT '',
+  ADD COLUMN uid TEXT NOT NULL DEFAULT '',
+  ADD COLUMN start_time TIMESTAMPTZ,
+  ADD COLUMN end_time TIMESTAMPTZ;
+
+CREATE INDEX object_refs_time_range ON object_refs (start_time, end_time);
+
+-- +brant Down
+DROP INDEX object_refs_time_range;
+
+ALTER TABLE object_refs
+  DROP COLUMN end_time,
+  DROP COLUMN start_time,
+  DROP COLUMN uid,
+  DROP COLUMN component;
@@ -0,0 +1,7 @@
+-- +brant Up
+-- The size of objects stored before this migration is unknown, and is
+-- backfilled from S3 when the store starts.
+ALTER TABLE object_refs ADD COLUMN size BIGINT NOT NULL DEFAULT 0;
+
+-- +brant Down
+ALTER TABLE object_refs DROP COLUMN size;
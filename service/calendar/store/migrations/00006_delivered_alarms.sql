@@ -0,0 +1,13 @@
+-- +brant Up
+CREATE TABLE delivered_alarms (
+  path TEXT NOT NULL,
+  trigger_time TIMESTAMPTZ NOT NULL,
+  action TEXT NOT NULL,
+  notifier TEXT NOT NULL,
+  PRIMARY KEY (path, trigger_time, action, notifier)
+);
+
+CREATE INDEX delivered_alarms_trigger_time ON delivered_alarms (trigger_time);
+
+-- +brant Down
+DROP TABLE delivered_alarms;
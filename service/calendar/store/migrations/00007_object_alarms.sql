@@ -0,0 +1,7 @@
+-- +brant Up
+-- Whether objects stored before this migration have alarms is unknown, so they
+-- are assumed to have some until they are written again.
+ALTER TABLE object_refs ADD COLUMN alarms BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- +brant Down
+ALTER TABLE object_refs DROP COLUMN alarms;
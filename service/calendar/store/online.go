@@ -34,6 +34,9 @@ type Online struct {
 	pool           *pgxpool.Pool
 	calendarTable  *pgcache.Table[Path, Calendar]
 	objectRefTable *pgcache.Table[Path, objectRef]
+	feeds          *feedIndex
+	shares         *shareIndex
+	usage          *usageIndex
 
 	httpTrace     *httptrace.HTTPTrace
 	s3endpoint    string
@@ -100,6 +103,12 @@ func NewOnline(ctx context.Context, psql string, s3 StoreS3Config, logger *slog.
 		return nil, fmt.Errorf("error while bulding cachnig table: %w", err)
 	}
 
+	feeds := newFeedIndex()
+	calendarTable.AddListener(feeds)
+
+	shares := newShareIndex()
+	calendarTable.AddListener(shares)
+
 	objectTable, err := pgcache.NewTable(
 		pool,
 		"objects",
@@ -114,6 +123,9 @@ func NewOnline(ctx context.Context, psql string, s3 StoreS3Config, logger *slog.
 		return nil, fmt.Errorf("error while bulding cachnig table: %w", err)
 	}
 
+	usage := newUsageIndex()
+	objectTable.AddListener(usage)
+
 	u, err := url.Parse(s3.URL)
 	if err != nil {
 		return nil, fmt.Errorf("error while parsing the S3 connection string: %w", err)
@@ -131,6 +143,9 @@ func NewOnline(ctx context.Context, psql string, s3 StoreS3Config, logger *slog.
 		pool:           pool,
 		calendarTable:  calendarTable,
 		objectRefTable: objectTable,
+		feeds:          feeds,
+		shares:         shares,
+		usage:          usage,
 
 		httpTrace:     httpTrace,
 		s3endpoint:    u.Host,
@@ -159,6 +174,9 @@ func runInTx[T pgcache.Object[Path], R any](
 			return empty, fmt.Errorf("error while starting transaction: %w", err)
 		}
 
+		// Rolling back a committed transaction is a no-op
+		defer func() { _ = tx.Rollback(ctx) }()
+
 		result, err := fn(ctx, tx)
 		if err != nil {
 			return empty, fmt.Errorf("error while running transaction body: %w", err)
@@ -198,6 +216,11 @@ func (o *Online) Run(ctx context.Context, notify run.Notify) (err error) {
 		return fmt.Errorf("error while building s3 cache: %w", err)
 	}
 
+	// The tables are loaded after the backfill, so that they see the new sizes.
+	if err := o.backfillSizes(ctx); err != nil {
+		o.logger.WarnContext(ctx, "could not backfill object sizes", "err", err)
+	}
+
 	return run.Combine(o.calendarTable, o.objectRefTable).Run(ctx, notify)
 }
 
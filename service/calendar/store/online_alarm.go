@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var alarmDeliveredQuery = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM delivered_alarms
+			WHERE path = $1 AND trigger_time = $2 AND action = $3 AND notifier = $4
+		);
+`
+
+// AlarmDelivered implements Store.
+func (o *Online) AlarmDelivered(ctx context.Context, alarm DeliveredAlarm) (bool, error) {
+	var delivered bool
+
+	err := o.pool.QueryRow(
+		ctx,
+		alarmDeliveredQuery,
+		alarm.Path.String(),
+		alarm.Trigger,
+		alarm.Action,
+		alarm.Notifier,
+	).Scan(&delivered)
+	if err != nil {
+		return false, fmt.Errorf("error while checking delivery of alarm for %q in psql: %w", alarm.Path, err)
+	}
+
+	return delivered, nil
+}
+
+var markAlarmDeliveredQuery = `
+		INSERT INTO delivered_alarms (path, trigger_time, action, notifier)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING;
+`
+
+// MarkAlarmDelivered implements Store.
+func (o *Online) MarkAlarmDelivered(ctx context.Context, alarm DeliveredAlarm) error {
+	_, err := o.pool.Exec(
+		ctx,
+		markAlarmDeliveredQuery,
+		alarm.Path.String(),
+		alarm.Trigger,
+		alarm.Action,
+		alarm.Notifier,
+	)
+	if err != nil {
+		return fmt.Errorf("error while recording delivery of alarm for %q in psql: %w", alarm.Path, err)
+	}
+
+	return nil
+}
+
+var pruneDeliveredAlarmsQuery = `DELETE FROM delivered_alarms WHERE trigger_time < $1;`
+
+// PruneDeliveredAlarms implements Store.
+func (o *Online) PruneDeliveredAlarms(ctx context.Context, before time.Time) error {
+	if _, err := o.pool.Exec(ctx, pruneDeliveredAlarmsQuery, before); err != nil {
+		return fmt.Errorf("error while pruning delivered alarms in psql: %w", err)
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/teapotovh/teapot/lib/pgcache"
 )
@@ -109,6 +110,17 @@ func storeCalendarPSQL(ctx context.Context, tx pgx.Tx, calendars []Calendar) err
 
 var deleteCalendarQuery = `DELETE FROM calendars WHERE path = ANY($1);`
 
+// The change log and the delivered alarms of a calendar are removed along with
+// it, as they would otherwise be inherited by a calendar created later at the
+// same path.
+var deleteCalendarDataQuery = `
+		WITH
+			changes AS (DELETE FROM object_changes WHERE calendar = ANY($1)),
+			revisions AS (DELETE FROM calendar_revisions WHERE calendar = ANY($1))
+		DELETE FROM delivered_alarms
+		WHERE regexp_replace(path, '/[^/]*$', '') = ANY($1);
+`
+
 func deleteCalendarPSQL(ctx context.Context, tx pgx.Tx, paths []Path) error {
 	ps := make([]string, 0, len(paths))
 	for _, path := range paths {
@@ -120,6 +132,11 @@ func deleteCalendarPSQL(ctx context.Context, tx pgx.Tx, paths []Path) error {
 		return fmt.Errorf("error while deleting calendars in psql: %w", err)
 	}
 
+	_, err = tx.Exec(ctx, deleteCalendarDataQuery, ps)
+	if err != nil {
+		return fmt.Errorf("error while deleting change log and delivered alarms of calendars in psql: %w", err)
+	}
+
 	return nil
 }
 
@@ -144,6 +161,19 @@ func (o *Online) ListCalendars(ctx context.Context, basePath Path) ([]Calendar,
 	return slices.Collect(iter), nil
 }
 
+// ListSharedCalendars implements Store.
+func (o *Online) ListSharedCalendars(ctx context.Context, username string, groups []string) ([]Calendar, error) {
+	var calendars []Calendar
+
+	for _, path := range o.shares.Lookup(username, groups) {
+		if calendar, found := o.calendarTable.Get(path); found {
+			calendars = append(calendars, calendar)
+		}
+	}
+
+	return calendars, nil
+}
+
 // GetCalendar implements Store.
 func (o *Online) GetCalendar(ctx context.Context, path Path) (*Calendar, error) {
 	calendar, found := o.calendarTable.Get(path)
@@ -153,3 +183,86 @@ func (o *Online) GetCalendar(ctx context.Context, path Path) (*Calendar, error)
 
 	return &calendar, nil
 }
+
+// GetCalendarByFeedToken implements Store.
+func (o *Online) GetCalendarByFeedToken(ctx context.Context, tokenHash string) (*Calendar, error) {
+	path, ok := o.feeds.Lookup(tokenHash)
+	if !ok {
+		return nil, ErrCalendarNotFound
+	}
+
+	return o.GetCalendar(ctx, path)
+}
+
+// UpdateCalendar implements Store.
+func (o *Online) UpdateCalendar(ctx context.Context, calendar Calendar) error {
+	if _, exists := o.calendarTable.Get(calendar.Path); !exists {
+		return ErrCalendarNotFound
+	}
+
+	_, err := runInTx(o.calendarTable, func(ctx context.Context, tx *pgcache.TableTx[Path, Calendar]) (unit, error) {
+		return unit{}, tx.Store(ctx, []Calendar{calendar})
+	})(ctx)
+
+	return err
+}
+
+// DeleteCalendar implements Store.
+//
+// S3 objects are removed before their refs and the calendar itself, so that a
+// failed deletion can be retried without leaking any object in S3.
+func (o *Online) DeleteCalendar(ctx context.Context, path Path) error {
+	if _, exists := o.calendarTable.Get(path); !exists {
+		return ErrCalendarNotFound
+	}
+
+	basePath := path + "/"
+	endPath := Path(fmt.Sprintf("%s%c", basePath, utf8.MaxRune))
+	refs := slices.Collect(o.objectRefTable.Between(basePath, endPath))
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(MaxRequestsInParallel)
+
+	for _, ref := range refs {
+		eg.Go(func() error {
+			if err := o.objectCache.Remove(egCtx, ref.s3Key()); err != nil {
+				return fmt.Errorf("error while removing entry for object %q from s3: %w", ref.Path, err)
+			}
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	if len(refs) > 0 {
+		if err := o.deleteObjectRefs(ctx, refs); err != nil {
+			return fmt.Errorf("error while removing object refs of calendar %q: %w", path, err)
+		}
+	}
+
+	_, err := runInTx(o.calendarTable, func(ctx context.Context, tx *pgcache.TableTx[Path, Calendar]) (unit, error) {
+		return unit{}, tx.Delete(ctx, []Path{path})
+	})(ctx)
+	if err != nil {
+		return fmt.Errorf("error while removing calendar %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// deleteObjectRefs removes the given object refs within a single transaction.
+func (o *Online) deleteObjectRefs(ctx context.Context, refs []objectRef) error {
+	paths := make([]Path, 0, len(refs))
+	for _, ref := range refs {
+		paths = append(paths, ref.Path)
+	}
+
+	_, err := runInTx(o.objectRefTable, func(ctx context.Context, tx *pgcache.TableTx[Path, objectRef]) (unit, error) {
+		return unit{}, tx.Delete(ctx, paths)
+	})(ctx)
+
+	return err
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var recordChangeQuery = `
+		WITH revision AS (
+			INSERT INTO calendar_revisions (calendar, revision)
+			VALUES ($1, 1)
+			ON CONFLICT (calendar) DO UPDATE
+			SET revision = calendar_revisions.revision + 1
+			RETURNING revision
+		)
+		INSERT INTO object_changes (path, calendar, revision, deleted)
+		SELECT $2, $1, revision.revision, $3
+		FROM revision
+		ON CONFLICT (path) DO UPDATE
+		SET calendar = EXCLUDED.calendar, revision = EXCLUDED.revision, deleted = EXCLUDED.deleted;
+`
+
+// recordChangesPSQL appends the given object paths to the change log of their
+// calendars. It is called from within the transactions which store or delete
+// object refs, so that the change log never diverges from the refs.
+func recordChangesPSQL(ctx context.Context, tx pgx.Tx, paths []Path, deleted bool) error {
+	for _, path := range paths {
+		_, err := tx.Exec(ctx, recordChangeQuery, path.Dir().String(), path.String(), deleted)
+		if err != nil {
+			return fmt.Errorf("error while recording change for object %q in psql: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+var getRevisionQuery = `
+		SELECT revision
+		FROM calendar_revisions
+		WHERE calendar = $1;
+`
+
+func (o *Online) getRevision(ctx context.Context, path Path) (uint64, error) {
+	var revision uint64
+
+	err := o.pool.QueryRow(ctx, getRevisionQuery, path.String()).Scan(&revision)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// No object has ever been stored in this calendar
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("error while fetching revision of calendar %q from psql: %w", path, err)
+	}
+
+	return revision, nil
+}
+
+// CalendarRevision implements Store.
+func (o *Online) CalendarRevision(ctx context.Context, path Path) (uint64, error) {
+	if _, found := o.calendarTable.Get(path); !found {
+		return 0, ErrCalendarNotFound
+	}
+
+	return o.getRevision(ctx, path)
+}
+
+var listChangesQuery = `
+		SELECT path, revision, deleted
+		FROM object_changes
+		WHERE calendar = $1 AND revision > $2
+		ORDER BY revision;
+`
+
+// ListCalendarChanges implements Store.
+func (o *Online) ListCalendarChanges(ctx context.Context, path Path, since uint64) ([]Change, uint64, error) {
+	if _, found := o.calendarTable.Get(path); !found {
+		return nil, 0, ErrCalendarNotFound
+	}
+
+	revision, err := o.getRevision(ctx, path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if since > revision {
+		return nil, 0, fmt.Errorf("revision %d is ahead of calendar %q: %w", since, path, ErrInvalidRevision)
+	}
+
+	rows, err := o.pool.Query(ctx, listChangesQuery, path.String(), since)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error while listing changes of calendar %q from psql: %w", path, err)
+	}
+	defer rows.Close()
+
+	var changes []Change
+
+	for rows.Next() {
+		var change Change
+		if err := rows.Scan(&change.Path, &change.Revision, &change.Deleted); err != nil {
+			return nil, 0, fmt.Errorf("could not extract three columns from psql list: %w", err)
+		}
+
+		// The revision is read before the changes, so changes committed in the
+		// meantime are left for the next sync.
+		if change.Revision > revision {
+			break
+		}
+
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("could not read all psql results: %w", err)
+	}
+
+	return changes, revision, nil
+}
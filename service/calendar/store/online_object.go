@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -22,8 +23,10 @@ type objectRef struct {
 	Path    Path
 	ModTime time.Time
 
-	Ref  uuid.UUID
-	ETag s3cache.Hash
+	Ref   uuid.UUID
+	ETag  s3cache.Hash
+	Size  int64
+	Index ObjectIndex
 }
 
 // Key implements pgcache.Object.
@@ -35,14 +38,45 @@ func (ref objectRef) s3Key() string {
 	return ref.Ref.String()
 }
 
+// nullTime maps the zero time, which marks unbounded time ranges, to NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
 func parseObjectRows(rows pgx.Rows) (refs []objectRef, err error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var ref objectRef
+		var (
+			ref        objectRef
+			start, end *time.Time
+		)
 
-		if err := rows.Scan(&ref.Path, &ref.ModTime, &ref.Ref, &ref.ETag); err != nil {
-			return nil, fmt.Errorf("could not extract three columns from psql list: %w", err)
+		if err := rows.Scan(
+			&ref.Path,
+			&ref.ModTime,
+			&ref.Ref,
+			&ref.ETag,
+			&ref.Size,
+			&ref.Index.Component,
+			&ref.Index.UID,
+			&start,
+			&end,
+			&ref.Index.Alarms,
+		); err != nil {
+			return nil, fmt.Errorf("could not extract ten columns from psql list: %w", err)
+		}
+
+		if start != nil {
+			ref.Index.Start = *start
+		}
+
+		if end != nil {
+			ref.Index.End = *end
 		}
 
 		refs = append(refs, ref)
@@ -56,7 +90,7 @@ func parseObjectRows(rows pgx.Rows) (refs []objectRef, err error) {
 }
 
 var listObjectQuery = `
-		SELECT path, mod_time, ref, etag
+		SELECT path, mod_time, ref, etag, size, component, uid, start_time, end_time, alarms
 		FROM object_refs;
 `
 
@@ -70,7 +104,7 @@ func listObjectRefPSQL(ctx context.Context, conn *pgxpool.Pool) ([]objectRef, er
 }
 
 var getObjectQuery = `
-		SELECT path, mod_time, ref, etag
+		SELECT path, mod_time, ref, etag, size, component, uid, start_time, end_time, alarms
 		FROM object_refs
 		WHERE path = ANY($1);
 `
@@ -90,10 +124,15 @@ func getObjectRefPSQL(ctx context.Context, conn *pgxpool.Pool, paths []Path) ([]
 }
 
 var storeObjectQuery = `
-		INSERT INTO object_refs (path, mod_time, ref, etag)
-		SELECT unnest($1::text[]), unnest($2::timestamptz[]), unnest($3::uuid[]), unnest($4::text[])
+		INSERT INTO object_refs (path, mod_time, ref, etag, size, component, uid, start_time, end_time, alarms)
+		SELECT
+			unnest($1::text[]), unnest($2::timestamptz[]), unnest($3::uuid[]), unnest($4::text[]),
+			unnest($5::bigint[]), unnest($6::text[]), unnest($7::text[]), unnest($8::timestamptz[]),
+			unnest($9::timestamptz[]), unnest($10::boolean[])
 		ON CONFLICT (path) DO UPDATE
-		SET mod_time = EXCLUDED.mod_time, etag = EXCLUDED.etag;
+		SET mod_time = EXCLUDED.mod_time, etag = EXCLUDED.etag, size = EXCLUDED.size,
+			component = EXCLUDED.component, uid = EXCLUDED.uid, start_time = EXCLUDED.start_time,
+			end_time = EXCLUDED.end_time, alarms = EXCLUDED.alarms;
 `
 
 func storeObjectRefPSQL(ctx context.Context, tx pgx.Tx, refs []objectRef) error {
@@ -101,20 +140,50 @@ func storeObjectRefPSQL(ctx context.Context, tx pgx.Tx, refs []objectRef) error
 	modTimes := make([]time.Time, 0, len(refs))
 	uuids := make([]uuid.UUID, 0, len(refs))
 	etags := make([]string, 0, len(refs))
+	sizes := make([]int64, 0, len(refs))
+	components := make([]string, 0, len(refs))
+	uids := make([]string, 0, len(refs))
+	starts := make([]*time.Time, 0, len(refs))
+	ends := make([]*time.Time, 0, len(refs))
+	alarms := make([]bool, 0, len(refs))
 
 	for _, ref := range refs {
 		paths = append(paths, string(ref.Path))
 		modTimes = append(modTimes, ref.ModTime)
 		uuids = append(uuids, ref.Ref)
 		etags = append(etags, ref.ETag.String())
+		sizes = append(sizes, ref.Size)
+		components = append(components, ref.Index.Component)
+		uids = append(uids, ref.Index.UID)
+		starts = append(starts, nullTime(ref.Index.Start))
+		ends = append(ends, nullTime(ref.Index.End))
+		alarms = append(alarms, ref.Index.Alarms)
 	}
 
-	_, err := tx.Exec(ctx, storeObjectQuery, paths, modTimes, uuids, etags)
+	_, err := tx.Exec(
+		ctx,
+		storeObjectQuery,
+		paths,
+		modTimes,
+		uuids,
+		etags,
+		sizes,
+		components,
+		uids,
+		starts,
+		ends,
+		alarms,
+	)
 	if err != nil {
 		return fmt.Errorf("error while inserting object refs with psql: %w", err)
 	}
 
-	return nil
+	changed := make([]Path, 0, len(refs))
+	for _, ref := range refs {
+		changed = append(changed, ref.Path)
+	}
+
+	return recordChangesPSQL(ctx, tx, changed, false)
 }
 
 var deleteObjectQuery = `DELETE FROM object_refs WHERE path = ANY($1);`
@@ -130,7 +199,7 @@ func deleteObjectRefPSQL(ctx context.Context, tx pgx.Tx, paths []Path) error {
 		return fmt.Errorf("error while deleting object refs in psql: %w", err)
 	}
 
-	return nil
+	return recordChangesPSQL(ctx, tx, paths, true)
 }
 
 // CreateCalendarObject implements Store.
@@ -149,6 +218,9 @@ func (o *Online) CreateCalendarObject(ctx context.Context, object Object) error
 		}
 	}
 
+	ref.Size = object.Size()
+	ref.Index = object.Index
+
 	etag, err := o.objectCache.Put(ctx, ref.s3Key(), object.Data)
 	if err != nil {
 		return fmt.Errorf("error while storing calendar object in s3: %w", err)
@@ -167,8 +239,48 @@ func (o *Online) CreateCalendarObject(ctx context.Context, object Object) error
 func (o *Online) ListCalendarObjects(ctx context.Context, basePath Path) ([]Object, error) {
 	endPath := Path(fmt.Sprintf("%s%c", basePath, utf8.MaxRune))
 	iter := o.objectRefTable.Between(basePath, endPath)
-	refs := slices.Collect(iter)
 
+	return o.getObjectsFromRefs(ctx, slices.Collect(iter))
+}
+
+var queryObjectQuery = `
+		SELECT path, mod_time, ref, etag, size, component, uid, start_time, end_time, alarms
+		FROM object_refs
+		WHERE starts_with(path, $1)
+			AND (component = '' OR $2 = '' OR component = $2)
+			AND (start_time IS NULL OR $4::timestamptz IS NULL OR start_time < $4)
+			AND (end_time IS NULL OR $3::timestamptz IS NULL OR end_time >= $3)
+			AND (alarms OR NOT $5 OR component = '');
+`
+
+// QueryCalendarObjects implements Store.
+//
+// Candidate refs are selected by psql, so that only the matching objects are
+// fetched from S3. See ObjectIndex.Matches for the same logic in Go.
+func (o *Online) QueryCalendarObjects(ctx context.Context, basePath Path, query ObjectQuery) ([]Object, error) {
+	rows, err := o.pool.Query(
+		ctx,
+		queryObjectQuery,
+		basePath.String(),
+		query.Component,
+		nullTime(query.Start),
+		nullTime(query.End),
+		query.Alarms,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error while querying object refs from psql: %w", err)
+	}
+
+	refs, err := parseObjectRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.getObjectsFromRefs(ctx, refs)
+}
+
+// getObjectsFromRefs fetches the objects of many refs in parallel.
+func (o *Online) getObjectsFromRefs(ctx context.Context, refs []objectRef) ([]Object, error) {
 	objects := make([]Object, len(refs))
 	eg, ctx := errgroup.WithContext(ctx)
 	eg.SetLimit(MaxRequestsInParallel)
@@ -193,6 +305,110 @@ func (o *Online) ListCalendarObjects(ctx context.Context, basePath Path) ([]Obje
 	return objects, nil
 }
 
+var unsizedObjectQuery = `
+		SELECT path, ref
+		FROM object_refs
+		WHERE size = 0;
+`
+
+var backfillSizeQuery = `
+		UPDATE object_refs AS o
+		SET size = sizes.size
+		FROM (SELECT unnest($1::text[]) AS path, unnest($2::uuid[]) AS ref, unnest($3::bigint[]) AS size) AS sizes
+		WHERE o.path = sizes.path AND o.ref = sizes.ref AND o.size = 0
+		RETURNING
+			o.path, o.mod_time, o.ref, o.etag, o.size, o.component, o.uid, o.start_time, o.end_time, o.alarms;
+`
+
+// backfillSizes fetches from S3 the size of the objects stored before sizes
+// were recorded, which are accounted for in quotas as empty. Objects whose
+// size cannot be fetched are left as they are, to be retried at the next
+// start. Objects written in the meantime are not touched.
+func (o *Online) backfillSizes(ctx context.Context) error {
+	rows, err := o.pool.Query(ctx, unsizedObjectQuery)
+	if err != nil {
+		return fmt.Errorf("error while listing object refs without size from psql: %w", err)
+	}
+
+	var refs []objectRef
+
+	for rows.Next() {
+		var ref objectRef
+		if err := rows.Scan(&ref.Path, &ref.Ref); err != nil {
+			rows.Close()
+			return fmt.Errorf("could not extract two columns from psql list: %w", err)
+		}
+
+		refs = append(refs, ref)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("could not read all psql results: %w", err)
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+
+	var (
+		mu    sync.Mutex
+		paths []string
+		uuids []uuid.UUID
+		sizes []int64
+	)
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(MaxRequestsInParallel)
+
+	for _, ref := range refs {
+		eg.Go(func() error {
+			size, err := o.objectCache.Stat(egCtx, ref.s3Key())
+			if err != nil {
+				o.logger.WarnContext(egCtx, "could not fetch size of object from s3", "path", ref.Path, "err", err)
+				return nil
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			paths = append(paths, ref.Path.String())
+			uuids = append(uuids, ref.Ref)
+			sizes = append(sizes, size)
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	_, err = runInTx(o.objectRefTable, func(ctx context.Context, tx *pgcache.TableTx[Path, objectRef]) (unit, error) {
+		return unit{}, tx.Update(ctx, func(ctx context.Context, tx pgx.Tx) ([]objectRef, error) {
+			rows, err := tx.Query(ctx, backfillSizeQuery, paths, uuids, sizes)
+			if err != nil {
+				return nil, fmt.Errorf("error while updating object sizes in psql: %w", err)
+			}
+
+			return parseObjectRows(rows)
+		})
+	})(ctx)
+	if err != nil {
+		return fmt.Errorf("error while backfilling object sizes: %w", err)
+	}
+
+	o.logger.InfoContext(ctx, "backfilled object sizes from s3", "count", len(paths), "missing", len(refs)-len(paths))
+
+	return nil
+}
+
+// CalendarUsage implements Store.
+func (o *Online) CalendarUsage(ctx context.Context, basePath Path) (Usage, error) {
+	return o.usage.Usage(basePath), nil
+}
+
 // GetCalendarObject implements Store.
 func (o *Online) GetCalendarObject(ctx context.Context, path Path) (*Object, error) {
 	ref, found := o.objectRefTable.Get(path)
@@ -253,7 +469,8 @@ func (o *Online) getObjectFromRef(ctx context.Context, ref objectRef) (Object, e
 		ModTime: ref.ModTime,
 		Data:    data,
 		// Use the ground truth from s3 for etag
-		ETag: string(etag),
+		ETag:  string(etag),
+		Index: ref.Index,
 	}
 
 	return object, nil
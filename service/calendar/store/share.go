@@ -0,0 +1,102 @@
+package store
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/teapotovh/teapot/lib/pgcache"
+)
+
+// shareKey identifies a user or a group calendars are shared with.
+type shareKey struct {
+	kind ShareKind
+	name string
+}
+
+// shareIndex maps the users and groups calendars are shared with to the paths
+// of those calendars, so that the calendars shared with a user can be listed
+// without scanning all calendars.
+type shareIndex struct {
+	mu     sync.RWMutex
+	paths  map[shareKey]map[Path]struct{}
+	shares map[Path][]shareKey
+}
+
+func newShareIndex() *shareIndex {
+	return &shareIndex{
+		paths:  map[shareKey]map[Path]struct{}{},
+		shares: map[Path][]shareKey{},
+	}
+}
+
+// Lookup returns the sorted paths of the calendars shared with the user or
+// with any of the groups.
+func (idx *shareIndex) Lookup(username string, groups []string) []Path {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	keys := []shareKey{{kind: ShareKindUser, name: username}}
+	for _, group := range groups {
+		keys = append(keys, shareKey{kind: ShareKindGroup, name: group})
+	}
+
+	var paths []Path
+
+	for _, key := range keys {
+		for path := range idx.paths[key] {
+			if !slices.Contains(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	slices.Sort(paths)
+
+	return paths
+}
+
+// Stored implements pgcache.Listener.
+func (idx *shareIndex) Stored(path Path, calendar Calendar) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(path)
+
+	for _, share := range calendar.Metadata.Shares {
+		key := shareKey{kind: share.Kind, name: share.Name}
+		if slices.Contains(idx.shares[path], key) {
+			continue
+		}
+
+		if idx.paths[key] == nil {
+			idx.paths[key] = map[Path]struct{}{}
+		}
+
+		idx.paths[key][path] = struct{}{}
+		idx.shares[path] = append(idx.shares[path], key)
+	}
+}
+
+// Deleted implements pgcache.Listener.
+func (idx *shareIndex) Deleted(path Path) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(path)
+}
+
+// remove drops the shares of a calendar. The caller must hold the lock.
+func (idx *shareIndex) remove(path Path) {
+	for _, key := range idx.shares[path] {
+		delete(idx.paths[key], path)
+
+		if len(idx.paths[key]) == 0 {
+			delete(idx.paths, key)
+		}
+	}
+
+	delete(idx.shares, path)
+}
+
+// Ensure *shareIndex implements pgcache.Listener.
+var _ pgcache.Listener[Path, Calendar] = &shareIndex{}
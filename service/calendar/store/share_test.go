@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestMemListSharedCalendars(t *testing.T) {
+	ctx := context.Background()
+	m := NewMem()
+
+	calendars := []Calendar{
+		{Path: "/alice/calendar"},
+		{Path: "/bob/calendar", Metadata: CalendarMetadata{Shares: []Share{
+			{Kind: ShareKindUser, Name: "alice", Access: AccessRead},
+		}}},
+		{Path: "/bob/team", Metadata: CalendarMetadata{Shares: []Share{
+			{Kind: ShareKindGroup, Name: "team", Access: AccessReadWrite},
+			{Kind: ShareKindUser, Name: "alice", Access: AccessRead},
+		}}},
+		{Path: "/carol/calendar", Metadata: CalendarMetadata{Shares: []Share{
+			{Kind: ShareKindGroup, Name: "alice", Access: AccessRead},
+		}}},
+	}
+
+	for _, cal := range calendars {
+		if err := m.CreateCalendar(ctx, cal); err != nil {
+			t.Fatalf("could not create calendar: %v", err)
+		}
+	}
+
+	paths := func(username string, groups []string) []Path {
+		t.Helper()
+
+		shared, err := m.ListSharedCalendars(ctx, username, groups)
+		if err != nil {
+			t.Fatalf("could not list shared calendars: %v", err)
+		}
+
+		var paths []Path
+		for _, cal := range shared {
+			paths = append(paths, cal.Path)
+		}
+
+		return paths
+	}
+
+	// Groups and users with the same name are told apart
+	if got := paths("alice", nil); !slices.Equal(got, []Path{"/bob/calendar", "/bob/team"}) {
+		t.Errorf("expected the calendars shared with alice, got %v", got)
+	}
+
+	if got := paths("dave", []string{"team"}); !slices.Equal(got, []Path{"/bob/team"}) {
+		t.Errorf("expected the calendars shared with the group, got %v", got)
+	}
+
+	calendars[2].Metadata.Shares = nil
+	if err := m.UpdateCalendar(ctx, calendars[2]); err != nil {
+		t.Fatalf("could not update calendar: %v", err)
+	}
+
+	if err := m.DeleteCalendar(ctx, "/bob/calendar"); err != nil {
+		t.Fatalf("could not delete calendar: %v", err)
+	}
+
+	if got := paths("alice", []string{"team"}); len(got) != 0 {
+		t.Errorf("expected no calendars after revoking the shares, got %v", got)
+	}
+}
@@ -21,6 +21,7 @@ var (
 	ErrCalendarNotFound            = fmt.Errorf("calendar: %w", ErrNotFound)
 	ErrCalendarObjectAlreadyExists = fmt.Errorf("calendar object: %w", ErrAlreadyExists)
 	ErrCalendarObjectNotFound      = fmt.Errorf("calendar object: %w", ErrNotFound)
+	ErrInvalidRevision             = errors.New("invalid revision")
 )
 
 // Store provides an interface implemented by all types of stores for LDAP entries.
@@ -39,20 +40,63 @@ type Store interface {
 	// ListCalendars returns a list of all calendar resources below the given path.
 	ListCalendars(ctx context.Context, basePath Path) ([]Calendar, error)
 
+	// ListSharedCalendars returns the calendars shared with the user or with
+	// any of the groups, according to the shares in their metadata.
+	ListSharedCalendars(ctx context.Context, username string, groups []string) ([]Calendar, error)
+
 	// GetCalendar fetches a single calendar from the store.
 	GetCalendar(ctx context.Context, path Path) (*Calendar, error)
 
+	// GetCalendarByFeedToken fetches the calendar whose subscription feed has
+	// a token with the given hash.
+	GetCalendarByFeedToken(ctx context.Context, tokenHash string) (*Calendar, error)
+
+	// UpdateCalendar replaces the metadata of an existing calendar.
+	UpdateCalendar(ctx context.Context, calendar Calendar) error
+
+	// DeleteCalendar removes a calendar from the store, along with all of its
+	// objects, its change log and the delivery records of its alarms.
+	DeleteCalendar(ctx context.Context, path Path) error
+
 	// CreateCalendarObject inserts a calendar object into the store.
 	CreateCalendarObject(ctx context.Context, object Object) error
 
 	// ListCalendarObjects lists all calendar object under the given path from the store.
 	ListCalendarObjects(ctx context.Context, path Path) ([]Object, error)
 
+	// QueryCalendarObjects lists the calendar objects under the given path
+	// which may match the query, according to their index. It may return
+	// objects which do not match, but never leaves out any which does.
+	QueryCalendarObjects(ctx context.Context, path Path, query ObjectQuery) ([]Object, error)
+
+	// CalendarUsage returns the number and total size of the calendar objects
+	// under the given path, which must end with a slash.
+	CalendarUsage(ctx context.Context, basePath Path) (Usage, error)
+
 	// GetCalendarObject fetches a single calendar object from the store.
 	GetCalendarObject(ctx context.Context, path Path) (*Object, error)
 
 	// DeleteCalendarObject removes a calendar object from the store.
 	DeleteCalendarObject(ctx context.Context, path Path) error
+
+	// CalendarRevision returns the current revision of a calendar, which is
+	// incremented every time one of its objects is created, updated or deleted.
+	CalendarRevision(ctx context.Context, path Path) (uint64, error)
+
+	// ListCalendarChanges returns the latest change to each object of a calendar
+	// made after the given revision, ordered by revision, along with the current
+	// revision of the calendar.
+	ListCalendarChanges(ctx context.Context, path Path, since uint64) ([]Change, uint64, error)
+
+	// AlarmDelivered reports whether an alarm has already been delivered.
+	AlarmDelivered(ctx context.Context, alarm DeliveredAlarm) (bool, error)
+
+	// MarkAlarmDelivered records the delivery of an alarm.
+	MarkAlarmDelivered(ctx context.Context, alarm DeliveredAlarm) error
+
+	// PruneDeliveredAlarms forgets about the delivery of all alarms which
+	// triggered before the given time.
+	PruneDeliveredAlarms(ctx context.Context, before time.Time) error
 }
 
 type StoreConfig struct {
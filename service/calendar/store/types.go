@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	pathpkg "path"
 	"strings"
 	"time"
 
@@ -28,6 +29,11 @@ func (path Path) String() string {
 	return string(path)
 }
 
+// Dir returns the path of the parent resource, i.e., the calendar of an object.
+func (path Path) Dir() Path {
+	return Path(pathpkg.Dir(string(path)))
+}
+
 type Calendar struct {
 	Path     Path
 	Metadata CalendarMetadata
@@ -41,6 +47,35 @@ type CalendarMetadata struct {
 	MaxResourceSize       int64    `json:"max-resource-size,omitempty"`
 	Color                 string   `json:"color,omitempty"`
 	Tag                   string   `json:"tag,omitempty"`
+	Shares                []Share  `json:"shares,omitempty"`
+	// FeedTokenHash is the hash of the secret which grants read-only access
+	// to the calendar through its subscription feed, as computed by
+	// HashFeedToken. The feed is disabled if empty.
+	FeedTokenHash string `json:"feed-token-hash,omitempty"`
+}
+
+// ShareKind tells whether a share grants access to a single LDAP user, or to
+// all the members of an LDAP group.
+type ShareKind string
+
+const (
+	ShareKindUser  ShareKind = "user"
+	ShareKindGroup ShareKind = "group"
+)
+
+// Access is the level of access to a calendar granted by a share.
+type Access string
+
+const (
+	AccessRead      Access = "read"
+	AccessReadWrite Access = "read-write"
+)
+
+// Share grants a user or a group access to a calendar owned by someone else.
+type Share struct {
+	Kind   ShareKind `json:"kind"`
+	Name   string    `json:"name"`
+	Access Access    `json:"access"`
 }
 
 // Key implements pgcache.Object.
@@ -53,6 +88,30 @@ type Object struct {
 	ModTime time.Time
 	Data    []byte
 	ETag    string
+	Index   ObjectIndex
+}
+
+// Usage is the storage used by a set of calendar objects.
+type Usage struct {
+	Objects int64
+	Bytes   int64
+}
+
+// Change is an entry in the change log of a calendar. Changes with Deleted set
+// are tombstones for objects which have been removed from the calendar.
+type Change struct {
+	Path     Path
+	Revision uint64
+	Deleted  bool
+}
+
+// DeliveredAlarm identifies an alarm of a calendar object which has been
+// delivered through a notifier, so that it is not delivered again.
+type DeliveredAlarm struct {
+	Path     Path
+	Trigger  time.Time
+	Action   string
+	Notifier string
 }
 
 func (o *Object) Size() int64 {
@@ -89,6 +148,7 @@ func SerializeObject(obj caldav.CalendarObject) (*Object, error) {
 		ModTime: obj.ModTime,
 		Data:    data,
 		ETag:    string(etag),
+		Index:   NewObjectIndex(obj.Data),
 	}
 
 	return &object, nil
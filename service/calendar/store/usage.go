@@ -0,0 +1,87 @@
+package store
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/teapotovh/teapot/lib/pgcache"
+)
+
+// usageIndex keeps track of the usage of each calendar, so that quotas can be
+// checked without scanning the refs of all the objects of a user.
+type usageIndex struct {
+	mu        sync.RWMutex
+	sizes     map[Path]int64
+	calendars map[Path]Usage
+}
+
+func newUsageIndex() *usageIndex {
+	return &usageIndex{
+		sizes:     map[Path]int64{},
+		calendars: map[Path]Usage{},
+	}
+}
+
+// Usage returns the usage of the calendars under basePath, which is expected
+// to end with a slash.
+func (idx *usageIndex) Usage(basePath Path) Usage {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var usage Usage
+
+	for calendar, u := range idx.calendars {
+		if strings.HasPrefix(string(calendar)+"/", string(basePath)) {
+			usage.Objects += u.Objects
+			usage.Bytes += u.Bytes
+		}
+	}
+
+	return usage
+}
+
+// Stored implements pgcache.Listener.
+func (idx *usageIndex) Stored(path Path, ref objectRef) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	calendar := path.Dir()
+	usage := idx.calendars[calendar]
+
+	if size, ok := idx.sizes[path]; ok {
+		usage.Bytes -= size
+	} else {
+		usage.Objects++
+	}
+
+	usage.Bytes += ref.Size
+	idx.sizes[path] = ref.Size
+	idx.calendars[calendar] = usage
+}
+
+// Deleted implements pgcache.Listener.
+func (idx *usageIndex) Deleted(path Path) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	size, ok := idx.sizes[path]
+	if !ok {
+		return
+	}
+
+	calendar := path.Dir()
+	usage := idx.calendars[calendar]
+	usage.Objects--
+	usage.Bytes -= size
+
+	delete(idx.sizes, path)
+
+	if usage.Objects > 0 {
+		idx.calendars[calendar] = usage
+	} else {
+		delete(idx.calendars, calendar)
+	}
+}
+
+// Ensure *usageIndex implements pgcache.Listener.
+var _ pgcache.Listener[Path, objectRef] = &usageIndex{}
@@ -0,0 +1,46 @@
+package store
+
+import "testing"
+
+func TestUsageIndex(t *testing.T) {
+	idx := newUsageIndex()
+
+	idx.Stored("/alice/work/a.ics", objectRef{Size: 100})
+	idx.Stored("/alice/work/b.ics", objectRef{Size: 50})
+	idx.Stored("/alice/home/c.ics", objectRef{Size: 10})
+	idx.Stored("/bob/work/d.ics", objectRef{Size: 1000})
+
+	// Overwriting an object only changes its size
+	idx.Stored("/alice/work/a.ics", objectRef{Size: 200})
+
+	// Deleting an unknown object is a no-op
+	idx.Deleted("/alice/work/missing.ics")
+
+	tests := []struct {
+		basePath Path
+		usage    Usage
+	}{
+		{basePath: "/alice/", usage: Usage{Objects: 3, Bytes: 260}},
+		{basePath: "/alice/work/", usage: Usage{Objects: 2, Bytes: 250}},
+		{basePath: "/bob/", usage: Usage{Objects: 1, Bytes: 1000}},
+		{basePath: "/al/", usage: Usage{}},
+		{basePath: "/", usage: Usage{Objects: 4, Bytes: 1260}},
+	}
+
+	for _, tt := range tests {
+		if usage := idx.Usage(tt.basePath); usage != tt.usage {
+			t.Errorf("expected usage of %q to be %+v, got %+v", tt.basePath, tt.usage, usage)
+		}
+	}
+
+	idx.Deleted("/alice/work/a.ics")
+	idx.Deleted("/alice/work/b.ics")
+
+	if usage := idx.Usage("/alice/"); usage != (Usage{Objects: 1, Bytes: 10}) {
+		t.Errorf("expected deleted objects to be released, got %+v", usage)
+	}
+
+	if _, ok := idx.calendars["/alice/work"]; ok {
+		t.Errorf("expected empty calendars to be dropped from the index")
+	}
+}
@@ -1,10 +1,12 @@
 package files
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 
 	"github.com/teapotovh/teapot/lib/ldap"
+	"github.com/teapotovh/teapot/lib/run"
 )
 
 // Files is the service instance for teapot's file storage service.
@@ -19,12 +21,15 @@ type VFS uint8
 
 const (
 	VFSOS VFS = iota
+	VFSS3
 )
 
 func (vfs VFS) String() string {
 	switch vfs {
 	case VFSOS:
 		return "os"
+	case VFSS3:
+		return "s3"
 	default:
 		return ""
 	}
@@ -56,6 +61,11 @@ func NewFiles(config FilesConfig, logger *slog.Logger) (*Files, error) {
 	}, nil
 }
 
+// Run implements run.Runnable.
+func (f *Files) Run(ctx context.Context, notify run.Notify) error {
+	return f.sessions.Run(ctx, notify)
+}
+
 func (f *Files) Sesssions() *Sessions {
 	return f.sessions
 }
@@ -9,39 +9,62 @@ import (
 	hpfs "github.com/hack-pad/hackpadfs"
 	hpfsos "github.com/hack-pad/hackpadfs/os"
 
+	"github.com/teapotovh/teapot/lib/s3fs"
 	"github.com/teapotovh/teapot/lib/tmplstring"
 )
 
 var (
-	ErrExpectMountFormat = errors.New("expected mount format to be: <vfs>:<src>:<dest>, missing some parts")
+	ErrExpectMountFormat = errors.New("expected mount format to be: <vfs>:<src>:<dest>[:<mode>], missing some parts")
 	ErrInvalidVFSType    = errors.New("invalid VFS type")
+	ErrInvalidMountMode  = errors.New("invalid mount mode")
+)
+
+const (
+	mountModeReadWrite = "rw"
+	mountModeReadOnly  = "ro"
 )
 
 type mountConfig struct {
 	Source      string
 	Destination string
 	VFS         VFS
+	ReadOnly    bool
 }
 
 // parseRawMount parses a mount string format into a configuration struct.
 // The expected format is in shape:
-// <vfs>:<src>:<dest>
+// <vfs>:<src>:<dest>[:<mode>]
 // <vfs> must be a valid files.VFS.
 // <src> must include a templated user variable, but will be checked later by
-// the files service itself.
+// the files service itself. For s3 mounts, <src> is the key prefix within the
+// configured bucket.
+// <mode> is optional, and must be either rw (the default) or ro for mounts
+// which users can only read from.
 func parseRawMount(mount string) (cfg mountConfig, err error) {
 	parts := strings.Split(mount, ":")
-	if len(parts) != 3 {
+	if len(parts) != 3 && len(parts) != 4 {
 		return cfg, ErrExpectMountFormat
 	}
 
 	switch parts[0] {
 	case VFSOS.String():
 		cfg.VFS = VFSOS
+	case VFSS3.String():
+		cfg.VFS = VFSS3
 	default:
 		return cfg, fmt.Errorf("could not parse VFS mount type %q: %w", parts[0], ErrInvalidVFSType)
 	}
 
+	if len(parts) == 4 {
+		switch parts[3] {
+		case mountModeReadWrite:
+		case mountModeReadOnly:
+			cfg.ReadOnly = true
+		default:
+			return cfg, fmt.Errorf("could not parse mount mode %q: %w", parts[3], ErrInvalidMountMode)
+		}
+	}
+
 	cfg.Source = path.Clean(parts[1])
 	cfg.Destination = path.Clean(parts[2])
 
@@ -49,17 +72,45 @@ func parseRawMount(mount string) (cfg mountConfig, err error) {
 }
 
 type mount struct {
-	srcTmpl *tmplstring.TMPL[mountSourceParameters]
-	dst     string
-	vfs     VFS
+	srcTmpl  *tmplstring.TMPL[mountSourceParameters]
+	dst      string
+	vfs      VFS
+	readOnly bool
 }
 
-func (m *mount) src(username string) (hpfs.FS, error) {
+// srcPath returns the path of the source of the mount for a user, which is a
+// directory for os mounts and a key prefix for s3 mounts.
+func (m *mount) srcPath(username string) (string, error) {
 	path, err := m.srcTmpl.Render(mountSourceParameters{Username: username})
 	if err != nil {
-		return nil, fmt.Errorf("error while generating mount source: %w", err)
+		return "", fmt.Errorf("error while generating mount source: %w", err)
+	}
+
+	return path, nil
+}
+
+// srcKey returns a key identifying the source of the mount for a user across
+// all mounts.
+func (m *mount) srcKey(username string) (string, error) {
+	path, err := m.srcPath(username)
+	if err != nil {
+		return "", err
 	}
 
+	return m.vfs.String() + ":" + path, nil
+}
+
+func (m *mount) src(username string, s3 *s3Storage) (hpfs.FS, error) {
+	path, err := m.srcPath(username)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.fs(path, s3)
+}
+
+// fs opens the filesystem at path, in the VFS of the mount.
+func (m *mount) fs(path string, s3 *s3Storage) (hpfs.FS, error) {
 	switch m.vfs {
 	case VFSOS:
 		fs, err := hpfsos.NewFS().Sub(path)
@@ -67,12 +118,78 @@ func (m *mount) src(username string) (hpfs.FS, error) {
 			return nil, fmt.Errorf("error while opening filesystem for %q mount source: %w", m.vfs, err)
 		}
 
+		return fs, nil
+	case VFSS3:
+		if path == "." {
+			path = ""
+		}
+
+		config := s3fs.FSConfig{Prefix: path, MaxCachedSize: s3.maxCachedSize}
+
+		fs, err := s3fs.NewFS(config, s3.client, s3.cache, s3.bucket)
+		if err != nil {
+			return nil, fmt.Errorf("error while opening filesystem for %q mount source: %w", m.vfs, err)
+		}
+
 		return fs, nil
 	}
 
 	return nil, nil //nolint:nilnil // unreachable
 }
 
+// usernameSentinel is rendered in place of the username to find where it
+// appears in the source of a mount, as it cannot be part of any username.
+const usernameSentinel = "\x00"
+
+// users lists the users found in the storage of the mount. Sources holding the
+// username as a whole path element, as in data/{{.Username}}/files, are found
+// by listing the parent directory, while shared sources list the users with a
+// trash in them. Other sources yield no users.
+func (m *mount) users(s3 *s3Storage) ([]string, error) {
+	tmpl, err := m.srcPath(usernameSentinel)
+	if err != nil {
+		return nil, err
+	}
+
+	before, after, found := strings.Cut(tmpl, usernameSentinel)
+
+	dir := path.Join(tmpl, TrashDir)
+	if found {
+		if (before != "" && !strings.HasSuffix(before, "/")) ||
+			(after != "" && !strings.HasPrefix(after, "/")) ||
+			strings.Contains(after, usernameSentinel) {
+			return nil, nil
+		}
+
+		dir = "."
+		if before != "" {
+			dir = path.Clean(before)
+		}
+	}
+
+	fs, err := m.fs(dir, s3)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := hpfs.ReadDir(fs, ".")
+	if errors.Is(err, hpfs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error while listing users in %q: %w", dir, err)
+	}
+
+	var users []string
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			users = append(users, entry.Name())
+		}
+	}
+
+	return users, nil
+}
+
 type mountSourceParameters struct {
 	Username string
 }
@@ -84,8 +201,9 @@ func parseMountConfig(mc mountConfig) (mount, error) {
 	}
 
 	return mount{
-		vfs:     mc.VFS,
-		srcTmpl: srcTmpl,
-		dst:     mc.Destination,
+		vfs:      mc.VFS,
+		srcTmpl:  srcTmpl,
+		dst:      mc.Destination,
+		readOnly: mc.ReadOnly,
 	}, nil
 }
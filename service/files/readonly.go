@@ -1,12 +1,14 @@
 package files
 
 import (
-	"errors"
+	"fmt"
+	"os"
+	"time"
 
 	hpfs "github.com/hack-pad/hackpadfs"
 )
 
-var ErrReadOnly = errors.New("filesystem is read-only")
+var ErrReadOnly = fmt.Errorf("filesystem is read-only: %w", hpfs.ErrPermission)
 
 type ReadOnlyFS[FS hpfs.FS] struct {
 	fs FS
@@ -22,3 +24,58 @@ func NewReadOnlyFS[FS hpfs.FS](fs FS) ReadOnlyFS[FS] {
 func (fs ReadOnlyFS[FS]) Open(name string) (hpfs.File, error) {
 	return fs.fs.Open(name)
 }
+
+// OpenFile implements hackpadfs.OpenFileFS, only allowing files to be opened
+// for reading.
+func (fs ReadOnlyFS[FS]) OpenFile(name string, flag int, perm hpfs.FileMode) (hpfs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, &hpfs.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+	}
+
+	return hpfs.OpenFile(fs.fs, name, flag, perm)
+}
+
+// Stat implements hackpadfs.StatFS.
+func (fs ReadOnlyFS[FS]) Stat(name string) (hpfs.FileInfo, error) {
+	return hpfs.Stat(fs.fs, name)
+}
+
+// ReadDir implements hackpadfs.ReadDirFS.
+func (fs ReadOnlyFS[FS]) ReadDir(name string) ([]hpfs.DirEntry, error) {
+	return hpfs.ReadDir(fs.fs, name)
+}
+
+// Mkdir implements hackpadfs.MkdirFS.
+func (fs ReadOnlyFS[FS]) Mkdir(name string, _ hpfs.FileMode) error {
+	return &hpfs.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
+
+// MkdirAll implements hackpadfs.MkdirAllFS.
+func (fs ReadOnlyFS[FS]) MkdirAll(name string, _ hpfs.FileMode) error {
+	return &hpfs.PathError{Op: "mkdirall", Path: name, Err: ErrReadOnly}
+}
+
+// Remove implements hackpadfs.RemoveFS.
+func (fs ReadOnlyFS[FS]) Remove(name string) error {
+	return &hpfs.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+// RemoveAll implements hackpadfs.RemoveAllFS.
+func (fs ReadOnlyFS[FS]) RemoveAll(name string) error {
+	return &hpfs.PathError{Op: "removeall", Path: name, Err: ErrReadOnly}
+}
+
+// Rename implements hackpadfs.RenameFS.
+func (fs ReadOnlyFS[FS]) Rename(oldname, newname string) error {
+	return &hpfs.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrReadOnly}
+}
+
+// Chmod implements hackpadfs.ChmodFS.
+func (fs ReadOnlyFS[FS]) Chmod(name string, _ hpfs.FileMode) error {
+	return &hpfs.PathError{Op: "chmod", Path: name, Err: ErrReadOnly}
+}
+
+// Chtimes implements hackpadfs.ChtimesFS.
+func (fs ReadOnlyFS[FS]) Chtimes(name string, _, _ time.Time) error {
+	return &hpfs.PathError{Op: "chtimes", Path: name, Err: ErrReadOnly}
+}
@@ -0,0 +1,62 @@
+package files
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/teapotovh/teapot/lib/s3cache"
+)
+
+// SessionsS3Config is the configuration of the S3 storage used by s3 mounts.
+type SessionsS3Config struct {
+	URL    string
+	Region string
+	Cache  s3cache.S3CacheConfig
+
+	// MaxCachedSize is the size of the largest file read through the local
+	// cache. Larger files are streamed from S3.
+	MaxCachedSize int64
+}
+
+// s3Storage is the S3 client and cache shared by all s3 mounts.
+type s3Storage struct {
+	client        *minio.Client
+	cache         *s3cache.S3Cache
+	bucket        string
+	maxCachedSize int64
+}
+
+func newS3Storage(config SessionsS3Config, logger *slog.Logger) (*s3Storage, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing the S3 connection string: %w", err)
+	}
+
+	key := u.User.Username()
+	secret, _ := u.User.Password()
+
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(key, secret, ""),
+		Secure: u.Scheme == "https",
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the S3 client: %w", err)
+	}
+
+	cache, err := s3cache.NewS3Cache(config.Cache, client, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error while building s3 cache: %w", err)
+	}
+
+	return &s3Storage{
+		client:        client,
+		cache:         cache,
+		bucket:        config.Cache.Bucket,
+		maxCachedSize: config.MaxCachedSize,
+	}, nil
+}
@@ -1,15 +1,22 @@
 package files
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ammario/tlru"
 	hpfs "github.com/hack-pad/hackpadfs"
 	hpfsmem "github.com/hack-pad/hackpadfs/mem"
 	hpfsmount "github.com/hack-pad/hackpadfs/mount"
+
+	"github.com/teapotovh/teapot/lib/run"
 )
 
 const (
@@ -24,17 +31,29 @@ type Sessions struct {
 	logger        *slog.Logger
 	cache         SessionsCache
 	mounts        []mount
+	s3            *s3Storage
 	cacheLifetime time.Duration
+	trash         TrashConfig
+
+	// users holds all users a session was constructed for, whose trash is
+	// purged periodically.
+	usersMu sync.Mutex
+	users   map[string]struct{}
 }
 
 type SessionsConfig struct {
 	Mounts        []string
 	CacheSize     int
 	CacheLifetime time.Duration
+	S3            SessionsS3Config
+	Trash         TrashConfig
 }
 
 func NewSessions(config SessionsConfig, logger *slog.Logger) (*Sessions, error) {
-	var mounts []mount
+	var (
+		mounts []mount
+		s3     *s3Storage
+	)
 
 	for _, m := range config.Mounts {
 		mc, err := parseRawMount(m)
@@ -50,12 +69,29 @@ func NewSessions(config SessionsConfig, logger *slog.Logger) (*Sessions, error)
 		mounts = append(mounts, mount)
 	}
 
+	// The S3 storage is only set up when needed, so that deployments without
+	// s3 mounts are not required to configure it.
+	for _, mount := range mounts {
+		if mount.vfs == VFSS3 {
+			var err error
+			if s3, err = newS3Storage(config.S3, logger.With("component", "s3cache")); err != nil {
+				return nil, err
+			}
+
+			break
+		}
+	}
+
 	return &Sessions{
 		logger: logger,
 
 		mounts:        mounts,
+		s3:            s3,
 		cache:         tlru.New[string, *Session](nil, config.CacheSize),
 		cacheLifetime: config.CacheLifetime,
+		trash:         config.Trash,
+
+		users: map[string]struct{}{},
 	}, nil
 }
 
@@ -84,13 +120,23 @@ func (s *Sessions) newSessionFn(username string) func() (*Session, error) {
 			return nil, fmt.Errorf("error while creating root mount filesystem: %w", err)
 		}
 
+		trashes := map[string]*TrashFS{}
+
 		// mount all mountpoints
 		for _, mount := range s.mounts {
-			src, err := mount.src(username)
+			src, err := mount.src(username, s.s3)
 			if err != nil {
 				return nil, fmt.Errorf("error while getting filesystem for mountpoint %q: %w", mount.dst, err)
 			}
 
+			if mount.readOnly {
+				src = NewReadOnlyFS(src)
+			} else if s.trash.Enabled {
+				trash := NewTrashFS(src, username, s.trash)
+				trashes[mount.dst] = trash
+				src = trash
+			}
+
 			if err = memFS.MkdirAll(mount.dst, DirPerm); err != nil {
 				return nil, fmt.Errorf("error while creating mounting directory at %q: %w", mount.dst, err)
 			}
@@ -100,16 +146,158 @@ func (s *Sessions) newSessionFn(username string) func() (*Session, error) {
 			}
 		}
 
+		s.usersMu.Lock()
+		s.users[username] = struct{}{}
+		s.usersMu.Unlock()
+
 		return &Session{
-			fs: rootFS,
+			fs:      rootFS,
+			trashes: trashes,
 		}, nil
 	}
 }
 
+// Run implements run.Runnable, periodically purging the trash of all users
+// a session was constructed for.
+func (s *Sessions) Run(ctx context.Context, notify run.Notify) error {
+	notify.Notify()
+
+	if !s.trash.Enabled {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(s.trash.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.purge(ctx)
+		}
+	}
+}
+
+// purge removes the trash items and previous versions past their retention
+// from the mount sources of all users. The sources are opened directly, rather
+// than through sessions, so that purging does not churn the sessions cache.
+// Users are both those with a session since startup and those found in the
+// storage of the mounts, so that the trash of users who do not log in again
+// is purged too.
+func (s *Sessions) purge(ctx context.Context) {
+	s.usersMu.Lock()
+	users := maps.Clone(s.users)
+	s.usersMu.Unlock()
+
+	for _, mount := range s.mounts {
+		if mount.readOnly {
+			continue
+		}
+
+		found, err := mount.users(s.s3)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "error while listing users to purge", "mount", mount.dst, "err", err)
+		}
+
+		for _, username := range found {
+			users[username] = struct{}{}
+		}
+	}
+
+	now := time.Now()
+	purged := map[string]struct{}{}
+
+	for _, username := range slices.Sorted(maps.Keys(users)) {
+		for _, mount := range s.mounts {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if mount.readOnly {
+				continue
+			}
+
+			if err := s.purgeMount(&mount, username, now, purged); err != nil {
+				s.logger.ErrorContext(
+					ctx,
+					"error while purging trash",
+					"username", username,
+					"mount", mount.dst,
+					"err", err,
+				)
+			}
+		}
+	}
+}
+
+// purgeMount purges the source of a mount for a user. Sources may be shared
+// by multiple users, so each of them is purged only once, keeping track of
+// them in purged. Sources which are also mounted read-only for the user are
+// left untouched, as users found in the storage might not be actual users.
+func (s *Sessions) purgeMount(m *mount, username string, now time.Time, purged map[string]struct{}) error {
+	key, err := m.srcKey(username)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := purged[key]; ok {
+		return nil
+	}
+
+	for _, other := range s.mounts {
+		if !other.readOnly {
+			continue
+		}
+
+		otherKey, err := other.srcKey(username)
+		if err != nil {
+			return err
+		}
+
+		if otherKey == key {
+			return nil
+		}
+	}
+
+	purged[key] = struct{}{}
+
+	src, err := m.src(username, s.s3)
+	if err != nil {
+		return fmt.Errorf("error while getting filesystem for mountpoint %q: %w", m.dst, err)
+	}
+
+	return NewTrashFS(src, username, s.trash).Purge(now)
+}
+
 type Session struct {
-	fs hpfs.FS
+	fs      hpfs.FS
+	trashes map[string]*TrashFS
 }
 
 func (s *Session) FS() hpfs.FS {
 	return s.fs
 }
+
+// Trashes returns the TrashFS of all mounts with a trash, keyed by their
+// mount point.
+func (s *Session) Trashes() map[string]*TrashFS {
+	return s.trashes
+}
+
+// Trash returns the TrashFS of the mount holding name, along with the path of
+// name within the mount. It returns nil if the mount has no trash.
+func (s *Session) Trash(name string) (*TrashFS, string) {
+	for dst, trash := range s.trashes {
+		if name == dst {
+			return trash, "."
+		}
+
+		if rel, ok := strings.CutPrefix(name, dst+"/"); ok {
+			return trash, rel
+		}
+	}
+
+	return nil, ""
+}
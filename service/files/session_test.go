@@ -0,0 +1,88 @@
+package files
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	hpfs "github.com/hack-pad/hackpadfs"
+)
+
+func TestSessionsPurge(t *testing.T) {
+	// Sources of os mounts are relative to the root of the filesystem
+	root := t.TempDir()
+	src := strings.TrimPrefix(root, "/")
+
+	sessions, err := NewSessions(SessionsConfig{
+		Mounts: []string{
+			"os:" + src + "/{{.Username}}:/home",
+			"os:" + src + "/shared:/shared",
+			"os:" + src + "/public:/public:ro",
+		},
+		CacheSize:     1,
+		CacheLifetime: time.Hour,
+		Trash:         testTrashConfig,
+	}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("error creating sessions: %s", err)
+	}
+
+	old := time.Now().Add(-2 * testTrashConfig.Retention)
+
+	var expired []string
+
+	for _, dir := range []string{"alice", "bob", "shared", "public"} {
+		for _, username := range []string{"alice", "bob"} {
+			name := filepath.Join(root, dir, TrashDir, username, itemID(old, "file"))
+			if err := os.MkdirAll(filepath.Dir(name), DirPerm); err != nil {
+				t.Fatalf("error creating trash directory: %s", err)
+			}
+
+			if err := os.WriteFile(name, []byte("data"), FilePerm); err != nil {
+				t.Fatalf("error writing trash item: %s", err)
+			}
+
+			expired = append(expired, name)
+		}
+	}
+
+	// Users found in the storage are purged even if they had no session
+	// since startup, as carol who only has a trash in her home
+	carol := filepath.Join(root, "carol", TrashDir, "carol", itemID(old, "file"))
+	if err := os.MkdirAll(filepath.Dir(carol), DirPerm); err != nil {
+		t.Fatalf("error creating trash directory: %s", err)
+	}
+
+	if err := os.WriteFile(carol, []byte("data"), FilePerm); err != nil {
+		t.Fatalf("error writing trash item: %s", err)
+	}
+
+	expired = append(expired, carol)
+
+	sessions.purge(t.Context())
+
+	for _, name := range expired {
+		_, err := os.Stat(name)
+
+		// Read-only mounts are never purged
+		if filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(name)))) == "public" {
+			if err != nil {
+				t.Errorf("expected %q in a read-only mount to be kept, got %v", name, err)
+			}
+
+			continue
+		}
+
+		if !errors.Is(err, hpfs.ErrNotExist) {
+			t.Errorf("expected %q to be purged, got %v", name, err)
+		}
+	}
+
+	if _, _, ok := sessions.cache.Get("alice"); ok {
+		t.Errorf("expected purging not to construct sessions")
+	}
+}
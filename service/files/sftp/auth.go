@@ -0,0 +1,132 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/teapotovh/teapot/lib/ldap"
+)
+
+const authTimeout = 10 * time.Second
+
+const (
+	authMethodPassword  = "password"
+	authMethodPublicKey = "publickey"
+
+	authStatusSuccess = "success"
+	authStatusFailure = "failure"
+	authStatusError   = "error"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUnauthorizedKey    = errors.New("public key is not authorized")
+)
+
+func permissions(user *ldap.User) *ssh.Permissions {
+	return &ssh.Permissions{
+		Extensions: map[string]string{extensionUsername: user.Username},
+	}
+}
+
+func (s *SFTP) passwordCallback(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), authTimeout)
+	defer cancel()
+
+	client, err := s.files.LDAPFactory().NewClient(ctx)
+	if err != nil {
+		s.metrics.auth.WithLabelValues(authMethodPassword, authStatusError).Inc()
+		s.logger.ErrorContext(ctx, "error while creating LDAP client", "err", err)
+
+		return nil, fmt.Errorf("error while creating LDAP client: %w", err)
+	}
+	defer client.Close()
+
+	user, err := client.Authenticate(ctx, conn.User(), string(password))
+	if err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) {
+			s.metrics.auth.WithLabelValues(authMethodPassword, authStatusFailure).Inc()
+			s.logger.DebugContext(ctx, "rejected password", "username", conn.User(), "remote", conn.RemoteAddr())
+
+			return nil, ErrInvalidCredentials
+		}
+
+		s.metrics.auth.WithLabelValues(authMethodPassword, authStatusError).Inc()
+		s.logger.ErrorContext(ctx, "unexpected error while authenticating", "username", conn.User(), "err", err)
+
+		return nil, err
+	}
+
+	s.metrics.auth.WithLabelValues(authMethodPassword, authStatusSuccess).Inc()
+
+	return permissions(user), nil
+}
+
+// publicKeyCallback accepts a key when it is among the authorized keys stored
+// in the LDAP entry of the user.
+func (s *SFTP) publicKeyCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), authTimeout)
+	defer cancel()
+
+	user, authorized, err := s.authorizedKeys(ctx, conn.User())
+	if err != nil {
+		if errors.Is(err, ldap.ErrUserNotFound) {
+			s.metrics.auth.WithLabelValues(authMethodPublicKey, authStatusFailure).Inc()
+			return nil, ErrUnauthorizedKey
+		}
+
+		s.metrics.auth.WithLabelValues(authMethodPublicKey, authStatusError).Inc()
+		s.logger.ErrorContext(ctx, "error while fetching authorized keys", "username", conn.User(), "err", err)
+
+		return nil, err
+	}
+
+	for _, candidate := range authorized {
+		if bytes.Equal(candidate.Marshal(), key.Marshal()) {
+			s.metrics.auth.WithLabelValues(authMethodPublicKey, authStatusSuccess).Inc()
+			return permissions(user), nil
+		}
+	}
+
+	s.metrics.auth.WithLabelValues(authMethodPublicKey, authStatusFailure).Inc()
+	s.logger.DebugContext(ctx, "rejected public key", "username", conn.User(), "remote", conn.RemoteAddr())
+
+	return nil, ErrUnauthorizedKey
+}
+
+func (s *SFTP) authorizedKeys(ctx context.Context, username string) (*ldap.User, []ssh.PublicKey, error) {
+	client, err := s.files.LDAPFactory().NewClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while creating LDAP client: %w", err)
+	}
+	defer client.Close()
+
+	user, err := client.User(ctx, username)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while looking up user: %w", err)
+	}
+
+	attributes, err := client.UserAttributes(ctx, user.Username, s.publicKeyAttribute)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while fetching user attributes: %w", err)
+	}
+
+	var keys []ssh.PublicKey
+
+	for _, value := range attributes[s.publicKeyAttribute] {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(value))
+		if err != nil {
+			s.logger.WarnContext(ctx, "ignoring invalid public key", "username", user.Username, "err", err)
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	return user, keys, nil
+}
@@ -0,0 +1,192 @@
+package sftp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	hpfs "github.com/hack-pad/hackpadfs"
+)
+
+// MaxPendingWrite is how many bytes of out-of-order writes are buffered for
+// files which can only be written sequentially.
+const MaxPendingWrite = 32 << 20 // 32MiB
+
+var (
+	ErrNonSequentialWrite = errors.New("file can only be written sequentially")
+	ErrTooManyPending     = errors.New("too many out-of-order writes pending")
+	ErrIncompleteWrite    = errors.New("file was closed with gaps in its content")
+)
+
+// readerAt reads a file at arbitrary offsets, seeking when the file does not
+// support io.ReaderAt itself.
+type readerAt struct {
+	mu   sync.Mutex
+	file hpfs.File
+}
+
+func newReaderAt(file hpfs.File) *readerAt {
+	return &readerAt{file: file}
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if ra, ok := r.file.(io.ReaderAt); ok {
+		return ra.ReadAt(p, off)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := hpfs.SeekFile(r.file, off, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("error while seeking file: %w", err)
+	}
+
+	n, err := io.ReadFull(r.file, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// Close implements io.Closer.
+func (r *readerAt) Close() error {
+	return r.file.Close()
+}
+
+// writerAt writes a file at arbitrary offsets through io.WriterAt.
+type writerAt struct {
+	io.WriterAt
+
+	file hpfs.File
+}
+
+// Close implements io.Closer.
+func (w *writerAt) Close() error {
+	return w.file.Close()
+}
+
+// orderedWriter writes a file which can only be written sequentially, like
+// objects uploaded to S3. As the SFTP server handles writes concurrently,
+// chunks arriving ahead of the current offset are buffered until the gap
+// before them is filled.
+type orderedWriter struct {
+	mu   sync.Mutex
+	file hpfs.File
+
+	offset      int64
+	pending     map[int64][]byte
+	pendingSize int64
+}
+
+// newWriterAt returns an io.WriterAt for a file opened for writing. Appends
+// are always sequential, and start at the current end of the file.
+func newWriterAt(file hpfs.File, appending bool) (io.WriterAt, error) {
+	if wa, ok := file.(io.WriterAt); ok && !appending {
+		return &writerAt{WriterAt: wa, file: file}, nil
+	}
+
+	var offset int64
+
+	if appending {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("error while getting file info: %w", err)
+		}
+
+		offset = info.Size()
+	}
+
+	return &orderedWriter{file: file, offset: offset, pending: map[int64][]byte{}}, nil
+}
+
+// WriteAt implements io.WriterAt.
+func (w *orderedWriter) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch {
+	case off < w.offset:
+		return 0, ErrNonSequentialWrite
+	case off > w.offset:
+		if _, ok := w.pending[off]; ok {
+			return 0, ErrNonSequentialWrite
+		}
+
+		if w.pendingSize+int64(len(p)) > MaxPendingWrite {
+			return 0, ErrTooManyPending
+		}
+
+		// The buffer is reused by the server once this call returns
+		w.pending[off] = bytes.Clone(p)
+		w.pendingSize += int64(len(p))
+
+		return len(p), nil
+	}
+
+	if err := w.write(p); err != nil {
+		return 0, err
+	}
+
+	for {
+		data, ok := w.pending[w.offset]
+		if !ok {
+			break
+		}
+
+		delete(w.pending, w.offset)
+		w.pendingSize -= int64(len(data))
+
+		if err := w.write(data); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (w *orderedWriter) write(p []byte) error {
+	n, err := hpfs.WriteFile(w.file, p)
+	w.offset += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("error while writing file: %w", err)
+	}
+
+	if n < len(p) {
+		return io.ErrShortWrite
+	}
+
+	return nil
+}
+
+// Close implements io.Closer.
+func (w *orderedWriter) Close() error {
+	err := w.file.Close()
+	if len(w.pending) > 0 {
+		return ErrIncompleteWrite
+	}
+
+	return err
+}
+
+// listerAt lists a fixed set of files.
+type listerAt []os.FileInfo
+
+// ListAt implements sftp.ListerAt.
+func (l listerAt) ListAt(infos []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+
+	n := copy(infos, l[offset:])
+	if n < len(infos) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
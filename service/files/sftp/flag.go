@@ -0,0 +1,41 @@
+package sftp
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+func SFTPFlagSet() (*flag.FlagSet, func() SFTPConfig) {
+	fs := flag.NewFlagSet("files/sftp", flag.ExitOnError)
+
+	ip := fs.IP("files-sftp-ip", net.IPv4zero, "the address on which to open the SFTP server")
+	port := fs.Uint16("files-sftp-port", 2022, "the port on which to open the SFTP server")
+	hostKeyPath := fs.String(
+		"files-sftp-host-key",
+		"/var/lib/filesd/ssh_host_ed25519_key",
+		"the path of the SSH host key, which is generated if missing",
+	)
+	publicKeyAttribute := fs.String(
+		"files-sftp-public-key-attribute",
+		"sshPublicKey",
+		"the LDAP attribute holding the authorized SSH public keys of users",
+	)
+
+	handshakeTimeout := fs.Duration(
+		"files-sftp-handshake-timeout",
+		30*time.Second,
+		"the time allowed for the SSH handshake and authentication (0 to disable)",
+	)
+
+	return fs, func() SFTPConfig {
+		return SFTPConfig{
+			Address:            net.JoinHostPort(ip.String(), strconv.Itoa(int(*port))),
+			HostKeyPath:        *hostKeyPath,
+			PublicKeyAttribute: *publicKeyAttribute,
+			HandshakeTimeout:   *handshakeTimeout,
+		}
+	}
+}
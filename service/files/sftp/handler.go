@@ -0,0 +1,226 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	hpfs "github.com/hack-pad/hackpadfs"
+	"github.com/pkg/sftp"
+
+	"github.com/teapotovh/teapot/service/files"
+)
+
+var ErrNotDirectory = errors.New("not a directory")
+
+// handler implements the sftp request handlers on top of a session filesystem.
+type handler struct {
+	logger *slog.Logger
+
+	fs hpfs.FS
+}
+
+func newHandlers(fs hpfs.FS, logger *slog.Logger) sftp.Handlers {
+	h := &handler{logger: logger, fs: fs}
+
+	return sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+}
+
+// statusError maps filesystem errors to the SFTP status codes clients
+// understand, as the request server only recognizes errors from the os package.
+func statusError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, hpfs.ErrNotExist):
+		return sftp.ErrSSHFxNoSuchFile
+	case errors.Is(err, hpfs.ErrPermission):
+		return sftp.ErrSSHFxPermissionDenied
+	case errors.Is(err, hpfs.ErrNotImplemented):
+		return sftp.ErrSSHFxOpUnsupported
+	default:
+		return err
+	}
+}
+
+// Fileread implements sftp.FileReader.
+func (h *handler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	name := sanitizeName(r.Filepath)
+	h.logger.Debug("performing Fileread", "name", name)
+
+	file, err := hpfs.OpenFile(h.fs, name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return newReaderAt(file), nil
+}
+
+// Filewrite implements sftp.FileWriter.
+func (h *handler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	name := sanitizeName(r.Filepath)
+	pflags := r.Pflags()
+	h.logger.Debug("performing Filewrite", "name", name, "flags", pflags)
+
+	flag := os.O_WRONLY
+	if pflags.Creat {
+		flag |= os.O_CREATE
+	}
+
+	if pflags.Trunc {
+		flag |= os.O_TRUNC
+	}
+
+	if pflags.Excl {
+		flag |= os.O_EXCL
+	}
+
+	if pflags.Append {
+		flag |= os.O_APPEND
+	}
+
+	file, err := hpfs.OpenFile(h.fs, name, flag, files.FilePerm)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	writer, err := newWriterAt(file, pflags.Append)
+	if err != nil {
+		_ = file.Close()
+		return nil, statusError(err)
+	}
+
+	return writer, nil
+}
+
+// Filecmd implements sftp.FileCmder.
+func (h *handler) Filecmd(r *sftp.Request) error {
+	name := sanitizeName(r.Filepath)
+	h.logger.Debug("performing Filecmd", "method", r.Method, "name", name)
+
+	switch r.Method {
+	case "Setstat":
+		return statusError(h.setstat(name, r))
+	case "Rename":
+		return statusError(hpfs.Rename(h.fs, name, sanitizeName(r.Target)))
+	case "Rmdir":
+		info, err := hpfs.Stat(h.fs, name)
+		if err != nil {
+			return statusError(err)
+		}
+
+		if !info.IsDir() {
+			return &hpfs.PathError{Op: "rmdir", Path: name, Err: ErrNotDirectory}
+		}
+
+		return statusError(hpfs.Remove(h.fs, name))
+	case "Remove":
+		return statusError(hpfs.Remove(h.fs, name))
+	case "Mkdir":
+		return statusError(hpfs.Mkdir(h.fs, name, files.DirPerm))
+	default:
+		return sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// setstat applies the attributes of a Setstat request. Permissions and times
+// are best-effort, as many clients set them after every upload while not all
+// filesystems support them.
+func (h *handler) setstat(name string, r *sftp.Request) error {
+	flags := r.AttrFlags()
+	attrs := r.Attributes()
+
+	if flags.Size {
+		file, err := hpfs.OpenFile(h.fs, name, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+
+		err = hpfs.TruncateFile(file, int64(attrs.Size))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			return fmt.Errorf("error while truncating file: %w", err)
+		}
+	}
+
+	if flags.Permissions {
+		err := hpfs.Chmod(h.fs, name, attrs.FileMode().Perm())
+		if err != nil && !errors.Is(err, hpfs.ErrNotImplemented) {
+			return err
+		}
+	}
+
+	if flags.Acmodtime {
+		err := hpfs.Chtimes(h.fs, name, attrs.AccessTime(), attrs.ModTime())
+		if err != nil && !errors.Is(err, hpfs.ErrNotImplemented) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Filelist implements sftp.FileLister.
+func (h *handler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	name := sanitizeName(r.Filepath)
+	h.logger.Debug("performing Filelist", "method", r.Method, "name", name)
+
+	switch r.Method {
+	case "List":
+		entries, err := hpfs.ReadDir(h.fs, name)
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		infos := make(listerAt, 0, len(entries))
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return nil, statusError(err)
+			}
+
+			infos = append(infos, info)
+		}
+
+		return infos, nil
+	case "Stat":
+		info, err := hpfs.Stat(h.fs, name)
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		return listerAt{info}, nil
+	default:
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// sanitizeName turns an absolute SFTP path into a name valid for hackpadfs.
+func sanitizeName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		// Use relative indexing as required by hpfs
+		name = "."
+	}
+
+	return name
+}
+
+var (
+	_ sftp.FileReader = &handler{}
+	_ sftp.FileWriter = &handler{}
+	_ sftp.FileCmder  = &handler{}
+	_ sftp.FileLister = &handler{}
+)
@@ -0,0 +1,63 @@
+package sftp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/teapotovh/teapot/service/files"
+)
+
+const HostKeyPerm = os.FileMode(0o0600)
+
+// loadHostKey reads the host key at path, generating it on first use so that
+// clients keep recognizing the server across restarts.
+func loadHostKey(path string, logger *slog.Logger) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		logger.Info("generating new SSH host key", "path", path)
+		data, err = generateHostKey(path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error while reading host key at %q: %w", path, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing host key at %q: %w", path, err)
+	}
+
+	return signer, nil
+}
+
+func generateHostKey(path string) ([]byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error while generating ed25519 key: %w", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while marshaling host key: %w", err)
+	}
+
+	data := pem.EncodeToMemory(block)
+
+	if err := os.MkdirAll(filepath.Dir(path), files.DirPerm); err != nil {
+		return nil, fmt.Errorf("error while creating host key directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, HostKeyPerm); err != nil {
+		return nil, fmt.Errorf("error while writing host key: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,39 @@
+package sftp
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	connections prometheus.Gauge
+	auth        *prometheus.CounterVec
+}
+
+const namespace = "files"
+
+func (s *SFTP) initMetrics() {
+	s.metrics.connections = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sftp_connections_active",
+			Help:      "Current number of authenticated SFTP connections",
+		},
+	)
+
+	s.metrics.auth = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sftp_auth_total",
+			Help:      "Total number of SFTP authentication attempts",
+		},
+		[]string{"method", "status"},
+	)
+}
+
+// Metrics implements observability.Metrics.
+func (s *SFTP) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		s.metrics.connections,
+		s.metrics.auth,
+	}
+}
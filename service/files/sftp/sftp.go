@@ -0,0 +1,225 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/teapotovh/teapot/lib/run"
+	"github.com/teapotovh/teapot/service/files"
+)
+
+const (
+	// extensionUsername is the ssh.Permissions extension holding the LDAP
+	// username a connection has authenticated as.
+	extensionUsername = "username"
+
+	subsystemSFTP = "sftp"
+)
+
+// SFTP serves the files of each user session over SFTP.
+type SFTP struct {
+	logger *slog.Logger
+
+	files   *files.Files
+	address string
+
+	publicKeyAttribute string
+	serverConfig       *ssh.ServerConfig
+	handshakeTimeout   time.Duration
+
+	metrics metrics
+}
+
+type SFTPConfig struct {
+	Address string
+	// HostKeyPath is the path of the private host key of the server. A new
+	// key is generated and stored there when none exists yet.
+	HostKeyPath string
+	// PublicKeyAttribute is the LDAP attribute holding the authorized public
+	// keys of users, in the authorized_keys format.
+	PublicKeyAttribute string
+	// HandshakeTimeout bounds the SSH handshake and authentication of each
+	// connection, so that clients cannot hold connections open without ever
+	// authenticating. Zero disables the timeout.
+	HandshakeTimeout time.Duration
+}
+
+func NewSFTP(files *files.Files, config SFTPConfig, logger *slog.Logger) (*SFTP, error) {
+	hostKey, err := loadHostKey(config.HostKeyPath, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error while loading the SSH host key: %w", err)
+	}
+
+	s := SFTP{
+		logger: logger,
+
+		files:   files,
+		address: config.Address,
+
+		publicKeyAttribute: config.PublicKeyAttribute,
+		handshakeTimeout:   config.HandshakeTimeout,
+	}
+
+	s.serverConfig = &ssh.ServerConfig{
+		PasswordCallback:  s.passwordCallback,
+		PublicKeyCallback: s.publicKeyCallback,
+	}
+	s.serverConfig.AddHostKey(hostKey)
+
+	s.initMetrics()
+
+	return &s, nil
+}
+
+// Run implements run.Runnable.
+func (s *SFTP) Run(ctx context.Context, notify run.Notify) error {
+	var lc net.ListenConfig
+
+	listener, err := lc.Listen(ctx, "tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("error while listening on %q: %w", s.address, err)
+	}
+
+	s.logger.Info("opening SFTP server", "address", s.address)
+	notify.Notify()
+
+	stop := context.AfterFunc(ctx, func() {
+		if err := listener.Close(); err != nil {
+			s.logger.Error("error while closing the SFTP listener", "err", err)
+		}
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("error while accepting SFTP connection: %w", err)
+		}
+
+		wg.Go(func() { s.handleConn(ctx, conn) })
+	}
+}
+
+func (s *SFTP) handleConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	// Connections are torn down on shutdown, which in turn ends all of their
+	// channels.
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if s.handshakeTimeout != 0 {
+		if err := conn.SetDeadline(time.Now().Add(s.handshakeTimeout)); err != nil {
+			s.logger.ErrorContext(ctx, "error while setting the SSH handshake deadline", "err", err)
+			return
+		}
+	}
+
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, s.serverConfig)
+	if err != nil {
+		s.logger.DebugContext(ctx, "error during SSH handshake", "remote", conn.RemoteAddr(), "err", err)
+		return
+	}
+	defer sshConn.Close()
+
+	// Authenticated connections may stay idle for as long as they like
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		s.logger.ErrorContext(ctx, "error while clearing the SSH handshake deadline", "err", err)
+		return
+	}
+
+	username := sshConn.Permissions.Extensions[extensionUsername]
+	logger := s.logger.With("username", username, "remote", conn.RemoteAddr())
+	logger.DebugContext(ctx, "accepted SSH connection")
+
+	s.metrics.connections.Inc()
+	defer s.metrics.connections.Dec()
+
+	go ssh.DiscardRequests(requests)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			logger.ErrorContext(ctx, "error while accepting SSH channel", "err", err)
+			continue
+		}
+
+		wg.Go(func() { s.handleChannel(ctx, username, channel, requests, logger) })
+	}
+}
+
+// handleChannel serves the sftp subsystem on a session channel, refusing
+// any other request such as shells or commands.
+func (s *SFTP) handleChannel(
+	ctx context.Context,
+	username string,
+	channel ssh.Channel,
+	requests <-chan *ssh.Request,
+	logger *slog.Logger,
+) {
+	defer channel.Close()
+
+	serving := false
+
+	for req := range requests {
+		var payload struct{ Name string }
+
+		ok := !serving && req.Type == "subsystem" &&
+			ssh.Unmarshal(req.Payload, &payload) == nil && payload.Name == subsystemSFTP
+		if req.WantReply {
+			_ = req.Reply(ok, nil)
+		}
+
+		if ok {
+			serving = true
+
+			go func() {
+				s.serve(ctx, username, channel, logger)
+
+				// Closing the channel also ends the loop over its requests.
+				status := struct{ Status uint32 }{0}
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(&status))
+				_ = channel.Close()
+			}()
+		}
+	}
+}
+
+func (s *SFTP) serve(ctx context.Context, username string, channel ssh.Channel, logger *slog.Logger) {
+	session, err := s.files.Sesssions().Get(username)
+	if err != nil {
+		logger.ErrorContext(ctx, "error while getting files session", "err", err)
+		return
+	}
+
+	server := sftp.NewRequestServer(channel, newHandlers(session.FS(), logger.With("component", "handler")))
+	defer server.Close()
+
+	if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+		logger.ErrorContext(ctx, "error while serving SFTP", "err", err)
+	}
+}
@@ -0,0 +1,31 @@
+package share
+
+import (
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+func StoreFlagSet() (*flag.FlagSet, func() StoreConfig) {
+	fs := flag.NewFlagSet("files/share", flag.ExitOnError)
+
+	timeout := fs.Duration("files-share-store-timeout", time.Minute, "timeout for share store connection setup")
+	typ := fs.String(
+		"files-share-store-type",
+		"mem",
+		"the type of store to keep public share links in. Options: mem, psql",
+	)
+	psqlURL := fs.String(
+		"files-share-store-psql-url",
+		"",
+		"the URL connection string to connect to the share store. mandatory for the psql backend",
+	)
+
+	return fs, func() StoreConfig {
+		return StoreConfig{
+			Timeout: *timeout,
+			Type:    *typ,
+			URL:     *psqlURL,
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package share
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemStore keeps shares in memory, and thus loses them on restart.
+type MemStore struct {
+	mu sync.Mutex
+
+	shares map[string]Share
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		shares: map[string]Share{},
+	}
+}
+
+// Create implements Store.
+func (m *MemStore) Create(_ context.Context, share Share) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.shares[share.Token] = share
+
+	return nil
+}
+
+// Get implements Store.
+func (m *MemStore) Get(_ context.Context, token string, now time.Time) (*Share, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	share, ok := m.shares[token]
+	if !ok || share.Expired(now) {
+		return nil, ErrNotFound
+	}
+
+	return &share, nil
+}
+
+// List implements Store.
+func (m *MemStore) List(_ context.Context, owner string) ([]Share, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var shares []Share
+
+	for _, share := range m.shares {
+		if share.Owner == owner {
+			shares = append(shares, share)
+		}
+	}
+
+	slices.SortFunc(shares, func(a, b Share) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return shares, nil
+}
+
+// Delete implements Store.
+func (m *MemStore) Delete(_ context.Context, owner, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	share, ok := m.shares[token]
+	if !ok || share.Owner != owner {
+		return ErrNotFound
+	}
+
+	delete(m.shares, token)
+
+	return nil
+}
+
+// Download implements Store.
+func (m *MemStore) Download(_ context.Context, token string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	share, ok := m.shares[token]
+	if !ok || share.Expired(now) {
+		return ErrNotFound
+	}
+
+	if share.Exhausted() {
+		return ErrExhausted
+	}
+
+	share.Downloads++
+	m.shares[token] = share
+
+	return nil
+}
+
+var _ Store = &MemStore{}
@@ -0,0 +1,17 @@
+-- +brant Up
+CREATE TABLE file_shares (
+  token TEXT PRIMARY KEY,
+  owner TEXT NOT NULL,
+  path TEXT NOT NULL,
+  mode INTEGER NOT NULL,
+  password_hash BYTEA,
+  expires_at TIMESTAMPTZ,
+  max_downloads INTEGER NOT NULL DEFAULT 0,
+  downloads INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX file_shares_owner ON file_shares (owner);
+
+-- +brant Down
+DROP TABLE file_shares;
@@ -0,0 +1,235 @@
+package share
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"git.sr.ht/~bitfehler/brant"
+	"git.sr.ht/~bitfehler/brant/database/dialect"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+//go:embed migrations/*.sql
+var migraitions embed.FS
+
+// PSQLStore keeps shares in Postgres, and thus shares them across replicas.
+type PSQLStore struct {
+	logger *slog.Logger
+
+	pool *pgxpool.Pool
+}
+
+func NewPSQLStore(ctx context.Context, url string, logger *slog.Logger) (*PSQLStore, error) {
+	// The shares may live in the same database as other stores, so they keep
+	// track of their migrations in a table of their own.
+	options := brant.DefaultOptions().
+		WithTableName("_version_file_shares").
+		WithFilesystem(migraitions).
+		WithDataSourceName(url)
+
+	provider, err := brant.NewProvider(logger, dialect.Postgres, options)
+	if err != nil {
+		return nil, fmt.Errorf("error while constructing migration provider: %w", err)
+	}
+
+	applied, err := provider.Up(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while applying migrations: %w", err)
+	}
+
+	for _, migration := range applied {
+		logger.Info("applied migration", "migration", migration)
+	}
+
+	if err := provider.Close(); err != nil {
+		return nil, fmt.Errorf("error while closing migration connection: %w", err)
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing psql connection URL: %w", err)
+	}
+
+	config.MaxConns = 4
+	config.MinConns = 1
+
+	// Use context.Background() here, as we want the pool to live for the lifetime
+	// of the program, while the provided context is only meant for databse initialization.
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening connection pool to psql: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("error while connecting to psql: %w", err)
+	}
+
+	return &PSQLStore{
+		logger: logger,
+
+		pool: pool,
+	}, nil
+}
+
+const shareColumns = `token, owner, path, mode, password_hash, expires_at, max_downloads, downloads, created_at`
+
+// nullTime maps the zero time to NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func scanShare(row pgx.Row) (*Share, error) {
+	var (
+		share     Share
+		mode      int
+		expiresAt *time.Time
+	)
+
+	err := row.Scan(
+		&share.Token,
+		&share.Owner,
+		&share.Path,
+		&mode,
+		&share.PasswordHash,
+		&expiresAt,
+		&share.MaxDownloads,
+		&share.Downloads,
+		&share.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	share.Mode = Mode(mode)
+	if expiresAt != nil {
+		share.ExpiresAt = *expiresAt
+	}
+
+	return &share, nil
+}
+
+var createQuery = `INSERT INTO file_shares (` + shareColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+
+// Create implements Store.
+func (p *PSQLStore) Create(ctx context.Context, share Share) error {
+	_, err := p.pool.Exec(
+		ctx,
+		createQuery,
+		share.Token,
+		share.Owner,
+		share.Path,
+		int(share.Mode),
+		share.PasswordHash,
+		nullTime(share.ExpiresAt),
+		share.MaxDownloads,
+		share.Downloads,
+		share.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error while creating share for %q in psql: %w", share.Path, err)
+	}
+
+	return nil
+}
+
+var getQuery = `
+		SELECT ` + shareColumns + `
+		FROM file_shares
+		WHERE token = $1 AND (expires_at IS NULL OR expires_at > $2);
+`
+
+// Get implements Store.
+func (p *PSQLStore) Get(ctx context.Context, token string, now time.Time) (*Share, error) {
+	share, err := scanShare(p.pool.QueryRow(ctx, getQuery, token, now))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("error while getting share from psql: %w", err)
+	}
+
+	return share, nil
+}
+
+var listQuery = `SELECT ` + shareColumns + ` FROM file_shares WHERE owner = $1 ORDER BY created_at;`
+
+// List implements Store.
+func (p *PSQLStore) List(ctx context.Context, owner string) ([]Share, error) {
+	rows, err := p.pool.Query(ctx, listQuery, owner)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing shares of %q from psql: %w", owner, err)
+	}
+	defer rows.Close()
+
+	var shares []Share
+
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error while scanning share of %q from psql: %w", owner, err)
+		}
+
+		shares = append(shares, *share)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while listing shares of %q from psql: %w", owner, err)
+	}
+
+	return shares, nil
+}
+
+var deleteQuery = `DELETE FROM file_shares WHERE owner = $1 AND token = $2;`
+
+// Delete implements Store.
+func (p *PSQLStore) Delete(ctx context.Context, owner, token string) error {
+	tag, err := p.pool.Exec(ctx, deleteQuery, owner, token)
+	if err != nil {
+		return fmt.Errorf("error while deleting share of %q from psql: %w", owner, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+var downloadQuery = `
+		UPDATE file_shares
+		SET downloads = downloads + 1
+		WHERE token = $1
+			AND (expires_at IS NULL OR expires_at > $2)
+			AND (max_downloads = 0 OR downloads < max_downloads);
+`
+
+// Download implements Store.
+func (p *PSQLStore) Download(ctx context.Context, token string, now time.Time) error {
+	tag, err := p.pool.Exec(ctx, downloadQuery, token, now)
+	if err != nil {
+		return fmt.Errorf("error while recording share download in psql: %w", err)
+	}
+
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	// Nothing was updated: tell apart missing shares from exhausted ones
+	if _, err := p.Get(ctx, token, now); err != nil {
+		return err
+	}
+
+	return ErrExhausted
+}
+
+var _ Store = &PSQLStore{}
@@ -0,0 +1,161 @@
+package share
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TokenSize is the number of random bytes in a share token.
+const TokenSize = 32
+
+var (
+	ErrInvalidBackend = errors.New("invalid backend")
+	ErrInvalidMode    = errors.New("invalid share mode")
+
+	ErrNotFound  = errors.New("share not found")
+	ErrExhausted = errors.New("share download limit reached")
+)
+
+// Mode defines what visitors of a share are allowed to do.
+type Mode int
+
+const (
+	// ModeDownload allows visitors to browse and download the shared files.
+	ModeDownload Mode = iota
+	// ModeUpload allows visitors to upload files into the shared folder,
+	// without being able to see its contents.
+	ModeUpload
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeDownload:
+		return "download"
+	case ModeUpload:
+		return "upload"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+func ParseMode(raw string) (Mode, error) {
+	switch raw {
+	case ModeDownload.String():
+		return ModeDownload, nil
+	case ModeUpload.String():
+		return ModeUpload, nil
+	default:
+		return 0, fmt.Errorf("error while parsing share mode %q: %w", raw, ErrInvalidMode)
+	}
+}
+
+// Share is a public link to a path in the session FS of its owner.
+type Share struct {
+	Token string
+	Owner string
+	// Path is the clean path of the shared file or folder, relative to the
+	// root of the session FS of the owner.
+	Path string
+	Mode Mode
+	// PasswordHash is the bcrypt hash of the share password, or empty if the
+	// share is not password protected.
+	PasswordHash []byte
+	// ExpiresAt is when the share stops being accessible, or the zero time
+	// if it never expires.
+	ExpiresAt time.Time
+	// MaxDownloads is how many downloads the share allows, or zero if they
+	// are unlimited.
+	MaxDownloads int
+	Downloads    int
+	CreatedAt    time.Time
+}
+
+// Expired reports whether the share is no longer accessible at the given time.
+func (s Share) Expired(now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && !s.ExpiresAt.After(now)
+}
+
+// Exhausted reports whether the share has reached its download limit.
+func (s Share) Exhausted() bool {
+	return s.MaxDownloads > 0 && s.Downloads >= s.MaxDownloads
+}
+
+// HasPassword reports whether the share is password protected.
+func (s Share) HasPassword() bool {
+	return len(s.PasswordHash) > 0
+}
+
+// CheckPassword reports whether password unlocks the share.
+func (s Share) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword(s.PasswordHash, []byte(password)) == nil
+}
+
+// HashPassword hashes a share password for storage in Share.PasswordHash.
+func HashPassword(password string) ([]byte, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("error while hashing share password: %w", err)
+	}
+
+	return hash, nil
+}
+
+// NewToken returns a new random share token.
+func NewToken() (string, error) {
+	raw := make([]byte, TokenSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("error while generating share token: %w", err)
+	}
+
+	return hex.EncodeToString(raw), nil
+}
+
+// Store keeps the metadata of all shares.
+type Store interface {
+	// Create saves a new share in the store.
+	Create(ctx context.Context, share Share) error
+
+	// Get fetches a share by its token, returning ErrNotFound if it does not
+	// exist or has expired at the given time.
+	Get(ctx context.Context, token string, now time.Time) (*Share, error)
+
+	// List returns all shares of an owner, including expired ones, sorted by
+	// creation time.
+	List(ctx context.Context, owner string) ([]Share, error)
+
+	// Delete removes a share of an owner, returning ErrNotFound if the owner
+	// has no share with the given token.
+	Delete(ctx context.Context, owner, token string) error
+
+	// Download records a download from a share, returning ErrExhausted if its
+	// download limit has already been reached, and ErrNotFound if it does not
+	// exist or has expired at the given time.
+	Download(ctx context.Context, token string, now time.Time) error
+}
+
+type StoreConfig struct {
+	Timeout time.Duration
+	Type    string
+	URL     string
+}
+
+func NewStore(config StoreConfig, logger *slog.Logger) (Store, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	switch config.Type {
+	case "mem":
+		return NewMemStore(), nil
+	case "psql":
+		return NewPSQLStore(ctx, config.URL, logger)
+	default:
+		return nil, fmt.Errorf("error instantiating share store of type %q: %w", config.Type, ErrInvalidBackend)
+	}
+}
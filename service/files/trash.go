@@ -0,0 +1,613 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	hpfs "github.com/hack-pad/hackpadfs"
+)
+
+const (
+	// TrashDir is the directory at the root of each mount holding the trash
+	// of each user, in a subdirectory named after them.
+	TrashDir = ".trash"
+	// VersionsDir is the directory at the root of each mount holding the
+	// previous versions of files, at the same path as the files themselves.
+	VersionsDir = ".versions"
+)
+
+var (
+	ErrReservedPath       = fmt.Errorf("path is reserved for the trash: %w", hpfs.ErrPermission)
+	ErrInvalidTrashItem   = fmt.Errorf("invalid trash item: %w", hpfs.ErrNotExist)
+	ErrInvalidVersion     = fmt.Errorf("invalid version: %w", hpfs.ErrNotExist)
+	ErrRestoreDestination = fmt.Errorf("restore destination already exists: %w", hpfs.ErrExist)
+)
+
+type TrashConfig struct {
+	Enabled bool
+	// Retention is how long trashed files and previous versions are kept.
+	Retention time.Duration
+	// MaxVersions is how many previous versions of each file are kept. Zero
+	// disables versioning.
+	MaxVersions int
+	// PurgeInterval is how often trashed files and previous versions past
+	// their retention are removed.
+	PurgeInterval time.Duration
+}
+
+// TrashItem is a file or directory in the trash.
+type TrashItem struct {
+	// ID identifies the item within the trash of its mount.
+	ID        string
+	Path      string
+	DeletedAt time.Time
+	Dir       bool
+	Size      int64
+}
+
+// Version is a previous version of a file.
+type Version struct {
+	// ID identifies the version among the ones of its file.
+	ID         string
+	Path       string
+	ReplacedAt time.Time
+	Size       int64
+}
+
+// TrashFS soft-deletes files by moving them to the trash of a user instead
+// of removing them, and keeps the previous versions of files when they are
+// overwritten. Both are kept in reserved directories within the underlying
+// FS, which are hidden from and cannot be accessed by users.
+type TrashFS struct {
+	fs       hpfs.FS
+	username string
+	config   TrashConfig
+}
+
+// NewTrashFS returns a new TrashFS which proxies all calls to the underlying
+// FS, keeping the trash for the given user.
+func NewTrashFS(fs hpfs.FS, username string, config TrashConfig) *TrashFS {
+	return &TrashFS{fs: fs, username: username, config: config}
+}
+
+// reserved reports whether name lies within the reserved directories.
+func reserved(name string) bool {
+	first, _, _ := strings.Cut(name, "/")
+	return first == TrashDir || first == VersionsDir
+}
+
+// validPath reports whether name is a valid path of a file users can access,
+// other than the root.
+func validPath(name string) bool {
+	return hpfs.ValidPath(name) && name != "." && !reserved(name)
+}
+
+// validVersion reports whether id is a valid version of the file at name.
+func validVersion(name, id string) bool {
+	_, err := strconv.ParseInt(id, 10, 64)
+	return err == nil && validPath(name)
+}
+
+func reservedError(op, name string) error {
+	return &hpfs.PathError{Op: op, Path: name, Err: ErrReservedPath}
+}
+
+// itemID encodes the deletion time and original path of a trash item in the
+// name of its entry in the trash.
+func itemID(deletedAt time.Time, name string) string {
+	return strconv.FormatInt(deletedAt.UnixNano(), 10) + "_" + url.PathEscape(name)
+}
+
+// parseItemID decodes the deletion time and original path of a trash item.
+func parseItemID(id string) (time.Time, string, error) {
+	rawNano, escaped, ok := strings.Cut(id, "_")
+	if !ok || strings.Contains(id, "/") {
+		return time.Time{}, "", ErrInvalidTrashItem
+	}
+
+	nano, err := strconv.ParseInt(rawNano, 10, 64)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("error while parsing deletion time of %q: %w", id, ErrInvalidTrashItem)
+	}
+
+	name, err := url.PathUnescape(escaped)
+	if err != nil || !validPath(name) {
+		return time.Time{}, "", fmt.Errorf("error while parsing path of %q: %w", id, ErrInvalidTrashItem)
+	}
+
+	return time.Unix(0, nano), name, nil
+}
+
+func (fs *TrashFS) userTrashDir() string {
+	return path.Join(TrashDir, fs.username)
+}
+
+// trash moves name to the trash of the user.
+func (fs *TrashFS) trash(op, name string) error {
+	if name == "." {
+		return &hpfs.PathError{Op: op, Path: name, Err: hpfs.ErrPermission}
+	}
+
+	dir := fs.userTrashDir()
+	if err := hpfs.MkdirAll(fs.fs, dir, DirPerm); err != nil {
+		return fmt.Errorf("error while creating trash directory: %w", err)
+	}
+
+	return hpfs.Rename(fs.fs, name, path.Join(dir, itemID(time.Now(), name)))
+}
+
+// version moves the file at name to its previous versions, if it is a non
+// empty file, and prunes the oldest ones. It returns whether a version was
+// created.
+func (fs *TrashFS) version(name string) (bool, error) {
+	versioned, err := fs.keepVersion(name)
+	if err != nil || !versioned {
+		return versioned, err
+	}
+
+	return true, fs.pruneVersions(name)
+}
+
+// keepVersion moves the file at name to its previous versions, if it is a non
+// empty file, without pruning them. It returns whether a version was created.
+func (fs *TrashFS) keepVersion(name string) (bool, error) {
+	if fs.config.MaxVersions <= 0 {
+		return false, nil
+	}
+
+	info, err := hpfs.Stat(fs.fs, name)
+	if errors.Is(err, hpfs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return false, nil
+	}
+
+	dir := path.Join(VersionsDir, name)
+	if err := hpfs.MkdirAll(fs.fs, dir, DirPerm); err != nil {
+		return false, fmt.Errorf("error while creating versions directory: %w", err)
+	}
+
+	id := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := hpfs.Rename(fs.fs, name, path.Join(dir, id)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// pruneVersions removes the oldest versions of name beyond the maximum.
+func (fs *TrashFS) pruneVersions(name string) error {
+	versions, err := fs.Versions(name)
+	if err != nil {
+		return err
+	}
+
+	// Versions are sorted from the newest to the oldest
+	for i := fs.config.MaxVersions; i < len(versions); i++ {
+		if err := hpfs.Remove(fs.fs, path.Join(VersionsDir, name, versions[i].ID)); err != nil {
+			return fmt.Errorf("error while removing version %q of %q: %w", versions[i].ID, name, err)
+		}
+	}
+
+	return nil
+}
+
+// Open implements hackpadfs.FS.
+func (fs *TrashFS) Open(name string) (hpfs.File, error) {
+	if reserved(name) {
+		return nil, reservedError("open", name)
+	}
+
+	file, err := fs.fs.Open(name)
+	if err != nil || name != "." {
+		return file, err
+	}
+
+	return rootFile{file}, nil
+}
+
+// OpenFile implements hackpadfs.OpenFileFS. Files opened for truncation are
+// first moved to their previous versions.
+func (fs *TrashFS) OpenFile(name string, flag int, perm hpfs.FileMode) (hpfs.File, error) {
+	if reserved(name) {
+		return nil, reservedError("open", name)
+	}
+
+	if flag&os.O_TRUNC != 0 && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		versioned, err := fs.version(name)
+		if err != nil {
+			return nil, &hpfs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		// The file has been moved away, so it must be created anew
+		if versioned {
+			flag |= os.O_CREATE
+		}
+	}
+
+	file, err := hpfs.OpenFile(fs.fs, name, flag, perm)
+	if err != nil || name != "." {
+		return file, err
+	}
+
+	return rootFile{file}, nil
+}
+
+// Stat implements hackpadfs.StatFS.
+func (fs *TrashFS) Stat(name string) (hpfs.FileInfo, error) {
+	if reserved(name) {
+		return nil, reservedError("stat", name)
+	}
+
+	return hpfs.Stat(fs.fs, name)
+}
+
+// ReadDir implements hackpadfs.ReadDirFS, hiding the reserved directories.
+func (fs *TrashFS) ReadDir(name string) ([]hpfs.DirEntry, error) {
+	if reserved(name) {
+		return nil, reservedError("readdir", name)
+	}
+
+	entries, err := hpfs.ReadDir(fs.fs, name)
+	if err != nil || name != "." {
+		return entries, err
+	}
+
+	return withoutReserved(entries), nil
+}
+
+func withoutReserved(entries []hpfs.DirEntry) []hpfs.DirEntry {
+	return slices.DeleteFunc(entries, func(entry hpfs.DirEntry) bool {
+		return reserved(entry.Name())
+	})
+}
+
+// rootFile hides the reserved directories when listing the root directory
+// through its file, as done by WebDAV.
+type rootFile struct {
+	hpfs.File
+}
+
+// ReadDir implements hackpadfs.ReadDirFile.
+func (f rootFile) ReadDir(n int) ([]hpfs.DirEntry, error) {
+	for {
+		entries, err := hpfs.ReadDirFile(f.File, n)
+		entries = withoutReserved(entries)
+
+		// Keep reading when a whole batch of entries was hidden, as empty
+		// batches mark the end of the directory.
+		if len(entries) > 0 || err != nil || n <= 0 {
+			return entries, err
+		}
+	}
+}
+
+// Mkdir implements hackpadfs.MkdirFS.
+func (fs *TrashFS) Mkdir(name string, perm hpfs.FileMode) error {
+	if reserved(name) {
+		return reservedError("mkdir", name)
+	}
+
+	return hpfs.Mkdir(fs.fs, name, perm)
+}
+
+// MkdirAll implements hackpadfs.MkdirAllFS.
+func (fs *TrashFS) MkdirAll(name string, perm hpfs.FileMode) error {
+	if reserved(name) {
+		return reservedError("mkdirall", name)
+	}
+
+	return hpfs.MkdirAll(fs.fs, name, perm)
+}
+
+// Remove implements hackpadfs.RemoveFS, moving name to the trash.
+func (fs *TrashFS) Remove(name string) error {
+	if reserved(name) {
+		return reservedError("remove", name)
+	}
+
+	info, err := hpfs.Stat(fs.fs, name)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		entries, err := hpfs.ReadDir(fs.fs, name)
+		if err != nil {
+			return err
+		}
+
+		if len(entries) > 0 {
+			return &hpfs.PathError{Op: "remove", Path: name, Err: hpfs.ErrNotEmpty}
+		}
+	}
+
+	return fs.trash("remove", name)
+}
+
+// RemoveAll implements hackpadfs.RemoveAllFS, moving name to the trash.
+func (fs *TrashFS) RemoveAll(name string) error {
+	if reserved(name) {
+		return reservedError("removeall", name)
+	}
+
+	if _, err := hpfs.Stat(fs.fs, name); errors.Is(err, hpfs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return fs.trash("removeall", name)
+}
+
+// Rename implements hackpadfs.RenameFS. Files replaced by the rename are
+// first moved to their previous versions.
+func (fs *TrashFS) Rename(oldname, newname string) error {
+	if reserved(oldname) || reserved(newname) {
+		return &hpfs.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrReservedPath}
+	}
+
+	if oldname != newname {
+		if _, err := fs.version(newname); err != nil {
+			return &hpfs.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+		}
+	}
+
+	return hpfs.Rename(fs.fs, oldname, newname)
+}
+
+// Chmod implements hackpadfs.ChmodFS.
+func (fs *TrashFS) Chmod(name string, mode hpfs.FileMode) error {
+	if reserved(name) {
+		return reservedError("chmod", name)
+	}
+
+	return hpfs.Chmod(fs.fs, name, mode)
+}
+
+// Chtimes implements hackpadfs.ChtimesFS.
+func (fs *TrashFS) Chtimes(name string, atime, mtime time.Time) error {
+	if reserved(name) {
+		return reservedError("chtimes", name)
+	}
+
+	return hpfs.Chtimes(fs.fs, name, atime, mtime)
+}
+
+// Trash lists the items in the trash of the user, from the most recently
+// deleted one.
+func (fs *TrashFS) Trash() ([]TrashItem, error) {
+	entries, err := hpfs.ReadDir(fs.fs, fs.userTrashDir())
+	if errors.Is(err, hpfs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error while reading trash directory: %w", err)
+	}
+
+	var items []TrashItem
+
+	for _, entry := range entries {
+		deletedAt, name, err := parseItemID(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error while reading trash item %q: %w", entry.Name(), err)
+		}
+
+		items = append(items, TrashItem{
+			ID:        entry.Name(),
+			Path:      name,
+			DeletedAt: deletedAt,
+			Dir:       entry.IsDir(),
+			Size:      info.Size(),
+		})
+	}
+
+	slices.SortFunc(items, func(a, b TrashItem) int {
+		return b.DeletedAt.Compare(a.DeletedAt)
+	})
+
+	return items, nil
+}
+
+// Restore moves an item out of the trash, back to its original path.
+func (fs *TrashFS) Restore(id string) error {
+	_, name, err := parseItemID(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := hpfs.Stat(fs.fs, name); err == nil {
+		return fmt.Errorf("error while restoring %q: %w", name, ErrRestoreDestination)
+	} else if !errors.Is(err, hpfs.ErrNotExist) {
+		return fmt.Errorf("error while restoring %q: %w", name, err)
+	}
+
+	if err := hpfs.MkdirAll(fs.fs, path.Dir(name), DirPerm); err != nil {
+		return fmt.Errorf("error while restoring %q: %w", name, err)
+	}
+
+	if err := hpfs.Rename(fs.fs, path.Join(fs.userTrashDir(), id), name); err != nil {
+		return fmt.Errorf("error while restoring %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// Delete removes an item from the trash for good.
+func (fs *TrashFS) Delete(id string) error {
+	if _, _, err := parseItemID(id); err != nil {
+		return err
+	}
+
+	if err := hpfs.RemoveAll(fs.fs, path.Join(fs.userTrashDir(), id)); err != nil {
+		return fmt.Errorf("error while deleting trash item %q: %w", id, err)
+	}
+
+	return nil
+}
+
+// Versions lists the previous versions of the file at name, from the newest
+// to the oldest.
+func (fs *TrashFS) Versions(name string) ([]Version, error) {
+	if !validPath(name) {
+		return nil, nil
+	}
+
+	entries, err := hpfs.ReadDir(fs.fs, path.Join(VersionsDir, name))
+	if errors.Is(err, hpfs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error while reading versions of %q: %w", name, err)
+	}
+
+	var versions []Version
+
+	for _, entry := range entries {
+		// Versions of files below name, if it used to be a directory, are
+		// kept in directories.
+		nano, err := strconv.ParseInt(entry.Name(), 10, 64)
+		if err != nil || entry.IsDir() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error while reading version %q of %q: %w", entry.Name(), name, err)
+		}
+
+		versions = append(versions, Version{
+			ID:         entry.Name(),
+			Path:       name,
+			ReplacedAt: time.Unix(0, nano),
+			Size:       info.Size(),
+		})
+	}
+
+	slices.SortFunc(versions, func(a, b Version) int {
+		return b.ReplacedAt.Compare(a.ReplacedAt)
+	})
+
+	return versions, nil
+}
+
+// RestoreVersion replaces the file at name with one of its previous
+// versions. The replaced file becomes a previous version itself.
+func (fs *TrashFS) RestoreVersion(name, id string) error {
+	if !validVersion(name, id) {
+		return fmt.Errorf("error while restoring version %q of %q: %w", id, name, ErrInvalidVersion)
+	}
+
+	src := path.Join(VersionsDir, name, id)
+	if _, err := hpfs.Stat(fs.fs, src); err != nil {
+		return fmt.Errorf("error while restoring version %q of %q: %w", id, name, err)
+	}
+
+	// The versions are pruned only once the restored one has been moved out
+	// of them, as it may be the oldest one.
+	if _, err := fs.keepVersion(name); err != nil {
+		return fmt.Errorf("error while keeping current version of %q: %w", name, err)
+	}
+
+	if err := hpfs.MkdirAll(fs.fs, path.Dir(name), DirPerm); err != nil {
+		return fmt.Errorf("error while restoring version %q of %q: %w", id, name, err)
+	}
+
+	if err := hpfs.Rename(fs.fs, src, name); err != nil {
+		return fmt.Errorf("error while restoring version %q of %q: %w", id, name, err)
+	}
+
+	return fs.pruneVersions(name)
+}
+
+// OpenVersion opens a previous version of the file at name for reading.
+func (fs *TrashFS) OpenVersion(name, id string) (hpfs.File, error) {
+	if !validVersion(name, id) {
+		return nil, fmt.Errorf("error while opening version %q of %q: %w", id, name, ErrInvalidVersion)
+	}
+
+	return fs.fs.Open(path.Join(VersionsDir, name, id))
+}
+
+// Purge removes the trash items and previous versions, of all users, which
+// are past their retention at the given time.
+func (fs *TrashFS) Purge(now time.Time) error {
+	cutoff := now.Add(-fs.config.Retention)
+
+	var expired []string
+
+	err := hpfs.WalkDir(fs.fs, TrashDir, func(name string, entry hpfs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Trash items are found at .trash/<username>/<id>
+		if strings.Count(name, "/") != 2 {
+			return nil
+		}
+
+		if deletedAt, _, err := parseItemID(entry.Name()); err == nil && deletedAt.Before(cutoff) {
+			expired = append(expired, name)
+		}
+
+		if entry.IsDir() {
+			return hpfs.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, hpfs.ErrNotExist) {
+		return fmt.Errorf("error while walking trash directory: %w", err)
+	}
+
+	err = hpfs.WalkDir(fs.fs, VersionsDir, func(name string, entry hpfs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		nano, perr := strconv.ParseInt(entry.Name(), 10, 64)
+		if perr == nil && !entry.IsDir() && time.Unix(0, nano).Before(cutoff) {
+			expired = append(expired, name)
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, hpfs.ErrNotExist) {
+		return fmt.Errorf("error while walking versions directory: %w", err)
+	}
+
+	for _, name := range expired {
+		if err := hpfs.RemoveAll(fs.fs, name); err != nil {
+			return fmt.Errorf("error while purging %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+var (
+	_ hpfs.OpenFileFS  = &TrashFS{}
+	_ hpfs.StatFS      = &TrashFS{}
+	_ hpfs.ReadDirFS   = &TrashFS{}
+	_ hpfs.MkdirFS     = &TrashFS{}
+	_ hpfs.MkdirAllFS  = &TrashFS{}
+	_ hpfs.RemoveFS    = &TrashFS{}
+	_ hpfs.RemoveAllFS = &TrashFS{}
+	_ hpfs.RenameFS    = &TrashFS{}
+	_ hpfs.ChmodFS     = &TrashFS{}
+	_ hpfs.ChtimesFS   = &TrashFS{}
+)
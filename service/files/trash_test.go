@@ -0,0 +1,247 @@
+package files
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	hpfs "github.com/hack-pad/hackpadfs"
+	hpfsmem "github.com/hack-pad/hackpadfs/mem"
+)
+
+var testTrashConfig = TrashConfig{
+	Enabled:     true,
+	Retention:   time.Hour,
+	MaxVersions: 2,
+}
+
+func newTestTrashFS(t *testing.T, username string) (*TrashFS, hpfs.FS) {
+	t.Helper()
+
+	fs, err := hpfsmem.NewFS()
+	if err != nil {
+		t.Fatalf("error creating filesystem: %s", err)
+	}
+
+	return NewTrashFS(fs, username, testTrashConfig), fs
+}
+
+func writeFile(t *testing.T, fs hpfs.FS, name, data string) {
+	t.Helper()
+
+	if err := hpfs.MkdirAll(fs, path.Dir(name), DirPerm); err != nil {
+		t.Fatalf("error creating parent of %q: %s", name, err)
+	}
+
+	if err := hpfs.WriteFullFile(fs, name, []byte(data), FilePerm); err != nil {
+		t.Fatalf("error writing %q: %s", name, err)
+	}
+}
+
+func readFile(t *testing.T, fs hpfs.FS, name string) string {
+	t.Helper()
+
+	data, err := hpfs.ReadFile(fs, name)
+	if err != nil {
+		t.Fatalf("error reading %q: %s", name, err)
+	}
+
+	return string(data)
+}
+
+func TestTrashFSReserved(t *testing.T) {
+	trash, fs := newTestTrashFS(t, "alice")
+	writeFile(t, fs, "file", "data")
+	writeFile(t, fs, path.Join(TrashDir, "alice", "item"), "trashed")
+
+	if _, err := trash.Stat(TrashDir); !errors.Is(err, ErrReservedPath) {
+		t.Errorf("expected the trash to be reserved, got %v", err)
+	}
+
+	if _, err := trash.Open(path.Join(VersionsDir, "file", "1")); !errors.Is(err, ErrReservedPath) {
+		t.Errorf("expected the versions to be reserved, got %v", err)
+	}
+
+	if err := trash.Rename("file", path.Join(TrashDir, "file")); !errors.Is(err, ErrReservedPath) {
+		t.Errorf("expected renames into the trash to be rejected, got %v", err)
+	}
+
+	entries, err := trash.ReadDir(".")
+	if err != nil {
+		t.Fatalf("error reading root directory: %s", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	if !slices.Equal(names, []string{"file"}) {
+		t.Errorf("expected the reserved directories to be hidden, got %v", names)
+	}
+}
+
+func TestTrashFSRemoveRestore(t *testing.T) {
+	trash, fs := newTestTrashFS(t, "alice")
+	writeFile(t, fs, "dir/file", "data")
+
+	if err := trash.Remove("dir"); !errors.Is(err, hpfs.ErrNotEmpty) {
+		t.Errorf("expected non-empty directories not to be removed, got %v", err)
+	}
+
+	if err := trash.Remove("dir/file"); err != nil {
+		t.Fatalf("error removing file: %s", err)
+	}
+
+	if _, err := trash.Stat("dir/file"); !errors.Is(err, hpfs.ErrNotExist) {
+		t.Errorf("expected removed file to be gone, got %v", err)
+	}
+
+	items, err := trash.Trash()
+	if err != nil {
+		t.Fatalf("error listing trash: %s", err)
+	}
+
+	if len(items) != 1 || items[0].Path != "dir/file" || items[0].Size != 4 {
+		t.Fatalf("expected the removed file in the trash, got %+v", items)
+	}
+
+	// The trash of other users is separate
+	other := NewTrashFS(fs, "bob", testTrashConfig)
+	if items, err := other.Trash(); err != nil || len(items) != 0 {
+		t.Errorf("expected the trash of other users to be empty, got %+v (%v)", items, err)
+	}
+
+	writeFile(t, fs, "dir/file", "new")
+
+	if err := trash.Restore(items[0].ID); !errors.Is(err, ErrRestoreDestination) {
+		t.Errorf("expected restores not to overwrite files, got %v", err)
+	}
+
+	if err := trash.Remove("dir/file"); err != nil {
+		t.Fatalf("error removing file: %s", err)
+	}
+
+	if err := trash.Restore(items[0].ID); err != nil {
+		t.Fatalf("error restoring file: %s", err)
+	}
+
+	if data := readFile(t, trash, "dir/file"); data != "data" {
+		t.Errorf("expected the restored file to hold its contents, got %q", data)
+	}
+
+	if err := trash.Restore("invalid"); !errors.Is(err, ErrInvalidTrashItem) {
+		t.Errorf("expected invalid items to be rejected, got %v", err)
+	}
+}
+
+func TestTrashFSVersions(t *testing.T) {
+	trash, _ := newTestTrashFS(t, "alice")
+
+	for _, data := range []string{"v1", "v2", "v3", "v4"} {
+		file, err := trash.OpenFile("file", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, FilePerm)
+		if err != nil {
+			t.Fatalf("error opening file: %s", err)
+		}
+
+		if _, err := hpfs.WriteFile(file, []byte(data)); err != nil {
+			t.Fatalf("error writing file: %s", err)
+		}
+
+		if err := file.Close(); err != nil {
+			t.Fatalf("error closing file: %s", err)
+		}
+
+		// Versions are named after the time they were replaced at
+		time.Sleep(time.Millisecond)
+	}
+
+	versions, err := trash.Versions("file")
+	if err != nil {
+		t.Fatalf("error listing versions: %s", err)
+	}
+
+	if len(versions) != testTrashConfig.MaxVersions {
+		t.Fatalf("expected %d versions, got %+v", testTrashConfig.MaxVersions, versions)
+	}
+
+	if err := trash.RestoreVersion("file", versions[1].ID); err != nil {
+		t.Fatalf("error restoring version: %s", err)
+	}
+
+	if data := readFile(t, trash, "file"); data != "v2" {
+		t.Errorf("expected the restored version to be current, got %q", data)
+	}
+
+	// The replaced file is kept as a version itself
+	versions, err = trash.Versions("file")
+	if err != nil {
+		t.Fatalf("error listing versions: %s", err)
+	}
+
+	file, err := trash.OpenVersion("file", versions[0].ID)
+	if err != nil {
+		t.Fatalf("error opening version: %s", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil || string(data) != "v4" {
+		t.Errorf("expected the replaced file to be the newest version, got %q (%v)", data, err)
+	}
+}
+
+// versionID returns the ID of a version replaced at the given time.
+func versionID(replacedAt time.Time) string {
+	return strconv.FormatInt(replacedAt.UnixNano(), 10)
+}
+
+func TestTrashFSPurge(t *testing.T) {
+	trash, fs := newTestTrashFS(t, "alice")
+
+	now := time.Now()
+	old := now.Add(-2 * testTrashConfig.Retention)
+	recent := now.Add(-testTrashConfig.Retention / 2)
+
+	expired := []string{
+		path.Join(TrashDir, "alice", itemID(old, "a")),
+		path.Join(TrashDir, "bob", itemID(old, "b")),
+		path.Join(VersionsDir, "file", versionID(old)),
+	}
+	kept := []string{
+		path.Join(TrashDir, "alice", itemID(recent, "c")),
+		path.Join(TrashDir, "alice", "unrelated"),
+		path.Join(VersionsDir, "file", versionID(recent)),
+	}
+
+	for _, name := range slices.Concat(expired, kept) {
+		writeFile(t, fs, name, "data")
+	}
+
+	// Trashed directories are purged as a whole
+	dir := path.Join(TrashDir, "alice", itemID(old, "dir"))
+	writeFile(t, fs, path.Join(dir, "file"), "data")
+
+	expired = append(expired, dir)
+
+	if err := trash.Purge(now); err != nil {
+		t.Fatalf("error purging trash: %s", err)
+	}
+
+	for _, name := range expired {
+		if _, err := hpfs.Stat(fs, name); !errors.Is(err, hpfs.ErrNotExist) {
+			t.Errorf("expected %q to be purged, got %v", name, err)
+		}
+	}
+
+	for _, name := range kept {
+		if _, err := hpfs.Stat(fs, name); err != nil {
+			t.Errorf("expected %q to be kept, got %v", name, err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/teapotovh/teapot/lib/httplog"
 	"github.com/teapotovh/teapot/lib/webauth"
 	"github.com/teapotovh/teapot/lib/webhandler"
+	"github.com/teapotovh/teapot/service/files/share"
 )
 
 func WebFlagSet() (*flag.FlagSet, func() WebConfig) {
@@ -20,11 +21,15 @@ func WebFlagSet() (*flag.FlagSet, func() WebConfig) {
 	webAuthFS, getWebAuthConfig := webauth.WebAuthFlagSet("files/web")
 	fs.AddFlagSet(webAuthFS)
 
+	sharesFS, getSharesConfig := share.StoreFlagSet()
+	fs.AddFlagSet(sharesFS)
+
 	return fs, func() WebConfig {
 		return WebConfig{
 			HTTPLog:    getHTTPLogConfig(),
 			WebHandler: getWebHandlerConfig(),
 			WebAuth:    getWebAuthConfig(),
+			Shares:     getSharesConfig(),
 		}
 	}
 }
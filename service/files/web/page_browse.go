@@ -159,6 +159,10 @@ var browseStyle = ui.MustParseStyle(`
 	& .mode {
 	  display: none;
 	}
+	& .versions {
+	  margin-left: var(--size-2);
+	  font-size: var(--font-size-0);
+	}
 	@media (min-width: 768px) {
 		grid-template-columns: 2fr 20fr 5fr;
 		& .mode {
@@ -208,6 +212,7 @@ func (b browse) Render(ctx ui.Context) g.Node {
 			h.Div(h.Class("buttons"),
 				components.DialogButton(ctx, PathBrowseDialogOf(BrowseDialogNewFolder), g.Text("New Folder")),
 				components.DialogButton(ctx, PathBrowseDialogOf(BrowseDialogUpload), g.Text("Upload")),
+				components.DialogButton(ctx, PathBrowseDialogOf(BrowseDialogShare), g.Text("Share")),
 			),
 		),
 
@@ -222,7 +227,15 @@ func (b browse) Render(ctx ui.Context) g.Node {
 
 				return g.Group{
 					h.Div(h.Class("mode"), g.Text(entry.mode.String())),
-					h.Div(h.A(target, h.Href(href), g.Text(entry.name))),
+					h.Div(
+						h.A(target, h.Href(href), g.Text(entry.name)),
+						g.If(entry.mode != os.ModeDir, h.A(
+							h.Class("versions"),
+							hx.Boost("true"),
+							h.Href(PathVersionsAt(entry.path)),
+							g.Text("versions"),
+						)),
+					),
 					h.Div(h.Class("size"), g.Text(humanize.IBytes(entry.size))),
 				}
 			}),
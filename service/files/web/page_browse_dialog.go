@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/hack-pad/hackpadfs"
 	g "maragu.dev/gomponents"
@@ -19,14 +21,20 @@ import (
 	"github.com/teapotovh/teapot/lib/webauth"
 	"github.com/teapotovh/teapot/lib/webhandler"
 	"github.com/teapotovh/teapot/service/files"
+	"github.com/teapotovh/teapot/service/files/share"
 )
 
 const (
 	BrowseDialogNewFolder = "newfolder"
 	BrowseDialogUpload    = "upload"
+	BrowseDialogShare     = "share"
 )
 
-var ErrInvalidBrowseDialog = errors.New("invalid browse dialog")
+var (
+	ErrInvalidBrowseDialog = errors.New("invalid browse dialog")
+	ErrInvalidShareOption  = errors.New("invalid share option")
+	ErrUploadShareNotDir   = errors.New("only folders can be shared in upload mode")
+)
 
 func (web *Web) BrowseDialog(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
 	dialog := r.PathValue("dialog")
@@ -37,6 +45,9 @@ func (web *Web) BrowseDialog(w http.ResponseWriter, r *http.Request) (ui.Compone
 	case BrowseDialogUpload:
 		return web.BrowseDialogUpload(w, r)
 
+	case BrowseDialogShare:
+		return web.BrowseDialogShare(w, r)
+
 	default:
 		return nil, fmt.Errorf("could not serve requested dialog %q: %w", dialog, ErrInvalidBrowseDialog)
 	}
@@ -164,11 +175,133 @@ func (web *Web) BrowseDialogUpload(w http.ResponseWriter, r *http.Request) (ui.C
 	return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
 }
 
+func (web *Web) BrowseDialogShare(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
+	auth := webauth.GetAuth(r)
+	if auth == nil {
+		return nil, webhandler.NewRedirectError(PathIndex, http.StatusFound)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		path, err := getDialogBasePath(r, PathBrowse)
+		if err != nil {
+			return nil, err
+		}
+
+		return shareDialog{
+			url:  r.URL.Path,
+			path: path,
+		}, nil
+
+	case http.MethodPost:
+		base := r.FormValue(dialogBaseID)
+		if base == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: fmt.Errorf("invalid empty path: %w", webhandler.ErrBadRequest)}, nil
+		}
+
+		path := filepath.Clean(filepath.Join(base, r.FormValue(shareDialogPathID)))
+
+		sh, err := parseShare(r, auth.Username, path, time.Now())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: err}, nil
+		}
+
+		session, err := web.files.Sesssions().Get(auth.Username)
+		if err != nil {
+			return nil, webhandler.NewInternalError(err, nil)
+		}
+
+		stat, err := hackpadfs.Stat(session.FS(), path)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: fmt.Errorf("error while accessing %q: %w", path, err)}, nil
+		}
+
+		if sh.Mode == share.ModeUpload && !stat.IsDir() {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: fmt.Errorf("could not share %q: %w", path, ErrUploadShareNotDir)}, nil
+		}
+
+		if err := web.shares.Create(r.Context(), *sh); err != nil {
+			return nil, webhandler.NewInternalError(err, nil)
+		}
+
+		return shareCreated{url: publicURL(r, PathPublicAt(sh.Token)+sep)}, nil
+	}
+
+	return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+}
+
+// parseShare builds a new share of path from the options in the share dialog form.
+func parseShare(r *http.Request, owner, path string, now time.Time) (*share.Share, error) {
+	expiresIn, err := parseShareCount(r.FormValue(shareDialogExpiresID))
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing expiry: %w", err)
+	}
+
+	maxDownloads, err := parseShareCount(r.FormValue(shareDialogDownloadsID))
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing download limit: %w", err)
+	}
+
+	token, err := share.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sh := share.Share{
+		Token:        token,
+		Owner:        owner,
+		Path:         path,
+		Mode:         share.ModeDownload,
+		MaxDownloads: maxDownloads,
+		CreatedAt:    now,
+	}
+
+	if r.FormValue(shareDialogUploadID) != "" {
+		sh.Mode = share.ModeUpload
+	}
+
+	if expiresIn > 0 {
+		sh.ExpiresAt = now.AddDate(0, 0, expiresIn)
+	}
+
+	if password := r.FormValue(shareDialogPasswordID); password != "" {
+		if sh.PasswordHash, err = share.HashPassword(password); err != nil {
+			return nil, err
+		}
+	}
+
+	return &sh, nil
+}
+
+// parseShareCount parses an optional, non-negative number from the share
+// dialog form. Empty values are parsed as zero.
+func parseShareCount(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+
+	count, err := strconv.Atoi(raw)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("expected a non-negative number, got %q: %w", raw, ErrInvalidShareOption)
+	}
+
+	return count, nil
+}
+
 const (
 	newFolderDialogErrorContainerID = "newfolder-error"
 	dialogBaseID                    = "base"
 	newFolderDialogPathID           = "path"
 	uploadDialogFileID              = "file"
+	shareDialogPathID               = "path"
+	shareDialogPasswordID           = "password"
+	shareDialogExpiresID            = "expires"
+	shareDialogDownloadsID          = "downloads"
+	shareDialogUploadID             = "upload"
 )
 
 var dialogStyle = ui.MustParseStyle(`
@@ -325,6 +458,87 @@ func (ud uploadDialog) Render(ctx ui.Context) g.Node {
 	)
 }
 
+type shareDialog struct {
+	url  string
+	path string
+}
+
+var shareFormStyle = ui.MustParseStyle(`
+	display: flex;
+	flex-direction: column;
+	justify-content: center;
+
+	& .hidden {
+	  display: none;
+	}
+
+	& .buttons {
+	  width: 100%;
+	  display: flex;
+		flex-direction: row;
+	  justify-content: center;
+	}
+
+	& .input {
+    margin: var(--size-3) 0;
+	}
+
+	& .error {
+		margin: var(--size-3) 0;
+	}
+`)
+
+func (sd shareDialog) Render(ctx ui.Context) g.Node {
+	return h.Div(ctx.Class(dialogStyle),
+		h.H3(g.Text("Share")),
+		h.P(g.Text("Create a public link to the current folder, or to a file or folder within it.")),
+		h.Br(),
+		h.P(
+			g.Text("Anyone with the link can access the shared files, without logging in. "),
+			g.Text("All options are optional: leave the path empty to share the current folder. "),
+			g.Text("In upload mode, visitors can only upload files into the shared folder, "),
+			g.Text("without seeing its contents."),
+		),
+
+		h.Form(
+			ctx.Class(shareFormStyle),
+			hx.Ext("response-targets"),
+			hx.Post(sd.url),
+			hx.Swap("innerHTML"),
+			g.Attr("hx-target-error", "#"+newFolderDialogErrorContainerID),
+
+			h.Input(h.Class("hidden"),
+				h.Type("text"),
+				h.Name(dialogBaseID),
+				h.ID(dialogBaseID),
+				h.Value(sd.path),
+			),
+			components.Input(ctx, shareDialogPathID, "text", "Path", h.Class("input")),
+			components.Input(ctx, shareDialogPasswordID, "password", "Password", h.Class("input")),
+			components.Input(ctx, shareDialogExpiresID, "number", "Expires in (days)", h.Class("input")),
+			components.Input(ctx, shareDialogDownloadsID, "number", "Download limit", h.Class("input")),
+			components.Input(ctx, shareDialogUploadID, "checkbox", "Upload only (file drop)", h.Class("input")),
+
+			h.Div(h.Class("buttons"),
+				components.Button(ctx, h.Type("submit"), g.Text("Share")),
+			),
+
+			h.Div(h.ID(newFolderDialogErrorContainerID), h.Class("error")),
+		),
+	)
+}
+
+type shareCreated struct {
+	url string
+}
+
+func (sc shareCreated) Render(ctx ui.Context) g.Node {
+	return components.SuccessNotification(ctx, g.Group{
+		g.Text("Share created, at "),
+		h.A(h.Href(sc.url), h.Target("_blank"), g.Text(sc.url)),
+	})
+}
+
 type dialogError struct {
 	err error
 }
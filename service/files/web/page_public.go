@@ -0,0 +1,593 @@
+package web
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/hack-pad/hackpadfs"
+	g "maragu.dev/gomponents"
+	hx "maragu.dev/gomponents-htmx"
+	h "maragu.dev/gomponents/html"
+
+	"github.com/teapotovh/teapot/lib/pagetitle"
+	"github.com/teapotovh/teapot/lib/ui"
+	"github.com/teapotovh/teapot/lib/ui/components"
+	"github.com/teapotovh/teapot/lib/webhandler"
+	"github.com/teapotovh/teapot/service/files"
+	"github.com/teapotovh/teapot/service/files/share"
+)
+
+const (
+	// maxUploadSuffix is how many numeric suffixes are tried to find a free
+	// name for a file uploaded to a share.
+	maxUploadSuffix = 100
+	// maxUploadOverhead is the room left in the body of an upload for the
+	// multipart encoding, on top of the largest allowed file.
+	maxUploadOverhead = 1 << 20 // 1MiB
+)
+
+var (
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidFilename = errors.New("invalid file name")
+	ErrFilenameTaken   = errors.New("file name already taken")
+	ErrTooManyAttempts = errors.New("too many attempts, try again later")
+	ErrMissingUpload   = errors.New("missing file to upload")
+)
+
+// getShare fetches the share addressed by the request.
+func (web *Web) getShare(r *http.Request) (*share.Share, error) {
+	sh, err := web.shares.Get(r.Context(), r.PathValue("token"), time.Now())
+	if err != nil {
+		if errors.Is(err, share.ErrNotFound) {
+			return nil, webhandler.ErrNotFound
+		}
+
+		return nil, webhandler.NewInternalError(err, nil)
+	}
+
+	return sh, nil
+}
+
+// sharePath returns the path in the session FS of the owner of a share for a
+// path relative to the shared folder. The result never escapes the share.
+func sharePath(sh *share.Share, path string) (string, string) {
+	rel := filepath.Clean(sep + path)
+
+	return filepath.Join(sh.Path, rel), rel
+}
+
+func shareCookieName(sh *share.Share) string {
+	return "share-" + sh.Token
+}
+
+// shareCookieValue proves knowledge of the password of a share. It is keyed
+// with the password hash, so it is invalidated if the password changes.
+func shareCookieValue(sh *share.Share) string {
+	mac := hmac.New(sha256.New, sh.PasswordHash)
+	mac.Write([]byte(sh.Token))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// unlocked reports whether the request can access a share, either because
+// the share has no password or because it was previously unlocked.
+func unlocked(r *http.Request, sh *share.Share) bool {
+	if !sh.HasPassword() {
+		return true
+	}
+
+	cookie, err := r.Cookie(shareCookieName(sh))
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal([]byte(cookie.Value), []byte(shareCookieValue(sh)))
+}
+
+func (web *Web) Public(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
+	sh, err := web.getShare(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !unlocked(r, sh) {
+		return web.publicUnlock(w, r, sh)
+	}
+
+	switch sh.Mode {
+	case share.ModeDownload:
+		return web.publicBrowse(w, r, sh)
+
+	case share.ModeUpload:
+		return web.publicUpload(w, r, sh)
+	}
+
+	return nil, webhandler.ErrNotFound
+}
+
+func (web *Web) publicUnlock(w http.ResponseWriter, r *http.Request, sh *share.Share) (ui.Component, error) {
+	switch r.Method {
+	case http.MethodGet:
+		return webhandler.NewPage(
+			pagetitle.Title("Unlock share", App),
+			"Enter the password to access the shared files",
+			shareUnlock{url: r.URL.Path},
+		), nil
+
+	case http.MethodPost:
+		// Attempts are throttled before checking the password, as guessing is
+		// what the limit protects against, and checking is expensive
+		keys, now := unlockKeys(r, sh.Token), time.Now()
+		if !web.unlocks.Allowed(now, keys...) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return dialogError{err: ErrTooManyAttempts}, nil
+		}
+
+		if !sh.CheckPassword(r.FormValue(shareDialogPasswordID)) {
+			web.unlocks.Failed(now, keys...)
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return dialogError{err: ErrInvalidPassword}, nil
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     shareCookieName(sh),
+			Value:    shareCookieValue(sh),
+			Path:     PathPublic,
+			Secure:   web.secure,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+
+		return nil, webhandler.NewRedirectError(r.URL.Path, http.StatusFound)
+	}
+
+	return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+}
+
+func (web *Web) publicBrowse(w http.ResponseWriter, r *http.Request, sh *share.Share) (ui.Component, error) {
+	if r.Method != http.MethodGet {
+		return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+	}
+
+	path, rel := sharePath(sh, r.PathValue("path"))
+
+	session, err := web.files.Sesssions().Get(sh.Owner)
+	if err != nil {
+		return nil, webhandler.NewInternalError(err, nil)
+	}
+
+	stat, err := hackpadfs.Stat(session.FS(), path)
+	if err != nil {
+		// Paths below a shared file are reported as not being directories
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+			return nil, webhandler.ErrNotFound
+		}
+
+		return nil, webhandler.NewInternalError(fmt.Errorf("could not stat file at %q: %w", path, err), nil)
+	}
+
+	name := filepath.Base(sh.Path)
+	if sh.Path == here {
+		name = sh.Owner
+	}
+
+	if !stat.IsDir() {
+		if rel != sep {
+			return nil, webhandler.ErrNotFound
+		}
+
+		component := publicFile{
+			name:      name,
+			href:      PathPublicFileAt(sh.Token),
+			size:      uint64(stat.Size()), //nolint:gosec
+			exhausted: sh.Exhausted(),
+		}
+
+		return webhandler.NewPage(pagetitle.Title(name, App), "Download the shared file "+name, component), nil
+	}
+
+	entries, err := publicEntries(session.FS(), path, rel)
+	if err != nil {
+		return nil, webhandler.NewInternalError(err, nil)
+	}
+
+	segments := []entry{{name: name, path: sep, mode: os.ModeDir}}
+
+	var segmentPath string
+	for segment := range strings.SplitSeq(strings.TrimPrefix(rel, sep), sep) {
+		if segment == "" {
+			continue
+		}
+
+		segmentPath = filepath.Join(segmentPath, segment)
+		segments = append(segments, entry{name: segment, path: segmentPath, mode: os.ModeDir})
+	}
+
+	component := publicBrowse{
+		token:     sh.Token,
+		path:      rel,
+		segments:  segments,
+		entries:   entries,
+		exhausted: sh.Exhausted(),
+	}
+
+	return webhandler.NewPage(
+		pagetitle.Title("Shared files at "+rel, App),
+		"Browse the files shared with you at "+rel,
+		component,
+	), nil
+}
+
+// publicEntries lists the entries of the directory at path, with their paths
+// relative to the share.
+func publicEntries(fs hackpadfs.FS, path, rel string) ([]entry, error) {
+	dirEntries, err := hackpadfs.ReadDir(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read directory at %q: %w", path, err)
+	}
+
+	var entries []entry
+
+	for _, e := range dirEntries {
+		stat, err := hackpadfs.Stat(fs, filepath.Join(path, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("could not stat file at %q: %w", path, err)
+		}
+
+		entries = append(entries, entry{
+			name: e.Name(),
+			path: filepath.Join(rel, e.Name()),
+			mode: e.Type(),
+			size: uint64(stat.Size()), //nolint:gosec
+		})
+	}
+
+	return entries, nil
+}
+
+func (web *Web) publicUpload(w http.ResponseWriter, r *http.Request, sh *share.Share) (ui.Component, error) {
+	if _, rel := sharePath(sh, r.PathValue("path")); rel != sep {
+		return nil, webhandler.ErrNotFound
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		return webhandler.NewPage(
+			pagetitle.Title("Upload files", App),
+			"Upload files to "+sh.Owner,
+			publicUpload{url: r.URL.Path, owner: sh.Owner},
+		), nil
+
+	case http.MethodPost:
+		// The upload is streamed to the filesystem of the owner, and the body
+		// is capped so that visitors cannot upload files of any size
+		r.Body = http.MaxBytesReader(w, r.Body, files.MaxSize+maxUploadOverhead)
+
+		part, err := uploadPart(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: err}, nil
+		}
+		defer part.Close()
+
+		name := filepath.Base(part.FileName())
+		if name == here || name == ".." || name == sep {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: fmt.Errorf("could not upload %q: %w", part.FileName(), ErrInvalidFilename)}, nil
+		}
+
+		body := bufio.NewReader(part)
+		if _, err := body.Peek(1); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: fmt.Errorf("invalid empty file: %w", webhandler.ErrBadRequest)}, nil
+		}
+
+		session, err := web.files.Sesssions().Get(sh.Owner)
+		if err != nil {
+			return nil, webhandler.NewInternalError(err, nil)
+		}
+
+		path, err := freePath(session.FS(), sh.Path, name)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: err}, nil
+		}
+
+		if err := writeUpload(session.FS(), path, body); err != nil {
+			status := http.StatusBadRequest
+			if _, ok := errors.AsType[*http.MaxBytesError](err); ok {
+				status = http.StatusRequestEntityTooLarge
+			}
+
+			w.WriteHeader(status)
+
+			return dialogError{err: fmt.Errorf("error while writing file %q: %w", name, err)}, nil
+		}
+
+		return publicUploaded{url: r.URL.Path, name: filepath.Base(path)}, nil
+	}
+
+	return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+}
+
+// uploadPart returns the part of a multipart upload request holding the file,
+// without buffering the request body.
+func uploadPart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("could not read upload: %w", webhandler.ErrBadRequest)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, ErrMissingUpload
+		} else if err != nil {
+			return nil, fmt.Errorf("could not read upload: %w", err)
+		}
+
+		if part.FormName() == uploadDialogFileID {
+			return part, nil
+		}
+
+		if err := part.Close(); err != nil {
+			return nil, fmt.Errorf("could not read upload: %w", err)
+		}
+	}
+}
+
+// writeUpload streams an uploaded file to a new file at path. The file is
+// removed if the upload can not be written in full, e.g., because it exceeds
+// the size limit.
+func writeUpload(fs hackpadfs.FS, path string, r io.Reader) (err error) {
+	file, err := hackpadfs.OpenFile(fs, path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, files.FilePerm)
+	if err != nil {
+		return fmt.Errorf("could not create file at %q: %w", path, err)
+	}
+
+	defer func() {
+		if e := file.Close(); e != nil && err == nil {
+			err = fmt.Errorf("could not close file at %q: %w", path, e)
+		}
+
+		if err != nil {
+			_ = hackpadfs.Remove(fs, path)
+		}
+	}()
+
+	if _, err := io.Copy(fileWriter{file}, r); err != nil {
+		return fmt.Errorf("could not write file at %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// fileWriter adapts a hackpadfs.File to io.Writer.
+type fileWriter struct {
+	file hackpadfs.File
+}
+
+// Write implements io.Writer.
+func (fw fileWriter) Write(p []byte) (int, error) {
+	return hackpadfs.WriteFile(fw.file, p)
+}
+
+// freePath returns a path in dir for a new file called name, which does not
+// overwrite any existing file. Numeric suffixes are added to the name until
+// a free one is found, as visitors of upload shares cannot see which names
+// are taken.
+func freePath(fs hackpadfs.FS, dir, name string) (string, error) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	for i := range maxUploadSuffix {
+		candidate := name
+		if i > 0 {
+			candidate = fmt.Sprintf("%s (%d)%s", stem, i, ext)
+		}
+
+		path := filepath.Join(dir, candidate)
+
+		_, err := hackpadfs.Stat(fs, path)
+		if errors.Is(err, os.ErrNotExist) {
+			return path, nil
+		} else if err != nil {
+			return "", fmt.Errorf("could not stat file at %q: %w", path, err)
+		}
+	}
+
+	return "", fmt.Errorf("could not upload %q: %w", name, ErrFilenameTaken)
+}
+
+type shareUnlock struct {
+	url string
+}
+
+func (su shareUnlock) Render(ctx ui.Context) g.Node {
+	return h.Div(ctx.Class(publicStyle),
+		h.H2(g.Text("Password required")),
+		h.P(g.Text("The shared files are protected by a password.")),
+		h.Form(
+			hx.Ext("response-targets"),
+			hx.Post(su.url),
+			hx.Swap("innerHTML"),
+			g.Attr("hx-target-error", "#"+newFolderDialogErrorContainerID),
+
+			components.Input(ctx, shareDialogPasswordID, "password", "Password", h.Class("input")),
+			h.Div(h.Class("buttons"),
+				components.Button(ctx, h.Type("submit"), g.Text("Unlock")),
+			),
+
+			h.Div(h.ID(newFolderDialogErrorContainerID), h.Class("error")),
+		),
+	)
+}
+
+var publicStyle = ui.MustParseStyle(`
+	display: flex;
+	flex-direction: column;
+	justify-content: center;
+	padding: var(--size-3) var(--size-2);
+	font-size: var(--font-size-2);
+
+	& .input {
+		margin: var(--size-5) 0;
+	}
+
+	& .buttons {
+		display: flex;
+		flex-direction: row;
+		justify-content: flex-end;
+	}
+
+	& .error {
+		margin: var(--size-3) 0;
+	}
+`)
+
+// exhaustedWarning warns visitors that a share can no longer be downloaded from.
+func exhaustedWarning(ctx ui.Context, exhausted bool) g.Node {
+	return g.If(exhausted, components.WarningNotification(ctx,
+		g.Text("The download limit of this share has been reached, and files can no longer be downloaded."),
+	))
+}
+
+type publicFile struct {
+	name      string
+	href      string
+	size      uint64
+	exhausted bool
+}
+
+func (pf publicFile) Render(ctx ui.Context) g.Node {
+	return h.Div(ctx.Class(publicStyle),
+		exhaustedWarning(ctx, pf.exhausted),
+		h.H2(g.Text(pf.name)),
+		h.P(g.Textf("A file of %s has been shared with you.", humanize.IBytes(pf.size))),
+		g.If(!pf.exhausted, h.Div(h.Class("buttons"),
+			h.A(h.Href(pf.href), components.Button(ctx, g.Text("Download"))),
+		)),
+	)
+}
+
+type publicBrowse struct {
+	token     string
+	path      string
+	segments  []entry
+	entries   []entry
+	exhausted bool
+}
+
+func (pb publicBrowse) Render(ctx ui.Context) g.Node {
+	entries := pb.entries
+	if pb.path != sep {
+		entries = append([]entry{
+			{
+				name: "..",
+				path: filepath.Join(pb.path, ".."),
+				mode: os.ModeDir,
+			},
+		}, entries...)
+	}
+
+	href := func(entry entry) string {
+		if entry.mode == os.ModeDir {
+			return PathPublicAt(pb.token, entry.path) + sep
+		}
+
+		return PathPublicFileAt(pb.token, entry.path)
+	}
+
+	return g.Group{
+		exhaustedWarning(ctx, pb.exhausted),
+		h.Div(ctx.Class(browseTitleStyle),
+			h.Div(
+				g.Map(pb.segments, func(segment entry) g.Node {
+					return g.Group{
+						h.A(hx.Boost("true"), h.Href(href(segment)), g.Text(segment.name)),
+						h.Span(g.Text(sep)),
+					}
+				}),
+			),
+		),
+
+		h.Section(ctx.Class(browseStyle),
+			g.Map(entries, func(entry entry) g.Node {
+				target := hx.Boost("true")
+				if entry.mode != os.ModeDir {
+					target = h.Target("_blank")
+				}
+
+				return g.Group{
+					h.Div(h.Class("mode"), g.Text(entry.mode.String())),
+					h.Div(h.A(target, h.Href(href(entry)), g.Text(entry.name))),
+					h.Div(h.Class("size"), g.Text(humanize.IBytes(entry.size))),
+				}
+			}),
+		),
+	}
+}
+
+type publicUpload struct {
+	url   string
+	owner string
+}
+
+func (pu publicUpload) Render(ctx ui.Context) g.Node {
+	return h.Div(ctx.Class(publicStyle),
+		h.H2(g.Text("Upload files")),
+		h.P(g.Textf("Files you upload here are handed to %s. You cannot see the files uploaded by others.", pu.owner)),
+		h.Form(
+			ctx.Class(uploadFormStyle),
+			hx.Ext("response-targets"),
+			hx.Encoding("multipart/form-data"),
+			hx.Post(pu.url),
+			hx.Swap("innerHTML"),
+			g.Attr("hx-target-error", "#"+newFolderDialogErrorContainerID),
+
+			h.Div(h.Class("input"),
+				components.FileInput(
+					ctx,
+					uploadDialogFileID,
+					"Select a file",
+					g.Attr(
+						"onchange",
+						"document.querySelector('label[for="+uploadDialogFileID+"]').textContent = this.files[0]?.name || 'Select a file'",
+					),
+				),
+				components.Button(ctx, h.Type("submit"), g.Text("Upload")),
+			),
+
+			h.Div(h.ID(newFolderDialogErrorContainerID), h.Class("error")),
+		),
+	)
+}
+
+type publicUploaded struct {
+	url  string
+	name string
+}
+
+func (pu publicUploaded) Render(ctx ui.Context) g.Node {
+	return components.SuccessNotification(ctx, g.Group{
+		g.Textf("Uploaded %s. ", pu.name),
+		h.A(h.Href(pu.url), g.Text("Upload another file")),
+	})
+}
+
+// Ensure publicBrowse implements ui.Component.
+var _ ui.Component = publicBrowse{}
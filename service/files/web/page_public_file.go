@@ -0,0 +1,79 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/hack-pad/hackpadfs"
+
+	"github.com/teapotovh/teapot/lib/httphandler"
+	"github.com/teapotovh/teapot/service/files/share"
+)
+
+func (web *Web) PublicFile(w http.ResponseWriter, r *http.Request) error {
+	sh, err := web.getShare(r)
+	if err != nil {
+		return err
+	}
+
+	if sh.Mode != share.ModeDownload {
+		return httphandler.ErrNotFound
+	}
+
+	if !unlocked(r, sh) {
+		return httphandler.NewRedirectError(PathPublicAt(sh.Token)+sep, http.StatusFound)
+	}
+
+	path, _ := sharePath(sh, r.PathValue("path"))
+
+	session, err := web.files.Sesssions().Get(sh.Owner)
+	if err != nil {
+		return httphandler.NewInternalError(err, nil)
+	}
+
+	file, err := hackpadfs.OpenFile(session.FS(), path, os.O_RDONLY, 0)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+			return httphandler.ErrNotFound
+		}
+
+		return httphandler.NewInternalError(fmt.Errorf("could not read file at %q: %w", path, err), nil)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return httphandler.NewInternalError(fmt.Errorf("could not stat file at %q: %w", path, err), nil)
+	}
+
+	if stat.IsDir() {
+		return httphandler.ErrNotFound
+	}
+
+	if err := web.shares.Download(r.Context(), sh.Token, time.Now()); err != nil {
+		if errors.Is(err, share.ErrExhausted) {
+			return httphandler.Write(w, http.StatusGone, []byte(err.Error()))
+		} else if errors.Is(err, share.ErrNotFound) {
+			return httphandler.ErrNotFound
+		}
+
+		return httphandler.NewInternalError(err, nil)
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(path)})
+	w.Header().Set("Content-Disposition", disposition)
+
+	_, err = io.Copy(w, file)
+	if err != nil {
+		return fmt.Errorf("error while writing response file at %q: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hack-pad/hackpadfs"
+	hpfsmem "github.com/hack-pad/hackpadfs/mem"
+
+	"github.com/teapotovh/teapot/service/files/share"
+)
+
+func TestSharePath(t *testing.T) {
+	tests := []struct {
+		share, path string
+		full, rel   string
+	}{
+		{share: "docs", path: "", full: "docs", rel: "/"},
+		{share: "docs", path: "a/b.txt", full: "docs/a/b.txt", rel: "/a/b.txt"},
+		{share: "docs", path: "a/../b.txt", full: "docs/b.txt", rel: "/b.txt"},
+		{share: "docs", path: "..", full: "docs", rel: "/"},
+		{share: "docs", path: "../secret", full: "docs/secret", rel: "/secret"},
+		{share: "docs", path: "a/../../../secret", full: "docs/secret", rel: "/secret"},
+		{share: "docs", path: "/etc/passwd", full: "docs/etc/passwd", rel: "/etc/passwd"},
+		{share: "docs/sub", path: "../../other", full: "docs/sub/other", rel: "/other"},
+		{share: ".", path: "../secret", full: "secret", rel: "/secret"},
+		{share: ".", path: "", full: ".", rel: "/"},
+	}
+
+	for _, tt := range tests {
+		full, rel := sharePath(&share.Share{Path: tt.share}, tt.path)
+		if full != tt.full || rel != tt.rel {
+			t.Errorf(
+				"expected %q in share %q to be at %q (%q), got %q (%q)",
+				tt.path, tt.share, tt.full, tt.rel, full, rel,
+			)
+		}
+	}
+}
+
+// newUploadRequest returns a multipart request uploading a file with the given
+// field name.
+func newUploadRequest(t *testing.T, field, name, data string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("other", "value"); err != nil {
+		t.Fatalf("error writing field: %s", err)
+	}
+
+	fw, err := mw.CreateFormFile(field, name)
+	if err != nil {
+		t.Fatalf("error creating form file: %s", err)
+	}
+
+	if _, err := fw.Write([]byte(data)); err != nil {
+		t.Fatalf("error writing form file: %s", err)
+	}
+
+	if err := mw.Close(); err != nil {
+		t.Fatalf("error closing multipart writer: %s", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	return r
+}
+
+func TestUploadPart(t *testing.T) {
+	part, err := uploadPart(newUploadRequest(t, uploadDialogFileID, "a.txt", "hello"))
+	if err != nil {
+		t.Fatalf("error reading upload: %s", err)
+	}
+
+	if part.FileName() != "a.txt" {
+		t.Errorf("expected the file part to be returned, got %q", part.FileName())
+	}
+
+	if _, err := uploadPart(newUploadRequest(t, "unknown", "a.txt", "hello")); !errors.Is(err, ErrMissingUpload) {
+		t.Errorf("expected a missing upload error, got %v", err)
+	}
+}
+
+func TestWriteUpload(t *testing.T) {
+	fs, err := hpfsmem.NewFS()
+	if err != nil {
+		t.Fatalf("error creating filesystem: %s", err)
+	}
+
+	if err := writeUpload(fs, "file", strings.NewReader("hello")); err != nil {
+		t.Fatalf("error writing upload: %s", err)
+	}
+
+	data, err := hackpadfs.ReadFile(fs, "file")
+	if err != nil || string(data) != "hello" {
+		t.Errorf("expected the upload to be written, got %q (%v)", data, err)
+	}
+
+	// Uploads over the size limit are removed
+	body := http.MaxBytesReader(httptest.NewRecorder(), io.NopCloser(strings.NewReader("too large")), 3)
+
+	err = writeUpload(fs, "large", body)
+	if _, ok := errors.AsType[*http.MaxBytesError](err); !ok {
+		t.Errorf("expected a size limit error, got %v", err)
+	}
+
+	if _, err := hackpadfs.Stat(fs, "large"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected oversized uploads to be removed, got %v", err)
+	}
+}
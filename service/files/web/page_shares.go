@@ -0,0 +1,197 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	g "maragu.dev/gomponents"
+	hx "maragu.dev/gomponents-htmx"
+	h "maragu.dev/gomponents/html"
+
+	"github.com/teapotovh/teapot/lib/pagetitle"
+	"github.com/teapotovh/teapot/lib/ui"
+	"github.com/teapotovh/teapot/lib/ui/components"
+	"github.com/teapotovh/teapot/lib/webauth"
+	"github.com/teapotovh/teapot/lib/webhandler"
+	"github.com/teapotovh/teapot/service/files/share"
+)
+
+// publicURL returns the absolute URL of path, as reached by the client of
+// the given request.
+func publicURL(r *http.Request, path string) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return (&url.URL{Scheme: scheme, Host: r.Host, Path: path}).String()
+}
+
+func (web *Web) Shares(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
+	switch r.Method {
+	case http.MethodGet:
+		auth := webauth.GetAuth(r)
+		if auth == nil {
+			return nil, webhandler.NewRedirectError(PathIndex, http.StatusFound)
+		}
+
+		shares, err := web.shares.List(r.Context(), auth.Username)
+		if err != nil {
+			return nil, webhandler.NewInternalError(err, nil)
+		}
+
+		var links []shareLink
+		for _, sh := range shares {
+			links = append(links, shareLink{
+				Share: sh,
+				url:   publicURL(r, PathPublicAt(sh.Token)+sep),
+			})
+		}
+
+		component := sharesList{
+			links: links,
+			now:   time.Now(),
+		}
+
+		return webhandler.NewPage(
+			pagetitle.Title("Shares", App),
+			"Manage the public links to your files",
+			component,
+		), nil
+	}
+
+	return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+}
+
+func (web *Web) SharesRevoke(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
+	switch r.Method {
+	case http.MethodPost:
+		auth := webauth.GetAuth(r)
+		if auth == nil {
+			return nil, webhandler.NewRedirectError(PathIndex, http.StatusFound)
+		}
+
+		token := r.PathValue("token")
+		if err := web.shares.Delete(r.Context(), auth.Username, token); err != nil {
+			if errors.Is(err, share.ErrNotFound) {
+				return nil, webhandler.ErrNotFound
+			}
+
+			return nil, webhandler.NewInternalError(err, nil)
+		}
+
+		return nil, webhandler.NewRedirectError(PathShares, http.StatusFound)
+	}
+
+	return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+}
+
+type shareLink struct {
+	share.Share
+
+	url string
+}
+
+type sharesList struct {
+	links []shareLink
+	now   time.Time
+}
+
+var sharesTitleStyle = ui.MustParseStyle(`
+	font-size: var(--font-size-2);
+	padding: var(--size-3) var(--size-2);
+`)
+
+var sharesStyle = ui.MustParseStyle(`
+	display: grid;
+	padding: 0 var(--size-2);
+	width: 100%;
+  grid-template-columns: 10fr 5fr 3fr;
+	gap: var(--size-2);
+	align-items: center;
+
+	& .header {
+		font-weight: var(--font-weight-6);
+	}
+
+	& .link, & .details {
+	  display: none;
+	}
+	@media (min-width: 768px) {
+		grid-template-columns: 6fr 10fr 2fr 4fr 3fr;
+		& .link, & .details {
+	    display: block;
+		}
+	}
+`)
+
+func (sl sharesList) status(link shareLink) string {
+	switch {
+	case link.Expired(sl.now):
+		return "expired"
+	case link.Exhausted():
+		return "exhausted"
+	case link.ExpiresAt.IsZero():
+		return "active"
+	default:
+		return "until " + link.ExpiresAt.Format(time.DateOnly)
+	}
+}
+
+func (sl sharesList) downloads(link shareLink) string {
+	if link.MaxDownloads == 0 {
+		return strconv.Itoa(link.Downloads)
+	}
+
+	return fmt.Sprintf("%d/%d", link.Downloads, link.MaxDownloads)
+}
+
+func (sl sharesList) Render(ctx ui.Context) g.Node {
+	if len(sl.links) == 0 {
+		return h.Div(ctx.Class(sharesTitleStyle),
+			g.Text("You have not shared anything yet. Use the Share button while browsing your files to do so."),
+		)
+	}
+
+	return g.Group{
+		h.Div(ctx.Class(sharesTitleStyle), g.Text("Your public links")),
+
+		h.Section(ctx.Class(sharesStyle),
+			h.Div(h.Class("header"), g.Text("Path")),
+			h.Div(h.Class("header link"), g.Text("Link")),
+			h.Div(h.Class("header details"), g.Text("Downloads")),
+			h.Div(h.Class("header"), g.Text("Status")),
+			h.Div(),
+
+			g.Map(sl.links, func(link shareLink) g.Node {
+				details := link.Mode.String()
+				if link.HasPassword() {
+					details += ", password"
+				}
+
+				return g.Group{
+					h.Div(
+						h.A(hx.Boost("true"), h.Href(PathBrowseAt(filepath.Dir(link.Path))+sep), g.Text(link.Path)),
+						h.Div(h.Class("details"), g.Text(details)),
+					),
+					h.Div(h.Class("link"), h.A(h.Href(link.url), h.Target("_blank"), g.Text(link.url))),
+					h.Div(h.Class("details"), g.Text(sl.downloads(link))),
+					h.Div(g.Text(sl.status(link))),
+					h.Form(
+						hx.Post(PathSharesRevokeOf(link.Token)),
+						hx.Confirm("Revoke the public link to "+link.Path+"?"),
+						components.Button(ctx, h.Type("submit"), g.Text("Revoke")),
+					),
+				}
+			}),
+		),
+	}
+}
+
+// Ensure sharesList implements ui.Component.
+var _ ui.Component = sharesList{}
@@ -0,0 +1,207 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	g "maragu.dev/gomponents"
+	hx "maragu.dev/gomponents-htmx"
+	h "maragu.dev/gomponents/html"
+
+	"github.com/teapotovh/teapot/lib/pagetitle"
+	"github.com/teapotovh/teapot/lib/ui"
+	"github.com/teapotovh/teapot/lib/ui/components"
+	"github.com/teapotovh/teapot/lib/webauth"
+	"github.com/teapotovh/teapot/lib/webhandler"
+	"github.com/teapotovh/teapot/service/files"
+)
+
+const (
+	trashMountID          = "mount"
+	trashItemID           = "id"
+	trashErrorContainerID = "trash-error"
+)
+
+var ErrTrashDisabled = errors.New("trash is disabled for this path")
+
+func (web *Web) Trash(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
+	switch r.Method {
+	case http.MethodGet:
+		auth := webauth.GetAuth(r)
+		if auth == nil {
+			return nil, webhandler.NewRedirectError(PathIndex, http.StatusFound)
+		}
+
+		session, err := web.files.Sesssions().Get(auth.Username)
+		if err != nil {
+			return nil, webhandler.NewInternalError(err, nil)
+		}
+
+		var items []trashEntry
+
+		for mount, trash := range session.Trashes() {
+			mountItems, err := trash.Trash()
+			if err != nil {
+				return nil, webhandler.NewInternalError(err, nil)
+			}
+
+			for _, item := range mountItems {
+				items = append(items, trashEntry{TrashItem: item, mount: mount})
+			}
+		}
+
+		slices.SortFunc(items, func(a, b trashEntry) int {
+			return b.DeletedAt.Compare(a.DeletedAt)
+		})
+
+		return webhandler.NewPage(
+			pagetitle.Title("Trash", App),
+			"Browse and restore your deleted files",
+			trashList{items: items},
+		), nil
+	}
+
+	return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+}
+
+// trashAction applies fn to the trash item referenced by the submitted form.
+func (web *Web) trashAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	fn func(trash *files.TrashFS, id string) error,
+) (ui.Component, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+	}
+
+	auth := webauth.GetAuth(r)
+	if auth == nil {
+		return nil, webhandler.NewRedirectError(PathIndex, http.StatusFound)
+	}
+
+	session, err := web.files.Sesssions().Get(auth.Username)
+	if err != nil {
+		return nil, webhandler.NewInternalError(err, nil)
+	}
+
+	mount := r.FormValue(trashMountID)
+
+	trash, ok := session.Trashes()[mount]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return dialogError{err: fmt.Errorf("invalid mount %q: %w", mount, ErrTrashDisabled)}, nil
+	}
+
+	if err := fn(trash, r.FormValue(trashItemID)); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return dialogError{err: err}, nil
+	}
+
+	return nil, webhandler.NewRedirectError(PathTrash, http.StatusFound)
+}
+
+func (web *Web) TrashRestore(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
+	return web.trashAction(w, r, (*files.TrashFS).Restore)
+}
+
+func (web *Web) TrashDelete(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
+	return web.trashAction(w, r, (*files.TrashFS).Delete)
+}
+
+type trashEntry struct {
+	files.TrashItem
+
+	mount string
+}
+
+type trashList struct {
+	items []trashEntry
+}
+
+var trashStyle = ui.MustParseStyle(`
+	display: grid;
+	padding: 0 var(--size-2);
+	width: 100%;
+  grid-template-columns: 10fr 4fr 4fr;
+	gap: var(--size-2);
+	align-items: center;
+
+	& .header {
+		font-weight: var(--font-weight-6);
+	}
+
+	& .details {
+	  display: none;
+	}
+	& .size {
+	  text-align: right;
+	}
+	& form {
+		display: inline;
+		margin-left: var(--size-2);
+	}
+	@media (min-width: 768px) {
+		grid-template-columns: 10fr 4fr 2fr 5fr;
+		& .details {
+	    display: block;
+		}
+	}
+`)
+
+func (tl trashList) Render(ctx ui.Context) g.Node {
+	if len(tl.items) == 0 {
+		return h.Div(ctx.Class(sharesTitleStyle), g.Text("Your trash is empty."))
+	}
+
+	return g.Group{
+		h.Div(ctx.Class(sharesTitleStyle), g.Text("Deleted files, kept until their retention expires")),
+		h.Div(h.ID(trashErrorContainerID)),
+
+		h.Section(ctx.Class(trashStyle),
+			h.Div(h.Class("header"), g.Text("Path")),
+			h.Div(h.Class("header"), g.Text("Deleted")),
+			h.Div(h.Class("header details size"), g.Text("Size")),
+			h.Div(),
+
+			g.Map(tl.items, func(item trashEntry) g.Node {
+				name := filepath.Join(item.mount, item.Path)
+
+				size := humanize.IBytes(uint64(item.Size)) //nolint:gosec
+				if item.Dir {
+					size = "folder"
+				}
+
+				return g.Group{
+					h.Div(g.Text(name)),
+					h.Div(g.Text(item.DeletedAt.Format(time.DateTime))),
+					h.Div(h.Class("details size"), g.Text(size)),
+					h.Div(
+						trashForm(ctx, PathTrashRestore, item, "Restore "+name+"?", "Restore"),
+						trashForm(ctx, PathTrashDelete, item, "Delete "+name+" forever?", "Delete"),
+					),
+				}
+			}),
+		),
+	}
+}
+
+func trashForm(ctx ui.Context, url string, item trashEntry, confirm, label string) g.Node {
+	return h.Form(
+		hx.Ext("response-targets"),
+		hx.Post(url),
+		hx.Confirm(confirm),
+		g.Attr("hx-target-error", "#"+trashErrorContainerID),
+
+		h.Input(h.Type("hidden"), h.Name(trashMountID), h.Value(item.mount)),
+		h.Input(h.Type("hidden"), h.Name(trashItemID), h.Value(item.ID)),
+		components.Button(ctx, h.Type("submit"), g.Text(label)),
+	)
+}
+
+// Ensure trashList implements ui.Component.
+var _ ui.Component = trashList{}
@@ -0,0 +1,184 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	g "maragu.dev/gomponents"
+	hx "maragu.dev/gomponents-htmx"
+	h "maragu.dev/gomponents/html"
+
+	"github.com/teapotovh/teapot/lib/httphandler"
+	"github.com/teapotovh/teapot/lib/pagetitle"
+	"github.com/teapotovh/teapot/lib/ui"
+	"github.com/teapotovh/teapot/lib/ui/components"
+	"github.com/teapotovh/teapot/lib/webauth"
+	"github.com/teapotovh/teapot/lib/webhandler"
+	"github.com/teapotovh/teapot/service/files"
+)
+
+const (
+	versionsPathID           = "path"
+	versionsVersionID        = "version"
+	versionsErrorContainerID = "versions-error"
+)
+
+func (web *Web) Versions(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
+	switch r.Method {
+	case http.MethodGet:
+		auth := webauth.GetAuth(r)
+		if auth == nil {
+			return nil, webhandler.NewRedirectError(PathIndex, http.StatusFound)
+		}
+
+		path := filepath.Clean(r.PathValue("path"))
+
+		session, err := web.files.Sesssions().Get(auth.Username)
+		if err != nil {
+			return nil, webhandler.NewInternalError(err, nil)
+		}
+
+		var versions []files.Version
+		if trash, rel := session.Trash(path); trash != nil {
+			if versions, err = trash.Versions(rel); err != nil {
+				return nil, webhandler.NewInternalError(err, nil)
+			}
+		}
+
+		component := versionsList{
+			path:     path,
+			versions: versions,
+		}
+
+		return webhandler.NewPage(
+			pagetitle.Title("Versions of "+path, App),
+			"Browse and restore the previous versions of "+path,
+			component,
+		), nil
+	}
+
+	return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+}
+
+func (web *Web) VersionsRestore(w http.ResponseWriter, r *http.Request) (ui.Component, error) {
+	switch r.Method {
+	case http.MethodPost:
+		auth := webauth.GetAuth(r)
+		if auth == nil {
+			return nil, webhandler.NewRedirectError(PathIndex, http.StatusFound)
+		}
+
+		path := filepath.Clean(r.FormValue(versionsPathID))
+
+		session, err := web.files.Sesssions().Get(auth.Username)
+		if err != nil {
+			return nil, webhandler.NewInternalError(err, nil)
+		}
+
+		trash, rel := session.Trash(path)
+		if trash == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: fmt.Errorf("could not restore %q: %w", path, ErrTrashDisabled)}, nil
+		}
+
+		if err := trash.RestoreVersion(rel, r.FormValue(versionsVersionID)); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return dialogError{err: err}, nil
+		}
+
+		return nil, webhandler.NewRedirectError(PathVersionsAt(path), http.StatusFound)
+	}
+
+	return nil, fmt.Errorf("invalid method %q: %w", r.Method, webhandler.ErrBadRequest)
+}
+
+func (web *Web) VersionFile(w http.ResponseWriter, r *http.Request) error {
+	auth := webauth.GetAuth(r)
+	if auth == nil {
+		return httphandler.NewRedirectError(PathIndex, http.StatusFound)
+	}
+
+	path := filepath.Clean(r.PathValue("path"))
+
+	session, err := web.files.Sesssions().Get(auth.Username)
+	if err != nil {
+		return httphandler.NewInternalError(err, nil)
+	}
+
+	trash, rel := session.Trash(path)
+	if trash == nil {
+		return httphandler.ErrNotFound
+	}
+
+	file, err := trash.OpenVersion(rel, r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return httphandler.ErrNotFound
+		}
+
+		return httphandler.NewInternalError(fmt.Errorf("could not read version of file at %q: %w", path, err), nil)
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	if err != nil {
+		return fmt.Errorf("error while writing response version of file at %q: %w", path, err)
+	}
+
+	return nil
+}
+
+type versionsList struct {
+	path     string
+	versions []files.Version
+}
+
+func (vl versionsList) Render(ctx ui.Context) g.Node {
+	title := h.Div(ctx.Class(sharesTitleStyle),
+		g.Text("Previous versions of "),
+		h.A(h.Href(PathFileAt(vl.path)), h.Target("_blank"), g.Text(vl.path)),
+	)
+
+	if len(vl.versions) == 0 {
+		return g.Group{title, h.Div(ctx.Class(sharesTitleStyle), g.Text("This file has no previous versions."))}
+	}
+
+	return g.Group{
+		title,
+		h.Div(h.ID(versionsErrorContainerID)),
+
+		h.Section(ctx.Class(trashStyle),
+			h.Div(h.Class("header"), g.Text("Replaced")),
+			h.Div(h.Class("header"), g.Text("Download")),
+			h.Div(h.Class("header details size"), g.Text("Size")),
+			h.Div(),
+
+			g.Map(vl.versions, func(version files.Version) g.Node {
+				return g.Group{
+					h.Div(g.Text(version.ReplacedAt.Format(time.DateTime))),
+					h.Div(h.A(h.Href(PathVersionFileAt(version.ID, vl.path)), h.Target("_blank"), g.Text("Download"))),
+					h.Div(h.Class("details size"), g.Text(humanize.IBytes(uint64(version.Size)))), //nolint:gosec
+					h.Form(
+						hx.Ext("response-targets"),
+						hx.Post(PathVersionsRestore),
+						hx.Confirm("Replace the current file with this version?"),
+						g.Attr("hx-target-error", "#"+versionsErrorContainerID),
+
+						h.Input(h.Type("hidden"), h.Name(versionsPathID), h.Value(vl.path)),
+						h.Input(h.Type("hidden"), h.Name(versionsVersionID), h.Value(version.ID)),
+						components.Button(ctx, h.Type("submit"), g.Text("Restore")),
+					),
+				}
+			}),
+		),
+	}
+}
+
+// Ensure versionsList implements ui.Component.
+var _ ui.Component = versionsList{}
@@ -15,6 +15,17 @@ const (
 	PathBrowse       = "/browse/"
 	PathBrowseDialog = "/internal/browse/dialog/"
 	PathFile         = "/file/"
+	PathShares       = "/shares/"
+	PathSharesRevoke = "/internal/shares/revoke/"
+	PathPublic       = "/public/"
+	PathPublicFile   = "/public/file/"
+
+	PathTrash           = "/trash/"
+	PathTrashRestore    = "/internal/trash/restore"
+	PathTrashDelete     = "/internal/trash/delete"
+	PathVersions        = "/versions/"
+	PathVersionsRestore = "/internal/versions/restore"
+	PathVersionFile     = "/internal/versions/file/"
 )
 
 func PathBrowseAt(paths ...string) string {
@@ -28,3 +39,23 @@ func PathBrowseDialogOf(dialog string) string {
 func PathFileAt(paths ...string) string {
 	return filepath.Join(append([]string{PathFile}, paths...)...)
 }
+
+func PathSharesRevokeOf(token string) string {
+	return filepath.Join(PathSharesRevoke, token)
+}
+
+func PathPublicAt(token string, paths ...string) string {
+	return filepath.Join(append([]string{PathPublic, token}, paths...)...)
+}
+
+func PathPublicFileAt(token string, paths ...string) string {
+	return filepath.Join(append([]string{PathPublicFile, token}, paths...)...)
+}
+
+func PathVersionsAt(paths ...string) string {
+	return filepath.Join(append([]string{PathVersions}, paths...)...)
+}
+
+func PathVersionFileAt(id string, paths ...string) string {
+	return filepath.Join(append([]string{PathVersionFile, id}, paths...)...)
+}
@@ -33,6 +33,8 @@ func (skeleton skeleton) Render(ctx ui.Context) g.Node {
 	if skeleton.auth != nil {
 		login = g.Group{
 			h.Div(g.Textf("Hi %s!", skeleton.auth.Username)),
+			components.HeaderLink(ctx, hx.Boost("true"), h.Href(PathShares), g.Text("Shares")),
+			components.HeaderLink(ctx, hx.Boost("true"), h.Href(PathTrash), g.Text("Trash")),
 			components.HeaderLink(ctx, h.Href(PathLogout), g.Text("Logout")),
 		}
 	} else {
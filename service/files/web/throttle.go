@@ -0,0 +1,89 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// unlockWindow is the period over which failed attempts at unlocking a
+	// share are counted.
+	unlockWindow = time.Minute
+	// maxUnlockAttempts is how many failed attempts at unlocking are allowed
+	// within unlockWindow, both for each share and for each client address.
+	maxUnlockAttempts = 10
+)
+
+type attempts struct {
+	count int
+	reset time.Time
+}
+
+// throttle counts failed attempts by key over fixed windows, to slow down the
+// guessing of share passwords.
+type throttle struct {
+	mu       sync.Mutex
+	window   time.Duration
+	max      int
+	attempts map[string]attempts
+}
+
+func newThrottle(window time.Duration, maxAttempts int) *throttle {
+	return &throttle{
+		window:   window,
+		max:      maxAttempts,
+		attempts: map[string]attempts{},
+	}
+}
+
+// Allowed reports whether none of the keys has reached the limit of failed
+// attempts.
+func (t *throttle) Allowed(now time.Time, keys ...string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range keys {
+		if a, ok := t.attempts[key]; ok && now.Before(a.reset) && a.count >= t.max {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Failed records a failed attempt for each of the keys.
+func (t *throttle) Failed(now time.Time, keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Drop the expired windows, so that the map only grows with the keys
+	// which failed recently
+	for key, a := range t.attempts {
+		if !now.Before(a.reset) {
+			delete(t.attempts, key)
+		}
+	}
+
+	for _, key := range keys {
+		a, ok := t.attempts[key]
+		if !ok {
+			a = attempts{reset: now.Add(t.window)}
+		}
+
+		a.count++
+		t.attempts[key] = a
+	}
+}
+
+// unlockKeys returns the throttle keys of an attempt at unlocking a share: one
+// for the share and one for the address of the client.
+func unlockKeys(r *http.Request, token string) []string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return []string{"token:" + token, "ip:" + host}
+}
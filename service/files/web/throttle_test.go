@@ -0,0 +1,50 @@
+package web
+
+import (
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	th := newThrottle(time.Minute, 2)
+	now := time.Now()
+
+	th.Failed(now, "a", "b")
+
+	if !th.Allowed(now, "a", "b") {
+		t.Errorf("expected keys below the limit to be allowed")
+	}
+
+	th.Failed(now, "a")
+
+	if th.Allowed(now, "a") || th.Allowed(now, "b", "a") {
+		t.Errorf("expected keys at the limit to be throttled")
+	}
+
+	if !th.Allowed(now, "b", "c") {
+		t.Errorf("expected other keys to be allowed")
+	}
+
+	later := now.Add(time.Minute)
+	if !th.Allowed(later, "a") {
+		t.Errorf("expected keys to be allowed again once the window expired")
+	}
+
+	th.Failed(later, "c")
+
+	if _, ok := th.attempts["a"]; ok {
+		t.Errorf("expected expired windows to be dropped")
+	}
+}
+
+func TestUnlockKeys(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	keys := unlockKeys(r, "token")
+	if !slices.Equal(keys, []string{"token:token", "ip:192.0.2.1"}) {
+		t.Errorf("expected keys for the share and the address, got %v", keys)
+	}
+}
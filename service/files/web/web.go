@@ -10,12 +10,14 @@ import (
 	"github.com/teapotovh/teapot/lib/webauth"
 	"github.com/teapotovh/teapot/lib/webhandler"
 	"github.com/teapotovh/teapot/service/files"
+	"github.com/teapotovh/teapot/service/files/share"
 )
 
 type WebConfig struct {
 	HTTPLog    httplog.HTTPLogConfig
 	WebHandler webhandler.WebHandlerConfig
 	WebAuth    webauth.WebAuthConfig
+	Shares     share.StoreConfig
 }
 
 type Web struct {
@@ -26,6 +28,13 @@ type Web struct {
 	httpLog    *httplog.HTTPLog
 	webHandler *webhandler.WebHandler
 	webAuth    *webauth.WebAuth
+
+	shares share.Store
+	// secure marks the cookies unlocking password protected shares as
+	// HTTPS-only, just like the authentication ones.
+	secure bool
+	// unlocks throttles failed attempts at unlocking password protected shares.
+	unlocks *throttle
 }
 
 func NewWeb(files *files.Files, config WebConfig, logger *slog.Logger) (*Web, error) {
@@ -57,6 +66,11 @@ func NewWeb(files *files.Files, config WebConfig, logger *slog.Logger) (*Web, er
 		return nil, fmt.Errorf("error while constructing webauth: %w", err)
 	}
 
+	shares, err := share.NewStore(config.Shares, logger.With("component", "shares"))
+	if err != nil {
+		return nil, fmt.Errorf("error while constructing share store: %w", err)
+	}
+
 	web := Web{
 		logger: logger,
 
@@ -65,6 +79,10 @@ func NewWeb(files *files.Files, config WebConfig, logger *slog.Logger) (*Web, er
 		httpLog:    httpLog,
 		webHandler: webHandler,
 		webAuth:    webAuth,
+
+		shares:  shares,
+		secure:  config.WebAuth.JWTAuth.Secure,
+		unlocks: newThrottle(unlockWindow, maxUnlockAttempts),
 	}
 
 	return &web, nil
@@ -82,6 +100,19 @@ func (web *Web) Handler(prefix string) http.Handler {
 	mux.Handle(PathBrowseAt("{path...}"), web.webHandler.Adapt(web.Browse))
 	mux.Handle(PathBrowseDialogOf("{dialog}"), web.webHandler.Adapt(web.BrowseDialog))
 	mux.Handle(PathFileAt("{path...}"), web.webHandler.AdaptHTTP(web.File))
+	mux.Handle(PathShares+"{$}", web.webHandler.Adapt(web.Shares))
+	mux.Handle(PathSharesRevokeOf("{token}"), web.webHandler.Adapt(web.SharesRevoke))
+
+	mux.Handle(PathTrash+"{$}", web.webHandler.Adapt(web.Trash))
+	mux.Handle(PathTrashRestore, web.webHandler.Adapt(web.TrashRestore))
+	mux.Handle(PathTrashDelete, web.webHandler.Adapt(web.TrashDelete))
+	mux.Handle(PathVersionsAt("{path...}"), web.webHandler.Adapt(web.Versions))
+	mux.Handle(PathVersionsRestore, web.webHandler.Adapt(web.VersionsRestore))
+	mux.Handle(PathVersionFileAt("{id}", "{path...}"), web.webHandler.AdaptHTTP(web.VersionFile))
+
+	// Public routes serving shared files, which do not require a login
+	mux.Handle(PathPublicAt("{token}", "{path...}"), web.webHandler.Adapt(web.Public))
+	mux.Handle(PathPublicFileAt("{token}", "{path...}"), web.webHandler.AdaptHTTP(web.PublicFile))
 
 	mux.Handle("/{path...}", web.webHandler.Adapt(web.NotFound))
 
@@ -0,0 +1,57 @@
+package webdav
+
+import (
+	"time"
+
+	flag "github.com/spf13/pflag"
+)
+
+func WebDavFlagSet() (*flag.FlagSet, func() WebDavConfig) {
+	fs := flag.NewFlagSet("files/webdav", flag.ExitOnError)
+
+	locksFS, getLocksConfig := LocksFlagSet()
+	fs.AddFlagSet(locksFS)
+
+	return fs, func() WebDavConfig {
+		return WebDavConfig{
+			Locks: getLocksConfig(),
+		}
+	}
+}
+
+func LocksFlagSet() (*flag.FlagSet, func() LocksConfig) {
+	fs := flag.NewFlagSet("files/webdav/locks", flag.ExitOnError)
+
+	timeout := fs.Duration("files-webdav-locks-timeout", time.Minute, "timeout for locks connection setup")
+	typ := fs.String(
+		"files-webdav-locks-type",
+		"mem",
+		"the type of store to keep WebDAV locks in. Options: mem, psql",
+	)
+	psqlURL := fs.String(
+		"files-webdav-locks-psql-url",
+		"",
+		"the URL connection string to connect to the locks store. mandatory for the psql backend",
+	)
+	maxDuration := fs.Duration(
+		"files-webdav-locks-max-duration",
+		time.Hour*24,
+		"the maximum duration of WebDAV locks, including infinite ones",
+	)
+	purgeInterval := fs.Duration(
+		"files-webdav-locks-purge-interval",
+		time.Minute,
+		"how often to purge expired WebDAV locks",
+	)
+
+	return fs, func() LocksConfig {
+		return LocksConfig{
+			Timeout: *timeout,
+			Type:    *typ,
+			URL:     *psqlURL,
+
+			MaxDuration:   *maxDuration,
+			PurgeInterval: *purgeInterval,
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/webdav"
+
+	"github.com/teapotovh/teapot/lib/run"
+)
+
+var ErrInvalidLocksBackend = errors.New("invalid locks backend")
+
+// releaseTimeout bounds how long releasing the locks confirmed for a request,
+// or removing a lock, may take, as this happens after the request context
+// might be done.
+const releaseTimeout = 10 * time.Second
+
+// Locks stores WebDAV locks shared by all requests. Locks are kept in
+// separate namespaces, one per user, as the same name refers to different
+// files for different users. All names are absolute and clean.
+type Locks interface {
+	// Confirm implements webdav.LockSystem.Confirm within a namespace.
+	Confirm(
+		ctx context.Context,
+		namespace string,
+		now time.Time,
+		name0, name1 string,
+		conditions ...webdav.Condition,
+	) (release func(), err error)
+
+	// Create implements webdav.LockSystem.Create within a namespace.
+	Create(ctx context.Context, namespace string, now time.Time, details webdav.LockDetails) (token string, err error)
+
+	// Refresh implements webdav.LockSystem.Refresh within a namespace.
+	Refresh(
+		ctx context.Context,
+		namespace string,
+		now time.Time,
+		token string,
+		duration time.Duration,
+	) (webdav.LockDetails, error)
+
+	// Unlock implements webdav.LockSystem.Unlock within a namespace.
+	Unlock(ctx context.Context, namespace string, now time.Time, token string) error
+
+	// Purge removes all locks expired at the given time, returning how many
+	// locks are still held.
+	Purge(ctx context.Context, now time.Time) (int, error)
+}
+
+type LocksConfig struct {
+	Timeout time.Duration
+	Type    string
+	URL     string
+
+	// MaxDuration caps the duration of all locks, including infinite ones, so
+	// that locks are eventually released even when clients or replicas
+	// holding them are gone.
+	MaxDuration time.Duration
+	// PurgeInterval is how often expired locks are removed.
+	PurgeInterval time.Duration
+}
+
+func NewLocks(config LocksConfig, logger *slog.Logger) (Locks, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	switch config.Type {
+	case "mem":
+		return NewMemLocks(config.MaxDuration), nil
+	case "psql":
+		return NewPSQLLocks(ctx, config.URL, config.MaxDuration, logger)
+	default:
+		return nil, fmt.Errorf("error instantiating locks of type %q: %w", config.Type, ErrInvalidLocksBackend)
+	}
+}
+
+// newLockToken returns a new lock token, as an absolute URI.
+func newLockToken() string {
+	return "urn:uuid:" + uuid.NewString()
+}
+
+// lockExpiry returns when a lock of the given duration expires, capped to
+// maxDuration. Negative durations stand for infinite locks.
+func lockExpiry(now time.Time, duration, maxDuration time.Duration) time.Time {
+	if duration < 0 || duration > maxDuration {
+		duration = maxDuration
+	}
+
+	return now.Add(duration)
+}
+
+// lockCovers reports whether a lock on root covers the resource name.
+func lockCovers(root string, zeroDepth bool, name string) bool {
+	return root == name || (!zeroDepth && (root == "/" || strings.HasPrefix(name, root+"/")))
+}
+
+// lockConflicts reports whether a new lock on name conflicts with an existing
+// lock on root. Locks conflict when either covers the root of the other.
+func lockConflicts(root string, zeroDepth bool, name string, newZeroDepth bool) bool {
+	return lockCovers(root, zeroDepth, name) || lockCovers(name, newZeroDepth, root)
+}
+
+// conditionTokens returns the lock tokens claimed by the conditions of a
+// request. Negated conditions claim no lock.
+func conditionTokens(conditions []webdav.Condition) []string {
+	var tokens []string
+
+	for _, condition := range conditions {
+		if !condition.Not && condition.Token != "" {
+			tokens = append(tokens, condition.Token)
+		}
+	}
+
+	return tokens
+}
+
+func slashClean(name string) string {
+	if name == "" {
+		return ""
+	}
+
+	return path.Clean("/" + name)
+}
+
+// LockSystem hands out the webdav.LockSystem of each request, and purges
+// expired locks periodically.
+type LockSystem struct {
+	logger *slog.Logger
+
+	locks         Locks
+	purgeInterval time.Duration
+
+	metrics metrics
+}
+
+func NewLockSystem(config LocksConfig, logger *slog.Logger) (*LockSystem, error) {
+	locks, err := NewLocks(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	ls := LockSystem{
+		logger: logger,
+
+		locks:         locks,
+		purgeInterval: config.PurgeInterval,
+	}
+	ls.initMetrics()
+
+	return &ls, nil
+}
+
+// For returns the webdav.LockSystem for a request of the given user.
+func (ls *LockSystem) For(ctx context.Context, username string) webdav.LockSystem {
+	return &namespacedLockSystem{ctx: ctx, ls: ls, namespace: username}
+}
+
+// Run implements run.Runnable.
+func (ls *LockSystem) Run(ctx context.Context, notify run.Notify) error {
+	ticker := time.NewTicker(ls.purgeInterval)
+	defer ticker.Stop()
+
+	notify.Notify()
+
+	for {
+		ls.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ls *LockSystem) purge(ctx context.Context) {
+	held, err := ls.locks.Purge(ctx, time.Now())
+	if err != nil {
+		ls.logger.ErrorContext(ctx, "error while purging expired WebDAV locks", "err", err)
+		return
+	}
+
+	ls.metrics.held.Set(float64(held))
+}
+
+// observe records the outcome of a lock operation.
+func (ls *LockSystem) observe(operation string, err error) {
+	status := metricsStatusSuccess
+
+	switch {
+	case err == nil:
+	case errors.Is(err, webdav.ErrConfirmationFailed),
+		errors.Is(err, webdav.ErrLocked),
+		errors.Is(err, webdav.ErrNoSuchLock),
+		errors.Is(err, webdav.ErrForbidden):
+		status = metricsStatusRejected
+	default:
+		status = metricsStatusError
+	}
+
+	ls.metrics.operations.WithLabelValues(operation, status).Inc()
+}
+
+// namespacedLockSystem is the webdav.LockSystem of a single request, bound
+// to the namespace of the user making it.
+type namespacedLockSystem struct {
+	ctx       context.Context
+	ls        *LockSystem
+	namespace string
+}
+
+// Confirm implements webdav.LockSystem.
+func (nls *namespacedLockSystem) Confirm(
+	now time.Time,
+	name0, name1 string,
+	conditions ...webdav.Condition,
+) (func(), error) {
+	release, err := nls.ls.locks.Confirm(
+		nls.ctx,
+		nls.namespace,
+		now,
+		slashClean(name0),
+		slashClean(name1),
+		conditions...,
+	)
+	nls.ls.observe(metricsOperationConfirm, err)
+
+	return release, err
+}
+
+// Create implements webdav.LockSystem.
+func (nls *namespacedLockSystem) Create(now time.Time, details webdav.LockDetails) (string, error) {
+	details.Root = slashClean(details.Root)
+
+	token, err := nls.ls.locks.Create(nls.ctx, nls.namespace, now, details)
+	nls.ls.observe(metricsOperationCreate, err)
+
+	return token, err
+}
+
+// Refresh implements webdav.LockSystem.
+func (nls *namespacedLockSystem) Refresh(
+	now time.Time,
+	token string,
+	duration time.Duration,
+) (webdav.LockDetails, error) {
+	details, err := nls.ls.locks.Refresh(nls.ctx, nls.namespace, now, token, duration)
+	nls.ls.observe(metricsOperationRefresh, err)
+
+	return details, err
+}
+
+// Unlock implements webdav.LockSystem. Locks are removed even once the request
+// context is done, as the handler also unlocks to clean up after failing to
+// create the locked resource, and a leftover lock blocks the resource until it
+// expires.
+func (nls *namespacedLockSystem) Unlock(now time.Time, token string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(nls.ctx), releaseTimeout)
+	defer cancel()
+
+	err := nls.ls.locks.Unlock(ctx, nls.namespace, now, token)
+	nls.ls.observe(metricsOperationUnlock, err)
+
+	return err
+}
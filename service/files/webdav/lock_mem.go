@@ -0,0 +1,195 @@
+package webdav
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+type memLock struct {
+	namespace string
+	details   webdav.LockDetails
+	expiry    time.Time
+	held      bool
+}
+
+// MemLocks keeps locks in memory, and thus shares them across the requests
+// handled by a single replica.
+type MemLocks struct {
+	mu sync.Mutex
+
+	maxDuration time.Duration
+	locks       map[string]*memLock
+}
+
+func NewMemLocks(maxDuration time.Duration) *MemLocks {
+	return &MemLocks{
+		maxDuration: maxDuration,
+		locks:       map[string]*memLock{},
+	}
+}
+
+// purge removes expired locks. The caller must hold the mutex.
+func (m *MemLocks) purge(now time.Time) {
+	for token, lock := range m.locks {
+		if !lock.expiry.After(now) {
+			delete(m.locks, token)
+		}
+	}
+}
+
+// lookup returns the first lock among the given tokens which is not held and
+// covers name.
+func (m *MemLocks) lookup(namespace, name string, tokens []string) *memLock {
+	for _, token := range tokens {
+		lock, ok := m.locks[token]
+		if !ok || lock.namespace != namespace || lock.held {
+			continue
+		}
+
+		if lockCovers(lock.details.Root, lock.details.ZeroDepth, name) {
+			return lock
+		}
+	}
+
+	return nil
+}
+
+// Confirm implements Locks.
+func (m *MemLocks) Confirm(
+	_ context.Context,
+	namespace string,
+	now time.Time,
+	name0, name1 string,
+	conditions ...webdav.Condition,
+) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purge(now)
+
+	tokens := conditionTokens(conditions)
+
+	var lock0, lock1 *memLock
+
+	if name0 != "" {
+		if lock0 = m.lookup(namespace, name0, tokens); lock0 == nil {
+			return nil, webdav.ErrConfirmationFailed
+		}
+	}
+
+	if name1 != "" {
+		if lock1 = m.lookup(namespace, name1, tokens); lock1 == nil {
+			return nil, webdav.ErrConfirmationFailed
+		}
+	}
+
+	held := []*memLock{}
+	for _, lock := range []*memLock{lock0, lock1} {
+		if lock != nil && !lock.held {
+			lock.held = true
+			held = append(held, lock)
+		}
+	}
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		for _, lock := range held {
+			lock.held = false
+		}
+	}, nil
+}
+
+// Create implements Locks.
+func (m *MemLocks) Create(
+	_ context.Context,
+	namespace string,
+	now time.Time,
+	details webdav.LockDetails,
+) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purge(now)
+
+	for _, lock := range m.locks {
+		if lock.namespace == namespace &&
+			lockConflicts(lock.details.Root, lock.details.ZeroDepth, details.Root, details.ZeroDepth) {
+			return "", webdav.ErrLocked
+		}
+	}
+
+	token := newLockToken()
+	m.locks[token] = &memLock{
+		namespace: namespace,
+		details:   details,
+		expiry:    lockExpiry(now, details.Duration, m.maxDuration),
+	}
+
+	return token, nil
+}
+
+// Refresh implements Locks.
+func (m *MemLocks) Refresh(
+	_ context.Context,
+	namespace string,
+	now time.Time,
+	token string,
+	duration time.Duration,
+) (webdav.LockDetails, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purge(now)
+
+	lock, ok := m.locks[token]
+	if !ok || lock.namespace != namespace {
+		return webdav.LockDetails{}, webdav.ErrNoSuchLock
+	}
+
+	if lock.held {
+		return webdav.LockDetails{}, webdav.ErrLocked
+	}
+
+	lock.details.Duration = duration
+	lock.expiry = lockExpiry(now, duration, m.maxDuration)
+
+	return lock.details, nil
+}
+
+// Unlock implements Locks.
+func (m *MemLocks) Unlock(_ context.Context, namespace string, now time.Time, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purge(now)
+
+	lock, ok := m.locks[token]
+	if !ok || lock.namespace != namespace {
+		return webdav.ErrNoSuchLock
+	}
+
+	if lock.held {
+		return webdav.ErrLocked
+	}
+
+	delete(m.locks, token)
+
+	return nil
+}
+
+// Purge implements Locks.
+func (m *MemLocks) Purge(_ context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.purge(now)
+
+	return len(m.locks), nil
+}
+
+var _ Locks = &MemLocks{}
@@ -0,0 +1,373 @@
+package webdav
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"git.sr.ht/~bitfehler/brant"
+	"git.sr.ht/~bitfehler/brant/database/dialect"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/net/webdav"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// HoldTimeout is the longest a request may hold the locks it confirmed.
+// Holds outliving it are considered abandoned, e.g., by a replica which
+// crashed while serving a request.
+const HoldTimeout = time.Hour
+
+//go:embed migrations/*.sql
+var migraitions embed.FS
+
+// PSQLLocks keeps locks in Postgres, and thus shares them across replicas.
+// Operations on the locks of a namespace are serialized with an advisory
+// lock, so that conflicting locks are never created concurrently.
+type PSQLLocks struct {
+	logger *slog.Logger
+
+	pool        *pgxpool.Pool
+	maxDuration time.Duration
+}
+
+func NewPSQLLocks(ctx context.Context, url string, maxDuration time.Duration, logger *slog.Logger) (*PSQLLocks, error) {
+	// The locks may live in the same database as other stores, so they keep
+	// track of their migrations in a table of their own.
+	options := brant.DefaultOptions().
+		WithTableName("_version_webdav_locks").
+		WithFilesystem(migraitions).
+		WithDataSourceName(url)
+
+	provider, err := brant.NewProvider(logger, dialect.Postgres, options)
+	if err != nil {
+		return nil, fmt.Errorf("error while constructing migration provider: %w", err)
+	}
+
+	applied, err := provider.Up(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while applying migrations: %w", err)
+	}
+
+	for _, migration := range applied {
+		logger.Info("applied migration", "migration", migration)
+	}
+
+	if err := provider.Close(); err != nil {
+		return nil, fmt.Errorf("error while closing migration connection: %w", err)
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing psql connection URL: %w", err)
+	}
+
+	config.MaxConns = 4
+	config.MinConns = 1
+
+	// Use context.Background() here, as we want the pool to live for the lifetime
+	// of the program, while the provided context is only meant for databse initialization.
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, fmt.Errorf("error while opening connection pool to psql: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("error while connecting to psql: %w", err)
+	}
+
+	return &PSQLLocks{
+		logger: logger,
+
+		pool:        pool,
+		maxDuration: maxDuration,
+	}, nil
+}
+
+var (
+	advisoryLockQuery   = `SELECT pg_advisory_xact_lock(hashtext($1));`
+	purgeNamespaceQuery = `DELETE FROM webdav_locks WHERE namespace = $1 AND expires_at <= $2;`
+)
+
+// inTx runs fn in a transaction holding exclusive access to the locks of a
+// namespace, after purging its expired locks. Errors returned by fn are
+// passed through as they are, as the WebDAV handler compares them directly.
+func (p *PSQLLocks) inTx(ctx context.Context, namespace string, now time.Time, fn func(tx pgx.Tx) error) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error while starting transaction: %w", err)
+	}
+
+	// Rolling back a committed transaction is a no-op
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, advisoryLockQuery, namespace); err != nil {
+		return fmt.Errorf("error while locking namespace %q: %w", namespace, err)
+	}
+
+	if _, err := tx.Exec(ctx, purgeNamespaceQuery, namespace, now); err != nil {
+		return fmt.Errorf("error while purging expired locks in namespace %q: %w", namespace, err)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error while committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+var lookupQuery = `
+		SELECT token
+		FROM webdav_locks
+		WHERE namespace = $1
+			AND token = ANY($2)
+			AND (held_until IS NULL OR held_until <= $3)
+			AND (root = $4 OR (NOT zero_depth AND (root = '/' OR starts_with($4, root || '/'))))
+		LIMIT 1;
+`
+
+var (
+	holdQuery    = `UPDATE webdav_locks SET held_until = $2 WHERE token = ANY($1);`
+	releaseQuery = `UPDATE webdav_locks SET held_until = NULL WHERE token = ANY($1) AND held_until = $2;`
+)
+
+// Confirm implements Locks.
+func (p *PSQLLocks) Confirm(
+	ctx context.Context,
+	namespace string,
+	now time.Time,
+	name0, name1 string,
+	conditions ...webdav.Condition,
+) (func(), error) {
+	tokens := conditionTokens(conditions)
+	// Timestamps are stored with microsecond precision, and the release must
+	// match the hold exactly.
+	heldUntil := now.Add(HoldTimeout).Truncate(time.Microsecond)
+
+	var held []string
+
+	err := p.inTx(ctx, namespace, now, func(tx pgx.Tx) error {
+		for _, name := range []string{name0, name1} {
+			if name == "" {
+				continue
+			}
+
+			var token string
+
+			err := tx.QueryRow(ctx, lookupQuery, namespace, tokens, now, name).Scan(&token)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return webdav.ErrConfirmationFailed
+			} else if err != nil {
+				return fmt.Errorf("error while looking up lock for %q in psql: %w", name, err)
+			}
+
+			if !slices.Contains(held, token) {
+				held = append(held, token)
+			}
+		}
+
+		if len(held) == 0 {
+			return nil
+		}
+
+		if _, err := tx.Exec(ctx, holdQuery, held, heldUntil); err != nil {
+			return fmt.Errorf("error while holding locks in psql: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if len(held) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+		defer cancel()
+
+		if _, err := p.pool.Exec(ctx, releaseQuery, held, heldUntil); err != nil {
+			p.logger.ErrorContext(ctx, "error while releasing locks in psql", "tokens", held, "err", err)
+		}
+	}, nil
+}
+
+var conflictQuery = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM webdav_locks
+			WHERE namespace = $1 AND (
+				root = $2
+				OR (NOT zero_depth AND (root = '/' OR starts_with($2, root || '/')))
+				OR (NOT $3 AND ($2 = '/' OR starts_with(root, $2 || '/')))
+			)
+		);
+`
+
+var createQuery = `
+		INSERT INTO webdav_locks (token, namespace, root, zero_depth, owner_xml, duration, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);
+`
+
+// Create implements Locks.
+func (p *PSQLLocks) Create(
+	ctx context.Context,
+	namespace string,
+	now time.Time,
+	details webdav.LockDetails,
+) (string, error) {
+	token := newLockToken()
+
+	err := p.inTx(ctx, namespace, now, func(tx pgx.Tx) error {
+		var conflict bool
+		err := tx.QueryRow(ctx, conflictQuery, namespace, details.Root, details.ZeroDepth).Scan(&conflict)
+		if err != nil {
+			return fmt.Errorf("error while checking conflicting locks for %q in psql: %w", details.Root, err)
+		}
+
+		if conflict {
+			return webdav.ErrLocked
+		}
+
+		_, err = tx.Exec(
+			ctx,
+			createQuery,
+			token,
+			namespace,
+			details.Root,
+			details.ZeroDepth,
+			details.OwnerXML,
+			int64(details.Duration),
+			lockExpiry(now, details.Duration, p.maxDuration),
+		)
+		if err != nil {
+			return fmt.Errorf("error while creating lock for %q in psql: %w", details.Root, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+var getQuery = `
+		SELECT root, zero_depth, owner_xml, held_until
+		FROM webdav_locks
+		WHERE namespace = $1 AND token = $2;
+`
+
+// get returns the details of a lock, failing if the lock is currently held.
+func (p *PSQLLocks) get(
+	ctx context.Context,
+	tx pgx.Tx,
+	namespace string,
+	now time.Time,
+	token string,
+) (webdav.LockDetails, error) {
+	var (
+		details   webdav.LockDetails
+		heldUntil *time.Time
+	)
+
+	err := tx.QueryRow(ctx, getQuery, namespace, token).Scan(
+		&details.Root,
+		&details.ZeroDepth,
+		&details.OwnerXML,
+		&heldUntil,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return details, webdav.ErrNoSuchLock
+	} else if err != nil {
+		return details, fmt.Errorf("error while getting lock %q from psql: %w", token, err)
+	}
+
+	if heldUntil != nil && heldUntil.After(now) {
+		return details, webdav.ErrLocked
+	}
+
+	return details, nil
+}
+
+var refreshQuery = `UPDATE webdav_locks SET duration = $2, expires_at = $3 WHERE token = $1;`
+
+// Refresh implements Locks.
+func (p *PSQLLocks) Refresh(
+	ctx context.Context,
+	namespace string,
+	now time.Time,
+	token string,
+	duration time.Duration,
+) (details webdav.LockDetails, err error) {
+	err = p.inTx(ctx, namespace, now, func(tx pgx.Tx) error {
+		details, err = p.get(ctx, tx, namespace, now, token)
+		if err != nil {
+			return err
+		}
+
+		details.Duration = duration
+
+		_, err = tx.Exec(ctx, refreshQuery, token, int64(duration), lockExpiry(now, duration, p.maxDuration))
+		if err != nil {
+			return fmt.Errorf("error while refreshing lock %q in psql: %w", token, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return webdav.LockDetails{}, err
+	}
+
+	return details, nil
+}
+
+var deleteQuery = `DELETE FROM webdav_locks WHERE token = $1;`
+
+// Unlock implements Locks.
+func (p *PSQLLocks) Unlock(ctx context.Context, namespace string, now time.Time, token string) error {
+	return p.inTx(ctx, namespace, now, func(tx pgx.Tx) error {
+		if _, err := p.get(ctx, tx, namespace, now, token); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, deleteQuery, token); err != nil {
+			return fmt.Errorf("error while deleting lock %q in psql: %w", token, err)
+		}
+
+		return nil
+	})
+}
+
+var (
+	purgeQuery = `DELETE FROM webdav_locks WHERE expires_at <= $1;`
+	countQuery = `SELECT COUNT(*) FROM webdav_locks;`
+)
+
+// Purge implements Locks.
+func (p *PSQLLocks) Purge(ctx context.Context, now time.Time) (int, error) {
+	if _, err := p.pool.Exec(ctx, purgeQuery, now); err != nil {
+		return 0, fmt.Errorf("error while purging expired locks in psql: %w", err)
+	}
+
+	var count int
+	if err := p.pool.QueryRow(ctx, countQuery).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error while counting locks in psql: %w", err)
+	}
+
+	return count, nil
+}
+
+var _ Locks = &PSQLLocks{}
@@ -0,0 +1,55 @@
+package webdav
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// cancellableLocks fails operations on done contexts, as the psql backend
+// does when it cannot start a transaction.
+type cancellableLocks struct {
+	*MemLocks
+}
+
+func (c cancellableLocks) Unlock(ctx context.Context, namespace string, now time.Time, token string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("error while starting transaction: %w", err)
+	}
+
+	return c.MemLocks.Unlock(ctx, namespace, now, token)
+}
+
+func TestUnlockCancelledContext(t *testing.T) {
+	ls, err := NewLockSystem(LocksConfig{Type: "mem", MaxDuration: time.Hour}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("error creating lock system: %s", err)
+	}
+
+	ls.locks = cancellableLocks{MemLocks: NewMemLocks(time.Hour)}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	nls := ls.For(ctx, "alice")
+	now := time.Now()
+	details := webdav.LockDetails{Root: "/file", Duration: time.Minute}
+
+	token, err := nls.Create(now, details)
+	if err != nil {
+		t.Fatalf("error creating lock: %s", err)
+	}
+
+	// The request is gone by the time the handler cleans up its lock
+	cancel()
+
+	if err := nls.Unlock(now, token); err != nil {
+		t.Fatalf("expected unlocking to outlive the request context, got %s", err)
+	}
+
+	if _, err := ls.For(t.Context(), "alice").Create(now, details); err != nil {
+		t.Errorf("expected the lock to be removed, got %s", err)
+	}
+}
@@ -0,0 +1,50 @@
+package webdav
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	held       prometheus.Gauge
+	operations *prometheus.CounterVec
+}
+
+const namespace = "files"
+
+const (
+	metricsStatusSuccess  = "success"
+	metricsStatusRejected = "rejected"
+	metricsStatusError    = "error"
+
+	metricsOperationConfirm = "confirm"
+	metricsOperationCreate  = "create"
+	metricsOperationRefresh = "refresh"
+	metricsOperationUnlock  = "unlock"
+)
+
+func (ls *LockSystem) initMetrics() {
+	ls.metrics.held = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "webdav_locks_held",
+			Help:      "Current number of unexpired WebDAV locks, as of the last purge",
+		},
+	)
+
+	ls.metrics.operations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webdav_lock_operations_total",
+			Help:      "Total number of WebDAV lock operations",
+		},
+		[]string{"operation", "status"},
+	)
+}
+
+// Metrics implements observability.Metrics.
+func (ls *LockSystem) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		ls.metrics.held,
+		ls.metrics.operations,
+	}
+}
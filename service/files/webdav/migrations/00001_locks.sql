@@ -0,0 +1,17 @@
+-- +brant Up
+CREATE TABLE webdav_locks (
+  token TEXT PRIMARY KEY,
+  namespace TEXT NOT NULL,
+  root TEXT NOT NULL,
+  zero_depth BOOLEAN NOT NULL,
+  owner_xml TEXT NOT NULL,
+  duration BIGINT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  held_until TIMESTAMPTZ
+);
+
+CREATE INDEX webdav_locks_namespace_root ON webdav_locks (namespace, root);
+CREATE INDEX webdav_locks_expires_at ON webdav_locks (expires_at);
+
+-- +brant Down
+DROP TABLE webdav_locks;
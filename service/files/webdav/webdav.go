@@ -1,6 +1,8 @@
 package webdav
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 
@@ -9,6 +11,7 @@ import (
 	"golang.org/x/net/webdav"
 
 	"github.com/teapotovh/teapot/lib/httpauth"
+	"github.com/teapotovh/teapot/lib/run"
 	"github.com/teapotovh/teapot/service/files"
 )
 
@@ -17,13 +20,21 @@ type WebDav struct {
 
 	files    *files.Files
 	httpAuth *httpauth.BasicAuth
+	locks    *LockSystem
 
 	cors *cors.Cors
 }
 
-type WebDavConfig struct{}
+type WebDavConfig struct {
+	Locks LocksConfig
+}
 
 func NewWebDav(files *files.Files, config WebDavConfig, logger *slog.Logger) (*WebDav, error) {
+	locks, err := NewLockSystem(config.Locks, logger.With("component", "locks"))
+	if err != nil {
+		return nil, fmt.Errorf("error while building WebDAV lock system: %w", err)
+	}
+
 	cors := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedMethods: []string{
@@ -46,6 +57,7 @@ func NewWebDav(files *files.Files, config WebDavConfig, logger *slog.Logger) (*W
 			httpauth.DefaultBasicAuthErrorHandler,
 			logger.With("component", "auth"),
 		),
+		locks: locks,
 
 		cors: cors,
 	}
@@ -71,8 +83,7 @@ func (wd *WebDav) Handler(prefix string) http.Handler {
 		handler := &webdav.Handler{
 			Prefix:     prefix,
 			FileSystem: fs,
-			// TODO: implement a custom locking system
-			LockSystem: webdav.NewMemLS(),
+			LockSystem: wd.locks.For(r.Context(), auth.Username),
 			Logger: func(r *http.Request, err error) {
 				handlerLogger.Error(
 					"error while handling WebDav request",
@@ -96,9 +107,12 @@ func (wd *WebDav) Handler(prefix string) http.Handler {
 	return handler
 }
 
+// Run implements run.Runnable.
+func (wd *WebDav) Run(ctx context.Context, notify run.Notify) error {
+	return wd.locks.Run(ctx, notify)
+}
+
 // Metrics implements observability.Metrics.
 func (wd *WebDav) Metrics() []prometheus.Collector {
-	return []prometheus.Collector{
-		// TODO: define/re-export metrics for this module
-	}
+	return wd.locks.Metrics()
 }